@@ -0,0 +1,565 @@
+package zetabase
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	BackupFormatVersion    = 1
+	DefaultBackupChunkSize = 500
+	backupManifestFile     = "manifest.json"
+	backupTablesDir        = "tables"
+	backupRestoreStateFile = ".restore-state.json"
+	maxBackupChainLength   = 1000
+)
+
+// Type BackupManifest describes a backup directory. It is written last, so a directory without a
+// manifest holds an incomplete backup.
+type BackupManifest struct {
+	FormatVersion int       `json:"format_version"`
+	ClientVersion string    `json:"client_version"`
+	TableOwnerId  string    `json:"table_owner_id"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	// Path (relative to this backup) of the backup this one is incremental to; empty for full backups
+	Parent string                 `json:"parent,omitempty"`
+	Tables []*BackupTableManifest `json:"tables"`
+}
+
+// Type BackupTableManifest describes the files backing up a single table.
+type BackupTableManifest struct {
+	TableId    string     `json:"table_id"`
+	Definition BackupFile `json:"definition"`
+	// All keys present when the table was backed up, used to compute the next incremental backup
+	Keys            BackupFile   `json:"keys"`
+	KeyCount        int          `json:"key_count"`
+	NewKeyCount     int          `json:"new_key_count"`
+	DeletedKeyCount int          `json:"deleted_key_count"`
+	Chunks          []BackupFile `json:"chunks"`
+}
+
+// Type BackupFile is a checksummed file in a backup directory.
+type BackupFile struct {
+	Path    string `json:"path"`
+	Sha256  string `json:"sha256"`
+	Records int    `json:"records,omitempty"`
+}
+
+// Type BackupOptions configures Backup.
+type BackupOptions struct {
+	Tables    []string // tables to back up (default: all tables of the owner)
+	Parent    string   // previous backup directory, for an incremental backup
+	ChunkSize int      // records per chunk file
+}
+
+// Type RestoreOptions configures Restore.
+type RestoreOptions struct {
+	Tables       []string          // tables to restore (default: all tables in the backup)
+	TableOwnerId string            // owner to restore into (default: the client's own ID)
+	TableNames   map[string]string // target table ID for each backed-up table ID (default: unchanged)
+	// Replace the permissions of tables that already exist with those from the backup
+	ReapplyPermissions bool
+	// Ignore the progress saved by an earlier, interrupted restore
+	NoResume bool
+	// Where restore progress is saved (default: a file in the backup directory)
+	StateFile string
+}
+
+func (m *BackupManifest) table(tableId string) *BackupTableManifest {
+	for _, t := range m.Tables {
+		if t.TableId == tableId {
+			return t
+		}
+	}
+	return nil
+}
+
+func sha256Hex(bs []byte) string {
+	h := sha256.Sum256(bs)
+	return hex.EncodeToString(h[:])
+}
+
+func writeBackupFile(dir, rel string, bs []byte, records int) (BackupFile, error) {
+	fn := filepath.Join(dir, filepath.FromSlash(rel))
+	err := os.MkdirAll(filepath.Dir(fn), 0755)
+	if err != nil {
+		return BackupFile{}, err
+	}
+	err = WriteFileAtomic(fn, bs, 0600)
+	if err != nil {
+		return BackupFile{}, err
+	}
+	return BackupFile{Path: rel, Sha256: sha256Hex(bs), Records: records}, nil
+}
+
+// Read a file from a backup directory, checking its checksum
+func readBackupFile(dir string, f BackupFile) ([]byte, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(bs) != f.Sha256 {
+		return nil, fmt.Errorf("ChecksumMismatch: %s", filepath.Join(dir, f.Path))
+	}
+	return bs, nil
+}
+
+func encodeKeyList(keys []string) []byte {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		bs, _ := json.Marshal(k)
+		buf.Write(bs)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func decodeKeyList(bs []byte) ([]string, error) {
+	var keys []string
+	sc := bufio.NewScanner(bytes.NewReader(bs))
+	sc.Buffer(make([]byte, 64*1024), maxNdjsonLineBytes)
+	for sc.Scan() {
+		var k string
+		if err := json.Unmarshal(sc.Bytes(), &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, sc.Err()
+}
+
+// Function ReadBackupManifest reads the manifest of the backup in dir.
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, backupManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("BackupIncompleteOrMissing")
+		}
+		return nil, err
+	}
+	var m BackupManifest
+	err = json.Unmarshal(bs, &m)
+	if err != nil {
+		return nil, err
+	}
+	if m.FormatVersion > BackupFormatVersion {
+		return nil, errors.New("UnsupportedBackupFormat")
+	}
+	return &m, nil
+}
+
+// Follow the parents of the backup in dir, returning directories and manifests oldest first
+func backupChain(dir string) ([]string, []*BackupManifest, error) {
+	var dirs []string
+	var ms []*BackupManifest
+	for {
+		m, err := ReadBackupManifest(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		dirs = append([]string{dir}, dirs...)
+		ms = append([]*BackupManifest{m}, ms...)
+		if len(m.Parent) == 0 {
+			return dirs, ms, nil
+		} else if len(dirs) >= maxBackupChainLength {
+			return nil, nil, errors.New("BackupChainTooLong")
+		}
+		dir = filepath.Join(dir, filepath.FromSlash(m.Parent))
+	}
+}
+
+// Function VerifyBackup checks the checksums of all files of the backup in dir, including those of
+// the backups it is incremental to.
+func VerifyBackup(dir string) error {
+	dirs, ms, err := backupChain(dir)
+	if err != nil {
+		return err
+	}
+	for i, m := range ms {
+		for _, t := range m.Tables {
+			files := append([]BackupFile{t.Definition, t.Keys}, t.Chunks...)
+			for _, f := range files {
+				if _, err := readBackupFile(dirs[i], f); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func tableBackupDir(tableId string) string {
+	return backupTablesDir + "/" + url.PathEscape(tableId)
+}
+
+// Method Backup writes a backup of the tables of tableOwnerId to directory dir. If opts.Parent
+// names an earlier backup, only keys that did not exist at the time of that backup are stored
+// (values overwritten under existing keys are not picked up by incremental backups).
+func (z *ZetabaseClient) Backup(tableOwnerId, dir string, opts *BackupOptions) (*BackupManifest, error) {
+	var o BackupOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultBackupChunkSize
+	}
+	if _, err := os.Stat(filepath.Join(dir, backupManifestFile)); err == nil {
+		return nil, errors.New("BackupExists")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	m := &BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		ClientVersion: ClientVersion,
+		TableOwnerId:  tableOwnerId,
+		StartedAt:     time.Now().UTC(),
+	}
+	var parentDir string
+	var parent *BackupManifest
+	if len(o.Parent) > 0 {
+		p, err := ReadBackupManifest(o.Parent)
+		if err != nil {
+			return nil, err
+		}
+		if p.TableOwnerId != tableOwnerId {
+			return nil, errors.New("ParentBackupOwnerMismatch")
+		}
+		absDir, err1 := filepath.Abs(dir)
+		absParent, err2 := filepath.Abs(o.Parent)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("InvalidBackupPath")
+		}
+		rel, err := filepath.Rel(absDir, absParent)
+		if err != nil {
+			return nil, err
+		}
+		m.Parent = filepath.ToSlash(rel)
+		parentDir, parent = o.Parent, p
+	}
+
+	defns, err := z.ListTableDefinitions(tableOwnerId)
+	if err != nil {
+		return nil, err
+	}
+	for _, want := range o.Tables {
+		found := false
+		for _, d := range defns {
+			found = found || d.GetTableId() == want
+		}
+		if !found {
+			return nil, fmt.Errorf("TableNotFound: %s", want)
+		}
+	}
+	for _, defn := range defns {
+		if len(o.Tables) > 0 && !containsString(o.Tables, defn.GetTableId()) {
+			continue
+		}
+		var prevKeys []string
+		if parent != nil {
+			if pt := parent.table(defn.GetTableId()); pt != nil {
+				bs, err := readBackupFile(parentDir, pt.Keys)
+				if err != nil {
+					return nil, err
+				}
+				prevKeys, err = decodeKeyList(bs)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		tm, err := z.backupTable(tableOwnerId, dir, defn, prevKeys, o.ChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		tm.Definition, err = func() (BackupFile, error) {
+			bs, err := marshalTableDefinition(defn)
+			if err != nil {
+				return BackupFile{}, err
+			}
+			return writeBackupFile(dir, tableBackupDir(defn.GetTableId())+"/"+snapshotDefinitionFile, bs, 0)
+		}()
+		if err != nil {
+			return nil, err
+		}
+		m.Tables = append(m.Tables, tm)
+	}
+
+	m.FinishedAt = time.Now().UTC()
+	bs, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	err = WriteFileAtomic(filepath.Join(dir, backupManifestFile), bs, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (z *ZetabaseClient) backupTable(tableOwnerId, dir string, defn *zbprotocol.TableCreate, prevKeys []string, chunkSize int) (*BackupTableManifest, error) {
+	tableId := defn.GetTableId()
+	tdir := tableBackupDir(tableId)
+	keys, err := z.ListKeys(tableOwnerId, tableId).KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	prev := map[string]bool{}
+	for _, k := range prevKeys {
+		prev[k] = true
+	}
+	cur := map[string]bool{}
+	var newKeys, present []string
+	for _, k := range keys {
+		cur[k] = true
+		if prev[k] {
+			present = append(present, k)
+		} else {
+			newKeys = append(newKeys, k)
+		}
+	}
+	tm := &BackupTableManifest{TableId: tableId}
+	for _, k := range prevKeys {
+		if !cur[k] {
+			tm.DeletedKeyCount++
+		}
+	}
+	for i := 0; i < len(newKeys); i += chunkSize {
+		j := i + chunkSize
+		if j > len(newKeys) {
+			j = len(newKeys)
+		}
+		data, err := z.GetUncached(tableOwnerId, tableId, newKeys[i:j]).DataAll()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		n := 0
+		for _, k := range newKeys[i:j] {
+			v, ok := data[k]
+			if !ok {
+				// Deleted since it was listed
+				continue
+			}
+			line, err := encodeNdjsonRecord(k, v, defn)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(line)
+			present = append(present, k)
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		f, err := writeBackupFile(dir, fmt.Sprintf("%s/chunk-%06d.ndjson", tdir, len(tm.Chunks)+1), buf.Bytes(), n)
+		if err != nil {
+			return nil, err
+		}
+		tm.Chunks = append(tm.Chunks, f)
+		tm.NewKeyCount += n
+	}
+	sort.Strings(present)
+	tm.KeyCount = len(present)
+	tm.Keys, err = writeBackupFile(dir, tdir+"/keys.ndjson", encodeKeyList(present), len(present))
+	if err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+// Progress of a restore: completed steps for each target table
+type restoreState struct {
+	Done map[string]map[string]bool `json:"done"`
+}
+
+func loadRestoreState(fn string) (*restoreState, error) {
+	st := &restoreState{Done: map[string]map[string]bool{}}
+	bs, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return st, nil
+	} else if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bs, st)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *restoreState) isDone(target, step string) bool {
+	return s.Done[target][step]
+}
+
+func (s *restoreState) markDone(fn, target, step string) error {
+	if s.Done[target] == nil {
+		s.Done[target] = map[string]bool{}
+	}
+	s.Done[target][step] = true
+	bs, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(fn, bs, 0600)
+}
+
+// Method Restore restores the backup in dir (applying any backups it is incremental to first) and
+// returns the number of records written. All checksums are verified before anything is written.
+// An interrupted restore resumes where it left off when run again with the same options.
+func (z *ZetabaseClient) Restore(dir string, opts *RestoreOptions) (int, error) {
+	var o RestoreOptions
+	if opts != nil {
+		o = *opts
+	}
+	if len(o.TableOwnerId) == 0 {
+		o.TableOwnerId = z.userId
+	}
+	if len(o.StateFile) == 0 {
+		o.StateFile = filepath.Join(dir, backupRestoreStateFile)
+	}
+	if err := VerifyBackup(dir); err != nil {
+		return 0, err
+	}
+	dirs, ms, err := backupChain(dir)
+	if err != nil {
+		return 0, err
+	}
+	last := ms[len(ms)-1]
+	for _, want := range o.Tables {
+		if last.table(want) == nil {
+			return 0, fmt.Errorf("TableNotInBackup: %s", want)
+		}
+	}
+	st := &restoreState{Done: map[string]map[string]bool{}}
+	if !o.NoResume {
+		st, err = loadRestoreState(o.StateFile)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	for _, tm := range last.Tables {
+		if len(o.Tables) > 0 && !containsString(o.Tables, tm.TableId) {
+			continue
+		}
+		targetTbl := tm.TableId
+		if t, ok := o.TableNames[tm.TableId]; ok && len(t) > 0 {
+			targetTbl = t
+		}
+		k, err := z.restoreTable(dirs, ms, tm, o, targetTbl, st)
+		n += k
+		if err != nil {
+			return n, err
+		}
+	}
+	os.Remove(o.StateFile)
+	return n, nil
+}
+
+func (z *ZetabaseClient) restoreTable(dirs []string, ms []*BackupManifest, tm *BackupTableManifest, o RestoreOptions, targetTbl string, st *restoreState) (int, error) {
+	target := o.TableOwnerId + "/" + targetTbl
+	bs, err := readBackupFile(dirs[len(dirs)-1], tm.Definition)
+	if err != nil {
+		return 0, err
+	}
+	defn, err := unmarshalTableDefinition(bs)
+	if err != nil {
+		return 0, err
+	}
+	if !st.isDone(target, "definition") {
+		_, err := z.GetTableDefinition(o.TableOwnerId, targetTbl)
+		if err != nil && !errors.Is(err, ErrTableNotFound) {
+			return 0, err
+		} else if err != nil {
+			if o.TableOwnerId != z.userId {
+				return 0, ErrCannotCreateTableForOtherOwner
+			}
+			err = z.CreateTableFromDefinition(targetTbl, defn)
+		} else if o.ReapplyPermissions {
+			var perms []*PermEntry
+			for _, p := range defn.GetPermissions() {
+				perms = append(perms, PermEntryFromProtocol(p))
+			}
+			err = z.ReplacePermissions(o.TableOwnerId, targetTbl, perms)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := st.markDone(o.StateFile, target, "definition"); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	var prevKeys []string
+	for i, m := range ms {
+		t := m.table(tm.TableId)
+		if t == nil {
+			continue
+		}
+		for _, c := range t.Chunks {
+			step := fmt.Sprintf("%d:%s", i, c.Path)
+			if st.isDone(target, step) {
+				continue
+			}
+			bs, err := readBackupFile(dirs[i], c)
+			if err != nil {
+				return n, err
+			}
+			b := &importBatcher{z: z, tableOwnerId: o.TableOwnerId, tableId: targetTbl, batchSize: DefaultExportBatchSize, overwrite: true}
+			err = readNdjson(bytes.NewReader(bs), b.add)
+			if err == nil {
+				err = b.flush()
+			}
+			n += b.n
+			if err != nil {
+				return n, err
+			}
+			if err := st.markDone(o.StateFile, target, step); err != nil {
+				return n, err
+			}
+		}
+		bs, err := readBackupFile(dirs[i], t.Keys)
+		if err != nil {
+			return n, err
+		}
+		keys, err := decodeKeyList(bs)
+		if err != nil {
+			return n, err
+		}
+		step := fmt.Sprintf("%d:deletions", i)
+		if prevKeys != nil && !st.isDone(target, step) {
+			cur := map[string]bool{}
+			for _, k := range keys {
+				cur[k] = true
+			}
+			for _, k := range prevKeys {
+				if !cur[k] {
+					if err := z.DeleteKey(o.TableOwnerId, targetTbl, k); err != nil {
+						return n, err
+					}
+				}
+			}
+			if err := st.markDone(o.StateFile, target, step); err != nil {
+				return n, err
+			}
+		}
+		prevKeys = keys
+	}
+	return n, nil
+}
@@ -0,0 +1,107 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_BackupRestoreIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbbackup")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	z, _ := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"k1": "a", "k2": "b"})
+
+	full := filepath.Join(dir, "full")
+	m, err := z.Backup(testOwnerId, full, &BackupOptions{ChunkSize: 1})
+	if err != nil {
+		t.Fatalf("Backup failed: %s", err.Error())
+	}
+	if len(m.Tables) != 1 || m.Tables[0].KeyCount != 2 || len(m.Tables[0].Chunks) != 2 || m.ClientVersion != ClientVersion {
+		t.Fatalf("Unexpected manifest: %+v", m.Tables[0])
+	}
+
+	z.DeleteKey(testOwnerId, "src", "k1")
+	z.PutData(testOwnerId, "src", "k3", []byte("c"), false)
+	incr := filepath.Join(dir, "incr")
+	m, err = z.Backup(testOwnerId, incr, &BackupOptions{Parent: full})
+	if err != nil {
+		t.Fatalf("Incremental backup failed: %s", err.Error())
+	}
+	if m.Parent != "../full" || m.Tables[0].NewKeyCount != 1 || m.Tables[0].DeletedKeyCount != 1 {
+		t.Fatalf("Unexpected incremental manifest: %+v", m.Tables[0])
+	}
+
+	otherId := "66666666-2222-3333-4444-555555555555"
+	z2, srv2 := newFakeClient(otherId)
+	n, err := z2.Restore(incr, &RestoreOptions{TableNames: map[string]string{"src": "copy"}})
+	if err != nil || n != 3 {
+		t.Fatalf("Restore failed: %v (%d records)", err, n)
+	}
+	checkTableContents(t, srv2, otherId, "copy", map[string]string{"k2": "b", "k3": "c"}, false)
+	if _, err := os.Stat(filepath.Join(incr, backupRestoreStateFile)); !os.IsNotExist(err) {
+		t.Fatalf("Restore state not cleaned up")
+	}
+}
+
+func Test_RestoreVerifiesAndResumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbbackup")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"k1": "a", "k2": "b"})
+	m, err := z.Backup(testOwnerId, dir, &BackupOptions{ChunkSize: 1})
+	if err != nil {
+		t.Fatalf("Backup failed: %s", err.Error())
+	}
+
+	// Pretend an earlier restore wrote the first chunk and stopped
+	st := &restoreState{Done: map[string]map[string]bool{}}
+	target := testOwnerId + "/src"
+	st.markDone(filepath.Join(dir, backupRestoreStateFile), target, "definition")
+	st.markDone(filepath.Join(dir, backupRestoreStateFile), target, "0:"+m.Tables[0].Chunks[0].Path)
+	srv.Table(testOwnerId, "src").Defn.Permissions = nil
+	n, err := z.Restore(dir, &RestoreOptions{ReapplyPermissions: true})
+	if err != nil || n != 1 {
+		t.Fatalf("Resumed restore failed: %v (%d records)", err, n)
+	}
+	n, err = z.Restore(dir, &RestoreOptions{ReapplyPermissions: true})
+	if err != nil || n != 2 || len(srv.Table(testOwnerId, "src").Defn.Permissions) != 1 {
+		t.Fatalf("Full restore failed: %v (%d records)", err, n)
+	}
+
+	chunk := filepath.Join(dir, filepath.FromSlash(m.Tables[0].Chunks[1].Path))
+	ioutil.WriteFile(chunk, []byte(`{"key":"k2","text":"tampered"}`+"\n"), 0600)
+	_, err = z.Restore(dir, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "ChecksumMismatch") {
+		t.Fatalf("Expected checksum error, got %v", err)
+	}
+}
+
+func Test_BackupFailsOnUnreadableRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbbackup")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"k1": "a", "k2": "b"})
+
+	srv.GetErr = errors.New("Unavailable")
+	if m, err := z.Backup(testOwnerId, filepath.Join(dir, "full"), nil); err == nil {
+		t.Fatalf("Backup of unreadable records succeeded: %+v", m.Tables[0])
+	}
+	srv.GetErr = nil
+	if _, err := z.Backup(testOwnerId, filepath.Join(dir, "incr"), &BackupOptions{Parent: filepath.Join(dir, "full")}); err == nil {
+		t.Fatalf("Incremental backup on top of a failed one succeeded")
+	}
+}
@@ -0,0 +1,359 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Uncommitted staged batches younger than this are left alone by RecoverBatches, as their
+	// writer may still be staging them
+	DefaultBatchRecoveryGrace = 10 * time.Minute
+	batchNamespace            = "tx"
+)
+
+const (
+	batchSupportUnknown int32 = iota
+	batchSupportNative
+	batchSupportStaged
+)
+
+// Type Batch collects puts and deletes in one table, to be applied together by Commit. Later
+// operations on a key replace earlier ones.
+type Batch struct {
+	z            *ZetabaseClient
+	tableOwnerId string
+	tableId      string
+	order        []string
+	ops          map[string]*zbprotocol.DataPair // nil value: delete
+}
+
+// Commit marker of a staged batch
+type batchMarker struct {
+	Puts    int       `json:"puts"`
+	Deletes []string  `json:"deletes,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Method NewBatch starts a batch of writes to a table.
+func (z *ZetabaseClient) NewBatch(tableOwnerId, tableId string) *Batch {
+	return &Batch{z: z, tableOwnerId: tableOwnerId, tableId: tableId, ops: map[string]*zbprotocol.DataPair{}}
+}
+
+func (b *Batch) add(key string, op *zbprotocol.DataPair) *Batch {
+	if _, ok := b.ops[key]; !ok {
+		b.order = append(b.order, key)
+	}
+	b.ops[key] = op
+	return b
+}
+
+// Method Put adds a write of valu to key (replacing any existing value).
+func (b *Batch) Put(key string, valu []byte) *Batch {
+	return b.add(key, &zbprotocol.DataPair{Key: key, Value: append([]byte{}, valu...)})
+}
+
+// Method Delete adds a deletion of key.
+func (b *Batch) Delete(key string) *Batch {
+	return b.add(key, nil)
+}
+
+// Method Len returns the number of keys the batch writes.
+func (b *Batch) Len() int {
+	return len(b.order)
+}
+
+func (b *Batch) split() ([]*zbprotocol.DataPair, []string, int) {
+	var puts []*zbprotocol.DataPair
+	var deletes []string
+	size := 0
+	for _, k := range b.order {
+		if op := b.ops[k]; op != nil {
+			puts = append(puts, op)
+			size += len(k) + len(op.Value)
+		} else {
+			deletes = append(deletes, k)
+			size += len(k)
+		}
+	}
+	return puts, deletes, size
+}
+
+// Method Commit applies the batch so that either all or none of its writes take effect. Servers
+// that support it apply the batch in one call. Otherwise, and for batches too large for one call,
+// the writes are staged under reserved keys and committed by writing a marker record; if Commit
+// fails after that, RecoverBatches completes the batch. Readers may see a staged batch partly
+// applied while it is being committed. With soft deletes enabled, moving deleted values to the
+// trash is part of the batch.
+func (b *Batch) Commit() error {
+	z := b.z
+	if !z.checkReady() {
+		return errors.New("NotReady")
+	}
+	if len(b.order) == 0 {
+		return nil
+	}
+	puts, deletes, size := b.split()
+	var keys []string
+	var valus [][]byte
+	for _, k := range b.order {
+		keys = append(keys, k)
+		if op := b.ops[k]; op != nil {
+			valus = append(valus, op.Value)
+		} else {
+			valus = append(valus, nil)
+		}
+	}
+	if err := z.validateWrites(b.tableOwnerId, b.tableId, keys, valus); err != nil {
+		return err
+	}
+	indexed, err := z.indexEntries(b.tableOwnerId, b.tableId, keys, valus)
+	if err != nil {
+		return err
+	}
+	puts, err = z.withTombstones(b.tableOwnerId, b.tableId, puts, deletes)
+	if err != nil {
+		return err
+	}
+	if err := b.apply(puts, deletes, size); err != nil {
+		return err
+	}
+	return z.applyIndexEntries(b.tableOwnerId, b.tableId, indexed)
+}
+
+func (b *Batch) apply(puts []*zbprotocol.DataPair, deletes []string, size int) error {
+	z := b.z
+	if size < GrpcMaxBytes/2 && atomic.LoadInt32(&z.batchSupport) != batchSupportStaged {
+		err := z.applyBatch(b.tableOwnerId, b.tableId, puts, deletes)
+		if status.Code(err) != codes.Unimplemented {
+			if err == nil {
+				atomic.StoreInt32(&z.batchSupport, batchSupportNative)
+			}
+			return err
+		}
+		atomic.StoreInt32(&z.batchSupport, batchSupportStaged)
+	}
+	return z.commitStagedBatch(b.tableOwnerId, b.tableId, puts, deletes)
+}
+
+func (z *ZetabaseClient) applyBatch(tableOwnerId, tableId string, puts []*zbprotocol.DataPair, deletes []string) error {
+	if z.versionedTable(tableOwnerId, tableId) != nil {
+		for _, p := range puts {
+			if !IsReservedKey(p.Key) {
+				puts = append(puts, &zbprotocol.DataPair{Key: versionKey(p.Key, z.nextVersion()), Value: p.Value})
+			}
+		}
+	}
+	if z.tableCodec(tableOwnerId, tableId) != nil {
+		encoded := make([]*zbprotocol.DataPair, len(puts))
+		for i, p := range puts {
+			valu, err := z.encodeValue(tableOwnerId, tableId, p.Value)
+			if err != nil {
+				return err
+			}
+			encoded[i] = &zbprotocol.DataPair{Key: p.Key, Value: valu}
+		}
+		puts = encoded
+	}
+	nonce := z.nonceMaker.Get()
+	poc := z.getCredential(nonce, BatchExtraSigningBytes(puts, deletes))
+	res, err := z.client.ApplyBatch(z.ctx, &zbprotocol.TableBatch{
+		Id:           z.userId,
+		TableOwnerId: tableOwnerId,
+		TableId:      tableId,
+		Nonce:        nonce,
+		Credential:   poc,
+		Puts:         puts,
+		Deletes:      deletes,
+	})
+	keys := append([]string{}, deletes...)
+	for _, p := range puts {
+		keys = append(keys, p.Key)
+	}
+	z.noteWrite(tableOwnerId, tableId, keys...)
+	if err != nil {
+		return err
+	}
+	return unwrapZbError(res)
+}
+
+func newBatchId() string {
+	return fmt.Sprintf("%016x%08x", time.Now().UnixNano(), rand.Uint32())
+}
+
+func batchStagePrefix(id string) string {
+	return reservedKey(batchNamespace, id, "p") + "/"
+}
+
+func batchMarkerKey(id string) string {
+	return reservedKey(batchNamespace, id, "commit")
+}
+
+func (z *ZetabaseClient) commitStagedBatch(tableOwnerId, tableId string, puts []*zbprotocol.DataPair, deletes []string) error {
+	id := newBatchId()
+	if len(puts) > 0 {
+		keys := make([]string, len(puts))
+		valus := make([][]byte, len(puts))
+		for i, p := range puts {
+			keys[i], valus[i] = batchStagePrefix(id)+p.Key, p.Value
+		}
+		if err := z.PutMulti(tableOwnerId, tableId, keys, valus, true); err != nil {
+			z.discardBatch(tableOwnerId, tableId, id, keys)
+			return err
+		}
+	}
+	bs, err := json.Marshal(&batchMarker{Puts: len(puts), Deletes: deletes, Created: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	err = z.CompareAndSwap(tableOwnerId, tableId, batchMarkerKey(id), nil, bs)
+	if err != nil {
+		// The marker may have been written even if we did not hear back; leave it to recovery
+		return err
+	}
+	return z.completeBatch(tableOwnerId, tableId, id)
+}
+
+// Apply a committed staged batch and remove its staging records. Safe to repeat.
+func (z *ZetabaseClient) completeBatch(tableOwnerId, tableId, id string) error {
+	markerKey := batchMarkerKey(id)
+	cur, ok, err := z.getUncached(tableOwnerId, tableId, markerKey)
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.New("BatchNotCommitted")
+	}
+	var m batchMarker
+	if err := json.Unmarshal(cur, &m); err != nil {
+		return err
+	}
+	prefix := batchStagePrefix(id)
+	staged, err := z.listKeysRemote(tableOwnerId, tableId, prefix+"%").KeysAll()
+	if err != nil {
+		return err
+	}
+	if len(staged) == 0 && m.Puts > 0 {
+		// Applied, and its staging records removed, before the marker could be
+		return z.deleteKeyRaw(tableOwnerId, tableId, markerKey)
+	} else if len(staged) < m.Puts {
+		// Only possible if staging records were removed after the marker was written
+		return errors.New("BatchStagingIncomplete")
+	}
+	for i := 0; i < len(staged); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(staged) {
+			j = len(staged)
+		}
+		data, err := z.getPag(tableOwnerId, tableId, staged[i:j]).DataAll()
+		if err != nil {
+			return err
+		}
+		var keys []string
+		var valus [][]byte
+		for _, k := range staged[i:j] {
+			if v, ok := data[k]; ok {
+				keys = append(keys, strings.TrimPrefix(k, prefix))
+				valus = append(valus, v)
+			}
+		}
+		if len(keys) > 0 {
+			if err := z.PutMulti(tableOwnerId, tableId, keys, valus, true); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range m.Deletes {
+		// Deleted values were moved to the trash by the batch's own puts (see Commit)
+		if err := z.deleteKeyRaw(tableOwnerId, tableId, k); err != nil {
+			return err
+		}
+	}
+	// Puts were indexed by PutMulti
+	indexed, _ := z.indexEntries(tableOwnerId, tableId, m.Deletes, make([][]byte, len(m.Deletes)))
+	if err := z.applyIndexEntries(tableOwnerId, tableId, indexed); err != nil {
+		return err
+	}
+	return z.discardBatch(tableOwnerId, tableId, id, staged)
+}
+
+// Remove the marker of a batch, and then its staging records: staging records left without a
+// marker are rolled back by recovery, whereas a marker without them could not be completed
+func (z *ZetabaseClient) discardBatch(tableOwnerId, tableId, id string, staged []string) error {
+	if err := z.DeleteKey(tableOwnerId, tableId, batchMarkerKey(id)); err != nil {
+		return err
+	}
+	for _, k := range staged {
+		if err := z.DeleteKey(tableOwnerId, tableId, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method RecoverBatches finds staged batches in a table left behind by failed commits. Batches
+// that were committed are completed; uncommitted batches older than grace are rolled back. A batch
+// that cannot be recovered does not stop the others; the errors of all such batches are returned
+// together. Returns the numbers of batches completed and rolled back.
+func (z *ZetabaseClient) RecoverBatches(tableOwnerId, tableId string, grace time.Duration) (int, int, error) {
+	keys, err := z.listKeysRemote(tableOwnerId, tableId, reservedKey(batchNamespace)+"%").KeysAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	staged := map[string][]string{}
+	committed := map[string]bool{}
+	nsPrefix := reservedKey(batchNamespace)
+	for _, k := range keys {
+		rest := strings.TrimPrefix(k, nsPrefix)
+		i := strings.Index(rest, "/")
+		if i < 0 {
+			continue
+		}
+		id := rest[:i]
+		if k == batchMarkerKey(id) {
+			committed[id] = true
+		} else if strings.HasPrefix(k, batchStagePrefix(id)) {
+			staged[id] = append(staged[id], k)
+		}
+	}
+	completed, rolledBack := 0, 0
+	var failed []string
+	for id := range committed {
+		if err := z.completeBatch(tableOwnerId, tableId, id); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", id, err.Error()))
+			continue
+		}
+		completed++
+	}
+	for id, ks := range staged {
+		if committed[id] {
+			continue
+		}
+		if len(id) < 16 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(id[:16], 16, 64)
+		if err != nil || time.Since(time.Unix(0, nanos)) < grace {
+			continue
+		}
+		if err := z.discardBatch(tableOwnerId, tableId, id, ks); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", id, err.Error()))
+			continue
+		}
+		rolledBack++
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return completed, rolledBack, fmt.Errorf("BatchRecoveryFailed: %s", strings.Join(failed, "; "))
+	}
+	return completed, rolledBack, nil
+}
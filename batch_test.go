@@ -0,0 +1,90 @@
+package zetabase
+
+import (
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_BatchCommitNative(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = "0.2.0"
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+	err := z.NewBatch(testOwnerId, "src").Put("c", []byte("3")).Delete("a").Put("a", []byte("x")).Delete("b").Commit()
+	if err != nil {
+		t.Fatalf("Commit failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"a": "x", "c": "3"}, false)
+}
+
+func Test_BatchCommitStaged(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+	b := z.NewBatch(testOwnerId, "src").Put("c", []byte("3")).Put("a", []byte("x")).Delete("b")
+	if err := b.Commit(); err != nil {
+		t.Fatalf("Commit failed: %s", err.Error())
+	}
+	// Staging records and the commit marker are cleaned up
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"a": "x", "c": "3"}, false)
+
+	srv.SetWriteErr(fmt.Errorf("Unavailable"))
+	if err := z.NewBatch(testOwnerId, "src").Put("d", []byte("4")).Commit(); err == nil {
+		t.Fatalf("Expected commit to fail while offline")
+	}
+	srv.SetWriteErr(nil)
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"a": "x", "c": "3"}, false)
+}
+
+func Test_RecoverBatches(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+
+	// A committed batch whose writer died before applying it, an abandoned one, and one still
+	// being staged
+	done := fmt.Sprintf("%016x%08x", time.Now().Add(-time.Hour).UnixNano(), 1)
+	abandoned := fmt.Sprintf("%016x%08x", time.Now().Add(-time.Hour).UnixNano(), 2)
+	inFlight := newBatchId()
+	srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{
+		{Key: batchStagePrefix(done) + "a", Value: []byte("new")},
+		{Key: batchMarkerKey(done), Value: []byte(`{"puts": 1, "deletes": ["b"]}`)},
+		{Key: batchStagePrefix(abandoned) + "z", Value: []byte("lost")},
+		{Key: batchStagePrefix(inFlight) + "y", Value: []byte("pending")},
+	}, true)
+
+	completed, rolledBack, err := z.RecoverBatches(testOwnerId, "src", DefaultBatchRecoveryGrace)
+	if err != nil || completed != 1 || rolledBack != 1 {
+		t.Fatalf("Unexpected recovery: %d completed, %d rolled back (%v)", completed, rolledBack, err)
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{
+		"a":                              "new",
+		batchStagePrefix(inFlight) + "y": "pending",
+	}, false)
+}
+
+func Test_RecoverBatchesPastFailures(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1"})
+
+	// A batch applied but whose marker outlived its staging records, a batch with a corrupt
+	// marker, and a committed batch still to apply
+	applied := fmt.Sprintf("%016x%08x", time.Now().Add(-time.Hour).UnixNano(), 1)
+	corrupt := fmt.Sprintf("%016x%08x", time.Now().Add(-time.Hour).UnixNano(), 2)
+	pending := fmt.Sprintf("%016x%08x", time.Now().Add(-time.Hour).UnixNano(), 3)
+	srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{
+		{Key: batchMarkerKey(applied), Value: []byte(`{"puts": 2}`)},
+		{Key: batchMarkerKey(corrupt), Value: []byte(`{`)},
+		{Key: batchStagePrefix(pending) + "a", Value: []byte("new")},
+		{Key: batchMarkerKey(pending), Value: []byte(`{"puts": 1}`)},
+	}, true)
+
+	completed, _, err := z.RecoverBatches(testOwnerId, "src", DefaultBatchRecoveryGrace)
+	if err == nil || !strings.Contains(err.Error(), corrupt) || completed != 2 {
+		t.Fatalf("Unexpected recovery: %d completed (%v)", completed, err)
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{
+		"a":                     "new",
+		batchMarkerKey(corrupt): "{",
+	}, false)
+}
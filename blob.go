@@ -0,0 +1,322 @@
+package zetabase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// Values larger than this cannot be written with PutData or PutMulti; use PutBlob instead
+	MaxPlainValueSize      = GrpcMaxBytes / 2
+	DefaultBlobChunkSize   = 1 << 20
+	DefaultBlobParallelism = 4
+	blobNamespace          = "blob"
+	blobFormatVersion      = 1
+)
+
+// Type BlobOptions configures PutBlob and GetBlob.
+type BlobOptions struct {
+	ChunkSize   int // bytes per chunk (at most MaxPlainValueSize minus some room for the key)
+	Parallelism int // chunks transferred concurrently
+}
+
+// Manifest record stored under a blob's key. Chunks are named by the SHA-256 of their contents.
+type blobManifest struct {
+	Blob      int      `json:"__zbBlob"`
+	Size      int64    `json:"size"`
+	ChunkSize int      `json:"chunkSize"`
+	Sha256    string   `json:"sha256"`
+	Chunks    []string `json:"chunks"`
+}
+
+func (o *BlobOptions) withDefaults() BlobOptions {
+	var r BlobOptions
+	if o != nil {
+		r = *o
+	}
+	if r.ChunkSize <= 0 {
+		r.ChunkSize = DefaultBlobChunkSize
+	}
+	if r.ChunkSize > MaxPlainValueSize-4096 {
+		r.ChunkSize = MaxPlainValueSize - 4096
+	}
+	if r.Parallelism <= 0 {
+		r.Parallelism = DefaultBlobParallelism
+	}
+	return r
+}
+
+func blobChunkKey(key, chunk string) string {
+	return reservedKey(blobNamespace, key, chunk)
+}
+
+func parseBlobManifest(bs []byte) (*blobManifest, bool) {
+	var m blobManifest
+	if err := json.Unmarshal(bs, &m); err != nil || m.Blob != blobFormatVersion {
+		return nil, false
+	}
+	return &m, true
+}
+
+// Function IsBlob checks whether a value read from a table is the manifest of a blob written by
+// PutBlob.
+func IsBlob(valu []byte) bool {
+	_, ok := parseBlobManifest(valu)
+	return ok
+}
+
+// Method PutBlob stores everything read from r under key, split into chunks so that values larger
+// than MaxPlainValueSize can be stored. The key itself holds a small manifest listing the chunks,
+// which are kept under reserved keys. When a blob is overwritten, chunks it shares with the old
+// contents are not uploaded again; if another writer replaces the blob in the meantime, PutBlob
+// fails with CompareAndSwapFailed and leaves the other writer's blob in place. Returns the number
+// of bytes stored.
+func (z *ZetabaseClient) PutBlob(tableOwnerId, tableId, key string, r io.Reader, overwrite bool, opts *BlobOptions) (int64, error) {
+	if !z.checkReady() {
+		return 0, errors.New("NotReady")
+	}
+	o := opts.withDefaults()
+	cur, exists, err := z.getUncached(tableOwnerId, tableId, key)
+	if err != nil {
+		return 0, err
+	} else if exists && !overwrite {
+		return 0, ErrKeyAlreadyExists
+	}
+	have := map[string]bool{}
+	var old *blobManifest
+	if exists {
+		if m, ok := parseBlobManifest(cur); ok {
+			old = m
+			for _, c := range m.Chunks {
+				have[c] = true
+			}
+		}
+	}
+
+	type chunk struct {
+		name string
+		data []byte
+	}
+	jobs := make(chan chunk, o.Parallelism)
+	var lock sync.Mutex
+	var uploaded []string
+	var uploadErr error
+	failed := func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		return uploadErr
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < o.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				if failed() != nil {
+					continue
+				}
+				err := z.PutData(tableOwnerId, tableId, blobChunkKey(key, c.name), c.data, true)
+				lock.Lock()
+				if err != nil && uploadErr == nil {
+					uploadErr = err
+				} else if err == nil {
+					uploaded = append(uploaded, c.name)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	m := &blobManifest{Blob: blobFormatVersion, ChunkSize: o.ChunkSize}
+	whole := sha256.New()
+	var readErr error
+	for failed() == nil {
+		buf := make([]byte, o.ChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			name := hex.EncodeToString(sum[:])
+			whole.Write(buf[:n])
+			m.Size += int64(n)
+			m.Chunks = append(m.Chunks, name)
+			if !have[name] {
+				have[name] = true
+				jobs <- chunk{name: name, data: buf[:n]}
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			readErr = err
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if readErr == nil {
+		readErr = uploadErr
+	}
+	if readErr != nil {
+		z.discardBlobChunks(tableOwnerId, tableId, key, uploaded)
+		return 0, readErr
+	}
+
+	m.Sha256 = hex.EncodeToString(whole.Sum(nil))
+	bs, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	// Old chunks are only deleted once the manifest that replaces them is in place, and only if
+	// nobody replaced the blob since it was read
+	var expected []byte
+	if exists {
+		expected = ValueHash(cur)
+	}
+	err = z.CompareAndSwap(tableOwnerId, tableId, key, expected, bs)
+	if errors.Is(err, ErrCompareAndSwapFailed) && !overwrite {
+		err = ErrKeyAlreadyExists
+	}
+	if err != nil {
+		z.discardBlobChunks(tableOwnerId, tableId, key, uploaded)
+		return 0, err
+	}
+	if old != nil {
+		var unused []string
+		for _, c := range old.Chunks {
+			if !containsString(m.Chunks, c) && !containsString(unused, c) {
+				unused = append(unused, c)
+			}
+		}
+		z.deleteBlobChunks(tableOwnerId, tableId, key, unused)
+	}
+	return m.Size, nil
+}
+
+func (z *ZetabaseClient) getBlobManifest(tableOwnerId, tableId, key string) (*blobManifest, error) {
+	cur, exists, err := z.getUncached(tableOwnerId, tableId, key)
+	if err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, errors.New("KeyNotFound")
+	}
+	m, ok := parseBlobManifest(cur)
+	if !ok {
+		return nil, errors.New("NotABlob")
+	}
+	return m, nil
+}
+
+// Method GetBlob writes the contents of the blob stored under key to w, checking each chunk and
+// the whole against the hashes recorded by PutBlob. Returns the number of bytes written; if the
+// blob is found to be damaged, BlobCorrupt is returned after whatever was written before.
+func (z *ZetabaseClient) GetBlob(tableOwnerId, tableId, key string, w io.Writer, opts *BlobOptions) (int64, error) {
+	if !z.checkReady() {
+		return 0, errors.New("NotReady")
+	}
+	o := opts.withDefaults()
+	m, err := z.getBlobManifest(tableOwnerId, tableId, key)
+	if err != nil {
+		return 0, err
+	}
+	whole := sha256.New()
+	written := int64(0)
+	for i := 0; i < len(m.Chunks); i += o.Parallelism {
+		j := i + o.Parallelism
+		if j > len(m.Chunks) {
+			j = len(m.Chunks)
+		}
+		datas := make([][]byte, j-i)
+		errs := make([]error, j-i)
+		var wg sync.WaitGroup
+		for k := i; k < j; k++ {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				datas[k-i], errs[k-i] = z.getBlobChunk(tableOwnerId, tableId, key, m.Chunks[k])
+			}(k)
+		}
+		wg.Wait()
+		for k := range datas {
+			if errs[k] != nil {
+				return written, errs[k]
+			}
+			whole.Write(datas[k])
+			n, err := w.Write(datas[k])
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	if written != m.Size || hex.EncodeToString(whole.Sum(nil)) != m.Sha256 {
+		return written, errors.New("BlobCorrupt")
+	}
+	return written, nil
+}
+
+func (z *ZetabaseClient) getBlobChunk(tableOwnerId, tableId, key, name string) ([]byte, error) {
+	dat, ok, err := z.getUncached(tableOwnerId, tableId, blobChunkKey(key, name))
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("BlobCorrupt")
+	}
+	sum := sha256.Sum256(dat)
+	if want, err := hex.DecodeString(name); err != nil || !bytes.Equal(sum[:], want) {
+		return nil, errors.New("BlobCorrupt")
+	}
+	return dat, nil
+}
+
+// Method DeleteBlob deletes the blob stored under key along with its chunks. Blobs are deleted
+// immediately even with soft deletes enabled.
+func (z *ZetabaseClient) DeleteBlob(tableOwnerId, tableId, key string) error {
+	if !z.checkReady() {
+		return errors.New("NotReady")
+	}
+	m, err := z.getBlobManifest(tableOwnerId, tableId, key)
+	if err != nil {
+		return err
+	}
+	// The manifest goes first so a failure part way never leaves a blob with missing chunks
+	if err := z.deleteKeyRaw(tableOwnerId, tableId, key); err != nil {
+		return err
+	}
+	return z.deleteBlobChunks(tableOwnerId, tableId, key, m.Chunks)
+}
+
+// Delete chunks uploaded by a failed PutBlob, except those the blob's current manifest (possibly
+// written concurrently by another PutBlob) refers to
+func (z *ZetabaseClient) discardBlobChunks(tableOwnerId, tableId, key string, chunks []string) {
+	cur, exists, err := z.getUncached(tableOwnerId, tableId, key)
+	if err != nil {
+		return
+	}
+	var unused []string
+	m, ok := parseBlobManifest(cur)
+	for _, c := range chunks {
+		if !exists || !ok || !containsString(m.Chunks, c) {
+			unused = append(unused, c)
+		}
+	}
+	z.deleteBlobChunks(tableOwnerId, tableId, key, unused)
+}
+
+func (z *ZetabaseClient) deleteBlobChunks(tableOwnerId, tableId, key string, chunks []string) error {
+	done := map[string]bool{}
+	for _, c := range chunks {
+		if done[c] {
+			continue
+		}
+		done[c] = true
+		if err := z.DeleteKey(tableOwnerId, tableId, blobChunkKey(key, c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
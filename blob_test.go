@@ -0,0 +1,126 @@
+package zetabase
+
+import (
+	"bytes"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func countReservedKeys(srv *fakeServer, owner, tbl string) int {
+	n := 0
+	for k := range srv.Table(owner, tbl).Data {
+		if IsReservedKey(k) {
+			n++
+		}
+	}
+	return n
+}
+
+func Test_BlobRoundTrip(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, nil)
+	dat := make([]byte, MaxPlainValueSize+12345)
+	rand.New(rand.NewSource(1)).Read(dat)
+
+	n, err := z.PutBlob(testOwnerId, "src", "big", bytes.NewReader(dat), false, nil)
+	if err != nil || n != int64(len(dat)) {
+		t.Fatalf("PutBlob failed: %v (%d bytes)", err, n)
+	}
+	var out bytes.Buffer
+	n, err = z.GetBlob(testOwnerId, "src", "big", &out, nil)
+	if err != nil || n != int64(len(dat)) || !bytes.Equal(out.Bytes(), dat) {
+		t.Fatalf("GetBlob failed: %v (%d bytes)", err, n)
+	}
+	if _, err := z.PutBlob(testOwnerId, "src", "big", bytes.NewReader(dat), false, nil); err == nil || err.Error() != "KeyAlreadyExists" {
+		t.Fatalf("Expected KeyAlreadyExists, got %v", err)
+	}
+	if err := z.DeleteBlob(testOwnerId, "src", "big"); err != nil {
+		t.Fatalf("DeleteBlob failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{}, false)
+}
+
+func Test_BlobOverwriteAndCorruption(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, map[string]string{"plain": "x"})
+	opts := &BlobOptions{ChunkSize: 100, Parallelism: 3}
+	dat := make([]byte, 1000)
+	rand.New(rand.NewSource(2)).Read(dat)
+	if _, err := z.PutBlob(testOwnerId, "src", "b", bytes.NewReader(dat), false, opts); err != nil {
+		t.Fatalf("PutBlob failed: %s", err.Error())
+	}
+	if n := countReservedKeys(srv, testOwnerId, "src"); n != 10 {
+		t.Fatalf("Expected 10 chunks, found %d", n)
+	}
+
+	// Changing the last chunk replaces just that chunk
+	dat[999]++
+	if _, err := z.PutBlob(testOwnerId, "src", "b", bytes.NewReader(dat), true, opts); err != nil {
+		t.Fatalf("PutBlob failed: %s", err.Error())
+	}
+	if n := countReservedKeys(srv, testOwnerId, "src"); n != 10 {
+		t.Fatalf("Old chunks not cleaned up (%d chunks)", n)
+	}
+	var out bytes.Buffer
+	if _, err := z.GetBlob(testOwnerId, "src", "b", &out, opts); err != nil || !bytes.Equal(out.Bytes(), dat) {
+		t.Fatalf("GetBlob failed after overwrite: %v", err)
+	}
+
+	for k, v := range srv.Table(testOwnerId, "src").Data {
+		if IsReservedKey(k) {
+			v[0]++
+			break
+		}
+	}
+	if _, err := z.GetBlob(testOwnerId, "src", "b", &bytes.Buffer{}, opts); err == nil || err.Error() != "BlobCorrupt" {
+		t.Fatalf("Expected BlobCorrupt, got %v", err)
+	}
+	if _, err := z.GetBlob(testOwnerId, "src", "plain", &bytes.Buffer{}, opts); err == nil || err.Error() != "NotABlob" {
+		t.Fatalf("Expected NotABlob, got %v", err)
+	}
+}
+
+func Test_BlobConcurrentOverwrite(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, nil)
+	opts := &BlobOptions{ChunkSize: 100, Parallelism: 3}
+	rng := rand.New(rand.NewSource(4))
+	for round := 0; round < 20; round++ {
+		datas := make([][]byte, 3)
+		errs := make([]error, len(datas))
+		var wg sync.WaitGroup
+		for i := range datas {
+			datas[i] = make([]byte, 1000)
+			rng.Read(datas[i])
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = z.PutBlob(testOwnerId, "src", "b", bytes.NewReader(datas[i]), true, opts)
+			}(i)
+		}
+		wg.Wait()
+
+		var out bytes.Buffer
+		if _, err := z.GetBlob(testOwnerId, "src", "b", &out, opts); err != nil {
+			t.Fatalf("Blob damaged by concurrent writes in round %d: %s", round, err.Error())
+		}
+		winners := 0
+		for i, err := range errs {
+			if err == nil && bytes.Equal(out.Bytes(), datas[i]) {
+				winners++
+			} else if err != nil && !errors.Is(err, ErrCompareAndSwapFailed) {
+				t.Fatalf("Unexpected error in round %d: %s", round, err.Error())
+			}
+		}
+		if winners != 1 {
+			t.Fatalf("Expected the blob to hold one successful write in round %d", round)
+		}
+		if n := countReservedKeys(srv, testOwnerId, "src"); n != 10 {
+			t.Fatalf("Chunks left behind in round %d (%d chunks)", round, n)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package zetabase
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCacheMaxBytes = 64 * 1024 * 1024
+	// Bookkeeping bytes charged per cache entry on top of its key and value
+	cacheEntryOverhead = 64
+)
+
+// Type CacheOptions configures the read-through cache enabled with EnableCache.
+type CacheOptions struct {
+	MaxBytes   int64         // total size of cached keys and values
+	DefaultTTL time.Duration // lifetime of entries of tables without a TTL of their own (0: no expiry)
+}
+
+// Type CacheStats holds counters of the read-through cache.
+type CacheStats struct {
+	Hits      int64 // keys served from the cache
+	Misses    int64 // keys fetched from the server
+	Shared    int64 // keys whose fetch was already under way for another caller
+	Evictions int64 // entries dropped to stay under MaxBytes
+	Entries   int
+	Bytes     int64
+}
+
+type cacheKey struct {
+	tableOwnerId string
+	tableId      string
+	key          string
+}
+
+type cacheEntry struct {
+	k       cacheKey
+	valu    []byte
+	expires time.Time
+}
+
+// A fetch under way, which concurrent misses for the same keys wait for
+type cacheCall struct {
+	done  chan struct{}
+	data  map[string][]byte
+	err   error
+	stale bool // invalidated while in flight; do not store the result
+}
+
+// In-memory LRU of table values
+type valueCache struct {
+	lock       sync.Mutex
+	maxBytes   int64
+	defaultTTL time.Duration
+	tableTTLs  map[string]time.Duration
+	lru        *list.List
+	entries    map[cacheKey]*list.Element
+	inflight   map[cacheKey]*cacheCall
+	stats      CacheStats
+}
+
+func newValueCache(opts *CacheOptions) *valueCache {
+	c := &valueCache{
+		maxBytes:  DefaultCacheMaxBytes,
+		tableTTLs: map[string]time.Duration{},
+		lru:       list.New(),
+		entries:   map[cacheKey]*list.Element{},
+		inflight:  map[cacheKey]*cacheCall{},
+	}
+	if opts != nil {
+		if opts.MaxBytes > 0 {
+			c.maxBytes = opts.MaxBytes
+		}
+		c.defaultTTL = opts.DefaultTTL
+	}
+	return c
+}
+
+func cacheEntrySize(k cacheKey, valu []byte) int64 {
+	return int64(len(k.tableOwnerId) + len(k.tableId) + len(k.key) + len(valu) + cacheEntryOverhead)
+}
+
+// Method EnableCache turns on a read-through cache for Get (but not GetUncached, which the client
+// uses internally for watches, diffs, backups, copies and journal replays). Writes
+// made through this client (PutData, PutMulti, DeleteKey, DeleteTable) invalidate the affected
+// entries; writes made by others are only picked up once entries expire.
+func (z *ZetabaseClient) EnableCache(opts *CacheOptions) {
+	z.cache = newValueCache(opts)
+}
+
+// Method DisableCache turns off the read-through cache and drops its contents.
+func (z *ZetabaseClient) DisableCache() {
+	z.cache = nil
+}
+
+// Method SetCacheTTL sets the lifetime of cached values of a table, overriding the default. A
+// negative ttl stops values of the table being cached.
+func (z *ZetabaseClient) SetCacheTTL(tableOwnerId, tableId string, ttl time.Duration) {
+	if c := z.cache; c != nil {
+		c.lock.Lock()
+		c.tableTTLs[cacheTableKey(tableOwnerId, tableId)] = ttl
+		c.lock.Unlock()
+	}
+}
+
+// Method CacheStats returns the counters of the read-through cache (all zero if it is disabled).
+func (z *ZetabaseClient) CacheStats() CacheStats {
+	c := z.cache
+	if c == nil {
+		return CacheStats{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	st := c.stats
+	st.Entries = len(c.entries)
+	return st
+}
+
+// Method InvalidateCache drops cached values of the given keys of a table, or of the whole table
+// if no keys are given.
+func (z *ZetabaseClient) InvalidateCache(tableOwnerId, tableId string, keys ...string) {
+	if c := z.cache; c != nil {
+		c.invalidate(tableOwnerId, tableId, keys)
+	}
+}
+
+func cacheTableKey(tableOwnerId, tableId string) string {
+	return tableOwnerId + "/" + tableId
+}
+
+func (c *valueCache) ttl(tableOwnerId, tableId string) time.Duration {
+	if t, ok := c.tableTTLs[cacheTableKey(tableOwnerId, tableId)]; ok {
+		return t
+	}
+	return c.defaultTTL
+}
+
+func (c *valueCache) invalidate(tableOwnerId, tableId string, keys []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	match := func(k cacheKey) bool {
+		return k.tableOwnerId == tableOwnerId && k.tableId == tableId
+	}
+	if len(keys) == 0 {
+		for k, el := range c.entries {
+			if match(k) {
+				c.remove(el)
+			}
+		}
+		for k, call := range c.inflight {
+			if match(k) {
+				call.stale = true
+				delete(c.inflight, k)
+			}
+		}
+		return
+	}
+	for _, key := range keys {
+		k := cacheKey{tableOwnerId, tableId, key}
+		if el, ok := c.entries[k]; ok {
+			c.remove(el)
+		}
+		if call, ok := c.inflight[k]; ok {
+			call.stale = true
+			delete(c.inflight, k)
+		}
+	}
+}
+
+// Requires lock
+func (c *valueCache) remove(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.k)
+	c.stats.Bytes -= cacheEntrySize(e.k, e.valu)
+}
+
+// Requires lock
+func (c *valueCache) store(k cacheKey, valu []byte, ttl time.Duration) {
+	size := cacheEntrySize(k, valu)
+	if ttl < 0 || size > c.maxBytes {
+		return
+	}
+	if el, ok := c.entries[k]; ok {
+		c.remove(el)
+	}
+	e := &cacheEntry{k: k, valu: append([]byte{}, valu...)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	c.entries[k] = c.lru.PushFront(e)
+	c.stats.Bytes += size
+	for c.stats.Bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Requires lock
+func (c *valueCache) lookup(k cacheKey) ([]byte, bool) {
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e.valu, true
+}
+
+// Get keys of a table, serving cached keys locally, waiting for fetches of keys already under way,
+// and fetching the rest with one call to fetch
+func (c *valueCache) getMany(tableOwnerId, tableId string, keys []string, fetch func([]string) (map[string][]byte, error)) (map[string][]byte, error) {
+	res := map[string][]byte{}
+	var missing []string
+	waits := map[string]*cacheCall{}
+	call := &cacheCall{done: make(chan struct{})}
+
+	c.lock.Lock()
+	for _, key := range keys {
+		k := cacheKey{tableOwnerId, tableId, key}
+		if _, dup := waits[key]; dup {
+			continue
+		} else if v, ok := c.lookup(k); ok {
+			res[key] = append([]byte{}, v...)
+			c.stats.Hits++
+		} else if other, ok := c.inflight[k]; ok {
+			waits[key] = other
+			c.stats.Shared++
+		} else {
+			c.inflight[k] = call
+			waits[key] = call
+			missing = append(missing, key)
+			c.stats.Misses++
+		}
+	}
+	ttl := c.ttl(tableOwnerId, tableId)
+	c.lock.Unlock()
+
+	if len(missing) > 0 {
+		data, err := fetch(missing)
+		c.lock.Lock()
+		call.data, call.err = data, err
+		for _, key := range missing {
+			k := cacheKey{tableOwnerId, tableId, key}
+			if c.inflight[k] == call {
+				delete(c.inflight, k)
+			}
+			if v, ok := data[key]; ok && err == nil && !call.stale {
+				c.store(k, v, ttl)
+			}
+		}
+		c.lock.Unlock()
+		close(call.done)
+	}
+
+	for key, w := range waits {
+		<-w.done
+		if w.err != nil {
+			return nil, w.err
+		}
+		if v, ok := w.data[key]; ok {
+			res[key] = append([]byte{}, v...)
+		}
+	}
+	return res, nil
+}
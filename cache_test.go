@@ -0,0 +1,103 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_CacheServesHotKeys(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+	z.EnableCache(nil)
+
+	z.Get(testOwnerId, "src", []string{"a"}).DataAll()
+	data, _ := z.Get(testOwnerId, "src", []string{"a", "b"}).DataAll()
+	if string(data["a"]) != "1" || string(data["b"]) != "2" {
+		t.Fatalf("Unexpected data: %v", data)
+	}
+	st := z.CacheStats()
+	if st.Hits != 1 || st.Misses != 2 || st.Entries != 2 || srv.GetCalls != 2 {
+		t.Fatalf("Unexpected stats: %+v (%d server calls)", st, srv.GetCalls)
+	}
+
+	// Our own writes invalidate
+	z.PutData(testOwnerId, "src", "a", []byte("new"), true)
+	z.DeleteKey(testOwnerId, "src", "b")
+	data, _ = z.Get(testOwnerId, "src", []string{"a", "b"}).DataAll()
+	if string(data["a"]) != "new" || len(data) != 1 {
+		t.Fatalf("Stale data after write: %v", data)
+	}
+}
+
+func Test_CacheTtlAndEviction(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2", "c": "3"})
+	size := cacheEntrySize(cacheKey{testOwnerId, "src", "a"}, []byte("1"))
+	z.EnableCache(&CacheOptions{MaxBytes: 2 * size})
+	z.SetCacheTTL(testOwnerId, "src", 20*time.Millisecond)
+
+	for _, k := range []string{"a", "b", "c"} {
+		z.Get(testOwnerId, "src", []string{k}).DataAll()
+	}
+	if st := z.CacheStats(); st.Evictions != 1 || st.Entries != 2 || st.Bytes != 2*size {
+		t.Fatalf("Unexpected stats after eviction: %+v", st)
+	}
+	calls := srv.GetCalls
+	z.Get(testOwnerId, "src", []string{"c"}).DataAll()
+	if srv.GetCalls != calls {
+		t.Fatalf("Expected a cache hit")
+	}
+	time.Sleep(30 * time.Millisecond)
+	z.Get(testOwnerId, "src", []string{"c"}).DataAll()
+	if srv.GetCalls != calls+1 {
+		t.Fatalf("Expected expired entry to be fetched again")
+	}
+}
+
+func Test_CacheSharesConcurrentMisses(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"hot": "x"})
+	z.EnableCache(nil)
+	srv.GetDelay = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, _ := z.Get(testOwnerId, "src", []string{"hot"}).DataAll()
+			if string(data["hot"]) != "x" {
+				t.Errorf("Unexpected data: %v", data)
+			}
+		}()
+	}
+	wg.Wait()
+	if st := z.CacheStats(); srv.GetCalls != 1 || st.Misses != 1 || st.Shared+st.Hits != 9 {
+		t.Fatalf("Expected one fetch, got %d (%+v)", srv.GetCalls, st)
+	}
+}
+
+func Test_CacheOnlyServesGet(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1"})
+	z.CreateTable("dst", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+	z.PutData(testOwnerId, "dst", "a", []byte("1"), true)
+	z.EnableCache(nil)
+	z.Get(testOwnerId, "src", []string{"a"}).DataAll()
+	z.Get(testOwnerId, "dst", []string{"a"}).DataAll()
+
+	// Changed by another client: Get may serve the cached value, but not the client's own readers
+	srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{{Key: "a", Value: []byte("2")}}, true)
+	if data, _ := z.GetUncached(testOwnerId, "src", []string{"a"}).DataAll(); string(data["a"]) != "2" {
+		t.Fatalf("GetUncached served a cached value: %q", data["a"])
+	}
+	diff, err := DiffTables(z, testOwnerId, "src", z, testOwnerId, "dst", nil)
+	if err != nil || len(diff.Changed) != 1 {
+		t.Fatalf("DiffTables read through the cache: %+v (%v)", diff, err)
+	}
+	if data, _ := z.Get(testOwnerId, "src", []string{"a"}).DataAll(); string(data["a"]) != "1" {
+		t.Fatalf("Expected Get to still serve the cached value, got %q", data["a"])
+	}
+}
@@ -0,0 +1,180 @@
+package zetabase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Servers from this version on honour TablePut.expectedValueHash
+	CompareAndSwapMinServerVersion = "0.2.0"
+	DefaultUpdateAttempts          = 10
+	updateBackoff                  = 20 * time.Millisecond
+	maxUpdateBackoff               = time.Second
+)
+
+const (
+	casSupportUnknown int32 = iota
+	casSupportNative
+	casSupportEmulated
+)
+
+// Function ValueHash returns the hash of a value as expected by CompareAndSwap.
+func ValueHash(valu []byte) []byte {
+	h := sha256.Sum256(valu)
+	return h[:]
+}
+
+// Whether the server checks expected value hashes itself (determined once per client)
+func (z *ZetabaseClient) nativeCompareAndSwap() bool {
+	switch atomic.LoadInt32(&z.casSupport) {
+	case casSupportNative:
+		return true
+	case casSupportEmulated:
+		return false
+	}
+	support := casSupportEmulated
+	if _, info, err := z.CheckVersion(); err == nil && IsSemVerVersionAtLeast(info.GetServerVersion(), CompareAndSwapMinServerVersion) {
+		support = casSupportNative
+	}
+	atomic.StoreInt32(&z.casSupport, support)
+	return support == casSupportNative
+}
+
+// Method NativeCompareAndSwap returns whether the server checks the conditions of CompareAndSwap
+// itself, which makes it safe against concurrent writers (see CompareAndSwapMinServerVersion).
+func (z *ZetabaseClient) NativeCompareAndSwap() bool {
+	return z.nativeCompareAndSwap()
+}
+
+// Current value of a key, straight from the server (nil if the key does not exist)
+func (z *ZetabaseClient) getUncached(tableOwnerId, tableId, key string) ([]byte, bool, error) {
+	data, err := z.getPag(tableOwnerId, tableId, []string{key}).DataAll()
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := data[key]
+	return v, ok, nil
+}
+
+// Method CompareAndSwap writes valu to key only if the key's current value has hash
+// expectedValueHash (see ValueHash), or, if expectedValueHash is nil, only if the key does not
+// exist. It returns CompareAndSwapFailed if the condition does not hold. Against servers older than
+// CompareAndSwapMinServerVersion the check is made by the client just before writing, which
+// narrows but does not close the race with other writers.
+func (z *ZetabaseClient) CompareAndSwap(tableOwnerId, tableId, key string, expectedValueHash, valu []byte) error {
+	if !z.checkReady() {
+		return errors.New("NotReady")
+	}
+	overwrite := expectedValueHash != nil
+	plain := valu
+	if err := z.validateWrites(tableOwnerId, tableId, []string{key}, [][]byte{valu}); err != nil {
+		return err
+	}
+	indexed, err := z.indexEntries(tableOwnerId, tableId, []string{key}, [][]byte{valu})
+	if err != nil {
+		return err
+	}
+	if !z.nativeCompareAndSwap() {
+		cur, exists, err := z.getUncached(tableOwnerId, tableId, key)
+		if err != nil {
+			return err
+		}
+		if exists != overwrite || (exists && !bytes.Equal(ValueHash(cur), expectedValueHash)) {
+			return ErrCompareAndSwapFailed
+		}
+		expectedValueHash = nil
+	}
+	if expectedValueHash != nil {
+		// The server compares against the stored value, which may have been encoded by a client
+		// with a codec for the table whether or not this one has
+		raw, _, err := z.getRaw(tableOwnerId, tableId, []string{key}, 0)
+		if err != nil {
+			return err
+		}
+		if cur, ok := raw[key]; ok && hasCodecHeader(cur) {
+			dec, err := decodeValue(cur)
+			if err != nil || !bytes.Equal(ValueHash(dec), expectedValueHash) {
+				return ErrCompareAndSwapFailed
+			}
+			expectedValueHash = ValueHash(cur)
+		}
+	}
+	if valu, err = z.encodeValue(tableOwnerId, tableId, valu); err != nil {
+		return err
+	}
+	nonce := z.nonceMaker.Get()
+	var xBytes []byte
+	if expectedValueHash != nil {
+		xBytes = TablePutExtraSigningBytes(key, valu, expectedValueHash)
+	} else {
+		xBytes = TablePutExtraSigningBytes(key, valu)
+	}
+	poc := z.getCredential(nonce, xBytes)
+	res, err := z.client.PutData(z.ctx, &zbprotocol.TablePut{
+		Id:                z.userId,
+		TableOwnerId:      tableOwnerId,
+		TableId:           tableId,
+		Key:               key,
+		Value:             valu,
+		Overwrite:         overwrite,
+		Nonce:             nonce,
+		Credential:        poc,
+		ExpectedValueHash: expectedValueHash,
+	})
+	z.noteWrite(tableOwnerId, tableId, key)
+	if err != nil {
+		return err
+	}
+	if res.GetMessage() == ErrCompareAndSwapFailed.Error() || (!overwrite && res.GetMessage() == ErrKeyAlreadyExists.Error()) {
+		return ErrCompareAndSwapFailed
+	}
+	if err := unwrapZbError(res); err != nil {
+		return err
+	}
+	if err := z.recordVersion(tableOwnerId, tableId, key, plain); err != nil {
+		return err
+	}
+	return z.applyIndexEntries(tableOwnerId, tableId, indexed)
+}
+
+// Method Update applies f to the current value of key (nil if the key does not exist) and writes
+// the result with CompareAndSwap, retrying with backoff if the value changed in the meantime. It
+// returns UpdateConflict if the value kept changing, or the error returned by f.
+func (z *ZetabaseClient) Update(tableOwnerId, tableId, key string, f func(old []byte) ([]byte, error)) error {
+	return z.update(tableOwnerId, tableId, key, f, DefaultUpdateAttempts)
+}
+
+func (z *ZetabaseClient) update(tableOwnerId, tableId, key string, f func(old []byte) ([]byte, error), attempts int) error {
+	for attempt := 0; attempt < attempts; attempt++ {
+		cur, exists, err := z.getUncached(tableOwnerId, tableId, key)
+		if err != nil {
+			return err
+		}
+		var expected []byte
+		if exists {
+			expected = ValueHash(cur)
+		} else {
+			cur = nil
+		}
+		nxt, err := f(cur)
+		if err != nil {
+			return err
+		}
+		err = z.CompareAndSwap(tableOwnerId, tableId, key, expected, nxt)
+		if err == nil || !errors.Is(err, ErrCompareAndSwapFailed) {
+			return err
+		}
+		backoff := updateBackoff << uint(attempt/2)
+		if backoff > maxUpdateBackoff || backoff <= 0 {
+			backoff = maxUpdateBackoff
+		}
+		time.Sleep(time.Duration(rand.Int63n(int64(backoff))))
+	}
+	return errors.New("UpdateConflict")
+}
@@ -0,0 +1,86 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testCompareAndSwap(t *testing.T, serverVersion string) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = serverVersion
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"k": "old"})
+
+	if err := z.CompareAndSwap(testOwnerId, "src", "k", ValueHash([]byte("wrong")), []byte("x")); err == nil || err.Error() != "CompareAndSwapFailed" {
+		t.Fatalf("Expected swap with wrong hash to fail, got %v", err)
+	}
+	if err := z.CompareAndSwap(testOwnerId, "src", "k", ValueHash([]byte("old")), []byte("new")); err != nil {
+		t.Fatalf("Swap failed: %s", err.Error())
+	}
+	if err := z.CompareAndSwap(testOwnerId, "src", "k", nil, []byte("x")); err == nil {
+		t.Fatalf("Expected swap of an existing key with nil hash to fail")
+	}
+	if err := z.CompareAndSwap(testOwnerId, "src", "absent", nil, []byte("x")); err != nil {
+		t.Fatalf("Swap of absent key failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"k": "new", "absent": "x"}, false)
+}
+
+func Test_CompareAndSwapNative(t *testing.T) {
+	testCompareAndSwap(t, "0.2.0")
+}
+
+func Test_CompareAndSwapEmulated(t *testing.T) {
+	testCompareAndSwap(t, "0.1.0")
+}
+
+func Test_UpdateRetriesOnConflict(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := z.Update(testOwnerId, "src", "n", func(old []byte) ([]byte, error) {
+				n, _ := strconv.Atoi(string(old))
+				return []byte(strconv.Itoa(n + 1)), nil
+			})
+			if err != nil {
+				t.Errorf("Update failed: %s", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"n": "5"}, false)
+}
+
+func Test_UpdateAcrossCodecConfigurations(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, nil)
+	z.SetTableCodec(testOwnerId, "src", CodecGzip)
+	other := newFakeClientFor(testOwnerId, srv)
+	text := []byte(strings.Repeat("abc", 1000))
+	z.PutData(testOwnerId, "src", "k", text, true)
+
+	// A client without the codec updates a value encoded by one with it, and vice versa
+	grow := func(old []byte) ([]byte, error) {
+		return append(append([]byte{}, old...), "abc"...), nil
+	}
+	if err := other.Update(testOwnerId, "src", "k", grow); err != nil {
+		t.Fatalf("Update without the codec failed: %s", err.Error())
+	}
+	if err := z.Update(testOwnerId, "src", "k", grow); err != nil {
+		t.Fatalf("Update with the codec failed: %s", err.Error())
+	}
+	if v, _, _ := other.getUncached(testOwnerId, "src", "k"); len(v) != len(text)+6 {
+		t.Fatalf("Unexpected value after updates (%d bytes)", len(v))
+	}
+	if err := other.CompareAndSwap(testOwnerId, "src", "k", ValueHash(text), []byte("x")); err != ErrCompareAndSwapFailed {
+		t.Fatalf("Expected swap with a stale hash to fail, got %v", err)
+	}
+}
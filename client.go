@@ -21,6 +21,8 @@ import (
 	"github.com/zetabase/zetabase-client/zbprotocol"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -49,6 +51,20 @@ type ZetabaseClient struct {
 	debugMode    bool
 	ctx          context.Context
 	maxItemSize  int64
+	cache        *valueCache
+	mirrors      sync.Map
+	casSupport   int32
+	keyPatternSupport int32
+	batchSupport int32
+	codecs       sync.Map
+	versioned    sync.Map
+	lastVersion  int64
+	softDelete   atomic.Value
+	expiring     sync.Map
+	sequenceTable int32
+	secondaryIndexes sync.Map
+	schemas      sync.Map
+	derivedKekTables sync.Map
 }
 
 // Creates a new client for a given user ID uid. The user ID should be in UUID form.
@@ -159,7 +175,7 @@ func (z *ZetabaseClient) SetIdPassword(loginId, pwd string) {
 // Check if client is ready to communicate with server
 func (z *ZetabaseClient) checkReady() bool {
 	if z.privKey != nil || ((z.password != nil || z.source3pa != nil) && z.loginId != nil) {
-		if z.conn != nil {
+		if z.client != nil {
 			if z.loginId != nil && z.jwtToken == nil {
 				err := z.authLoginJwt()
 				if err != nil {
@@ -198,7 +214,7 @@ func (z *ZetabaseClient) ecdsaCredential(nonce int64, extraBytes []byte) *zbprot
 }
 
 func (z *ZetabaseClient) authLoginJwt() error {
-	if z.conn == nil {
+	if z.client == nil {
 		return errors.New("NotReady")
 	} else if z.password == nil && (z.source3pa == nil) && z.token3pa == nil {
 		return errors.New("NoPasswordProvided")
@@ -325,7 +341,7 @@ func unwrapZbError(zbError *zbprotocol.ZbError) error {
 	if zbError == nil {
 		return nil
 	} else if zbError.Code == 0 && len(zbError.Message) > 0 {
-		return errors.New(zbError.Message)
+		return errorFor(zbError.Message)
 	}
 	return nil
 }
@@ -372,16 +388,29 @@ func (z *ZetabaseClient) PutMulti(tableOwnerId, tableId string, keys []string, v
 	if len(valus) != len(keys) {
 		return errors.New("ImproperDimensions")
 	}
+	if err := z.validateWrites(tableOwnerId, tableId, keys, valus); err != nil {
+		return err
+	}
 
 	maxBytes := GrpcMaxBytes / 2
 
+	indexed, err := z.indexEntries(tableOwnerId, tableId, keys, valus)
+	if err != nil {
+		return err
+	}
+	keys, valus = z.withVersionRecords(tableOwnerId, tableId, keys, valus)
+	valus, err = z.encodeValues(tableOwnerId, tableId, valus)
+	if err != nil {
+		return err
+	}
 	pgs := makePutPages(z, keys, valus, uint64(maxBytes))
-	err := pgs.putAll(tableOwnerId, tableId, overwrite)
+	err = pgs.putAll(tableOwnerId, tableId, overwrite)
+	z.noteWrite(tableOwnerId, tableId, keys...)
 
 	if err != nil {
 		return err
 	}
-	return nil
+	return z.applyIndexEntries(tableOwnerId, tableId, indexed)
 }
 
 func (z *ZetabaseClient) SetMaxItemSize(newSize int64) {
@@ -390,9 +419,17 @@ func (z *ZetabaseClient) SetMaxItemSize(newSize int64) {
 
 func (z *ZetabaseClient) Get(tableOwnerId, tableId string, keys []string) *getPages {
 	getPages := MakeGetPages(z, keys, z.maxItemSize, tableOwnerId, tableId)
+	getPages.mirror = z.freshMirror(tableOwnerId, tableId)
+	getPages.cache = z.cache
 	return getPages
 }
 
+// Method GetUncached fetches keys like Get, but always from the server: the client's cache and any
+// mirror of the table are bypassed. Use it for reads that must see the latest values.
+func (z *ZetabaseClient) GetUncached(tableOwnerId, tableId string, keys []string) *getPages {
+	return MakeGetPages(z, keys, z.maxItemSize, tableOwnerId, tableId)
+}
+
 func (z *ZetabaseClient) StringDump() string {
 	pid := ""
 	if z.parentId != nil {
@@ -415,6 +452,20 @@ func (z *ZetabaseClient) getPag(tableOwnerId, tableId string, keys []string) *Pa
 }
 
 func (z *ZetabaseClient) get(tableOwnerId, tableId string, keys []string, pageIdx int64) (map[string][]byte, bool, error) {
+	m, hasNxt, err := z.getRaw(tableOwnerId, tableId, keys, pageIdx)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, v := range m {
+		if m[k], err = decodeValue(v); err != nil {
+			return nil, false, err
+		}
+	}
+	return m, hasNxt, nil
+}
+
+// Values as stored, without decoding (see SetTableCodec)
+func (z *ZetabaseClient) getRaw(tableOwnerId, tableId string, keys []string, pageIdx int64) (map[string][]byte, bool, error) {
 	if !z.checkReady() {
 		return nil, false, errors.New("NotReady")
 	}
@@ -431,6 +482,8 @@ func (z *ZetabaseClient) get(tableOwnerId, tableId string, keys []string, pageId
 	})
 	if err != nil {
 		return nil, false, err
+	} else if err := unwrapZbError(res.GetError()); err != nil {
+		return nil, false, err
 	}
 	m := map[string][]byte{}
 	for _, x := range res.GetData() {
@@ -544,6 +597,9 @@ func (z *ZetabaseClient) CreateTable(tblId string, dataType zbprotocol.TableData
 	if !z.checkReady() {
 		return errors.New("NotReady")
 	}
+	if err := z.checkKeyPatterns(perms); err != nil {
+		return err
+	}
 	var pEntries []*zbprotocol.PermissionsEntry
 	for _, p := range perms {
 		pEntries = append(pEntries, p.ToProtocol(z.userId, tblId))
@@ -570,11 +626,60 @@ func (z *ZetabaseClient) CreateTable(tblId string, dataType zbprotocol.TableData
 	return nil
 }
 
+// Method ListTableDefinitions lists the full definitions (data format, indices, permissions) of
+// the tables owned by tableOwnerId.
+func (z *ZetabaseClient) ListTableDefinitions(tableOwnerId string) ([]*zbprotocol.TableCreate, error) {
+	if !z.checkReady() {
+		return nil, errors.New("NotReady")
+	}
+	nonce := z.nonceMaker.Get()
+	poc := z.getCredential(nonce, nil)
+	res, err := z.client.ListTables(z.ctx, &zbprotocol.ListTablesRequest{
+		Id:           z.userId,
+		TableOwnerId: tableOwnerId,
+		Nonce:        nonce,
+		Credential:   poc,
+	})
+	if err != nil {
+		return nil, err
+	} else if err := unwrapZbError(res.GetError()); err != nil {
+		return nil, err
+	}
+	return res.GetTableDefinitions(), nil
+}
+
+// Method GetTableDefinition returns the definition of table tableId.
+func (z *ZetabaseClient) GetTableDefinition(tableOwnerId, tableId string) (*zbprotocol.TableCreate, error) {
+	defns, err := z.ListTableDefinitions(tableOwnerId)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range defns {
+		if d.GetTableId() == tableId {
+			return d, nil
+		}
+	}
+	return nil, ErrTableNotFound
+}
+
+// Create a new table tblId with the data format, indices, and permissions of definition defn
+// (e.g. as returned by GetTableDefinition)
+func (z *ZetabaseClient) CreateTableFromDefinition(tblId string, defn *zbprotocol.TableCreate) error {
+	var perms []*PermEntry
+	for _, p := range defn.GetPermissions() {
+		perms = append(perms, PermEntryFromProtocol(p))
+	}
+	return z.CreateTable(tblId, defn.GetDataFormat(), indexedFieldsFromProtocol(defn.GetIndices()), perms, defn.GetAllowTokenAuth())
+}
+
 // Method AddPermission adds permission perm to the given table tblId.
 func (z *ZetabaseClient) AddPermission(tblOwnerId, tblId string, perm *PermEntry) error {
 	if !z.checkReady() {
 		return errors.New("NotReady")
 	}
+	if err := z.checkKeyPatterns([]*PermEntry{perm}); err != nil {
+		return err
+	}
 	nonce := z.nonceMaker.Get()
 	permsEnt := perm.ToProtocol(tblOwnerId, tblId)
 	permsEnt.Nonce = nonce
@@ -588,9 +693,98 @@ func (z *ZetabaseClient) AddPermission(tblOwnerId, tblId string, perm *PermEntry
 	return nil
 }
 
+// Method ListPermissions lists the permission entries currently in effect on table tblId.
+func (z *ZetabaseClient) ListPermissions(tblOwnerId, tblId string) ([]*PermEntry, error) {
+	if !z.checkReady() {
+		return nil, errors.New("NotReady")
+	}
+	nonce := z.nonceMaker.Get()
+	poc := z.getCredential(nonce, nil)
+	res, err := z.client.ListPermissions(z.ctx, &zbprotocol.ListPermissionsRequest{
+		Id:           z.userId,
+		TableOwnerId: tblOwnerId,
+		TableId:      tblId,
+		Nonce:        nonce,
+		Credential:   poc,
+	})
+	if err != nil {
+		return nil, err
+	} else if err := unwrapZbError(res.GetError()); err != nil {
+		return nil, err
+	}
+	var perms []*PermEntry
+	for _, p := range res.GetEntries() {
+		perms = append(perms, PermEntryFromProtocol(p))
+	}
+	return perms, nil
+}
+
+// Method RevokePermission removes permission perm from the given table tblId. The entry must match
+// an existing entry (audience, level, and constraints) exactly.
+func (z *ZetabaseClient) RevokePermission(tblOwnerId, tblId string, perm *PermEntry) error {
+	if !z.checkReady() {
+		return errors.New("NotReady")
+	}
+	if err := z.checkKeyPatterns([]*PermEntry{perm}); err != nil {
+		return err
+	}
+	nonce := z.nonceMaker.Get()
+	permsEnt := perm.ToProtocol(tblOwnerId, tblId)
+	permsEnt.Nonce = nonce
+	permsEnt.Credential = z.getCredential(nonce, PermissionsEntrySigningBytes(permsEnt))
+
+	res, err := z.client.RevokePermission(z.ctx, permsEnt)
+	if err != nil {
+		return err
+	}
+	return unwrapZbError(res)
+}
+
+// Method ReplacePermissions replaces all permission entries on table tblId with perms in a single request.
+func (z *ZetabaseClient) ReplacePermissions(tblOwnerId, tblId string, perms []*PermEntry) error {
+	if !z.checkReady() {
+		return errors.New("NotReady")
+	}
+	if err := z.checkKeyPatterns(perms); err != nil {
+		return err
+	}
+	nonce := z.nonceMaker.Get()
+	var pEntries []*zbprotocol.PermissionsEntry
+	for _, p := range perms {
+		pEntries = append(pEntries, p.ToProtocol(tblOwnerId, tblId))
+	}
+	poc := z.getCredential(nonce, PermissionsReplaceSigningBytes(tblId, pEntries))
+	res, err := z.client.ReplacePermissions(z.ctx, &zbprotocol.PermissionsReplace{
+		Id:           z.userId,
+		TableOwnerId: tblOwnerId,
+		TableId:      tblId,
+		Nonce:        nonce,
+		Credential:   poc,
+		Permissions:  pEntries,
+	})
+	if err != nil {
+		return err
+	}
+	return unwrapZbError(res)
+}
+
 // Method ListKeysWithPattern lists keys with a given prefix pattern, where the suffix wildcard operator
 // is represented by %.
 func (z *ZetabaseClient) ListKeysWithPattern(tableOwnerId, tableId, pattern string) *PaginationHandler {
+	if m := z.cleanMirror(tableOwnerId, tableId); m != nil {
+		keys := m.ListKeysWithPattern(pattern)
+		return StandardPaginationHandlerFor(func(idx int64) (map[string][]byte, bool, error) {
+			res := map[string][]byte{}
+			for _, k := range keys {
+				res[k] = nil
+			}
+			return res, false, nil
+		})
+	}
+	return z.listKeysRemote(tableOwnerId, tableId, pattern)
+}
+
+func (z *ZetabaseClient) listKeysRemote(tableOwnerId, tableId, pattern string) *PaginationHandler {
 	f := func(idx int64) (map[string][]byte, bool, error) {
 		m := map[string][]byte{}
 		tim, hasNxt, err := z.listKeysWithPattern(tableOwnerId, tableId, pattern, idx)
@@ -700,6 +894,13 @@ func (z *ZetabaseClient) query(tblOwnerId, tblId string, pgIdx int64, qry *zbpro
 }
 
 func (z *ZetabaseClient) QueryData(tbldOwnerId, tblId string, qry SubQueryConvertible) (*getPages, error) {
+	if m := z.cleanMirror(tbldOwnerId, tblId); m != nil {
+		keys, err := m.Query(qry)
+		if err != nil {
+			return nil, err
+		}
+		return z.Get(tbldOwnerId, tblId, keys), nil
+	}
 	res := z.Query(tbldOwnerId, tblId, qry)
 	data, err := res.DataAll()
 
@@ -721,10 +922,20 @@ func (z *ZetabaseClient) PutData(tableOwnerId, tableId, key string, valu []byte,
 	if !z.checkReady() {
 		return errors.New("NotReady")
 	}
+	if z.versionedTable(tableOwnerId, tableId) != nil || z.tableIndexes(tableOwnerId, tableId) != nil {
+		return z.PutMulti(tableOwnerId, tableId, []string{key}, [][]byte{valu}, overwrite)
+	}
+	if err := z.validateWrites(tableOwnerId, tableId, []string{key}, [][]byte{valu}); err != nil {
+		return err
+	}
+	valu, err := z.encodeValue(tableOwnerId, tableId, valu)
+	if err != nil {
+		return err
+	}
 	nonce := z.nonceMaker.Get()
 	xBytes := TablePutExtraSigningBytes(key, valu)
 	poc := z.getCredential(nonce, xBytes)
-	_, err := z.client.PutData(z.ctx, &zbprotocol.TablePut{
+	_, err = z.client.PutData(z.ctx, &zbprotocol.TablePut{
 		Id:           z.userId,
 		TableOwnerId: tableOwnerId,
 		TableId:      tableId,
@@ -734,6 +945,7 @@ func (z *ZetabaseClient) PutData(tableOwnerId, tableId, key string, valu []byte,
 		Nonce:        nonce,
 		Credential:   poc,
 	})
+	z.noteWrite(tableOwnerId, tableId, key)
 	if err != nil {
 		return err
 	} else {
@@ -741,8 +953,29 @@ func (z *ZetabaseClient) PutData(tableOwnerId, tableId, key string, valu []byte,
 	}
 }
 
-// Delete a given key-value pair from a table
+// Delete a given key-value pair from a table (moving it to the table's trash if soft deletes are
+// enabled; see SetSoftDelete)
 func (z *ZetabaseClient) DeleteKey(tableOwnerId, tableId, key string) error {
+	if z.softDeleteMode() == nil || IsReservedKey(key) {
+		return z.DeleteKeyPermanently(tableOwnerId, tableId, key)
+	}
+	if err := z.trashKey(tableOwnerId, tableId, key); err != nil {
+		return err
+	}
+	indexed, _ := z.indexEntries(tableOwnerId, tableId, []string{key}, [][]byte{nil})
+	return z.applyIndexEntries(tableOwnerId, tableId, indexed)
+}
+
+// Method DeleteKeyPermanently deletes a key like DeleteKey, but never moves it to the trash.
+func (z *ZetabaseClient) DeleteKeyPermanently(tableOwnerId, tableId, key string) error {
+	if err := z.deleteKeyRaw(tableOwnerId, tableId, key); err != nil {
+		return err
+	}
+	indexed, _ := z.indexEntries(tableOwnerId, tableId, []string{key}, [][]byte{nil})
+	return z.applyIndexEntries(tableOwnerId, tableId, indexed)
+}
+
+func (z *ZetabaseClient) deleteKeyRaw(tableOwnerId, tableId, key string) error {
 	if !z.checkReady() {
 		return errors.New("NotReady")
 	}
@@ -759,6 +992,7 @@ func (z *ZetabaseClient) DeleteKey(tableOwnerId, tableId, key string) error {
 		Nonce:        nonce,
 		Credential:   poc,
 	})
+	z.noteWrite(tableOwnerId, tableId, key)
 
 	if err != nil {
 		return err
@@ -767,8 +1001,16 @@ func (z *ZetabaseClient) DeleteKey(tableOwnerId, tableId, key string) error {
 	}
 }
 
-// Delete a table and all its contents
+// Delete a table and all its contents (moving them to a trash table if soft deletes are enabled;
+// see SetSoftDelete)
 func (z *ZetabaseClient) DeleteTable(tableOwnerId, tableId string) error {
+	if z.softDeleteMode() != nil {
+		return z.trashTable(tableOwnerId, tableId)
+	}
+	return z.deleteTableRaw(tableOwnerId, tableId)
+}
+
+func (z *ZetabaseClient) deleteTableRaw(tableOwnerId, tableId string) error {
 	if !z.checkReady() {
 		return errors.New("NotReady")
 	}
@@ -785,6 +1027,7 @@ func (z *ZetabaseClient) DeleteTable(tableOwnerId, tableId string) error {
 		Nonce:        nonce,
 		Credential:   poc,
 	})
+	z.noteWrite(tableOwnerId, tableId)
 
 	if err != nil {
 		return err
@@ -810,6 +1053,8 @@ func (z *ZetabaseClient) listKeysWithPattern(tableOwnerId, tableId, pattern stri
 	})
 	if err != nil {
 		return nil, false, err
+	} else if err := unwrapZbError(res.GetError()); err != nil {
+		return nil, false, err
 	} else {
 		rig := res.GetKeys()
 		return rig, res.GetPagination().GetHasNextPage(), nil
@@ -881,3 +1126,9 @@ func (z *ZetabaseClient) Connect() error {
 	z.client = zbprotocol.NewZetabaseProviderClient(z.conn)
 	return nil
 }
+
+// Method ConnectProvider makes the client send its requests to c instead of connecting to the
+// server with Connect, e.g. to use an in-memory server in tests.
+func (z *ZetabaseClient) ConnectProvider(c zbprotocol.ZetabaseProviderClient) {
+	z.client = c
+}
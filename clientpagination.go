@@ -2,6 +2,8 @@ package zetabase
 
 import (
 	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
 )
 
@@ -19,6 +21,8 @@ type getPages struct {
 	KeyIndex      int
 	TableOwnerId  string 
 	TableId       string 
+	mirror        *TableMirror
+	cache         *valueCache
 }
 
 func makePutPages(client *ZetabaseClient, keys []string, valus [][]byte, maxBytes uint64) *putPages {
@@ -84,6 +88,9 @@ func (p *putPages) pagify() ([][]string, [][][]byte, error) {
 
 func MakeGetPages(client *ZetabaseClient, dataKeys []string, maxItemSize int64, tableOwnerId, tableId string) *getPages {
 	maxPageSize := int64(2000000)
+	if client != nil {
+		maxItemSize = client.wireItemSize(tableOwnerId, tableId, maxItemSize)
+	}
 	itemsPerPage := maxPageSize/maxItemSize
 	kgs := breakKeys(dataKeys, itemsPerPage)
 
@@ -123,19 +130,81 @@ func addData(data map[string][]byte, curData map[string][]byte) {
 	}
 }
 
-func (p *getPages) getCurPag() *PaginationHandler{
-	pag := p.Client.getPag(p.TableOwnerId, p.TableId, p.KeyGroups[p.KeyIndex])
-	return pag 
+// Data of the current key group, served from the client's cache where possible (for Get)
+func (p *getPages) curData() (map[string][]byte, error) {
+	data, err := p.curDataUnfiltered()
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.withoutExpired(p.TableOwnerId, p.TableId, data)
+}
+
+func (p *getPages) curDataUnfiltered() (map[string][]byte, error) {
+	fetch := func(keys []string) (map[string][]byte, error) {
+		return p.Client.getSplitting(p.TableOwnerId, p.TableId, keys)
+	}
+	keys := p.KeyGroups[p.KeyIndex]
+	var local map[string][]byte
+	if p.mirror != nil {
+		// Keys written through the client since the last sync are read from the server
+		var clean []string
+		clean, keys = p.mirror.splitDirty(keys)
+		local = p.mirror.Get(clean)
+		if len(keys) == 0 {
+			return local, nil
+		}
+	}
+	var data map[string][]byte
+	var err error
+	if p.cache == nil {
+		data, err = fetch(keys)
+	} else {
+		data, err = p.cache.getMany(p.TableOwnerId, p.TableId, keys, fetch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	addData(data, local)
+	return data, nil
+}
+
+// Fetch keys, splitting the request in halves while the response is too large for the connection:
+// key groups are sized from the table's average compression ratio (see wireItemSize), so a group
+// of values that did not compress can exceed the page size.
+func (z *ZetabaseClient) getSplitting(tableOwnerId, tableId string, keys []string) (map[string][]byte, error) {
+	data, err := z.getPag(tableOwnerId, tableId, keys).DataAll()
+	if status.Code(err) != codes.ResourceExhausted || len(keys) < 2 {
+		return data, err
+	}
+	half := len(keys) / 2
+	data, err = z.getSplitting(tableOwnerId, tableId, keys[:half])
+	if err != nil {
+		return nil, err
+	}
+	rest, err := z.getSplitting(tableOwnerId, tableId, keys[half:])
+	if err != nil {
+		return nil, err
+	}
+	addData(data, rest)
+	return data, nil
+}
+
+func dataKeys(data map[string][]byte) []string {
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	return keys
 }
 
 func (p *getPages) DataAll() (map[string][]byte, error) {
 	dataAll := make(map[string][]byte)
 
 	for p.KeyIndex < len(p.KeyGroups) {
-		var curData map[string][]byte
-
-		curPag := p.getCurPag()
-		curData, _ = curPag.DataAll()
+		curData, err := p.curData()
+		if err != nil {
+			return nil, err
+		}
 
 		addData(dataAll, curData)
 
@@ -148,8 +217,11 @@ func (p *getPages) KeysAll() ([]string, error) {
 	var keys []string 
 
 	for p.KeyIndex < len(p.KeyGroups) {
-		curPag := p.getCurPag()
-		pagKeys, _ := curPag.KeysAll()
+		curData, err := p.curData()
+		if err != nil {
+			return nil, err
+		}
+		pagKeys := dataKeys(curData)
 
 		keys = append(keys, pagKeys...)
 		p.KeyIndex ++
@@ -162,8 +234,7 @@ func (p *getPages) Data() (map[string][]byte, error) {
 		return make(map[string][]byte), nil
 	}
 
-	curPag := p.getCurPag()
-	return curPag.Data()
+	return p.curData()
 }
 
 func (p *getPages) Keys() ([]string, error) {
@@ -171,18 +242,21 @@ func (p *getPages) Keys() ([]string, error) {
         return []string{}, nil
     }
 
-	curPag := p.getCurPag()
-	return curPag.Keys()
+	curData, err := p.curData()
+	if err != nil {
+		return nil, err
+	}
+	return dataKeys(curData), nil
 }
 
 func (p *getPages) GetFirstNPages(numPages int) (map[string][]byte, error) {
 	dataAll := make(map[string][]byte)
 
-	for p.KeyIndex < numPages {
-		var curData map[string][]byte
-
-		curPag := p.getCurPag()
-		curData, _ = curPag.DataAll()
+	for p.KeyIndex < numPages && p.KeyIndex < len(p.KeyGroups) {
+		curData, err := p.curData()
+		if err != nil {
+			return nil, err
+		}
 
 		addData(dataAll, curData)
 
@@ -2,6 +2,7 @@ package zetabase
 
 import (
 	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
 	"log"
 	"math/rand"
 	"testing"
@@ -56,4 +57,19 @@ func Test_Pagify(t *testing.T) {
 	} else {
 		log.Printf("Got error: %s!\n", err.Error())
 	}
-}
\ No newline at end of file
+}
+func Test_GetPagesReturnsFetchErrors(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+	srv.GetErr = errors.New("Unavailable")
+
+	if data, err := z.GetUncached(testOwnerId, "src", []string{"a", "b"}).DataAll(); err == nil {
+		t.Fatalf("Expected DataAll to fail, got %v", data)
+	}
+	if keys, err := z.GetUncached(testOwnerId, "src", []string{"a", "b"}).KeysAll(); err == nil {
+		t.Fatalf("Expected KeysAll to fail, got %v", keys)
+	}
+	if data, err := z.Get(testOwnerId, "src", []string{"a", "b"}).GetFirstNPages(1); err == nil {
+		t.Fatalf("Expected GetFirstNPages to fail, got %v", data)
+	}
+}
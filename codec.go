@@ -0,0 +1,324 @@
+package zetabase
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"hash/crc32"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+)
+
+// Values written through a codec start with codecMagic, the codec's id and the CRC-32C of the
+// rest of the value, so that values written without a codec are not mistaken for encoded ones
+const (
+	codecMagic     = "\x00zbcodec"
+	codecHeaderLen = len(codecMagic) + 1 + 4
+	codecIdNone    = 0
+)
+
+var codecChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Type Codec compresses values written to a table (see SetTableCodec). Each codec has an id,
+// stored in the header of encoded values so that readers can decode them without configuration;
+// ids 0-15 are reserved for codecs built into the client library.
+type Codec interface {
+	Id() byte
+	Name() string
+	Encode(dat []byte) ([]byte, error)
+	Decode(dat []byte) ([]byte, error)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Id() byte     { return 1 }
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) Encode(dat []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(dat); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(dat []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(dat))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+type deflateCodec struct{}
+
+func (deflateCodec) Id() byte     { return 2 }
+func (deflateCodec) Name() string { return "deflate" }
+
+func (deflateCodec) Encode(dat []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(dat); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (deflateCodec) Decode(dat []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(dat))
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Snappy's block format, as written by github.com/golang/snappy
+type snappyCodec struct{}
+
+func (snappyCodec) Id() byte     { return 3 }
+func (snappyCodec) Name() string { return "snappy" }
+
+func (snappyCodec) Encode(dat []byte) ([]byte, error) {
+	return snappy.Encode(nil, dat), nil
+}
+
+func (snappyCodec) Decode(dat []byte) ([]byte, error) {
+	return snappy.Decode(nil, dat)
+}
+
+// Zstandard frames, written by github.com/klauspost/compress/zstd. The encoder and decoder are
+// shared (EncodeAll and DecodeAll are safe for concurrent use) and created on first use.
+type zstdCodec struct{}
+
+var zstdCoders struct {
+	once sync.Once
+	enc  *zstd.Encoder
+	dec  *zstd.Decoder
+	err  error
+}
+
+func zstdInit() error {
+	zstdCoders.once.Do(func() {
+		zstdCoders.enc, zstdCoders.err = zstd.NewWriter(nil)
+		if zstdCoders.err == nil {
+			zstdCoders.dec, zstdCoders.err = zstd.NewReader(nil)
+		}
+	})
+	return zstdCoders.err
+}
+
+func (zstdCodec) Id() byte     { return 4 }
+func (zstdCodec) Name() string { return "zstd" }
+
+func (zstdCodec) Encode(dat []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+	return zstdCoders.enc.EncodeAll(dat, nil), nil
+}
+
+func (zstdCodec) Decode(dat []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+	return zstdCoders.dec.DecodeAll(dat, nil)
+}
+
+var (
+	// Gzip at the default level: the better ratio
+	CodecGzip Codec = gzipCodec{}
+	// Raw deflate at its fastest level: cheaper on CPU for hot tables
+	CodecDeflate Codec = deflateCodec{}
+	// Snappy's block format: the cheapest on CPU, for a lower ratio
+	CodecSnappy Codec = snappyCodec{}
+	// Zstandard at its default level: close to gzip's ratio at a fraction of the CPU
+	CodecZstd Codec = zstdCodec{}
+)
+
+var codecRegistry = struct {
+	sync.RWMutex
+	byId   map[byte]Codec
+	byName map[string]Codec
+}{
+	byId:   map[byte]Codec{1: CodecGzip, 2: CodecDeflate, 3: CodecSnappy, 4: CodecZstd},
+	byName: map[string]Codec{"gzip": CodecGzip, "deflate": CodecDeflate, "snappy": CodecSnappy, "zstd": CodecZstd},
+}
+
+// Function RegisterCodec makes a codec (e.g. one wrapping another compression library) available
+// for SetTableCodec and for decoding values read from any table.
+func RegisterCodec(c Codec) error {
+	codecRegistry.Lock()
+	defer codecRegistry.Unlock()
+	if c.Id() < 16 {
+		return errors.New("ReservedCodecId")
+	}
+	if _, ok := codecRegistry.byId[c.Id()]; ok {
+		return errors.New("CodecIdInUse")
+	}
+	if _, ok := codecRegistry.byName[c.Name()]; ok {
+		return errors.New("CodecNameInUse")
+	}
+	codecRegistry.byId[c.Id()] = c
+	codecRegistry.byName[c.Name()] = c
+	return nil
+}
+
+// Function LookupCodec finds a built-in or registered codec by name.
+func LookupCodec(name string) (Codec, bool) {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+	c, ok := codecRegistry.byName[name]
+	return c, ok
+}
+
+func codecById(id byte) (Codec, bool) {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+	c, ok := codecRegistry.byId[id]
+	return c, ok
+}
+
+// Codec configured for a table, with the byte counts used to estimate its compression ratio
+type tableCodec struct {
+	codec     Codec
+	rawBytes  int64
+	wireBytes int64
+}
+
+// Method SetTableCodec makes the client compress values it writes to a BINARY table with codec c
+// (nil turns compression off again). Values are only stored compressed when that makes them
+// smaller. Reads decode compressed values from any table, whether or not a codec is set: encoded
+// values carry a checksum, so only values written through a codec are decoded.
+func (z *ZetabaseClient) SetTableCodec(tableOwnerId, tableId string, c Codec) error {
+	k := cacheTableKey(tableOwnerId, tableId)
+	if c == nil {
+		z.codecs.Delete(k)
+		return nil
+	}
+	defn, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err != nil {
+		return err
+	} else if defn.GetDataFormat() != zbprotocol.TableDataFormat_BINARY {
+		return errors.New("CodecRequiresBinaryTable")
+	}
+	z.codecs.Store(k, &tableCodec{codec: c})
+	return nil
+}
+
+func (z *ZetabaseClient) tableCodec(tableOwnerId, tableId string) *tableCodec {
+	if tc, ok := z.codecs.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		return tc.(*tableCodec)
+	}
+	return nil
+}
+
+func hasCodecHeader(dat []byte) bool {
+	if len(dat) < codecHeaderLen || string(dat[:len(codecMagic)]) != codecMagic {
+		return false
+	}
+	sum := binary.BigEndian.Uint32(dat[len(codecMagic)+1 : codecHeaderLen])
+	return sum == crc32.Checksum(dat[codecHeaderLen:], codecChecksumTable)
+}
+
+func withCodecHeader(id byte, dat []byte) []byte {
+	res := make([]byte, codecHeaderLen, codecHeaderLen+len(dat))
+	copy(res, codecMagic)
+	res[len(codecMagic)] = id
+	binary.BigEndian.PutUint32(res[len(codecMagic)+1:], crc32.Checksum(dat, codecChecksumTable))
+	return append(res, dat...)
+}
+
+// Encode a value for writing to a table, if the table has a codec
+func (z *ZetabaseClient) encodeValue(tableOwnerId, tableId string, valu []byte) ([]byte, error) {
+	tc := z.tableCodec(tableOwnerId, tableId)
+	if tc == nil {
+		return valu, nil
+	}
+	enc, err := tc.codec.Encode(valu)
+	if err != nil {
+		return nil, err
+	}
+	res := valu
+	if len(enc)+codecHeaderLen < len(valu) {
+		res = withCodecHeader(tc.codec.Id(), enc)
+	} else if hasCodecHeader(valu) {
+		// Stored as is, but framed so it is not mistaken for an encoded value
+		res = withCodecHeader(codecIdNone, valu)
+	}
+	atomic.AddInt64(&tc.rawBytes, int64(len(valu)))
+	atomic.AddInt64(&tc.wireBytes, int64(len(res)))
+	return res, nil
+}
+
+func (z *ZetabaseClient) encodeValues(tableOwnerId, tableId string, valus [][]byte) ([][]byte, error) {
+	if z.tableCodec(tableOwnerId, tableId) == nil {
+		return valus, nil
+	}
+	res := make([][]byte, len(valus))
+	for i, v := range valus {
+		enc, err := z.encodeValue(tableOwnerId, tableId, v)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = enc
+	}
+	return res, nil
+}
+
+// Decode a value read from a table. Values without a valid header, or with an unknown codec, are
+// returned as stored.
+func decodeValue(dat []byte) ([]byte, error) {
+	if !hasCodecHeader(dat) {
+		return dat, nil
+	}
+	id := dat[len(codecMagic)]
+	if id == codecIdNone {
+		return dat[codecHeaderLen:], nil
+	}
+	c, ok := codecById(id)
+	if !ok {
+		return dat, nil
+	}
+	res, err := c.Decode(dat[codecHeaderLen:])
+	if err != nil {
+		return nil, errors.New("CorruptEncodedValue")
+	}
+	return res, nil
+}
+
+// Estimated size on the wire of items of up to maxItemSize bytes, based on the compression seen
+// so far in writes to the table. This is an average: reads split pages the server finds too large
+// (see getSplitting).
+func (z *ZetabaseClient) wireItemSize(tableOwnerId, tableId string, maxItemSize int64) int64 {
+	tc := z.tableCodec(tableOwnerId, tableId)
+	if tc == nil {
+		return maxItemSize
+	}
+	raw, wire := atomic.LoadInt64(&tc.rawBytes), atomic.LoadInt64(&tc.wireBytes)
+	if raw == 0 || wire >= raw {
+		return maxItemSize
+	}
+	sz := maxItemSize * wire / raw
+	if sz < maxItemSize/20 {
+		sz = maxItemSize / 20
+	}
+	if sz < 1 {
+		sz = 1
+	}
+	return sz
+}
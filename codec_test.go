@@ -0,0 +1,163 @@
+package zetabase
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func Test_TableCodecRoundTrip(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, nil)
+	if err := z.SetTableCodec(testOwnerId, "src", CodecGzip); err != nil {
+		t.Fatalf("Failed to set codec: %s", err.Error())
+	}
+	text := []byte(strings.Repeat(`{"name": "ann", "age": 31}`, 100))
+	noise := make([]byte, 500)
+	rand.New(rand.NewSource(3)).Read(noise)
+	framed := withCodecHeader(CodecGzip.Id(), []byte("not gzip"))
+
+	z.PutData(testOwnerId, "src", "text", text, true)
+	if err := z.PutMulti(testOwnerId, "src", []string{"noise", "framed"}, [][]byte{noise, framed}, true); err != nil {
+		t.Fatalf("PutMulti failed: %s", err.Error())
+	}
+	stored := srv.Table(testOwnerId, "src").Data
+	if len(stored["text"]) >= len(text)/5 || !bytes.Equal(stored["noise"], noise) || bytes.Equal(stored["framed"], framed) {
+		t.Fatalf("Unexpected stored sizes: %d, %d, %d", len(stored["text"]), len(stored["noise"]), len(stored["framed"]))
+	}
+	if sz := z.wireItemSize(testOwnerId, "src", 1000); sz >= 1000 {
+		t.Fatalf("Page sizing does not account for compression (%d)", sz)
+	}
+
+	// Readers decode without configuration
+	other := newFakeClientFor(testOwnerId, srv)
+	data, err := other.getPag(testOwnerId, "src", []string{"text", "noise", "framed"}).DataAll()
+	if err != nil || !bytes.Equal(data["text"], text) || !bytes.Equal(data["noise"], noise) || !bytes.Equal(data["framed"], framed) {
+		t.Fatalf("Values not decoded: %v", err)
+	}
+
+	err = z.Update(testOwnerId, "src", "text", func(old []byte) ([]byte, error) {
+		return append(old, old...), nil
+	})
+	if err != nil {
+		t.Fatalf("Update of an encoded value failed: %s", err.Error())
+	}
+	if v, _, _ := z.getUncached(testOwnerId, "src", "text"); len(v) != 2*len(text) {
+		t.Fatalf("Unexpected value after update (%d bytes)", len(v))
+	}
+}
+
+func Test_TableCodecResplitsLargePages(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, nil)
+	if err := z.SetTableCodec(testOwnerId, "src", CodecGzip); err != nil {
+		t.Fatalf("Failed to set codec: %s", err.Error())
+	}
+	if err := z.PutData(testOwnerId, "src", "text", []byte(strings.Repeat("abc", 100000)), true); err != nil {
+		t.Fatalf("PutData failed: %s", err.Error())
+	}
+
+	// Values that do not compress, in groups sized for well-compressed ones
+	rng := rand.New(rand.NewSource(5))
+	var keys []string
+	var pairs []*zbprotocol.DataPair
+	for i := 0; i < 5000; i++ {
+		noise := make([]byte, 1000)
+		rng.Read(noise)
+		keys = append(keys, fmt.Sprintf("noise/%04d", i))
+		pairs = append(pairs, &zbprotocol.DataPair{Key: keys[i], Value: noise})
+	}
+	srv.Put(testOwnerId, "src", pairs, true)
+	srv.MaxResponseBytes = 2000000
+	if pgs := MakeGetPages(z, keys, 1000, testOwnerId, "src"); len(pgs.KeyGroups) != 1 {
+		t.Fatalf("Expected a single key group, got %d", len(pgs.KeyGroups))
+	}
+
+	data, err := z.Get(testOwnerId, "src", keys).DataAll()
+	if err != nil || len(data) != len(keys) {
+		t.Fatalf("Expected %d values, got %d (%v)", len(keys), len(data), err)
+	}
+}
+
+func Test_SnappyCodec(t *testing.T) {
+	// Reference block from the format description: "abcd" as a literal, then 7 bytes copied from 4 back
+	block := []byte{0x0b, 0x0c, 'a', 'b', 'c', 'd', 0x1a, 0x04, 0x00}
+	if dec, err := CodecSnappy.Decode(block); err != nil || string(dec) != "abcdabcdabc" {
+		t.Fatalf("Failed to decode a reference block: %q, %v", dec, err)
+	}
+	if _, err := CodecSnappy.Decode(block[:len(block)-1]); err == nil {
+		t.Fatalf("Expected an error decoding a truncated block")
+	}
+}
+
+func Test_BuiltInCodecsRoundTrip(t *testing.T) {
+	noise := make([]byte, 70000)
+	rand.New(rand.NewSource(7)).Read(noise)
+	inputs := [][]byte{
+		nil,
+		[]byte("abc"),
+		[]byte(strings.Repeat(`{"name": "ann", "age": 31}`, 1000)),
+		bytes.Repeat([]byte{0}, 100000),
+		noise,
+		append(append([]byte{}, noise...), noise[:500]...),
+	}
+	for _, c := range []Codec{CodecGzip, CodecDeflate, CodecSnappy, CodecZstd} {
+		if found, ok := LookupCodec(c.Name()); !ok || found != c {
+			t.Fatalf("Codec %s not registered", c.Name())
+		}
+		for i, in := range inputs {
+			enc, err := c.Encode(in)
+			if err != nil {
+				t.Fatalf("%s failed to encode input %d: %s", c.Name(), i, err.Error())
+			}
+			dec, err := c.Decode(enc)
+			if err != nil || !bytes.Equal(dec, in) {
+				t.Fatalf("Input %d did not round-trip through %s: %v", i, c.Name(), err)
+			}
+		}
+		if enc, _ := c.Encode(inputs[2]); len(enc) >= len(inputs[2])/10 {
+			t.Fatalf("Repetitive input not compressed by %s (%d bytes)", c.Name(), len(enc))
+		}
+	}
+	if _, err := CodecZstd.Decode([]byte("not zstd")); err == nil {
+		t.Fatalf("Expected an error decoding an invalid zstd frame")
+	}
+}
+
+func Test_TableCodecRequiresBinary(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, nil)
+	if err := z.SetTableCodec(testOwnerId, "src", CodecDeflate); err == nil {
+		t.Fatalf("Expected an error setting a codec on a JSON table")
+	}
+	if err := RegisterCodec(CodecGzip); err == nil {
+		t.Fatalf("Expected an error registering a reserved codec id")
+	}
+}
+
+func Test_TableCodecLeavesPlainValues(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, nil)
+	valus := [][]byte{
+		[]byte(codecMagic + "\x01\x00\x00\x00\x00not gzip"),
+		append(withCodecHeader(CodecGzip.Id(), []byte("x"))[:codecHeaderLen], 'y'),
+		[]byte("\x00zc\x01short"),
+	}
+	keys := []string{"a", "b", "c"}
+	if err := z.PutMulti(testOwnerId, "src", keys, valus, true); err != nil {
+		t.Fatalf("PutMulti failed: %s", err.Error())
+	}
+	data, err := z.getPag(testOwnerId, "src", keys).DataAll()
+	if err != nil {
+		t.Fatalf("Read failed: %s", err.Error())
+	}
+	for i, k := range keys {
+		if !bytes.Equal(data[k], valus[i]) {
+			t.Fatalf("Value %s written without a codec was decoded: %q", k, data[k])
+		}
+	}
+}
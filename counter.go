@@ -0,0 +1,84 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Table of the client's own tables that holds the sequences of NextSequence
+	SequenceTableId = "zbsequences"
+	// Counters are contended by design, so they retry for longer than Update
+	DefaultCounterAttempts = 100
+)
+
+// Method Increment atomically adds delta to the counter stored at key and returns its new value.
+// A missing key counts as zero. Counters are stored as decimal text, so they can be read with Get
+// from tables of any data format; a key holding anything else fails with NotACounter. Increments
+// are retried with CompareAndSwap until they apply, so that concurrent increments, including from
+// other processes, are never lost as long as the server supports CompareAndSwap natively (see
+// CompareAndSwapMinServerVersion).
+func (z *ZetabaseClient) Increment(tableOwnerId, tableId, key string, delta int64) (int64, error) {
+	var res int64
+	err := z.update(tableOwnerId, tableId, key, func(old []byte) ([]byte, error) {
+		cur, err := parseCounter(old)
+		if err != nil {
+			return nil, err
+		}
+		res = cur + delta
+		return []byte(strconv.FormatInt(res, 10)), nil
+	}, DefaultCounterAttempts)
+	if err != nil {
+		return 0, err
+	}
+	return res, nil
+}
+
+func parseCounter(valu []byte) (int64, error) {
+	if valu == nil {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(valu)), 10, 64)
+	if err != nil {
+		return 0, errors.New("NotACounter")
+	}
+	return n, nil
+}
+
+// Method NextSequence returns the next value of the named sequence, starting from 1. Sequences
+// are kept in the client's SequenceTableId table, which is created on first use. Every call
+// returns a distinct value, and values handed out later are larger, across all processes using
+// the same identity. This needs the server to support CompareAndSwap natively, and fails with
+// SequenceRequiresCompareAndSwap otherwise.
+func (z *ZetabaseClient) NextSequence(name string) (int64, error) {
+	if !z.checkReady() {
+		return 0, errors.New("NotReady")
+	}
+	if !z.nativeCompareAndSwap() {
+		return 0, errors.New("SequenceRequiresCompareAndSwap")
+	}
+	if err := z.ensureSequenceTable(); err != nil {
+		return 0, err
+	}
+	return z.Increment(z.Id(), SequenceTableId, name, 1)
+}
+
+func (z *ZetabaseClient) ensureSequenceTable() error {
+	if atomic.LoadInt32(&z.sequenceTable) != 0 {
+		return nil
+	}
+	if _, err := z.GetTableDefinition(z.Id(), SequenceTableId); err != nil {
+		err = z.CreateTable(SequenceTableId, zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+		// Another process may have created it in the meantime
+		if err != nil && !errors.Is(err, ErrTableExists) {
+			if _, err2 := z.GetTableDefinition(z.Id(), SequenceTableId); err2 != nil {
+				return err
+			}
+		}
+	}
+	atomic.StoreInt32(&z.sequenceTable, 1)
+	return nil
+}
@@ -0,0 +1,88 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func Test_IncrementConcurrent(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	if err := z.CreateTable("src", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	const workers, perWorker = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		// A client per worker, as if each were its own process
+		go func(c *ZetabaseClient) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := c.Increment(testOwnerId, "src", "hits", 2); err != nil {
+					errs <- err
+				}
+			}
+		}(newFakeClientFor(testOwnerId, srv))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Increment failed: %s", err.Error())
+	}
+	if n, err := z.Increment(testOwnerId, "src", "hits", -1); err != nil || n != 2*workers*perWorker-1 {
+		t.Fatalf("Lost increments: %d (%v)", n, err)
+	}
+	z.PutData(testOwnerId, "src", "text", []byte("abc"), true)
+	if _, err := z.Increment(testOwnerId, "src", "text", 1); err == nil || err.Error() != "NotACounter" {
+		t.Fatalf("Expected NotACounter, got %v", err)
+	}
+}
+
+func Test_NextSequence(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	// Support is detected once per client, so check an old server with another one
+	if _, err := newFakeClientFor(testOwnerId, srv).NextSequence("orders"); err == nil || err.Error() != "SequenceRequiresCompareAndSwap" {
+		t.Fatalf("Expected SequenceRequiresCompareAndSwap, got %v", err)
+	}
+	srv.Version = CompareAndSwapMinServerVersion
+
+	const workers, perWorker = 6, 20
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var all []int64
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(c *ZetabaseClient) {
+			defer wg.Done()
+			var last int64
+			for i := 0; i < perWorker; i++ {
+				n, err := c.NextSequence("orders")
+				if err != nil {
+					t.Errorf("NextSequence failed: %s", err.Error())
+					return
+				}
+				if n <= last {
+					t.Errorf("Sequence not monotonic: %d after %d", n, last)
+				}
+				last = n
+				lock.Lock()
+				all = append(all, n)
+				lock.Unlock()
+			}
+		}(newFakeClientFor(testOwnerId, srv))
+	}
+	wg.Wait()
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	for i, n := range all {
+		if n != int64(i+1) {
+			t.Fatalf("Sequence values not distinct and dense: %v", all)
+		}
+	}
+	if n, err := z.NextSequence("invoices"); err != nil || n != 1 {
+		t.Fatalf("Sequences not independent: %d (%v)", n, err)
+	}
+}
@@ -44,6 +44,14 @@ func permissionSigningBytes(entry zbprotocol.PermissionsEntry) []byte {
 			b3 = append(b3, byte(p.KeyConstraint.ValueType))
 			s := []byte(p.KeyConstraint.RequiredValue)
 			b3 = append(b3, s...)
+			if len(p.KeyConstraint.RequiredPrefix) > 0 || len(p.KeyConstraint.RequiredSuffix) > 0 {
+				// Signed from KeyPatternPermissionsMinServerVersion on; entries without a prefix or
+				// suffix sign as before
+				b3 = append(b3, 0)
+				b3 = append(b3, []byte(p.KeyConstraint.RequiredPrefix)...)
+				b3 = append(b3, 0)
+				b3 = append(b3, []byte(p.KeyConstraint.RequiredSuffix)...)
+			}
 		} else {
 			b3 = append(b3, byte(p.FieldConstraint.ConstraintType))
 			b3 = append(b3, byte(p.FieldConstraint.ValueType))
@@ -73,6 +81,17 @@ func PermissionsEntrySigningBytes(perm *zbprotocol.PermissionsEntry) []byte {
 	return permissionSetSigningBytes([]*zbprotocol.PermissionsEntry{perm})
 }
 
+// Get extra signing bytes for replacing the full permission set of table tblId
+func PermissionsReplaceSigningBytes(tblId string, perms []*zbprotocol.PermissionsEntry) []byte {
+	bs := []byte(tblId)
+	for _, p := range perms {
+		if p != nil {
+			bs = append(bs, PermissionsEntrySigningBytes(p)...)
+		}
+	}
+	return bs
+}
+
 func TableCreateSigningBytes(tblId string, perms []*zbprotocol.PermissionsEntry) []byte {
 	bs := []byte(tblId)
 	bs2 := permissionSetSigningBytes(perms)
@@ -93,6 +112,16 @@ func MultiPutExtraSigningBytesMd5(pairs []*zbprotocol.DataPair) []byte {
 	return bs
 }
 
+// Get extra signing bytes for a batch of puts and deletes (ApplyBatch).
+func BatchExtraSigningBytes(puts []*zbprotocol.DataPair, deletes []string) []byte {
+	hash := md5.New()
+	hash.Write(MultiPutExtraSigningBytesMd5(puts))
+	for _, k := range deletes {
+		hash.Write([]byte(k))
+	}
+	return hash.Sum(nil)
+}
+
 //func MultiPutExtraSigningBytesMurmur3(pairs []*zbprotocol.DataPair) []byte {
 //	hash := murmur3.New32WithSeed(1234)
 //	for _, v := range pairs {
@@ -151,10 +180,14 @@ func MultiPutExtraSigningBytesMd5(pairs []*zbprotocol.DataPair) []byte {
 // -----------------------------------------------
 
 // Get extra signing bytes for a single table put
-func TablePutExtraSigningBytes(key string, valu []byte) []byte {
+func TablePutExtraSigningBytes(key string, valu []byte, expectedValueHash ...[]byte) []byte {
 	hash := md5.New()
 	hash.Write([]byte(key))
 	hash.Write(valu)
+	// Conditional puts also sign the expected hash (unconditional puts are signed as before)
+	for _, h := range expectedValueHash {
+		hash.Write(h)
+	}
 	return hash.Sum(nil)
 }
 
@@ -237,19 +270,14 @@ func EncodeEcdsaPrivateKey(privateKey *ecdsa.PrivateKey) ([]byte, error) {
 	return pemEncoded, nil
 }
 
+// Decode a public key; besides PKIX PEM, any format accepted by DecodePublicKeyAny may be used.
 func DecodeEcdsaPublicKey(pemEncodedPub string) (*ecdsa.PublicKey, error) {
-	blockPub, _ := pem.Decode([]byte(pemEncodedPub))
-	x509EncodedPub := blockPub.Bytes
-	genericPublicKey, _ := x509.ParsePKIXPublicKey(x509EncodedPub)
-	publicKey := genericPublicKey.(*ecdsa.PublicKey)
-
-	return publicKey, nil
+	publicKey, _, err := DecodePublicKeyAny([]byte(pemEncodedPub))
+	return publicKey, err
 }
 
+// Decode a private key; besides PEM, any format accepted by DecodePrivateKeyAny may be used.
 func DecodeEcdsaPrivateKey(pemEncoded string) (*ecdsa.PrivateKey, error) {
-	block, _ := pem.Decode([]byte(pemEncoded))
-	x509Encoded := block.Bytes
-	privateKey, _ := x509.ParseECPrivateKey(x509Encoded)
-
-	return privateKey, nil
+	privateKey, _, err := DecodePrivateKeyAny([]byte(pemEncoded))
+	return privateKey, err
 }
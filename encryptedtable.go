@@ -0,0 +1,664 @@
+package zetabase
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	encEnvelopeVersion = byte(1)
+	encDataKeyNs       = "dk"
+	encTokenKeyNs      = "tk"
+	encJsonField       = "_zbenc"
+	encKeyBytes        = 32
+	encReEncryptBatch  = 500
+)
+
+// Type EncryptedTableOptions configures an EncryptedTable.
+type EncryptedTableOptions struct {
+	// Data format of the underlying table, which determines how ciphertext is stored: raw bytes
+	// for BINARY, base64 for PLAIN_TEXT, and a JSON document for JSON tables.
+	Format zbprotocol.TableDataFormat
+	// Optional 32-byte key-encryption key. If nil, one is derived from the client's ECDSA identity
+	// key, and rotating the identity key (see ReplaceIdentityKey) rewraps the table's data keys.
+	KEK []byte
+	// JSON fields copied in the clear next to the ciphertext so they remain queryable (JSON tables only).
+	ClearFields []string
+	// JSON fields stored as deterministic HMAC tokens so that equality queries still work (JSON tables only).
+	TokenFields []string
+}
+
+// Type EncryptedTable wraps a table so that values are encrypted client-side with AES-GCM under
+// per-table data keys. The data keys are themselves stored in the table, wrapped by a key-encryption key.
+type EncryptedTable struct {
+	client       *ZetabaseClient
+	tableOwnerId string
+	tableId      string
+	opts         EncryptedTableOptions
+	kek          []byte
+	tokenKey     []byte
+	dataKeys     map[string][]byte
+	activeKeyId  string
+	lock         *sync.Mutex
+}
+
+type wrappedKeyRecord struct {
+	Id      string `json:"id"`
+	Created int64  `json:"created"`
+	Wrapped []byte `json:"wrapped"`
+}
+
+// Open an encrypted view of table tableId, creating its first data key if it has none yet.
+func NewEncryptedTable(z *ZetabaseClient, tableOwnerId, tableId string, opts *EncryptedTableOptions) (*EncryptedTable, error) {
+	var o EncryptedTableOptions
+	if opts != nil {
+		o = *opts
+	}
+	if (len(o.ClearFields) > 0 || len(o.TokenFields) > 0) && o.Format != zbprotocol.TableDataFormat_JSON {
+		return nil, errors.New("FieldOptionsRequireJsonTable")
+	}
+	kek := o.KEK
+	if kek == nil {
+		if z.privKey == nil {
+			return nil, errors.New("NoKeyEncryptionKey")
+		}
+		kek = DeriveTableKEK(z.privKey.D.Bytes(), tableOwnerId, tableId)
+		z.TrackEncryptedTable(tableOwnerId, tableId)
+	} else if len(kek) != encKeyBytes {
+		return nil, errors.New("InvalidKeyLength")
+	}
+	e := &EncryptedTable{
+		client:       z,
+		tableOwnerId: tableOwnerId,
+		tableId:      tableId,
+		opts:         o,
+		kek:          kek,
+		dataKeys:     map[string][]byte{},
+		lock:         &sync.Mutex{},
+	}
+	err := e.loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(e.dataKeys) == 0 {
+		err = e.RotateKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Function DeriveTableKEK derives a per-table key-encryption key from secret material (e.g. the
+// scalar of an ECDSA private key) using HKDF-SHA256.
+func DeriveTableKEK(secret []byte, tableOwnerId, tableId string) []byte {
+	return hkdfSha256(secret, []byte("zetabase-kek"), []byte(tableOwnerId+"/"+tableId))
+}
+
+// Single-block HKDF (RFC 5869) with SHA-256, yielding a 32-byte key
+func hkdfSha256(secret, salt, info []byte) []byte {
+	ext := hmac.New(sha256.New, salt)
+	ext.Write(secret)
+	prk := ext.Sum(nil)
+	exp := hmac.New(sha256.New, prk)
+	exp.Write(info)
+	exp.Write([]byte{1})
+	return exp.Sum(nil)
+}
+
+func gcmFor(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealWithKey(key, plain, aad []byte) ([]byte, error) {
+	gcm, err := gcmFor(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+func openWithKey(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := gcmFor(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("CiphertextTooShort")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+}
+
+func (e *EncryptedTable) wrapKey(ns, id string, key []byte) ([]byte, error) {
+	wrapped, err := sealWithKey(e.kek, key, []byte(reservedKey(ns, id)))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&wrappedKeyRecord{
+		Id:      id,
+		Created: time.Now().Unix(),
+		Wrapped: wrapped,
+	})
+}
+
+func (e *EncryptedTable) unwrapKey(ns string, bs []byte) (string, []byte, error) {
+	var rec wrappedKeyRecord
+	err := json.Unmarshal(bs, &rec)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := openWithKey(e.kek, rec.Wrapped, []byte(reservedKey(ns, rec.Id)))
+	if err != nil {
+		return "", nil, errors.New("KeyUnwrapFailed")
+	}
+	return rec.Id, key, nil
+}
+
+func (e *EncryptedTable) fetchKeyRecords(ns string) (map[string][]byte, error) {
+	ks, err := e.client.ListKeysWithPattern(e.tableOwnerId, e.tableId, reservedKey(ns, "%")).KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(ks) == 0 {
+		return map[string][]byte{}, nil
+	}
+	return e.client.GetUncached(e.tableOwnerId, e.tableId, ks).DataAll()
+}
+
+func (e *EncryptedTable) loadKeys() error {
+	recs, err := e.fetchKeyRecords(encDataKeyNs)
+	if err != nil {
+		return err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, bs := range recs {
+		id, key, err := e.unwrapKey(encDataKeyNs, bs)
+		if err != nil {
+			return err
+		}
+		e.dataKeys[id] = key
+		if id > e.activeKeyId {
+			e.activeKeyId = id
+		}
+	}
+	if e.tokenKey == nil && len(e.opts.TokenFields) > 0 {
+		return e.loadTokenKey()
+	}
+	return nil
+}
+
+func (e *EncryptedTable) loadTokenKey() error {
+	recs, err := e.fetchKeyRecords(encTokenKeyNs)
+	if err != nil {
+		return err
+	}
+	for _, bs := range recs {
+		_, key, err := e.unwrapKey(encTokenKeyNs, bs)
+		if err != nil {
+			return err
+		}
+		e.tokenKey = key
+		return nil
+	}
+	key := make([]byte, encKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	rec, err := e.wrapKey(encTokenKeyNs, "0", key)
+	if err != nil {
+		return err
+	}
+	err = e.client.PutData(e.tableOwnerId, e.tableId, reservedKey(encTokenKeyNs, "0"), rec, false)
+	if err != nil {
+		return err
+	}
+	e.tokenKey = key
+	return nil
+}
+
+// Method RotateKey creates a new data key and makes it the active key for subsequent writes.
+// Existing values remain readable; use ReEncrypt to move them to the new key.
+func (e *EncryptedTable) RotateKey() error {
+	key := make([]byte, encKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	id := fmt.Sprintf("%016x", time.Now().UnixNano())
+	rec, err := e.wrapKey(encDataKeyNs, id, key)
+	if err != nil {
+		return err
+	}
+	err = e.client.PutData(e.tableOwnerId, e.tableId, reservedKey(encDataKeyNs, id), rec, false)
+	if err != nil {
+		return err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.dataKeys[id] = key
+	e.activeKeyId = id
+	return nil
+}
+
+// Method RewrapKeys re-wraps all data keys (and the token key) under a new key-encryption key.
+// Stored values are unaffected.
+func (e *EncryptedTable) RewrapKeys(newKEK []byte) error {
+	if len(newKEK) != encKeyBytes {
+		return errors.New("InvalidKeyLength")
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	old := e.kek
+	e.kek = newKEK
+	var keys []string
+	var valus [][]byte
+	for id, dk := range e.dataKeys {
+		rec, err := e.wrapKey(encDataKeyNs, id, dk)
+		if err != nil {
+			e.kek = old
+			return err
+		}
+		keys = append(keys, reservedKey(encDataKeyNs, id))
+		valus = append(valus, rec)
+	}
+	if e.tokenKey != nil {
+		rec, err := e.wrapKey(encTokenKeyNs, "0", e.tokenKey)
+		if err != nil {
+			e.kek = old
+			return err
+		}
+		keys = append(keys, reservedKey(encTokenKeyNs, "0"))
+		valus = append(valus, rec)
+	}
+	err := e.client.PutMulti(e.tableOwnerId, e.tableId, keys, valus, true)
+	if err != nil {
+		e.kek = old
+	}
+	return err
+}
+
+// Method ReEncrypt rewrites every value in the table under the active data key and returns the
+// number of values rewritten. If retireOld is set, data keys no longer in use are deleted afterwards.
+func (e *EncryptedTable) ReEncrypt(retireOld bool) (int, error) {
+	allKeys, err := e.client.ListKeys(e.tableOwnerId, e.tableId).KeysAll()
+	if err != nil {
+		return 0, err
+	}
+	keys := withoutReservedKeys(allKeys)
+	sort.Strings(keys)
+	n := 0
+	for i := 0; i < len(keys); i += encReEncryptBatch {
+		j := i + encReEncryptBatch
+		if j > len(keys) {
+			j = len(keys)
+		}
+		plain, err := e.Get(keys[i:j])
+		if err != nil {
+			return n, err
+		}
+		var ks []string
+		var vs [][]byte
+		for k, v := range plain {
+			ks = append(ks, k)
+			vs = append(vs, v)
+		}
+		if len(ks) == 0 {
+			continue
+		}
+		err = e.PutMulti(ks, vs, true)
+		if err != nil {
+			return n, err
+		}
+		n += len(ks)
+	}
+	if retireOld {
+		e.lock.Lock()
+		var retired []string
+		for id := range e.dataKeys {
+			if id != e.activeKeyId {
+				retired = append(retired, id)
+			}
+		}
+		e.lock.Unlock()
+		for _, id := range retired {
+			err := e.client.DeleteKey(e.tableOwnerId, e.tableId, reservedKey(encDataKeyNs, id))
+			if err != nil {
+				return n, err
+			}
+			e.lock.Lock()
+			delete(e.dataKeys, id)
+			e.lock.Unlock()
+		}
+	}
+	return n, nil
+}
+
+// Envelope layout: version (1 byte) | key ID length (1 byte) | key ID | nonce | ciphertext and tag
+func (e *EncryptedTable) encrypt(key string, plain []byte) ([]byte, error) {
+	e.lock.Lock()
+	id := e.activeKeyId
+	dk := e.dataKeys[id]
+	e.lock.Unlock()
+	if dk == nil {
+		return nil, errors.New("NoActiveDataKey")
+	}
+	sealed, err := sealWithKey(dk, plain, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	env := []byte{encEnvelopeVersion, byte(len(id))}
+	env = append(env, []byte(id)...)
+	return append(env, sealed...), nil
+}
+
+func (e *EncryptedTable) decrypt(key string, env []byte) ([]byte, error) {
+	if len(env) < 2 || env[0] != encEnvelopeVersion || len(env) < 2+int(env[1]) {
+		return nil, errors.New("InvalidEnvelope")
+	}
+	id := string(env[2 : 2+int(env[1])])
+	e.lock.Lock()
+	dk := e.dataKeys[id]
+	e.lock.Unlock()
+	if dk == nil {
+		// Possibly rotated by another client in the meantime
+		if err := e.loadKeys(); err != nil {
+			return nil, err
+		}
+		e.lock.Lock()
+		dk = e.dataKeys[id]
+		e.lock.Unlock()
+		if dk == nil {
+			return nil, errors.New("UnknownDataKey")
+		}
+	}
+	plain, err := openWithKey(dk, env[2+int(env[1]):], []byte(key))
+	if err != nil {
+		return nil, errors.New("DecryptionFailed")
+	}
+	return plain, nil
+}
+
+// Method Token returns the deterministic token stored for value valu of token field field.
+func (e *EncryptedTable) Token(field string, valu interface{}) (string, error) {
+	if e.tokenKey == nil {
+		return "", errors.New("NoTokenFields")
+	}
+	cv, err := canonicalJsonValue(valu)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, e.tokenKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write(cv)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func containsString(arr []string, s string) bool {
+	for _, x := range arr {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Method QEq builds an equality query on field that works against the stored representation:
+// token fields are compared by token, clear fields by value. Other fields cannot be queried.
+func (e *EncryptedTable) QEq(field string, valu interface{}) (*QueryEquals, error) {
+	if containsString(e.opts.TokenFields, field) {
+		tok, err := e.Token(field, valu)
+		if err != nil {
+			return nil, err
+		}
+		return QEq(field, tok), nil
+	} else if containsString(e.opts.ClearFields, field) {
+		return QEq(field, valu), nil
+	}
+	return nil, errors.New("FieldNotQueryable")
+}
+
+func (e *EncryptedTable) encodeStored(key string, plain []byte) ([]byte, error) {
+	env, err := e.encrypt(key, plain)
+	if err != nil {
+		return nil, err
+	}
+	switch e.opts.Format {
+	case zbprotocol.TableDataFormat_PLAIN_TEXT:
+		return []byte(base64.StdEncoding.EncodeToString(env)), nil
+	case zbprotocol.TableDataFormat_JSON:
+		stored := map[string]interface{}{}
+		if len(e.opts.ClearFields) > 0 || len(e.opts.TokenFields) > 0 {
+			doc, err := decodeJsonObject(plain)
+			if err != nil {
+				return nil, errors.New("NotJsonObject")
+			}
+			for _, f := range e.opts.ClearFields {
+				if v, ok := jsonFieldValue(doc, f); ok {
+					setJsonFieldValue(stored, f, v)
+				}
+			}
+			for _, f := range e.opts.TokenFields {
+				if v, ok := jsonFieldValue(doc, f); ok {
+					tok, err := e.Token(f, v)
+					if err != nil {
+						return nil, err
+					}
+					setJsonFieldValue(stored, f, tok)
+				}
+			}
+		}
+		stored[encJsonField] = env
+		return json.Marshal(stored)
+	default:
+		return env, nil
+	}
+}
+
+func (e *EncryptedTable) decodeStored(key string, stored []byte) ([]byte, error) {
+	var env []byte
+	switch e.opts.Format {
+	case zbprotocol.TableDataFormat_PLAIN_TEXT:
+		bs, err := base64.StdEncoding.DecodeString(string(stored))
+		if err != nil {
+			return nil, errors.New("InvalidEnvelope")
+		}
+		env = bs
+	case zbprotocol.TableDataFormat_JSON:
+		var doc struct {
+			Enc []byte `json:"_zbenc"`
+		}
+		err := json.Unmarshal(stored, &doc)
+		if err != nil || doc.Enc == nil {
+			return nil, errors.New("InvalidEnvelope")
+		}
+		env = doc.Enc
+	default:
+		env = stored
+	}
+	return e.decrypt(key, env)
+}
+
+// Encrypt and put a key-value pair into the table
+func (e *EncryptedTable) PutData(key string, valu []byte, overwrite bool) error {
+	stored, err := e.encodeStored(key, valu)
+	if err != nil {
+		return err
+	}
+	return e.client.PutData(e.tableOwnerId, e.tableId, key, stored, overwrite)
+}
+
+// Encrypt and put multiple key-value pairs into the table
+func (e *EncryptedTable) PutMulti(keys []string, valus [][]byte, overwrite bool) error {
+	if len(valus) != len(keys) {
+		return errors.New("ImproperDimensions")
+	}
+	var storedValus [][]byte
+	for i, k := range keys {
+		stored, err := e.encodeStored(k, valus[i])
+		if err != nil {
+			return err
+		}
+		storedValus = append(storedValus, stored)
+	}
+	return e.client.PutMulti(e.tableOwnerId, e.tableId, keys, storedValus, overwrite)
+}
+
+func (e *EncryptedTable) decodeAll(data map[string][]byte) (map[string][]byte, error) {
+	res := map[string][]byte{}
+	for k, v := range data {
+		if IsReservedKey(k) {
+			continue
+		}
+		plain, err := e.decodeStored(k, v)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = plain
+	}
+	return res, nil
+}
+
+// Fetch and decrypt the values for keys
+func (e *EncryptedTable) Get(keys []string) (map[string][]byte, error) {
+	data, err := e.client.Get(e.tableOwnerId, e.tableId, keys).DataAll()
+	if err != nil {
+		return nil, err
+	}
+	return e.decodeAll(data)
+}
+
+// Run a query (on clear or token fields, see QEq) and decrypt the matching values
+func (e *EncryptedTable) QueryData(qry SubQueryConvertible) (map[string][]byte, error) {
+	pages, err := e.client.QueryData(e.tableOwnerId, e.tableId, qry)
+	if err != nil {
+		return nil, err
+	}
+	data, err := pages.DataAll()
+	if err != nil {
+		return nil, err
+	}
+	return e.decodeAll(data)
+}
+
+// A key record of an encrypted table, as wrapped before and after an identity key rotation
+type rewrappedKeyRecord struct {
+	tableOwnerId string
+	tableId      string
+	key          string
+	oldRec       []byte
+	newRec       []byte
+}
+
+// Method TrackEncryptedTable records that table tableId of tableOwnerId may hold data keys wrapped
+// under the key-encryption key derived from the identity key, so that ReplaceIdentityKey wraps
+// them again. The identity's own tables, and tables opened on this client with NewEncryptedTable,
+// are covered without it; tables of other owners must otherwise be tracked before a rotation.
+func (z *ZetabaseClient) TrackEncryptedTable(tableOwnerId, tableId string) {
+	z.derivedKekTables.Store(cacheTableKey(tableOwnerId, tableId), [2]string{tableOwnerId, tableId})
+}
+
+// Tables whose key records may be wrapped under a key derived from the identity key, as owner
+// and table ID pairs: the identity's own tables and the tracked ones
+func (z *ZetabaseClient) derivedKekTableIds() ([][2]string, error) {
+	defns, err := z.tableDefinitions(z.userId)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var res [][2]string
+	for _, defn := range defns {
+		seen[cacheTableKey(z.userId, defn.GetTableId())] = true
+		res = append(res, [2]string{z.userId, defn.GetTableId()})
+	}
+	z.derivedKekTables.Range(func(k, v interface{}) bool {
+		if !seen[k.(string)] {
+			res = append(res, v.([2]string))
+		}
+		return true
+	})
+	return res, nil
+}
+
+// Key records wrapped under the default key-encryption key (see derivedKekTableIds), wrapped
+// again under the one derived from newPriv. Records wrapped under explicit keys are left out.
+func (z *ZetabaseClient) rewrapDefaultKeyRecords(newPriv *ecdsa.PrivateKey) ([]*rewrappedKeyRecord, error) {
+	tbls, err := z.derivedKekTableIds()
+	if err != nil {
+		return nil, err
+	}
+	var res []*rewrappedKeyRecord
+	for _, t := range tbls {
+		owner, tbl := t[0], t[1]
+		before := &EncryptedTable{kek: DeriveTableKEK(z.privKey.D.Bytes(), owner, tbl)}
+		after := &EncryptedTable{kek: DeriveTableKEK(newPriv.D.Bytes(), owner, tbl)}
+		for _, ns := range []string{encDataKeyNs, encTokenKeyNs} {
+			ks, err := z.listKeysRemote(owner, tbl, reservedKey(ns, "%")).KeysAll()
+			if err != nil {
+				return nil, err
+			} else if len(ks) == 0 {
+				continue
+			}
+			recs, err := z.getPag(owner, tbl, ks).DataAll()
+			if err != nil {
+				return nil, err
+			}
+			for k, bs := range recs {
+				id, key, err := before.unwrapKey(ns, bs)
+				if err != nil {
+					continue
+				}
+				rec, err := after.wrapKey(ns, id, key)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, &rewrappedKeyRecord{tableOwnerId: owner, tableId: tbl, key: k, oldRec: bs, newRec: rec})
+			}
+		}
+	}
+	return res, nil
+}
+
+// Write the rewrapped key records, or (if restore) put back the records they replaced
+func (z *ZetabaseClient) putKeyRecords(recs []*rewrappedKeyRecord, restore bool) error {
+	tbls := map[string][2]string{}
+	keys := map[string][]string{}
+	valus := map[string][][]byte{}
+	for _, r := range recs {
+		t := cacheTableKey(r.tableOwnerId, r.tableId)
+		tbls[t] = [2]string{r.tableOwnerId, r.tableId}
+		keys[t] = append(keys[t], r.key)
+		if restore {
+			valus[t] = append(valus[t], r.oldRec)
+		} else {
+			valus[t] = append(valus[t], r.newRec)
+		}
+	}
+	for t, ids := range tbls {
+		if err := z.PutMulti(ids[0], ids[1], keys[t], valus[t], true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package zetabase
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func makeOfflineEncryptedTable(format zbprotocol.TableDataFormat, clear, tokens []string) *EncryptedTable {
+	kek := make([]byte, encKeyBytes)
+	dk := make([]byte, encKeyBytes)
+	tk := make([]byte, encKeyBytes)
+	rand.Read(kek)
+	rand.Read(dk)
+	rand.Read(tk)
+	return &EncryptedTable{
+		tableOwnerId: "owner",
+		tableId:      "tbl",
+		opts:         EncryptedTableOptions{Format: format, ClearFields: clear, TokenFields: tokens},
+		kek:          kek,
+		tokenKey:     tk,
+		dataKeys:     map[string][]byte{"0000000000000001": dk},
+		activeKeyId:  "0000000000000001",
+		lock:         &sync.Mutex{},
+	}
+}
+
+func Test_EncryptedTableRoundTrip(t *testing.T) {
+	for _, f := range []zbprotocol.TableDataFormat{zbprotocol.TableDataFormat_BINARY, zbprotocol.TableDataFormat_PLAIN_TEXT, zbprotocol.TableDataFormat_JSON} {
+		e := makeOfflineEncryptedTable(f, nil, nil)
+		plain := []byte(`{"name": "alice"}`)
+		stored, err := e.encodeStored("k1", plain)
+		if err != nil {
+			t.Fatalf("Encode failed for %s: %s", f.String(), err.Error())
+		}
+		if bytes.Contains(stored, []byte("alice")) {
+			t.Fatalf("Plaintext leaked for %s", f.String())
+		}
+		res, err := e.decodeStored("k1", stored)
+		if err != nil || !bytes.Equal(res, plain) {
+			t.Fatalf("Round trip failed for %s: %v", f.String(), err)
+		}
+		// Ciphertext is bound to its key
+		_, err = e.decodeStored("k2", stored)
+		if err == nil {
+			t.Fatalf("Expected decryption under a different key to fail for %s", f.String())
+		}
+	}
+}
+
+func Test_EncryptedTableJsonFields(t *testing.T) {
+	e := makeOfflineEncryptedTable(zbprotocol.TableDataFormat_JSON, []string{"kind"}, []string{"user.email"})
+	stored, err := e.encodeStored("k1", []byte(`{"kind": "note", "user": {"email": "a@b.c"}, "body": "secret"}`))
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err.Error())
+	}
+	if strings.Contains(string(stored), "secret") || strings.Contains(string(stored), "a@b.c") {
+		t.Fatalf("Plaintext leaked: %s", string(stored))
+	}
+	var doc map[string]interface{}
+	json.Unmarshal(stored, &doc)
+	if doc["kind"] != "note" {
+		t.Fatalf("Clear field missing: %s", string(stored))
+	}
+	tok, _ := e.Token("user.email", "a@b.c")
+	if v, _ := jsonFieldValue(doc, "user.email"); v != tok {
+		t.Fatalf("Token mismatch: %v vs %s", v, tok)
+	}
+	q, err := e.QEq("user.email", "a@b.c")
+	if err != nil || q.CompValue != tok {
+		t.Fatalf("Token query mismatch: %v", err)
+	}
+	if _, err := e.QEq("body", "secret"); err == nil {
+		t.Fatalf("Expected encrypted field to be unqueryable")
+	}
+}
+
+func Test_EncryptedTableKeyWrap(t *testing.T) {
+	e := makeOfflineEncryptedTable(zbprotocol.TableDataFormat_BINARY, nil, nil)
+	dk := e.dataKeys[e.activeKeyId]
+	rec, err := e.wrapKey(encDataKeyNs, e.activeKeyId, dk)
+	if err != nil {
+		t.Fatalf("Wrap failed: %s", err.Error())
+	}
+	id, res, err := e.unwrapKey(encDataKeyNs, rec)
+	if err != nil || id != e.activeKeyId || !bytes.Equal(res, dk) {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	rand.Read(e.kek)
+	if _, _, err := e.unwrapKey(encDataKeyNs, rec); err == nil {
+		t.Fatalf("Expected unwrap under a different KEK to fail")
+	}
+}
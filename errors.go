@@ -0,0 +1,31 @@
+package zetabase
+
+import (
+	"errors"
+)
+
+// Errors that callers commonly handle, returned both when the client detects the condition and
+// when the server reports it. Compare with errors.Is.
+var (
+	ErrTableNotFound                  = errors.New("TableNotFound")
+	ErrTableExists                    = errors.New("TableExists")
+	ErrKeyAlreadyExists               = errors.New("KeyAlreadyExists")
+	ErrCompareAndSwapFailed           = errors.New("CompareAndSwapFailed")
+	ErrCannotCreateTableForOtherOwner = errors.New("CannotCreateTableForOtherOwner")
+)
+
+var knownErrors = map[string]error{}
+
+func init() {
+	for _, err := range []error{ErrTableNotFound, ErrTableExists, ErrKeyAlreadyExists, ErrCompareAndSwapFailed, ErrCannotCreateTableForOtherOwner} {
+		knownErrors[err.Error()] = err
+	}
+}
+
+// The error for a symbol reported by the server
+func errorFor(symbol string) error {
+	if err, ok := knownErrors[symbol]; ok {
+		return err
+	}
+	return errors.New(symbol)
+}
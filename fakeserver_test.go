@@ -0,0 +1,39 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/internal/zbfake"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+)
+
+type fakeServer = zbfake.Server
+
+// In-memory server evaluating queries as a mirror of the table would
+func newFakeServer() *fakeServer {
+	srv := zbfake.NewServer()
+	srv.Query = func(defn *zbprotocol.TableCreate, qry *zbprotocol.TableSubQuery, valu []byte) (bool, error) {
+		m := &TableMirror{orderings: map[string]zbprotocol.QueryOrdering{}}
+		for _, x := range defn.GetIndices().GetFields() {
+			m.orderings[x.GetField()] = x.GetOrdering()
+		}
+		doc, err := decodeJsonObject(valu)
+		if err != nil {
+			return false, nil
+		}
+		return m.eval(qry, doc)
+	}
+	return srv
+}
+
+// Client for user uid connected to a new fake server
+func newFakeClient(uid string) (*ZetabaseClient, *fakeServer) {
+	srv := newFakeServer()
+	return newFakeClientFor(uid, srv), srv
+}
+
+func newFakeClientFor(uid string, srv *fakeServer) *ZetabaseClient {
+	z := NewZetabaseClient(uid)
+	priv, pub := GenerateKeyPair()
+	z.SetIdKey(priv, pub)
+	z.ConnectProvider(srv)
+	return z
+}
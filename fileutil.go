@@ -0,0 +1,75 @@
+package zetabase
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Records in local log files are framed as length | CRC-32 | payload
+	recordHeaderBytes = 8
+	maxRecordBytes    = 64 * 1024 * 1024
+)
+
+// Function WriteFileAtomic writes dat to fn via a synced temporary file in the same directory, so
+// that fn is never left half-written.
+func WriteFileAtomic(fn string, dat []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), filepath.Base(fn)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpFn := tmp.Name()
+	_, err = tmp.Write(dat)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpFn, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpFn, fn)
+	}
+	if err != nil {
+		os.Remove(tmpFn)
+	}
+	return err
+}
+
+// Frame payload as a checksummed log record
+func checksummedRecord(payload []byte) []byte {
+	buf := make([]byte, recordHeaderBytes, recordHeaderBytes+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	return append(buf, payload...)
+}
+
+// Read checksummed log records from r, passing each payload to f until a record is damaged or
+// incomplete, or f returns false. Returns the length of the intact records read.
+func readChecksummedRecords(r io.Reader, f func(payload []byte) bool) int64 {
+	var good int64
+	hdr := make([]byte, recordHeaderBytes)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return good
+		}
+		n := binary.BigEndian.Uint32(hdr[:4])
+		if n > maxRecordBytes {
+			return good
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return good
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(hdr[4:]) || !f(payload) {
+			return good
+		}
+		good += int64(recordHeaderBytes) + int64(n)
+	}
+}
@@ -7,9 +7,11 @@ require (
 	github.com/c-bata/go-prompt v0.2.3
 	github.com/go-openapi/strfmt v0.19.5 // indirect
 	github.com/golang/protobuf v1.4.2
+	github.com/golang/snappy v0.0.4
 	github.com/hashicorp/go-version v1.2.1
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
 	github.com/johnsiilver/getcert v0.0.0-20190816170103-14357049f896
+	github.com/klauspost/compress v1.11.13
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/mattn/go-tty v0.0.3 // indirect
 	github.com/mitchellh/go-homedir v1.1.0
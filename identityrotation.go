@@ -0,0 +1,122 @@
+package zetabase
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+)
+
+// Method RotateIdentityKey generates a new key pair and installs it as this identity's key (see
+// ReplaceIdentityKey). On success the client signs with the new key and the new pair is returned.
+func (z *ZetabaseClient) RotateIdentityKey() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	priv, pub := GenerateKeyPair()
+	if priv == nil {
+		return nil, nil, errors.New("KeyGenerationFailed")
+	}
+	err := z.ReplaceIdentityKey(priv, pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv, pub, nil
+}
+
+// Method ReplaceIdentityKey registers pub as the public key of this identity and confirms that
+// requests signed with priv are accepted. The data keys of encrypted tables that use the default
+// key-encryption key, which is derived from the identity key (see NewEncryptedTable), are then
+// wrapped again under the key derived from priv: those of the identity's own tables, and of other
+// owners' tables opened on this client or tracked with TrackEncryptedTable. Other owners' tables
+// that were neither keep data keys under the old key, which can then no longer be unwrapped.
+// Encrypted tables opened before the rotation must be reopened. If any step fails, the previous
+// key is restored on the server (where possible) and in the client, along with the data keys, and
+// an error is returned.
+func (z *ZetabaseClient) ReplaceIdentityKey(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey) error {
+	if !z.checkReady() {
+		return errors.New("NotReady")
+	}
+	if z.privKey == nil || z.pubKey == nil {
+		return errors.New("NoIdentityKey")
+	}
+	oldPriv, oldPub := z.privKey, z.pubKey
+	rewrapped, err := z.rewrapDefaultKeyRecords(priv)
+	if err != nil {
+		return err
+	}
+	err = z.registerPubKey(pub)
+	if err != nil {
+		return err
+	}
+	z.SetIdKey(priv, pub)
+	failure := "KeyVerificationFailed"
+	if z.verifyIdentityKey() == nil {
+		if z.putKeyRecords(rewrapped, false) == nil {
+			return nil
+		}
+		// Put back the records already rewritten
+		if z.putKeyRecords(rewrapped, true) != nil {
+			return errors.New("KeyRotationRollbackFailed")
+		}
+		failure = "KeyRewrapFailed"
+	}
+	// Roll back: whichever key the server currently accepts must sign the restoring request
+	rbErr := z.registerPubKey(oldPub)
+	if rbErr != nil {
+		z.SetIdKey(oldPriv, oldPub)
+		rbErr = z.registerPubKey(oldPub)
+	}
+	if rbErr != nil {
+		z.SetIdKey(priv, pub)
+		return errors.New("KeyRotationRollbackFailed")
+	}
+	z.SetIdKey(oldPriv, oldPub)
+	return errors.New(failure)
+}
+
+func (z *ZetabaseClient) registerPubKey(pub *ecdsa.PublicKey) error {
+	enc, err := EncodeEcdsaPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	encStr := string(enc)
+	nonce := z.nonceMaker.Get()
+	poc := z.getCredential(nonce, nil)
+	res, err := z.client.ModifySubIdentity(z.ctx, &zbprotocol.SubIdentityModify{
+		Id:         z.userId,
+		SubId:      z.userId,
+		NewPubKey:  encStr,
+		Nonce:      nonce,
+		Credential: poc,
+	})
+	if err != nil {
+		return err
+	}
+	return unwrapZbError(res)
+}
+
+// Issue a cheap signed request to check that the server accepts the client's current key
+func (z *ZetabaseClient) verifyIdentityKey() error {
+	tblOwnerId := z.userId
+	if z.parentId != nil {
+		tblOwnerId = *z.parentId
+	}
+	_, err := z.tableDefinitions(tblOwnerId)
+	return err
+}
+
+// Definitions of the tables of an owner, failing if the server rejects the request
+func (z *ZetabaseClient) tableDefinitions(tableOwnerId string) ([]*zbprotocol.TableCreate, error) {
+	nonce := z.nonceMaker.Get()
+	poc := z.ecdsaCredential(nonce, nil)
+	res, err := z.client.ListTables(z.ctx, &zbprotocol.ListTablesRequest{
+		Id:           z.userId,
+		TableOwnerId: tableOwnerId,
+		Nonce:        nonce,
+		Credential:   poc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := unwrapZbError(res.GetError()); err != nil {
+		return nil, err
+	}
+	return res.GetTableDefinitions(), nil
+}
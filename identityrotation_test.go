@@ -0,0 +1,135 @@
+package zetabase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"google.golang.org/grpc"
+	"testing"
+)
+
+// Fake server that tracks a single identity's registered public key
+type fakeKeyServer struct {
+	zbprotocol.ZetabaseProviderClient
+	pub     *ecdsa.PublicKey
+	initial *ecdsa.PublicKey
+	// Reject listings signed with any key but the initial one
+	rejectListing bool
+}
+
+func (f *fakeKeyServer) checkSig(uid string, nonce int64, poc *zbprotocol.ProofOfCredential) bool {
+	if poc == nil || poc.Signature == nil {
+		return false
+	}
+	return ValidateZetabaseSignature(uid, nonce, nil, f.pub, poc.Signature.R, poc.Signature.S)
+}
+
+func (f *fakeKeyServer) ModifySubIdentity(ctx context.Context, in *zbprotocol.SubIdentityModify, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	if !f.checkSig(in.Id, in.Nonce, in.Credential) {
+		return &zbprotocol.ZbError{Message: "InvalidSignature"}, nil
+	}
+	pub, err := DecodeEcdsaPublicKey(in.NewPubKey)
+	if err != nil {
+		return nil, err
+	}
+	f.pub = pub
+	return &zbprotocol.ZbError{}, nil
+}
+
+func (f *fakeKeyServer) ListTables(ctx context.Context, in *zbprotocol.ListTablesRequest, opts ...grpc.CallOption) (*zbprotocol.ListTablesResponse, error) {
+	if (f.rejectListing && f.pub != f.initial) || !f.checkSig(in.Id, in.Nonce, in.Credential) {
+		return &zbprotocol.ListTablesResponse{Error: &zbprotocol.ZbError{Message: "InvalidSignature"}}, nil
+	}
+	return &zbprotocol.ListTablesResponse{}, nil
+}
+
+func makeFakeKeyClient() (*ZetabaseClient, *fakeKeyServer) {
+	priv, pub := GenerateKeyPair()
+	srv := &fakeKeyServer{pub: pub, initial: pub}
+	z := NewZetabaseClient("11111111-2222-3333-4444-555555555555")
+	z.SetIdKey(priv, pub)
+	z.conn = &grpc.ClientConn{}
+	z.client = srv
+	return z, srv
+}
+
+func Test_RotateIdentityKey(t *testing.T) {
+	z, srv := makeFakeKeyClient()
+	oldPub := z.pubKey
+	_, pub, err := z.RotateIdentityKey()
+	if err != nil {
+		t.Fatalf("Rotation failed: %s", err.Error())
+	}
+	if srv.pub.X.Cmp(pub.X) != 0 || z.pubKey != pub || oldPub.X.Cmp(pub.X) == 0 {
+		t.Fatalf("New key not installed")
+	}
+}
+
+func Test_RotateIdentityKey_rollback(t *testing.T) {
+	z, srv := makeFakeKeyClient()
+	oldPriv, oldPub := z.privKey, z.pubKey
+	srv.rejectListing = true
+	_, _, err := z.RotateIdentityKey()
+	if err == nil || err.Error() != "KeyVerificationFailed" {
+		t.Fatalf("Expected verification failure, got %v", err)
+	}
+	if srv.pub.X.Cmp(oldPub.X) != 0 || z.privKey != oldPriv {
+		t.Fatalf("Old key not restored")
+	}
+}
+
+func Test_RotateIdentityKeyRewrapsDataKeys(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	kek := make([]byte, encKeyBytes)
+	for _, tbl := range []string{"derived", "explicit"} {
+		if err := z.CreateTable(tbl, zbprotocol.TableDataFormat_BINARY, nil, nil, false); err != nil {
+			t.Fatalf("Failed to create table: %s", err.Error())
+		}
+	}
+	derived, err := NewEncryptedTable(z, testOwnerId, "derived", &EncryptedTableOptions{Format: zbprotocol.TableDataFormat_BINARY})
+	if err != nil {
+		t.Fatalf("Failed to open encrypted table: %s", err.Error())
+	}
+	explicit, _ := NewEncryptedTable(z, testOwnerId, "explicit", &EncryptedTableOptions{Format: zbprotocol.TableDataFormat_BINARY, KEK: kek})
+	derived.PutData("a", []byte("one"), true)
+	explicit.PutData("b", []byte("two"), true)
+
+	if _, _, err := z.RotateIdentityKey(); err != nil {
+		t.Fatalf("Rotation failed: %s", err.Error())
+	}
+	derived, err = NewEncryptedTable(z, testOwnerId, "derived", &EncryptedTableOptions{Format: zbprotocol.TableDataFormat_BINARY})
+	if err != nil {
+		t.Fatalf("Data keys not rewrapped: %s", err.Error())
+	}
+	if data, err := derived.Get([]string{"a"}); err != nil || string(data["a"]) != "one" {
+		t.Fatalf("Unexpected value after rotation: %q (%v)", data["a"], err)
+	}
+	explicit, _ = NewEncryptedTable(z, testOwnerId, "explicit", &EncryptedTableOptions{Format: zbprotocol.TableDataFormat_BINARY, KEK: kek})
+	if data, err := explicit.Get([]string{"b"}); err != nil || string(data["b"]) != "two" {
+		t.Fatalf("Table with an explicit key changed by rotation: %q (%v)", data["b"], err)
+	}
+}
+
+func Test_RotateIdentityKeyRewrapsOtherOwnersTables(t *testing.T) {
+	owner, srv := newFakeClient(testOwnerId)
+	if err := owner.CreateTable("shared", zbprotocol.TableDataFormat_BINARY, nil, nil, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	z := newFakeClientFor("66666666-2222-3333-4444-555555555555", srv)
+	shared, err := NewEncryptedTable(z, testOwnerId, "shared", &EncryptedTableOptions{Format: zbprotocol.TableDataFormat_BINARY})
+	if err != nil {
+		t.Fatalf("Failed to open encrypted table: %s", err.Error())
+	}
+	shared.PutData("a", []byte("one"), true)
+
+	if _, _, err := z.RotateIdentityKey(); err != nil {
+		t.Fatalf("Rotation failed: %s", err.Error())
+	}
+	shared, err = NewEncryptedTable(z, testOwnerId, "shared", &EncryptedTableOptions{Format: zbprotocol.TableDataFormat_BINARY})
+	if err != nil {
+		t.Fatalf("Data keys not rewrapped: %s", err.Error())
+	}
+	if data, err := shared.Get([]string{"a"}); err != nil || string(data["a"]) != "one" {
+		t.Fatalf("Unexpected value after rotation: %q (%v)", data["a"], err)
+	}
+}
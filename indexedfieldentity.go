@@ -37,3 +37,19 @@ func indexedFieldsToProtocol(ifs []*IndexedField) *zbprotocol.TableIndexFields {
 		Fields: arr,
 	}
 }
+
+func IndexedFieldFromProtocol(f *zbprotocol.TableIndexField) *IndexedField {
+	return &IndexedField{
+		FieldName: f.GetField(),
+		LangCode:  f.GetLanguageCode(),
+		IndexType: f.GetOrdering(),
+	}
+}
+
+func indexedFieldsFromProtocol(fs *zbprotocol.TableIndexFields) []*IndexedField {
+	var arr []*IndexedField
+	for _, x := range fs.GetFields() {
+		arr = append(arr, IndexedFieldFromProtocol(x))
+	}
+	return arr
+}
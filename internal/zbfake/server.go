@@ -0,0 +1,374 @@
+// Package zbfake is an in-memory stand-in for the Zetabase server, for the tests of the client
+// and of the packages built on it (see ZetabaseClient.ConnectProvider).
+package zbfake
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/go-version"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type Server implements the Zetabase API in memory. Credentials are not checked. Fields may be
+// set by tests between requests; Mu guards the tables.
+type Server struct {
+	zbprotocol.ZetabaseProviderClient
+	Mu     sync.Mutex
+	tables map[string]*Table
+	// GetData calls served, and how long each takes
+	GetCalls int
+	GetDelay time.Duration
+	// Error returned by all reads
+	GetErr error
+	// Called after each GetData call is served, e.g. to write concurrently with a reader
+	AfterGet func(in *zbprotocol.TableGet)
+	// Largest response GetData sends, in value bytes (0: unlimited); larger ones fail as they
+	// would on a connection with a maximum message size
+	MaxResponseBytes int
+	// Error returned by all writes, to simulate losing the connection
+	WriteErr error
+	// Called before each CreateTable call is served; an error fails the call
+	BeforeCreate func(in *zbprotocol.TableCreate) error
+	// Reported by VersionInfo; servers from 0.2.0 check expected value hashes and apply batches
+	Version string
+	// Evaluates a query against a value of a table, for QueryKeys (nil: queries fail)
+	Query func(defn *zbprotocol.TableCreate, qry *zbprotocol.TableSubQuery, valu []byte) (bool, error)
+}
+
+// Type Table is a table held by a Server.
+type Table struct {
+	Defn *zbprotocol.TableCreate
+	Data map[string][]byte
+}
+
+// Function NewServer returns a server without tables.
+func NewServer() *Server {
+	return &Server{tables: map[string]*Table{}}
+}
+
+func fakeTableKey(owner, tbl string) string {
+	return owner + "/" + tbl
+}
+
+// Method Table returns a table, or nil if it does not exist. Requires Mu.
+func (f *Server) Table(owner, tbl string) *Table {
+	return f.tables[fakeTableKey(owner, tbl)]
+}
+
+func fakeError(msg string) *zbprotocol.ZbError {
+	return &zbprotocol.ZbError{Message: msg}
+}
+
+func (f *Server) CreateTable(ctx context.Context, in *zbprotocol.TableCreate, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	if f.BeforeCreate != nil {
+		if err := f.BeforeCreate(in); err != nil {
+			return nil, err
+		}
+	}
+	k := fakeTableKey(in.Id, in.TableId)
+	if _, ok := f.tables[k]; ok {
+		return &zbprotocol.ZbError{Code: 1, Message: "TableExists"}, nil
+	}
+	f.tables[k] = &Table{Defn: proto.Clone(in).(*zbprotocol.TableCreate), Data: map[string][]byte{}}
+	return &zbprotocol.ZbError{}, nil
+}
+
+func (f *Server) ListTables(ctx context.Context, in *zbprotocol.ListTablesRequest, opts ...grpc.CallOption) (*zbprotocol.ListTablesResponse, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	var names []string
+	for k := range f.tables {
+		if strings.HasPrefix(k, in.TableOwnerId+"/") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	res := &zbprotocol.ListTablesResponse{}
+	for _, k := range names {
+		res.TableDefinitions = append(res.TableDefinitions, f.tables[k].Defn)
+	}
+	return res, nil
+}
+
+func (f *Server) ModifySubIdentity(ctx context.Context, in *zbprotocol.SubIdentityModify, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	return &zbprotocol.ZbError{}, nil
+}
+
+// Method Put writes pairs to a table as PutDataMulti does.
+func (f *Server) Put(owner, tbl string, pairs []*zbprotocol.DataPair, overwrite bool) *zbprotocol.ZbError {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(owner, tbl)
+	if t == nil {
+		return fakeError("TableNotFound")
+	}
+	if !overwrite {
+		for _, p := range pairs {
+			if _, ok := t.Data[p.Key]; ok {
+				return fakeError("KeyAlreadyExists")
+			}
+		}
+	}
+	for _, p := range pairs {
+		t.Data[p.Key] = append([]byte{}, p.Value...)
+	}
+	return &zbprotocol.ZbError{}
+}
+
+func (f *Server) failWrite() error {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	return f.WriteErr
+}
+
+// Method SetWriteErr makes all writes fail with err (nil to succeed again).
+func (f *Server) SetWriteErr(err error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	f.WriteErr = err
+}
+
+func (f *Server) PutData(ctx context.Context, in *zbprotocol.TablePut, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	if err := f.failWrite(); err != nil {
+		return nil, err
+	}
+	if in.ExpectedValueHash != nil && versionAtLeast(f.Version, "0.2.0") {
+		f.Mu.Lock()
+		defer f.Mu.Unlock()
+		t := f.Table(in.TableOwnerId, in.TableId)
+		if t == nil {
+			return fakeError("TableNotFound"), nil
+		}
+		if cur, ok := t.Data[in.Key]; !ok || !bytes.Equal(valueHash(cur), in.ExpectedValueHash) {
+			return fakeError("CompareAndSwapFailed"), nil
+		}
+		t.Data[in.Key] = append([]byte{}, in.Value...)
+		return &zbprotocol.ZbError{}, nil
+	}
+	return f.Put(in.TableOwnerId, in.TableId, []*zbprotocol.DataPair{{Key: in.Key, Value: in.Value}}, in.Overwrite), nil
+}
+
+func (f *Server) VersionInfo(ctx context.Context, in *zbprotocol.ZbEmpty, opts ...grpc.CallOption) (*zbprotocol.VersionDetails, error) {
+	v := f.Version
+	if len(v) == 0 {
+		v = "0.1.0"
+	}
+	return &zbprotocol.VersionDetails{ServerVersion: v, MinClientVersion: "0.0.1"}, nil
+}
+
+func (f *Server) PutDataMulti(ctx context.Context, in *zbprotocol.TablePutMulti, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	if err := f.failWrite(); err != nil {
+		return nil, err
+	}
+	return f.Put(in.TableOwnerId, in.TableId, in.Pairs, in.Overwrite), nil
+}
+
+func (f *Server) ApplyBatch(ctx context.Context, in *zbprotocol.TableBatch, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	if !versionAtLeast(f.Version, "0.2.0") {
+		return nil, status.Error(codes.Unimplemented, "unknown method ApplyBatch")
+	}
+	if err := f.failWrite(); err != nil {
+		return nil, err
+	}
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return fakeError("TableNotFound"), nil
+	}
+	for _, p := range in.Puts {
+		t.Data[p.Key] = append([]byte{}, p.Value...)
+	}
+	for _, k := range in.Deletes {
+		delete(t.Data, k)
+	}
+	return &zbprotocol.ZbError{}, nil
+}
+
+func (f *Server) GetData(ctx context.Context, in *zbprotocol.TableGet, opts ...grpc.CallOption) (*zbprotocol.TableGetResponse, error) {
+	time.Sleep(f.GetDelay)
+	if f.AfterGet != nil {
+		defer f.AfterGet(in)
+	}
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	f.GetCalls++
+	if f.GetErr != nil {
+		return nil, f.GetErr
+	}
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return &zbprotocol.TableGetResponse{Error: fakeError("TableNotFound")}, nil
+	}
+	res := &zbprotocol.TableGetResponse{Pagination: &zbprotocol.PaginationInfo{}}
+	size := 0
+	for _, k := range in.Keys {
+		if v, ok := t.Data[k]; ok {
+			res.Data = append(res.Data, &zbprotocol.DataPair{Key: k, Value: append([]byte{}, v...)})
+			size += len(v)
+		}
+	}
+	if f.MaxResponseBytes > 0 && size > f.MaxResponseBytes {
+		return nil, status.Error(codes.ResourceExhausted, "response larger than max message size")
+	}
+	return res, nil
+}
+
+// Key patterns use % as a wildcard
+func fakeKeyMatcher(pattern string) *regexp.Regexp {
+	if len(pattern) == 0 {
+		return regexp.MustCompile(".*")
+	}
+	parts := strings.Split(pattern, "%")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func (f *Server) ListKeys(ctx context.Context, in *zbprotocol.ListKeysRequest, opts ...grpc.CallOption) (*zbprotocol.ListKeysResponse, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return &zbprotocol.ListKeysResponse{Error: fakeError("TableNotFound")}, nil
+	}
+	m := fakeKeyMatcher(in.Pattern)
+	res := &zbprotocol.ListKeysResponse{Pagination: &zbprotocol.PaginationInfo{}}
+	for k := range t.Data {
+		if m.MatchString(k) {
+			res.Keys = append(res.Keys, k)
+		}
+	}
+	sort.Strings(res.Keys)
+	return res, nil
+}
+
+// Queries are evaluated by Server.Query
+func (f *Server) QueryKeys(ctx context.Context, in *zbprotocol.TableQuery, opts ...grpc.CallOption) (*zbprotocol.ListKeysResponse, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return &zbprotocol.ListKeysResponse{Error: fakeError("TableNotFound")}, nil
+	} else if f.Query == nil {
+		return &zbprotocol.ListKeysResponse{Error: fakeError("QueriesNotSupported")}, nil
+	}
+	res := &zbprotocol.ListKeysResponse{Pagination: &zbprotocol.PaginationInfo{}}
+	for k, v := range t.Data {
+		ok, err := f.Query(t.Defn, in.Query, v)
+		if err != nil {
+			return &zbprotocol.ListKeysResponse{Error: fakeError(err.Error())}, nil
+		} else if ok {
+			res.Keys = append(res.Keys, k)
+		}
+	}
+	sort.Strings(res.Keys)
+	return res, nil
+}
+
+func (f *Server) DeleteObject(ctx context.Context, in *zbprotocol.DeleteSystemObjectRequest, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return fakeError("TableNotFound"), nil
+	}
+	switch in.ObjectType {
+	case zbprotocol.SystemObjectType_KEY:
+		delete(t.Data, in.ObjectId)
+	case zbprotocol.SystemObjectType_TABLE:
+		delete(f.tables, fakeTableKey(in.TableOwnerId, in.TableId))
+	}
+	return &zbprotocol.ZbError{}, nil
+}
+
+// A permission entry without its request nonce and credential, as stored with a table
+func storedPermission(p *zbprotocol.PermissionsEntry) *zbprotocol.PermissionsEntry {
+	p = proto.Clone(p).(*zbprotocol.PermissionsEntry)
+	p.Nonce, p.Credential = 0, nil
+	return p
+}
+
+func (f *Server) SetPermission(ctx context.Context, in *zbprotocol.PermissionsEntry, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.Id, in.TableId)
+	if t == nil {
+		return fakeError("TableNotFound"), nil
+	}
+	t.Defn.Permissions = append(t.Defn.Permissions, storedPermission(in))
+	return &zbprotocol.ZbError{}, nil
+}
+
+// Removes the first entry equal to in (apart from its nonce and credential)
+func (f *Server) RevokePermission(ctx context.Context, in *zbprotocol.PermissionsEntry, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.Id, in.TableId)
+	if t == nil {
+		return fakeError("TableNotFound"), nil
+	}
+	want := storedPermission(in)
+	for i, p := range t.Defn.Permissions {
+		if proto.Equal(storedPermission(p), want) {
+			t.Defn.Permissions = append(t.Defn.Permissions[:i:i], t.Defn.Permissions[i+1:]...)
+			return &zbprotocol.ZbError{}, nil
+		}
+	}
+	return fakeError("PermissionNotFound"), nil
+}
+
+func (f *Server) ReplacePermissions(ctx context.Context, in *zbprotocol.PermissionsReplace, opts ...grpc.CallOption) (*zbprotocol.ZbError, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return fakeError("TableNotFound"), nil
+	}
+	t.Defn.Permissions = nil
+	for _, p := range in.Permissions {
+		t.Defn.Permissions = append(t.Defn.Permissions, proto.Clone(p).(*zbprotocol.PermissionsEntry))
+	}
+	return &zbprotocol.ZbError{}, nil
+}
+
+func (f *Server) ListPermissions(ctx context.Context, in *zbprotocol.ListPermissionsRequest, opts ...grpc.CallOption) (*zbprotocol.ListPermissionsResponse, error) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	t := f.Table(in.TableOwnerId, in.TableId)
+	if t == nil {
+		return &zbprotocol.ListPermissionsResponse{Error: fakeError("TableNotFound")}, nil
+	}
+	res := &zbprotocol.ListPermissionsResponse{}
+	for _, p := range t.Defn.Permissions {
+		res.Entries = append(res.Entries, proto.Clone(p).(*zbprotocol.PermissionsEntry))
+	}
+	return res, nil
+}
+
+func valueHash(valu []byte) []byte {
+	h := sha256.Sum256(valu)
+	return h[:]
+}
+
+func versionAtLeast(v, min string) bool {
+	have, err := version.NewVersion(v)
+	if err != nil {
+		return false
+	}
+	want, _ := version.NewVersion(min)
+	return !have.LessThan(want)
+}
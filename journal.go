@@ -0,0 +1,340 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJournalRetryInterval = 5 * time.Second
+	DefaultJournalBatchSize     = 100
+)
+
+// Type JournalOptions configures a WriteJournal.
+type JournalOptions struct {
+	RetryInterval time.Duration // wait after a failed replay (doubling up to 16x while failures persist)
+	BatchSize     int           // records sent per PutMulti
+	NoReplayer    bool          // do not replay in the background; call Flush instead
+}
+
+// A journal entry: either a write, or an acknowledgement that writes up to Ack were replayed
+type journalRecord struct {
+	Seq       int64  `json:"seq,omitempty"`
+	Owner     string `json:"owner,omitempty"`
+	Table     string `json:"table,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Value     []byte `json:"value,omitempty"`
+	Overwrite bool   `json:"overwrite,omitempty"`
+	Ack       int64  `json:"ack,omitempty"`
+}
+
+// Type WriteJournal queues writes in an append-only local file and replays them to the server, so
+// that writes can be accepted while offline. Writes are replayed in order; a write with overwrite
+// set replaces any existing value (last writer wins), otherwise it is skipped if the key exists.
+type WriteJournal struct {
+	z         *ZetabaseClient
+	opts      JournalOptions
+	lock      sync.Mutex
+	flushLock sync.Mutex
+	closeOnce sync.Once
+	f         *os.File
+	pending   []*journalRecord
+	seq       int64
+	lastErr   error
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Function OpenWriteJournal opens (or creates) the journal in file fn and, unless opts.NoReplayer
+// is set, starts replaying its pending writes through client z in the background. A damaged
+// record (e.g. from a crash mid-write) ends the journal; it and anything after it are discarded.
+func OpenWriteJournal(z *ZetabaseClient, fn string, opts *JournalOptions) (*WriteJournal, error) {
+	j := &WriteJournal{
+		z:    z,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if opts != nil {
+		j.opts = *opts
+	}
+	if j.opts.RetryInterval <= 0 {
+		j.opts.RetryInterval = DefaultJournalRetryInterval
+	}
+	if j.opts.BatchSize <= 0 {
+		j.opts.BatchSize = DefaultJournalBatchSize
+	}
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	j.f = f
+	if err := j.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if j.opts.NoReplayer {
+		close(j.done)
+	} else {
+		go j.replayLoop()
+		j.signal()
+	}
+	return j, nil
+}
+
+// Read the journal, keeping writes not yet acknowledged, and truncate any damaged tail
+func (j *WriteJournal) load() error {
+	var recs []*journalRecord
+	var acked int64
+	good := readChecksummedRecords(j.f, func(payload []byte) bool {
+		var r journalRecord
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return false
+		}
+		if r.Ack > acked {
+			acked = r.Ack
+		} else if r.Seq > 0 {
+			recs = append(recs, &r)
+		}
+		return true
+	})
+	for _, r := range recs {
+		if r.Seq > acked {
+			j.pending = append(j.pending, r)
+		}
+		if r.Seq > j.seq {
+			j.seq = r.Seq
+		}
+	}
+	if acked > j.seq {
+		j.seq = acked
+	}
+	if err := j.f.Truncate(good); err != nil {
+		return err
+	}
+	_, err := j.f.Seek(good, io.SeekStart)
+	return err
+}
+
+// Requires lock
+func (j *WriteJournal) append(r *journalRecord) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(checksummedRecord(payload)); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+func (j *WriteJournal) signal() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Method Put durably queues a write of valu to key in a table. It returns once the write is
+// synced to the journal; it is sent to the server later.
+func (j *WriteJournal) Put(tableOwnerId, tableId, key string, valu []byte, overwrite bool) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.f == nil {
+		return errors.New("JournalClosed")
+	}
+	r := &journalRecord{
+		Seq:       j.seq + 1,
+		Owner:     tableOwnerId,
+		Table:     tableId,
+		Key:       key,
+		Value:     append([]byte{}, valu...),
+		Overwrite: overwrite,
+	}
+	if err := j.append(r); err != nil {
+		return err
+	}
+	j.seq = r.Seq
+	j.pending = append(j.pending, r)
+	j.signal()
+	return nil
+}
+
+// Method Depth returns the number of queued writes not yet replayed.
+func (j *WriteJournal) Depth() int {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return len(j.pending)
+}
+
+// Method LastError returns the error of the last failed replay, or nil if the last replay
+// succeeded.
+func (j *WriteJournal) LastError() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.lastErr
+}
+
+// The next batch to replay: consecutive writes to the same table with the same overwrite flag.
+// Writes with overwrite stop before a key repeats so that writes to a key are applied in order;
+// others may repeat a key, as only the first of them can take effect.
+func (j *WriteJournal) nextBatch() []*journalRecord {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	var batch []*journalRecord
+	seen := map[string]bool{}
+	for _, r := range j.pending {
+		if len(batch) > 0 && (r.Owner != batch[0].Owner || r.Table != batch[0].Table || r.Overwrite != batch[0].Overwrite) {
+			break
+		}
+		if (r.Overwrite && seen[r.Key]) || len(batch) >= j.opts.BatchSize {
+			break
+		}
+		seen[r.Key] = true
+		batch = append(batch, r)
+	}
+	return batch
+}
+
+func (j *WriteJournal) sendBatch(batch []*journalRecord) error {
+	first := batch[0]
+	if !first.Overwrite {
+		return j.sendCreates(batch)
+	}
+	keys := make([]string, len(batch))
+	valus := make([][]byte, len(batch))
+	for i, r := range batch {
+		keys[i], valus[i] = r.Key, r.Value
+	}
+	return j.z.PutMulti(first.Owner, first.Table, keys, valus, true)
+}
+
+// Replay writes without overwrite: the first write to each key is put unless the key exists. If
+// another writer creates one of the keys before the put, the records are put one at a time so that
+// the others still are.
+func (j *WriteJournal) sendCreates(batch []*journalRecord) error {
+	first := batch[0]
+	var keys []string
+	var recs []*journalRecord
+	seen := map[string]bool{}
+	for _, r := range batch {
+		if !seen[r.Key] {
+			seen[r.Key] = true
+			keys = append(keys, r.Key)
+			recs = append(recs, r)
+		}
+	}
+	existing, err := j.z.getPag(first.Owner, first.Table, keys).DataAll()
+	if err != nil {
+		return err
+	}
+	keys = keys[:0]
+	var valus [][]byte
+	var fresh []*journalRecord
+	for _, r := range recs {
+		if _, ok := existing[r.Key]; !ok {
+			keys = append(keys, r.Key)
+			valus = append(valus, r.Value)
+			fresh = append(fresh, r)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	err = j.z.PutMulti(first.Owner, first.Table, keys, valus, false)
+	if !errors.Is(err, ErrKeyAlreadyExists) {
+		return err
+	}
+	for _, r := range fresh {
+		err := j.z.PutData(first.Owner, first.Table, r.Key, r.Value, false)
+		if err != nil && !errors.Is(err, ErrKeyAlreadyExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method Flush replays queued writes until the queue is empty or a write fails.
+func (j *WriteJournal) Flush() error {
+	j.flushLock.Lock()
+	defer j.flushLock.Unlock()
+	for {
+		batch := j.nextBatch()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := j.sendBatch(batch)
+		j.lock.Lock()
+		j.lastErr = err
+		if err == nil && j.f != nil {
+			last := batch[len(batch)-1].Seq
+			j.pending = j.pending[len(batch):]
+			if len(j.pending) == 0 {
+				// Everything is replayed, so the journal can start over
+				if err = j.f.Truncate(0); err == nil {
+					_, err = j.f.Seek(0, io.SeekStart)
+				}
+				if err == nil {
+					err = j.append(&journalRecord{Ack: last})
+				}
+			} else {
+				err = j.append(&journalRecord{Ack: last})
+			}
+			j.lastErr = err
+		} else if j.f == nil {
+			err = errors.New("JournalClosed")
+		}
+		j.lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (j *WriteJournal) replayLoop() {
+	defer close(j.done)
+	wait := j.opts.RetryInterval
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-j.wake:
+		}
+		if err := j.Flush(); err != nil {
+			// Try again later, backing off while failures persist
+			select {
+			case <-j.stop:
+				return
+			case <-time.After(wait):
+			}
+			if wait < j.opts.RetryInterval*16 {
+				wait *= 2
+			}
+			j.signal()
+		} else {
+			wait = j.opts.RetryInterval
+		}
+	}
+}
+
+// Method Close stops the background replayer and closes the journal file. Queued writes stay in
+// the journal and are replayed when it is next opened.
+func (j *WriteJournal) Close() error {
+	var err error
+	j.closeOnce.Do(func() {
+		if !j.opts.NoReplayer {
+			close(j.stop)
+		}
+		<-j.done
+		j.lock.Lock()
+		defer j.lock.Unlock()
+		err = j.f.Close()
+		j.f = nil
+	})
+	return err
+}
@@ -0,0 +1,109 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_JournalQueuesWhileOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbjournal")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "writes.journal")
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"existing": "old"})
+
+	srv.SetWriteErr(errors.New("Unavailable"))
+	j, err := OpenWriteJournal(z, fn, &JournalOptions{NoReplayer: true, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Failed to open journal: %s", err.Error())
+	}
+	j.Put(testOwnerId, "src", "k", []byte("1"), true)
+	j.Put(testOwnerId, "src", "k", []byte("2"), true)
+	j.Put(testOwnerId, "src", "existing", []byte("ignored"), false)
+	j.Put(testOwnerId, "src", "new", []byte("3"), false)
+	if err := j.Flush(); err == nil || j.Depth() != 4 || j.LastError() == nil {
+		t.Fatalf("Expected flush to fail while offline (depth %d)", j.Depth())
+	}
+	j.Close()
+
+	// A torn record at the end (e.g. from a crash) is dropped
+	f, _ := os.OpenFile(fn, os.O_WRONLY|os.O_APPEND, 0600)
+	f.Write([]byte{0, 0, 0, 9, 1, 2})
+	f.Close()
+
+	srv.SetWriteErr(nil)
+	j, err = OpenWriteJournal(z, fn, &JournalOptions{NoReplayer: true})
+	if err != nil || j.Depth() != 4 {
+		t.Fatalf("Failed to reopen journal: %v", err)
+	}
+	defer j.Close()
+	if err := j.Flush(); err != nil || j.Depth() != 0 {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"k": "2", "existing": "old", "new": "3"}, false)
+	if st, _ := os.Stat(fn); st.Size() > 100 {
+		t.Fatalf("Journal not compacted after replay (%d bytes)", st.Size())
+	}
+}
+
+func Test_JournalReplaysInBackground(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbjournal")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, nil)
+	srv.SetWriteErr(errors.New("Unavailable"))
+	j, err := OpenWriteJournal(z, filepath.Join(dir, "writes.journal"), &JournalOptions{RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to open journal: %s", err.Error())
+	}
+	defer j.Close()
+	j.Put(testOwnerId, "src", "k", []byte("v"), true)
+	time.Sleep(10 * time.Millisecond)
+	srv.SetWriteErr(nil)
+	for i := 0; j.Depth() > 0; i++ {
+		if i > 500 {
+			t.Fatalf("Journal was not replayed: %v", j.LastError())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"k": "v"}, false)
+}
+
+func Test_JournalCreatesRacingOtherWriters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbjournal")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, nil)
+	j, err := OpenWriteJournal(z, filepath.Join(dir, "writes.journal"), &JournalOptions{NoReplayer: true})
+	if err != nil {
+		t.Fatalf("Failed to open journal: %s", err.Error())
+	}
+	defer j.Close()
+	j.Put(testOwnerId, "src", "a", []byte("1"), false)
+	j.Put(testOwnerId, "src", "b", []byte("2"), false)
+	j.Put(testOwnerId, "src", "a", []byte("ignored"), false)
+
+	// Another writer creates a key once the journal has checked that it does not exist
+	srv.AfterGet = func(in *zbprotocol.TableGet) {
+		srv.AfterGet = nil
+		srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{{Key: "b", Value: []byte("other")}}, true)
+	}
+	if err := j.Flush(); err != nil || j.Depth() != 0 {
+		t.Fatalf("Flush failed: %v (depth %d)", err, j.Depth())
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"a": "1", "b": "other"}, false)
+}
@@ -0,0 +1,60 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Look up a (possibly dotted, e.g. "user.name") field path in a decoded JSON document
+func jsonFieldValue(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Set a (possibly dotted) field path in a decoded JSON document, creating intermediate objects
+func setJsonFieldValue(doc map[string]interface{}, path string, valu interface{}) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for _, part := range parts[:len(parts)-1] {
+		nxt, ok := cur[part].(map[string]interface{})
+		if !ok {
+			nxt = map[string]interface{}{}
+			cur[part] = nxt
+		}
+		cur = nxt
+	}
+	cur[parts[len(parts)-1]] = valu
+}
+
+func decodeJsonObject(bs []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	err := json.Unmarshal(bs, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Canonical JSON encoding of a scalar value, so that e.g. 30 and 30.0 compare equal
+func canonicalJsonValue(valu interface{}) ([]byte, error) {
+	bs, err := json.Marshal(valu)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(bs, &generic)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
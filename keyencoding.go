@@ -0,0 +1,495 @@
+package zetabase
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Type KeyFormat identifies an encoding for ECDSA identity keys.
+type KeyFormat int
+
+const (
+	KeyFormatSec1    KeyFormat = iota // SEC1 "EC PRIVATE KEY" PEM (public keys: PKIX "PUBLIC KEY" PEM)
+	KeyFormatPkcs8                    // PKCS#8 "PRIVATE KEY" PEM (public keys: PKIX "PUBLIC KEY" PEM)
+	KeyFormatDer                      // PKCS#8 DER (public keys: PKIX DER)
+	KeyFormatJwk                      // JSON Web Key (RFC 7517) with an RFC 7638 thumbprint as kid
+	KeyFormatOpenSSH                  // "OPENSSH PRIVATE KEY" PEM (public keys: authorized_keys line)
+)
+
+var keyFormatNames = map[KeyFormat]string{
+	KeyFormatSec1:    "sec1",
+	KeyFormatPkcs8:   "pkcs8",
+	KeyFormatDer:     "der",
+	KeyFormatJwk:     "jwk",
+	KeyFormatOpenSSH: "openssh",
+}
+
+func (f KeyFormat) String() string {
+	if s, ok := keyFormatNames[f]; ok {
+		return s
+	}
+	return "unknown"
+}
+
+// Parse a key format name: one of sec1, pkcs8, der, jwk, openssh
+func ParseKeyFormat(s string) (KeyFormat, error) {
+	for f, name := range keyFormatNames {
+		if strings.EqualFold(s, name) {
+			return f, nil
+		}
+	}
+	return 0, errors.New("UnknownKeyFormat")
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Curve names as used by JWK and OpenSSH
+var curveNames = []struct {
+	curve   elliptic.Curve
+	jwk     string
+	openssh string
+}{
+	{elliptic.P256(), "P-256", "nistp256"},
+	{elliptic.P384(), "P-384", "nistp384"},
+	{elliptic.P521(), "P-521", "nistp521"},
+}
+
+func curveJwkName(c elliptic.Curve) (string, error) {
+	for _, cn := range curveNames {
+		if cn.curve == c {
+			return cn.jwk, nil
+		}
+	}
+	return "", errors.New("UnsupportedCurve")
+}
+
+func curveOpenSSHName(c elliptic.Curve) (string, error) {
+	for _, cn := range curveNames {
+		if cn.curve == c {
+			return cn.openssh, nil
+		}
+	}
+	return "", errors.New("UnsupportedCurve")
+}
+
+func curveByName(jwk, openssh string) (elliptic.Curve, error) {
+	for _, cn := range curveNames {
+		if (len(jwk) > 0 && cn.jwk == jwk) || (len(openssh) > 0 && cn.openssh == openssh) {
+			return cn.curve, nil
+		}
+	}
+	return nil, errors.New("UnsupportedCurve")
+}
+
+// Fixed-width big-endian encoding of a curve coordinate or scalar
+func curveBytes(c elliptic.Curve, i *big.Int) []byte {
+	n := (c.Params().BitSize + 7) / 8
+	bs := i.Bytes()
+	if len(bs) >= n {
+		return bs
+	}
+	return append(make([]byte, n-len(bs)), bs...)
+}
+
+// Function JwkThumbprint computes the RFC 7638 thumbprint of pub, as used for the JWK kid.
+func JwkThumbprint(pub *ecdsa.PublicKey) (string, error) {
+	crv, err := curveJwkName(pub.Curve)
+	if err != nil {
+		return "", err
+	}
+	b64 := base64.RawURLEncoding
+	return jwkThumbprint(map[string]string{
+		"crv": crv,
+		"kty": "EC",
+		"x":   b64.EncodeToString(curveBytes(pub.Curve, pub.X)),
+		"y":   b64.EncodeToString(curveBytes(pub.Curve, pub.Y)),
+	})
+}
+
+// RFC 7638 thumbprint of a key given its required members. encoding/json writes them in
+// lexicographic order and without whitespace, as the RFC requires.
+func jwkThumbprint(members map[string]string) (string, error) {
+	canon, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(canon)
+	return base64.RawURLEncoding.EncodeToString(h[:]), nil
+}
+
+// Check that d is the private key of pub, so that a key file with mismatched halves is rejected
+// instead of producing signatures no one can verify
+func privateKeyMatches(pub *ecdsa.PublicKey, d *big.Int) bool {
+	if d.Sign() <= 0 || d.Cmp(pub.Curve.Params().N) >= 0 {
+		return false
+	}
+	x, y := pub.Curve.ScalarBaseMult(curveBytes(pub.Curve, d))
+	return x.Cmp(pub.X) == 0 && y.Cmp(pub.Y) == 0
+}
+
+func makeJwk(pub *ecdsa.PublicKey, d *big.Int) ([]byte, error) {
+	crv, err := curveJwkName(pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := JwkThumbprint(pub)
+	if err != nil {
+		return nil, err
+	}
+	b64 := base64.RawURLEncoding
+	jwk := jsonWebKey{
+		Kty: "EC",
+		Crv: crv,
+		X:   b64.EncodeToString(curveBytes(pub.Curve, pub.X)),
+		Y:   b64.EncodeToString(curveBytes(pub.Curve, pub.Y)),
+		Kid: kid,
+	}
+	if d != nil {
+		jwk.D = b64.EncodeToString(curveBytes(pub.Curve, d))
+	}
+	return json.MarshalIndent(jwk, "", " ")
+}
+
+func parseJwk(bs []byte) (*ecdsa.PublicKey, *big.Int, error) {
+	var jwk jsonWebKey
+	err := json.Unmarshal(bs, &jwk)
+	if err != nil {
+		return nil, nil, err
+	}
+	if jwk.Kty != "EC" {
+		return nil, nil, errors.New("UnsupportedKeyType")
+	}
+	c, err := curveByName(jwk.Crv, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	b64 := base64.RawURLEncoding
+	xb, err1 := b64.DecodeString(jwk.X)
+	yb, err2 := b64.DecodeString(jwk.Y)
+	if err1 != nil || err2 != nil {
+		return nil, nil, errors.New("InvalidJwk")
+	}
+	pub := &ecdsa.PublicKey{Curve: c, X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb)}
+	if !c.IsOnCurve(pub.X, pub.Y) {
+		return nil, nil, errors.New("InvalidJwk")
+	}
+	if len(jwk.D) == 0 {
+		return pub, nil, nil
+	}
+	db, err := b64.DecodeString(jwk.D)
+	if err != nil {
+		return nil, nil, errors.New("InvalidJwk")
+	}
+	d := new(big.Int).SetBytes(db)
+	if !privateKeyMatches(pub, d) {
+		return nil, nil, errors.New("PrivateKeyMismatch")
+	}
+	return pub, d, nil
+}
+
+// OpenSSH wire encoding (RFC 4251 strings and mpints)
+
+func sshPutString(buf *bytes.Buffer, bs []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(bs)))
+	buf.Write(n[:])
+	buf.Write(bs)
+}
+
+func sshPutMpint(buf *bytes.Buffer, i *big.Int) {
+	bs := i.Bytes()
+	if len(bs) > 0 && bs[0]&0x80 != 0 {
+		bs = append([]byte{0}, bs...)
+	}
+	sshPutString(buf, bs)
+}
+
+type sshReader struct {
+	bs  []byte
+	err error
+}
+
+func (r *sshReader) uint32() uint32 {
+	if r.err != nil || len(r.bs) < 4 {
+		r.err = errors.New("InvalidOpenSSHKey")
+		return 0
+	}
+	n := binary.BigEndian.Uint32(r.bs)
+	r.bs = r.bs[4:]
+	return n
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.bs)) < n {
+		r.err = errors.New("InvalidOpenSSHKey")
+		return nil
+	}
+	s := r.bs[:n]
+	r.bs = r.bs[n:]
+	return s
+}
+
+func sshPubKeyBlob(pub *ecdsa.PublicKey) ([]byte, error) {
+	crv, err := curveOpenSSHName(pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	sshPutString(&buf, []byte("ecdsa-sha2-"+crv))
+	sshPutString(&buf, []byte(crv))
+	sshPutString(&buf, elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	return buf.Bytes(), nil
+}
+
+func parseSshPubKeyBlob(r *sshReader) (*ecdsa.PublicKey, error) {
+	typ := string(r.string())
+	crv := string(r.string())
+	pt := r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if typ != "ecdsa-sha2-"+crv {
+		return nil, errors.New("UnsupportedKeyType")
+	}
+	c, err := curveByName("", crv)
+	if err != nil {
+		return nil, err
+	}
+	x, y := elliptic.Unmarshal(c, pt)
+	if x == nil {
+		return nil, errors.New("InvalidOpenSSHKey")
+	}
+	return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+const sshKeyMagic = "openssh-key-v1\x00"
+
+func makeOpenSSHPrivateKey(priv *ecdsa.PrivateKey) ([]byte, error) {
+	pubBlob, err := sshPubKeyBlob(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	crv, _ := curveOpenSSHName(priv.Curve)
+	var chk [4]byte
+	rand.Read(chk[:])
+	var sec bytes.Buffer
+	sec.Write(chk[:])
+	sec.Write(chk[:])
+	sshPutString(&sec, []byte("ecdsa-sha2-"+crv))
+	sshPutString(&sec, []byte(crv))
+	sshPutString(&sec, elliptic.Marshal(priv.Curve, priv.X, priv.Y))
+	sshPutMpint(&sec, priv.D)
+	sshPutString(&sec, nil) // comment
+	for i := byte(1); sec.Len()%8 != 0; i++ {
+		sec.WriteByte(i)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(sshKeyMagic)
+	sshPutString(&buf, []byte("none"))
+	sshPutString(&buf, []byte("none"))
+	sshPutString(&buf, nil)
+	buf.Write([]byte{0, 0, 0, 1})
+	sshPutString(&buf, pubBlob)
+	sshPutString(&buf, sec.Bytes())
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: buf.Bytes()}), nil
+}
+
+func parseOpenSSHPrivateKey(bs []byte) (*ecdsa.PrivateKey, error) {
+	if !bytes.HasPrefix(bs, []byte(sshKeyMagic)) {
+		return nil, errors.New("InvalidOpenSSHKey")
+	}
+	r := &sshReader{bs: bs[len(sshKeyMagic):]}
+	cipherName := string(r.string())
+	kdfName := string(r.string())
+	r.string() // kdf options
+	nKeys := r.uint32()
+	r.string() // public key
+	sec := r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if cipherName != "none" || kdfName != "none" {
+		return nil, errors.New("EncryptedKeyNotSupported")
+	}
+	if nKeys != 1 {
+		return nil, errors.New("InvalidOpenSSHKey")
+	}
+	sr := &sshReader{bs: sec}
+	if sr.uint32() != sr.uint32() {
+		return nil, errors.New("InvalidOpenSSHKey")
+	}
+	pub, err := parseSshPubKeyBlob(sr)
+	if err != nil {
+		return nil, err
+	}
+	db := sr.string()
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	d := new(big.Int).SetBytes(db)
+	if !privateKeyMatches(pub, d) {
+		return nil, errors.New("PrivateKeyMismatch")
+	}
+	return &ecdsa.PrivateKey{PublicKey: *pub, D: d}, nil
+}
+
+// Function EncodePrivateKeyAs encodes priv in format f.
+func EncodePrivateKeyAs(priv *ecdsa.PrivateKey, f KeyFormat) ([]byte, error) {
+	switch f {
+	case KeyFormatSec1:
+		der, err := x509.MarshalECPrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	case KeyFormatPkcs8, KeyFormatDer:
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		if f == KeyFormatDer {
+			return der, nil
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	case KeyFormatJwk:
+		return makeJwk(&priv.PublicKey, priv.D)
+	case KeyFormatOpenSSH:
+		return makeOpenSSHPrivateKey(priv)
+	}
+	return nil, errors.New("UnknownKeyFormat")
+}
+
+// Function EncodePublicKeyAs encodes pub in format f. SEC1 and PKCS#8 both yield a PKIX PEM block.
+func EncodePublicKeyAs(pub *ecdsa.PublicKey, f KeyFormat) ([]byte, error) {
+	switch f {
+	case KeyFormatSec1, KeyFormatPkcs8:
+		return EncodeEcdsaPublicKey(pub)
+	case KeyFormatDer:
+		return x509.MarshalPKIXPublicKey(pub)
+	case KeyFormatJwk:
+		return makeJwk(pub, nil)
+	case KeyFormatOpenSSH:
+		blob, err := sshPubKeyBlob(pub)
+		if err != nil {
+			return nil, err
+		}
+		crv, _ := curveOpenSSHName(pub.Curve)
+		return []byte("ecdsa-sha2-" + crv + " " + base64.StdEncoding.EncodeToString(blob) + "\n"), nil
+	}
+	return nil, errors.New("UnknownKeyFormat")
+}
+
+func parseDerPrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	if k, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if ek, ok := k.(*ecdsa.PrivateKey); ok {
+			return ek, nil
+		}
+		return nil, errors.New("UnsupportedKeyType")
+	}
+	return x509.ParseECPrivateKey(der)
+}
+
+// Function DecodePrivateKeyAny decodes an ECDSA private key in any supported format and reports
+// which format it was in.
+func DecodePrivateKeyAny(bs []byte) (*ecdsa.PrivateKey, KeyFormat, error) {
+	trimmed := bytes.TrimSpace(bs)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		pub, d, err := parseJwk(trimmed)
+		if err != nil {
+			return nil, 0, err
+		}
+		if d == nil {
+			return nil, 0, errors.New("NotAPrivateKey")
+		}
+		return &ecdsa.PrivateKey{PublicKey: *pub, D: d}, KeyFormatJwk, nil
+	}
+	if block, _ := pem.Decode(trimmed); block != nil {
+		switch block.Type {
+		case "OPENSSH PRIVATE KEY":
+			k, err := parseOpenSSHPrivateKey(block.Bytes)
+			return k, KeyFormatOpenSSH, err
+		case "EC PRIVATE KEY":
+			k, err := x509.ParseECPrivateKey(block.Bytes)
+			return k, KeyFormatSec1, err
+		case "PRIVATE KEY":
+			// EncodeEcdsaPrivateKey writes SEC1 bytes under this type, so accept both
+			if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+				return k, KeyFormatSec1, nil
+			}
+			k, err := parseDerPrivateKey(block.Bytes)
+			return k, KeyFormatPkcs8, err
+		}
+		return nil, 0, errors.New("UnsupportedPemType")
+	}
+	k, err := parseDerPrivateKey(bs)
+	if err != nil {
+		return nil, 0, errors.New("UnrecognizedKeyEncoding")
+	}
+	return k, KeyFormatDer, nil
+}
+
+// Function DecodePublicKeyAny decodes an ECDSA public key in any supported format and reports
+// which format it was in. Private keys are accepted too, in which case their public half is returned.
+func DecodePublicKeyAny(bs []byte) (*ecdsa.PublicKey, KeyFormat, error) {
+	trimmed := bytes.TrimSpace(bs)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		pub, _, err := parseJwk(trimmed)
+		return pub, KeyFormatJwk, err
+	}
+	if bytes.HasPrefix(trimmed, []byte("ecdsa-sha2-")) {
+		fields := strings.Fields(string(trimmed))
+		if len(fields) < 2 {
+			return nil, 0, errors.New("InvalidOpenSSHKey")
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, 0, errors.New("InvalidOpenSSHKey")
+		}
+		pub, err := parseSshPubKeyBlob(&sshReader{bs: blob})
+		return pub, KeyFormatOpenSSH, err
+	}
+	if block, _ := pem.Decode(trimmed); block != nil && block.Type == "PUBLIC KEY" {
+		pub, err := parsePkixPublicKey(block.Bytes)
+		return pub, KeyFormatPkcs8, err
+	} else if block == nil {
+		if pub, err := parsePkixPublicKey(bs); err == nil {
+			return pub, KeyFormatDer, nil
+		}
+	}
+	priv, f, err := DecodePrivateKeyAny(bs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &priv.PublicKey, f, nil
+}
+
+func parsePkixPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	k, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := k.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("UnsupportedKeyType")
+	}
+	return pub, nil
+}
@@ -0,0 +1,96 @@
+package zetabase
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+)
+
+func Test_KeyEncodingRoundTrip(t *testing.T) {
+	for _, c := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		priv, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatalf("Key generation failed: %s", err.Error())
+		}
+		for f := range keyFormatNames {
+			enc, err := EncodePrivateKeyAs(priv, f)
+			if err != nil {
+				t.Fatalf("Encoding private key as %s failed: %s", f, err.Error())
+			}
+			dec, df, err := DecodePrivateKeyAny(enc)
+			if err != nil || df != f || dec.D.Cmp(priv.D) != 0 || dec.X.Cmp(priv.X) != 0 {
+				t.Fatalf("Private key round trip failed for %s (%s): %v", f, df, err)
+			}
+			pubEnc, err := EncodePublicKeyAs(&priv.PublicKey, f)
+			if err != nil {
+				t.Fatalf("Encoding public key as %s failed: %s", f, err.Error())
+			}
+			pub, _, err := DecodePublicKeyAny(pubEnc)
+			if err != nil || pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0 {
+				t.Fatalf("Public key round trip failed for %s: %v", f, err)
+			}
+		}
+	}
+}
+
+func Test_KeyEncodingLegacy(t *testing.T) {
+	priv, pub := GenerateKeyPair()
+	enc, _ := EncodeEcdsaPrivateKey(priv)
+	dec, err := DecodeEcdsaPrivateKey(string(enc))
+	if err != nil || dec.D.Cmp(priv.D) != 0 {
+		t.Fatalf("Failed to decode legacy private key: %v", err)
+	}
+	pubEnc, _ := EncodeEcdsaPublicKey(pub)
+	decPub, err := DecodeEcdsaPublicKey(string(pubEnc))
+	if err != nil || decPub.X.Cmp(pub.X) != 0 {
+		t.Fatalf("Failed to decode legacy public key: %v", err)
+	}
+	if _, err := DecodeEcdsaPrivateKey("garbage"); err == nil {
+		t.Fatalf("Expected error decoding garbage")
+	}
+}
+
+func Test_JwkThumbprint(t *testing.T) {
+	// Example key from RFC 7517 appendix A.1
+	jwk := []byte(`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}`)
+	pub, _, err := DecodePublicKeyAny(jwk)
+	if err != nil {
+		t.Fatalf("Failed to decode JWK: %s", err.Error())
+	}
+	kid, _ := JwkThumbprint(pub)
+	enc, _ := EncodePublicKeyAs(pub, KeyFormatJwk)
+	var obj map[string]string
+	json.Unmarshal(enc, &obj)
+	if obj["kid"] != kid || len(kid) != 43 {
+		t.Fatalf("Unexpected kid %s / %s", obj["kid"], kid)
+	}
+
+	// Example RSA key and thumbprint from RFC 7638 section 3.1
+	rsaKid, _ := jwkThumbprint(map[string]string{
+		"e":   "AQAB",
+		"kty": "RSA",
+		"n": "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3o" +
+			"knjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qM" +
+			"QvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	})
+	if rsaKid != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("Wrong thumbprint for the RFC 7638 example: %s", rsaKid)
+	}
+}
+
+func Test_KeyEncodingRejectsMismatchedKeys(t *testing.T) {
+	priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	mixed := &ecdsa.PrivateKey{PublicKey: other.PublicKey, D: priv.D}
+	for _, f := range []KeyFormat{KeyFormatJwk, KeyFormatOpenSSH} {
+		enc, err := EncodePrivateKeyAs(mixed, f)
+		if err != nil {
+			t.Fatalf("Encoding private key as %s failed: %s", f, err.Error())
+		}
+		if _, _, err := DecodePrivateKeyAny(enc); err == nil || err.Error() != "PrivateKeyMismatch" {
+			t.Fatalf("Expected PrivateKeyMismatch decoding %s, got %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Package migrate runs versioned migrations against the tables of a Zetabase identity. Migrations
+// are Go functions or declarative steps (see Step), applied in version order with Up and undone
+// with Down. Applied migrations are recorded in a ledger table, and a lock record in the same
+// table keeps two runs from overlapping.
+package migrate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Table of the identity holding the migration ledger and lock
+	LedgerTableId = "zbmigrations"
+	// A lock not refreshed for this long is assumed to be left by a crashed run and may be taken over
+	DefaultLockTimeout = time.Hour
+	lockKey            = "lock"
+	ledgerKeyPrefix    = "applied/"
+	versionDigits      = 16
+)
+
+// Type Migration is one versioned change. Down undoes Up; a migration without Down steps cannot be
+// rolled back.
+type Migration struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	Up      []Step `json:"-"`
+	Down    []Step `json:"-"`
+}
+
+// Type Status is the state of a migration as recorded in the ledger.
+type Status struct {
+	Migration *Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type ledgerRecord struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	AppliedAt int64  `json:"appliedAt"`
+}
+
+type lockRecord struct {
+	Holder     string `json:"holder"`
+	AcquiredAt int64  `json:"acquiredAt"`
+}
+
+// Type Migrator applies a set of migrations to the tables of the client's identity. Runs hold a
+// lock, refreshed before every step, so that a run that stops refreshing it for longer than
+// LockTimeout (having crashed) can be taken over. Locking needs the server to support
+// CompareAndSwap natively; runs fail with MigrationRequiresCompareAndSwap otherwise.
+type Migrator struct {
+	LockTimeout time.Duration
+	// Called before each migration is applied (up true) or rolled back
+	Progress   func(m *Migration, up bool)
+	z          *zetabase.ZetabaseClient
+	migrations []*Migration
+	holder     string
+	lockRec    []byte // lock record as last written by this run
+}
+
+// Function New returns a migrator for the given migrations, which must have distinct positive
+// versions.
+func New(z *zetabase.ZetabaseClient, migrations ...*Migration) (*Migrator, error) {
+	ms := append([]*Migration{}, migrations...)
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	for i, m := range ms {
+		if m.Version <= 0 || (i > 0 && ms[i-1].Version == m.Version) {
+			return nil, errors.New("InvalidMigrationVersion")
+		}
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	host, _ := os.Hostname()
+	return &Migrator{
+		LockTimeout: DefaultLockTimeout,
+		z:           z,
+		migrations:  ms,
+		holder:      fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(id[:])),
+	}, nil
+}
+
+func ledgerKey(version int64) string {
+	return fmt.Sprintf("%s%0*d", ledgerKeyPrefix, versionDigits, version)
+}
+
+func (m *Migrator) ensureLedger() error {
+	if _, err := m.z.GetTableDefinition(m.z.Id(), LedgerTableId); err == nil {
+		return nil
+	}
+	return m.z.CreateTable(LedgerTableId, zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+}
+
+func (m *Migrator) ledger() (map[int64]*ledgerRecord, error) {
+	keys, err := m.z.ListKeysWithPattern(m.z.Id(), LedgerTableId, ledgerKeyPrefix+"%").KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	res := map[int64]*ledgerRecord{}
+	if len(keys) == 0 {
+		return res, nil
+	}
+	data, err := m.z.GetUncached(m.z.Id(), LedgerTableId, keys).DataAll()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range data {
+		var r ledgerRecord
+		if json.Unmarshal(v, &r) != nil {
+			return nil, errors.New("InvalidLedgerRecord")
+		}
+		if n, err := strconv.ParseInt(strings.TrimPrefix(k, ledgerKeyPrefix), 10, 64); err == nil {
+			res[n] = &r
+		}
+	}
+	return res, nil
+}
+
+// Method Status reports every known migration, and whether it has been applied, in version order.
+// Migrations recorded in the ledger but not known to the migrator are included without Up or Down
+// steps.
+func (m *Migrator) Status() ([]*Status, error) {
+	if err := m.ensureLedger(); err != nil {
+		return nil, err
+	}
+	applied, err := m.ledger()
+	if err != nil {
+		return nil, err
+	}
+	var res []*Status
+	for _, mig := range m.migrations {
+		s := &Status{Migration: mig}
+		if r, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = time.Unix(0, r.AppliedAt)
+			delete(applied, mig.Version)
+		}
+		res = append(res, s)
+	}
+	for v, r := range applied {
+		res = append(res, &Status{Migration: &Migration{Version: v, Name: r.Name}, Applied: true, AppliedAt: time.Unix(0, r.AppliedAt)})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Migration.Version < res[j].Migration.Version })
+	return res, nil
+}
+
+func (m *Migrator) lockRecord() []byte {
+	rec, _ := json.Marshal(&lockRecord{Holder: m.holder, AcquiredAt: time.Now().UnixNano()})
+	return rec
+}
+
+func (m *Migrator) lock() error {
+	if !m.z.NativeCompareAndSwap() {
+		return errors.New("MigrationRequiresCompareAndSwap")
+	}
+	if err := m.ensureLedger(); err != nil {
+		return err
+	}
+	rec := m.lockRecord()
+	err := m.z.CompareAndSwap(m.z.Id(), LedgerTableId, lockKey, nil, rec)
+	if err == nil {
+		m.lockRec = rec
+		return nil
+	} else if !errors.Is(err, zetabase.ErrCompareAndSwapFailed) {
+		return err
+	}
+	cur, err := m.z.GetUncached(m.z.Id(), LedgerTableId, []string{lockKey}).DataAll()
+	if err != nil {
+		return err
+	}
+	var held lockRecord
+	if bs, ok := cur[lockKey]; ok && json.Unmarshal(bs, &held) == nil && time.Since(time.Unix(0, held.AcquiredAt)) > m.LockTimeout {
+		// Take over a stale lock, unless another run got there first
+		if m.z.CompareAndSwap(m.z.Id(), LedgerTableId, lockKey, zetabase.ValueHash(bs), rec) == nil {
+			m.lockRec = rec
+			return nil
+		}
+	}
+	return errors.New("MigrationLocked")
+}
+
+// Show that the run is still alive, so that its lock is not taken over
+func (m *Migrator) refreshLock() error {
+	rec := m.lockRecord()
+	err := m.z.CompareAndSwap(m.z.Id(), LedgerTableId, lockKey, zetabase.ValueHash(m.lockRec), rec)
+	if errors.Is(err, zetabase.ErrCompareAndSwapFailed) {
+		return errors.New("MigrationLockLost")
+	} else if err != nil {
+		return err
+	}
+	m.lockRec = rec
+	return nil
+}
+
+func (m *Migrator) unlock() error {
+	cur, err := m.z.GetUncached(m.z.Id(), LedgerTableId, []string{lockKey}).DataAll()
+	if err != nil {
+		return err
+	}
+	var held lockRecord
+	if bs, ok := cur[lockKey]; !ok || json.Unmarshal(bs, &held) != nil || held.Holder != m.holder {
+		return errors.New("MigrationLockLost")
+	}
+	return m.z.DeleteKeyPermanently(m.z.Id(), LedgerTableId, lockKey)
+}
+
+// Method Unlock removes the lock left by a run that did not finish, whoever holds it.
+func (m *Migrator) Unlock() error {
+	return m.z.DeleteKeyPermanently(m.z.Id(), LedgerTableId, lockKey)
+}
+
+func (m *Migrator) run(f func(applied map[int64]*ledgerRecord) (int, error)) (int, error) {
+	if err := m.lock(); err != nil {
+		return 0, err
+	}
+	applied, err := m.ledger()
+	if err != nil {
+		m.unlock()
+		return 0, err
+	}
+	n, err := f(applied)
+	if uerr := m.unlock(); err == nil {
+		err = uerr
+	}
+	return n, err
+}
+
+// Apply steps, refreshing the lock before each of them and once they are done
+func (m *Migrator) applySteps(steps []Step) error {
+	for _, s := range steps {
+		if err := m.refreshLock(); err != nil {
+			return err
+		}
+		if err := s.Apply(m.z); err != nil {
+			return err
+		}
+	}
+	return m.refreshLock()
+}
+
+// Method Up applies the migrations not applied yet, up to and including version to (all of them
+// if to is 0), in version order. It stops at the first migration that fails, which is left
+// unrecorded. Returns the number of migrations applied.
+func (m *Migrator) Up(to int64) (int, error) {
+	return m.run(func(applied map[int64]*ledgerRecord) (int, error) {
+		n := 0
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if to > 0 && mig.Version > to {
+				break
+			}
+			if m.Progress != nil {
+				m.Progress(mig, true)
+			}
+			if err := m.applySteps(mig.Up); err != nil {
+				return n, fmt.Errorf("MigrationFailed: %d (%s): %s", mig.Version, mig.Name, err.Error())
+			}
+			rec, _ := json.Marshal(&ledgerRecord{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UnixNano()})
+			if err := m.z.PutData(m.z.Id(), LedgerTableId, ledgerKey(mig.Version), rec, true); err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	})
+}
+
+// Method Down rolls back the applied migrations with versions above to, newest first. Returns the
+// number of migrations rolled back.
+func (m *Migrator) Down(to int64) (int, error) {
+	return m.run(func(applied map[int64]*ledgerRecord) (int, error) {
+		known := map[int64]bool{}
+		for _, mig := range m.migrations {
+			known[mig.Version] = true
+		}
+		for v := range applied {
+			if v > to && !known[v] {
+				return 0, fmt.Errorf("UnknownMigration: %d", v)
+			}
+		}
+		n := 0
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if mig.Version <= to {
+				break
+			}
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if len(mig.Down) == 0 {
+				return n, fmt.Errorf("MigrationIrreversible: %d (%s)", mig.Version, mig.Name)
+			}
+			if m.Progress != nil {
+				m.Progress(mig, false)
+			}
+			if err := m.applySteps(mig.Down); err != nil {
+				return n, fmt.Errorf("MigrationFailed: %d (%s): %s", mig.Version, mig.Name, err.Error())
+			}
+			if err := m.z.DeleteKeyPermanently(m.z.Id(), LedgerTableId, ledgerKey(mig.Version)); err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	})
+}
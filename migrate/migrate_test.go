@@ -0,0 +1,264 @@
+package migrate_test
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client"
+	"github.com/zetabase/zetabase-client/internal/zbfake"
+	"github.com/zetabase/zetabase-client/migrate"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+const migrateTestOwner = "e1d0d1f2-0000-4000-8000-000000000001"
+
+// Clients sharing a new fake server, which supports CompareAndSwap, and a function returning the
+// contents of a table on that server
+func newFakeClients(n int) ([]*zetabase.ZetabaseClient, *zbfake.Server, func(owner, tbl string) map[string][]byte) {
+	srv := zbfake.NewServer()
+	srv.Version = zetabase.CompareAndSwapMinServerVersion
+	var res []*zetabase.ZetabaseClient
+	for i := 0; i < n; i++ {
+		z := zetabase.NewZetabaseClient(migrateTestOwner)
+		priv, pub := zetabase.GenerateKeyPair()
+		z.SetIdKey(priv, pub)
+		z.ConnectProvider(srv)
+		res = append(res, z)
+	}
+	return res, srv, func(owner, tbl string) map[string][]byte {
+		srv.Mu.Lock()
+		defer srv.Mu.Unlock()
+		t := srv.Table(owner, tbl)
+		if t == nil {
+			return nil
+		}
+		data := map[string][]byte{}
+		for k, v := range t.Data {
+			data[k] = v
+		}
+		return data
+	}
+}
+
+func makeMigrateTestTable(t *testing.T, z *zetabase.ZetabaseClient) {
+	if err := z.CreateTable("users", zbprotocol.TableDataFormat_JSON, nil, nil, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	z.PutMulti(migrateTestOwner, "users", []string{"u/1", "u/2", "admin/1"},
+		[][]byte{[]byte(`{"nm": "ann", "age": 31}`), []byte(`{"age": 4}`), []byte(`{"nm": "root", "age": 50}`)}, true)
+}
+
+func hasIndex(z *zetabase.ZetabaseClient, tbl, field string) bool {
+	defn, _ := z.GetTableDefinition(migrateTestOwner, tbl)
+	for _, f := range defn.GetIndices().GetFields() {
+		if f.GetField() == field {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_MigrateUpDown(t *testing.T) {
+	zs, _, contents := newFakeClients(1)
+	z := zs[0]
+	makeMigrateTestTable(t, z)
+	m, err := migrate.New(z,
+		&migrate.Migration{Version: 3, Name: "split admins",
+			Up:   []migrate.Step{&migrate.SplitTable{From: "users", To: "admins", KeyPattern: "admin/%"}},
+			Down: []migrate.Step{&migrate.SplitTable{From: "admins", To: "users", KeyPattern: "admin/%"}}},
+		&migrate.Migration{Version: 1, Name: "rename nm",
+			Up:   []migrate.Step{&migrate.RenameField{Table: "users", From: "nm", To: "name"}},
+			Down: []migrate.Step{&migrate.RenameField{Table: "users", From: "name", To: "nm"}}},
+		&migrate.Migration{Version: 2, Name: "index age",
+			Up:   []migrate.Step{&migrate.AddIndex{Table: "users", Field: "age", Ordering: zbprotocol.QueryOrdering_INTEGRAL_NUMBERS}},
+			Down: []migrate.Step{&migrate.DropIndex{Table: "users", Field: "age"}}},
+	)
+	if err != nil {
+		t.Fatalf("New failed: %s", err.Error())
+	}
+	if n, err := m.Up(0); err != nil || n != 3 {
+		t.Fatalf("Unexpected up: %v (%d applied)", err, n)
+	}
+	users := contents(migrateTestOwner, "users")
+	if len(users) != 2 || !strings.Contains(string(users["u/1"]), `"name":"ann"`) || string(users["u/2"]) != `{"age": 4}` {
+		t.Fatalf("Unexpected users after up: %v", users)
+	}
+	if admins := contents(migrateTestOwner, "admins"); len(admins) != 1 || !strings.Contains(string(admins["admin/1"]), `"name":"root"`) {
+		t.Fatalf("Unexpected admins after up: %v", admins)
+	}
+	if !hasIndex(z, "users", "age") {
+		t.Fatalf("Index not added")
+	}
+	if n, _ := m.Up(0); n != 0 {
+		t.Fatalf("Applied %d migrations twice", n)
+	}
+
+	if n, err := m.Down(1); err != nil || n != 2 {
+		t.Fatalf("Unexpected down: %v (%d rolled back)", err, n)
+	}
+	if hasIndex(z, "users", "age") || len(contents(migrateTestOwner, "users")) != 3 {
+		t.Fatalf("Migrations not rolled back: %v", contents(migrateTestOwner, "users"))
+	}
+	sts, err := m.Status()
+	if err != nil || len(sts) != 3 || !sts[0].Applied || sts[1].Applied || sts[2].Applied {
+		t.Fatalf("Unexpected status: %v (%v)", sts, err)
+	}
+	if _, ok := contents(migrateTestOwner, migrate.LedgerTableId)["lock"]; ok {
+		t.Fatalf("Lock not released")
+	}
+}
+
+func Test_MigrateLock(t *testing.T) {
+	zs, _, _ := newFakeClients(2)
+	makeMigrateTestTable(t, zs[0])
+	var concurrentErr error
+	other, _ := migrate.New(zs[1])
+	m, _ := migrate.New(zs[0], &migrate.Migration{Version: 1, Name: "one-way", Up: []migrate.Step{
+		migrate.Func(func(z *zetabase.ZetabaseClient) error {
+			_, concurrentErr = other.Up(0)
+			return nil
+		}),
+	}})
+	if n, err := m.Up(0); err != nil || n != 1 {
+		t.Fatalf("Unexpected up: %v (%d applied)", err, n)
+	}
+	if concurrentErr == nil || concurrentErr.Error() != "MigrationLocked" {
+		t.Fatalf("Expected MigrationLocked, got %v", concurrentErr)
+	}
+	if _, err := m.Down(0); err == nil || !strings.HasPrefix(err.Error(), "MigrationIrreversible") {
+		t.Fatalf("Expected MigrationIrreversible, got %v", err)
+	}
+	if _, err := other.Down(0); err == nil || !strings.HasPrefix(err.Error(), "UnknownMigration") {
+		t.Fatalf("Expected UnknownMigration, got %v", err)
+	}
+}
+
+func Test_MigrateLockRefresh(t *testing.T) {
+	zs, _, contents := newFakeClients(2)
+	makeMigrateTestTable(t, zs[0])
+	other, _ := migrate.New(zs[1], &migrate.Migration{Version: 1, Name: "noop", Up: []migrate.Step{
+		migrate.Func(func(z *zetabase.ZetabaseClient) error { return nil }),
+	}})
+	other.LockTimeout = 50 * time.Millisecond
+
+	// A run that keeps stepping keeps its lock, however long it takes overall
+	var takeoverErrs []error
+	step := migrate.Func(func(z *zetabase.ZetabaseClient) error {
+		time.Sleep(30 * time.Millisecond)
+		_, err := other.Up(0)
+		takeoverErrs = append(takeoverErrs, err)
+		return nil
+	})
+	m, _ := migrate.New(zs[0], &migrate.Migration{Version: 1, Name: "slow", Up: []migrate.Step{step, step, step},
+		Down: []migrate.Step{migrate.Func(func(z *zetabase.ZetabaseClient) error { return nil })}})
+	if n, err := m.Up(0); err != nil || n != 1 {
+		t.Fatalf("Unexpected up: %v (%d applied)", err, n)
+	}
+	for _, err := range takeoverErrs {
+		if err == nil || err.Error() != "MigrationLocked" {
+			t.Fatalf("Lock of a live run taken over: %v", err)
+		}
+	}
+	if _, ok := contents(migrateTestOwner, migrate.LedgerTableId)["lock"]; ok {
+		t.Fatalf("Lock not released")
+	}
+
+	// With soft deletes, the lock and ledger records are still deleted rather than trashed
+	zs[0].SetSoftDelete(&zetabase.SoftDeleteOptions{PurgeInterval: -1})
+	if n, err := m.Down(0); err != nil || n != 1 {
+		t.Fatalf("Unexpected down: %v (%d rolled back)", err, n)
+	}
+	if trash, _ := zs[0].ListTrash(migrateTestOwner, migrate.LedgerTableId); len(trash) != 0 {
+		t.Fatalf("Ledger records moved to the trash: %v", trash)
+	}
+
+	// Without native CompareAndSwap, runs could not exclude each other
+	olds, oldSrv, _ := newFakeClients(1)
+	oldSrv.Version = "0.1.0"
+	old, _ := migrate.New(olds[0])
+	if _, err := old.Up(0); err == nil || err.Error() != "MigrationRequiresCompareAndSwap" {
+		t.Fatalf("Expected MigrationRequiresCompareAndSwap, got %v", err)
+	}
+}
+
+func Test_MigrateReadFailures(t *testing.T) {
+	zs, srv, contents := newFakeClients(2)
+	makeMigrateTestTable(t, zs[0])
+	runs := 0
+	first := &migrate.Migration{Version: 1, Name: "count", Up: []migrate.Step{
+		migrate.Func(func(z *zetabase.ZetabaseClient) error { runs++; return nil }),
+	}}
+	m, _ := migrate.New(zs[0], first)
+	if n, err := m.Up(0); err != nil || n != 1 {
+		t.Fatalf("Unexpected up: %v (%d applied)", err, n)
+	}
+
+	// A ledger that cannot be read does not look empty
+	srv.GetErr = errors.New("Unavailable")
+	if n, err := m.Up(0); err == nil || runs != 1 {
+		t.Fatalf("Up with an unreadable ledger: %v (%d applied, %d runs)", err, n, runs)
+	}
+	srv.GetErr = nil
+	m.Unlock()
+
+	// Nor does a lock that cannot be read
+	other, _ := migrate.New(zs[1], first)
+	other.LockTimeout = time.Nanosecond
+	zs[0].PutData(migrateTestOwner, migrate.LedgerTableId, "lock", []byte(`{"holder": "x"}`), true)
+	srv.GetErr = errors.New("Unavailable")
+	if _, err := other.Up(0); err == nil || err.Error() != "Unavailable" {
+		t.Fatalf("Expected the lock read to fail, got %v", err)
+	}
+
+	// Nor do records of a table being rewritten
+	rewritten := 0
+	rw := &migrate.Rewrite{Table: "users", F: func(key string, valu []byte) ([]byte, bool, error) {
+		rewritten++
+		return valu, true, nil
+	}}
+	if err := rw.Apply(zs[0]); err == nil || rewritten != 0 {
+		t.Fatalf("Rewrite with unreadable records: %v (%d rewritten)", err, rewritten)
+	}
+	srv.GetErr = nil
+	if string(contents(migrateTestOwner, migrate.LedgerTableId)["lock"]) != `{"holder": "x"}` {
+		t.Fatalf("Unreadable lock taken over")
+	}
+}
+
+func Test_MigrateResumesRedefinedTable(t *testing.T) {
+	zs, srv, contents := newFakeClients(1)
+	z := zs[0]
+	makeMigrateTestTable(t, z)
+	want := contents(migrateTestOwner, "users")
+	m, _ := migrate.New(z, &migrate.Migration{Version: 1, Name: "index age",
+		Up: []migrate.Step{&migrate.AddIndex{Table: "users", Field: "age", Ordering: zbprotocol.QueryOrdering_INTEGRAL_NUMBERS}}})
+
+	// The table is deleted but cannot be recreated: its records are kept in the temporary copy
+	srv.BeforeCreate = func(in *zbprotocol.TableCreate) error {
+		if in.TableId == "users" {
+			return errors.New("Unavailable")
+		}
+		return nil
+	}
+	if _, err := m.Up(0); err == nil || !strings.Contains(err.Error(), "users_zbmigrate") {
+		t.Fatalf("Expected an error naming the temporary copy, got %v", err)
+	}
+	srv.BeforeCreate = nil
+	m.Unlock()
+	if contents(migrateTestOwner, "users") != nil || len(contents(migrateTestOwner, "users_zbmigrate")) != len(want)+1 {
+		t.Fatalf("Unexpected tables after the failure")
+	}
+
+	// A rerun resumes from the copy
+	if n, err := m.Up(0); err != nil || n != 1 {
+		t.Fatalf("Resumed up failed: %v (%d applied)", err, n)
+	}
+	got := contents(migrateTestOwner, "users")
+	if len(got) != len(want) || string(got["u/1"]) != string(want["u/1"]) || !hasIndex(z, "users", "age") {
+		t.Fatalf("Table not restored: %v", got)
+	}
+	if contents(migrateTestOwner, "users_zbmigrate") != nil {
+		t.Fatalf("Temporary copy left behind")
+	}
+}
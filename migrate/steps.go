@@ -0,0 +1,344 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/zetabase/zetabase-client"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Records rewritten per PutMulti call
+const DefaultPageSize = zetabase.DefaultCopyPageSize
+
+// Type Step is one change made by a migration to the tables of the client's identity.
+type Step interface {
+	Apply(z *zetabase.ZetabaseClient) error
+}
+
+// Type Func is a step given as a Go function.
+type Func func(z *zetabase.ZetabaseClient) error
+
+func (f Func) Apply(z *zetabase.ZetabaseClient) error {
+	return f(z)
+}
+
+// Type Rewrite is a step that rewrites every record of a table, a page at a time. F returns the
+// new value of a record, or keep false to leave it as it is.
+type Rewrite struct {
+	Table string
+	F     func(key string, valu []byte) (newValu []byte, keep bool, err error)
+}
+
+func (s *Rewrite) Apply(z *zetabase.ZetabaseClient) error {
+	keys, err := z.ListKeys(z.Id(), s.Table).KeysAll()
+	if err != nil {
+		return err
+	}
+	var userKeys []string
+	for _, k := range keys {
+		if !zetabase.IsReservedKey(k) {
+			userKeys = append(userKeys, k)
+		}
+	}
+	for i := 0; i < len(userKeys); i += DefaultPageSize {
+		j := i + DefaultPageSize
+		if j > len(userKeys) {
+			j = len(userKeys)
+		}
+		data, err := z.GetUncached(z.Id(), s.Table, userKeys[i:j]).DataAll()
+		if err != nil {
+			return err
+		}
+		var putKeys []string
+		var putValus [][]byte
+		for _, k := range userKeys[i:j] {
+			v, ok := data[k]
+			if !ok {
+				// Deleted since it was listed
+				continue
+			}
+			nv, keep, err := s.F(k, v)
+			if err != nil {
+				return err
+			}
+			if keep {
+				putKeys = append(putKeys, k)
+				putValus = append(putValus, nv)
+			}
+		}
+		if len(putKeys) > 0 {
+			if err := z.PutMulti(z.Id(), s.Table, putKeys, putValus, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Type RenameField is a step that renames a top-level field in every JSON document of a table.
+// Documents without the field are left alone.
+type RenameField struct {
+	Table string
+	From  string
+	To    string
+}
+
+func (s *RenameField) Apply(z *zetabase.ZetabaseClient) error {
+	return (&Rewrite{Table: s.Table, F: func(key string, valu []byte) ([]byte, bool, error) {
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(valu, &doc); err != nil {
+			return nil, false, fmt.Errorf("NotJsonObject: %s", key)
+		}
+		v, ok := doc[s.From]
+		if !ok {
+			return nil, false, nil
+		}
+		delete(doc, s.From)
+		doc[s.To] = v
+		bs, err := json.Marshal(doc)
+		return bs, err == nil, err
+	}}).Apply(z)
+}
+
+// Type AddIndex is a step that adds an indexed field to a JSON table. Tables cannot be altered in
+// place, so the table is copied aside (to <table>_zbmigrate), recreated with the new definition and
+// copied back. If this fails after the table was deleted, rerunning the step resumes from the copy.
+type AddIndex struct {
+	Table    string
+	Field    string
+	Ordering zbprotocol.QueryOrdering
+}
+
+func (s *AddIndex) Apply(z *zetabase.ZetabaseClient) error {
+	return redefineTable(z, s.Table, func(defn *zbprotocol.TableCreate) {
+		if defn.Indices == nil {
+			defn.Indices = &zbprotocol.TableIndexFields{}
+		}
+		defn.Indices.Fields = append(defn.Indices.Fields, &zbprotocol.TableIndexField{Field: s.Field, Ordering: s.Ordering})
+	})
+}
+
+// Type DropIndex is a step that removes an indexed field from a table (see AddIndex).
+type DropIndex struct {
+	Table string
+	Field string
+}
+
+func (s *DropIndex) Apply(z *zetabase.ZetabaseClient) error {
+	return redefineTable(z, s.Table, func(defn *zbprotocol.TableCreate) {
+		var fields []*zbprotocol.TableIndexField
+		for _, f := range defn.GetIndices().GetFields() {
+			if f.GetField() != s.Field {
+				fields = append(fields, f)
+			}
+		}
+		defn.Indices = &zbprotocol.TableIndexFields{Fields: fields}
+	})
+}
+
+// Reserved key of a table's temporary copy holding the table's original definition, written once
+// the copy is complete so that a rerun can resume after a failure
+const redefineDefinitionKey = zetabase.ReservedKeyPrefix + "migrate/definition"
+
+func redefineTable(z *zetabase.ZetabaseClient, tableId string, change func(defn *zbprotocol.TableCreate)) error {
+	owner := z.Id()
+	tmp := tableId + "_zbmigrate"
+	defn, err := savedDefinition(z, tmp)
+	if err != nil {
+		return err
+	}
+	all := &zetabase.CopyOptions{Overwrite: true, CopyPermissions: true}
+	if defn == nil {
+		defn, err = z.GetTableDefinition(owner, tableId)
+		if err != nil {
+			return err
+		}
+		if _, err := zetabase.CopyTable(z, owner, tableId, z, owner, tmp, all); err != nil {
+			return err
+		}
+		bs, err := (&jsonpb.Marshaler{}).MarshalToString(defn)
+		if err != nil {
+			return err
+		}
+		if err := z.PutData(owner, tmp, redefineDefinitionKey, []byte(bs), true); err != nil {
+			return err
+		}
+	}
+
+	// From here on the records and the original definition are kept in tmp until the end
+	interrupted := func(err error) error {
+		return fmt.Errorf("RedefineInterrupted: %s is kept in %s, rerun to resume: %w", tableId, tmp, err)
+	}
+	if _, err := z.GetTableDefinition(owner, tableId); err == nil {
+		if err := z.DeleteTable(owner, tableId); err != nil {
+			return interrupted(err)
+		}
+	} else if !errors.Is(err, zetabase.ErrTableNotFound) {
+		return interrupted(err)
+	}
+	change(defn)
+	if err := z.CreateTableFromDefinition(tableId, defn); err != nil {
+		return interrupted(err)
+	}
+	back := *all
+	back.Transform = func(key string, valu []byte) (string, []byte, bool, error) {
+		return key, valu, key != redefineDefinitionKey, nil
+	}
+	if _, err := zetabase.CopyTable(z, owner, tmp, z, owner, tableId, &back); err != nil {
+		return interrupted(err)
+	}
+	return z.DeleteTable(owner, tmp)
+}
+
+// Original definition stored in temporary copy tmp by an earlier, interrupted redefineTable, or
+// nil. A copy without it is incomplete and is deleted.
+func savedDefinition(z *zetabase.ZetabaseClient, tmp string) (*zbprotocol.TableCreate, error) {
+	owner := z.Id()
+	if _, err := z.GetTableDefinition(owner, tmp); errors.Is(err, zetabase.ErrTableNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, err := z.GetUncached(owner, tmp, []string{redefineDefinitionKey}).DataAll()
+	if err != nil {
+		return nil, err
+	}
+	bs, ok := data[redefineDefinitionKey]
+	if !ok {
+		return nil, z.DeleteTable(owner, tmp)
+	}
+	var defn zbprotocol.TableCreate
+	if err := jsonpb.UnmarshalString(string(bs), &defn); err != nil {
+		return nil, err
+	}
+	return &defn, nil
+}
+
+// Type SplitTable is a step that moves the records matching KeyPattern (% is the wildcard) from
+// table From to table To, creating To with From's definition if it does not exist. Moving them
+// back is the reverse step.
+type SplitTable struct {
+	From       string
+	To         string
+	KeyPattern string
+}
+
+func (s *SplitTable) Apply(z *zetabase.ZetabaseClient) error {
+	owner := z.Id()
+	keys, err := z.ListKeysWithPattern(owner, s.From, s.KeyPattern).KeysAll()
+	if err != nil {
+		return err
+	}
+	opts := &zetabase.CopyOptions{KeyPattern: s.KeyPattern, Overwrite: true, CopyPermissions: true, PageSize: DefaultPageSize}
+	if _, err := zetabase.CopyTable(z, owner, s.From, z, owner, s.To, opts); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if zetabase.IsReservedKey(k) {
+			continue
+		}
+		if err := z.DeleteKey(owner, s.From, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Declarative form of a step, as read by Load
+type stepSpec struct {
+	Op         string `json:"op"`
+	Table      string `json:"table"`
+	Field      string `json:"field"`
+	Ordering   string `json:"ordering"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	KeyPattern string `json:"keyPattern"`
+}
+
+type migrationSpec struct {
+	Version int64       `json:"version"`
+	Name    string      `json:"name"`
+	Up      []*stepSpec `json:"up"`
+	Down    []*stepSpec `json:"down"`
+}
+
+func (s *stepSpec) step() (Step, error) {
+	switch s.Op {
+	case "addIndex":
+		ord, ok := zbprotocol.QueryOrdering_value[strings.ToUpper(s.Ordering)]
+		if !ok {
+			return nil, fmt.Errorf("UnknownOrdering: %s", s.Ordering)
+		}
+		return &AddIndex{Table: s.Table, Field: s.Field, Ordering: zbprotocol.QueryOrdering(ord)}, nil
+	case "dropIndex":
+		return &DropIndex{Table: s.Table, Field: s.Field}, nil
+	case "renameField":
+		return &RenameField{Table: s.Table, From: s.From, To: s.To}, nil
+	case "splitTable":
+		return &SplitTable{From: s.From, To: s.To, KeyPattern: s.KeyPattern}, nil
+	}
+	return nil, fmt.Errorf("UnknownMigrationStep: %s", s.Op)
+}
+
+func steps(specs []*stepSpec) ([]Step, error) {
+	var res []Step
+	for _, s := range specs {
+		st, err := s.step()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, st)
+	}
+	return res, nil
+}
+
+// Function Load reads declarative migrations from a JSON array like
+//
+//	[{"version": 1, "name": "index ages",
+//	  "up": [{"op": "addIndex", "table": "users", "field": "age", "ordering": "INTEGRAL_NUMBERS"}],
+//	  "down": [{"op": "dropIndex", "table": "users", "field": "age"}]}]
+//
+// Steps are addIndex (table, field, ordering), dropIndex (table, field), renameField (table, from,
+// to) and splitTable (from, to, keyPattern).
+func Load(r io.Reader) ([]*Migration, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var specs []*migrationSpec
+	if err := json.Unmarshal(bs, &specs); err != nil {
+		return nil, err
+	}
+	var res []*Migration
+	for _, s := range specs {
+		up, err := steps(s.Up)
+		if err != nil {
+			return nil, err
+		}
+		down, err := steps(s.Down)
+		if err != nil {
+			return nil, err
+		}
+		if len(up) == 0 {
+			return nil, errors.New("MigrationWithoutSteps")
+		}
+		res = append(res, &Migration{Version: s.Version, Name: s.Name, Up: up, Down: down})
+	}
+	return res, nil
+}
+
+// Function LoadFile reads declarative migrations from a JSON file (see Load).
+func LoadFile(path string) ([]*Migration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
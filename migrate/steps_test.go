@@ -0,0 +1,28 @@
+package migrate
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"testing"
+)
+
+func Test_Load(t *testing.T) {
+	ms, err := Load(strings.NewReader(`[
+		{"version": 1, "name": "index ages",
+		 "up": [{"op": "addIndex", "table": "users", "field": "age", "ordering": "integral_numbers"}],
+		 "down": [{"op": "dropIndex", "table": "users", "field": "age"}]},
+		{"version": 2, "name": "rename", "up": [{"op": "renameField", "table": "users", "from": "nm", "to": "name"}]}
+	]`))
+	if err != nil || len(ms) != 2 || len(ms[1].Down) != 0 {
+		t.Fatalf("Unexpected migrations: %v (%v)", ms, err)
+	}
+	if s, ok := ms[0].Up[0].(*AddIndex); !ok || s.Ordering != zbprotocol.QueryOrdering_INTEGRAL_NUMBERS || s.Field != "age" {
+		t.Fatalf("Unexpected step: %#v", ms[0].Up[0])
+	}
+	if _, err := Load(strings.NewReader(`[{"version": 1, "up": [{"op": "dropTable"}]}]`)); err == nil || !strings.HasPrefix(err.Error(), "UnknownMigrationStep") {
+		t.Fatalf("Expected UnknownMigrationStep, got %v", err)
+	}
+	if _, err := Load(strings.NewReader(`[{"version": 1, "up": [{"op": "addIndex", "ordering": "sideways"}]}]`)); err == nil {
+		t.Fatalf("Expected an error for an unknown ordering")
+	}
+}
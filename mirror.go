@@ -0,0 +1,566 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMirrorSyncInterval = 30 * time.Second
+	// Every n-th sync refreshes the values of all mirrored keys, to pick up overwrites by others
+	DefaultMirrorFullSyncEvery = 10
+	// Mirror files are compacted once they are this many times the size of their live data
+	mirrorCompactionRatio = 2
+	mirrorCompactionSlack = 1024 * 1024
+)
+
+// Type MirrorOptions configures a TableMirror.
+type MirrorOptions struct {
+	SyncInterval  time.Duration // time between background syncs (negative: only sync when Sync is called)
+	MaxStaleness  time.Duration // reads are served locally only if the last sync is at most this old (default: twice SyncInterval)
+	FullSyncEvery int           // every n-th sync also refreshes values of existing keys (default: DefaultMirrorFullSyncEvery; negative: never)
+}
+
+// A mirror file entry: a value (or its deletion), or a marker of a completed sync
+type mirrorRecord struct {
+	Key     string    `json:"k,omitempty"`
+	Value   []byte    `json:"v,omitempty"`
+	Deleted bool      `json:"d,omitempty"`
+	Synced  time.Time `json:"synced,omitempty"`
+}
+
+// Type TableMirror is a local replica of a table, kept in a file and brought up to date by
+// periodic syncs. While a mirror is open and fresh, Get, ListKeysWithPattern and QueryData calls
+// for its table are served from it.
+type TableMirror struct {
+	z            *ZetabaseClient
+	tableOwnerId string
+	tableId      string
+	fn           string
+	opts         MirrorOptions
+	lock         sync.RWMutex
+	syncLock     sync.Mutex
+	data         map[string][]byte
+	dirty        map[string]bool // keys written through the client since the last sync
+	allDirty     bool
+	syncing      map[string]bool // dirty keys being fetched by the running sync
+	syncingAll   bool
+	orderings    map[string]zbprotocol.QueryOrdering
+	lastSync     time.Time
+	syncs        int
+	lastErr      error
+	f            *os.File
+	fileBytes    int64
+	liveBytes    int64
+	stop         chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// Method OpenMirror opens (or creates) a local mirror of a table in file fn, syncs it, registers it
+// with the client so that reads of the table are served locally, and starts syncing it in the
+// background. If the initial sync fails, the mirror is still opened with its existing contents
+// (and an error from LastError).
+func (z *ZetabaseClient) OpenMirror(tableOwnerId, tableId, fn string, opts *MirrorOptions) (*TableMirror, error) {
+	m := &TableMirror{
+		z:            z,
+		tableOwnerId: tableOwnerId,
+		tableId:      tableId,
+		fn:           fn,
+		data:         map[string][]byte{},
+		dirty:        map[string]bool{},
+		orderings:    map[string]zbprotocol.QueryOrdering{},
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.SyncInterval == 0 {
+		m.opts.SyncInterval = DefaultMirrorSyncInterval
+	}
+	if m.opts.FullSyncEvery == 0 {
+		m.opts.FullSyncEvery = DefaultMirrorFullSyncEvery
+	}
+	if m.opts.MaxStaleness <= 0 {
+		m.opts.MaxStaleness = 2 * m.opts.SyncInterval
+		if m.opts.SyncInterval < 0 {
+			m.opts.MaxStaleness = 2 * DefaultMirrorSyncInterval
+		}
+	}
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	m.f = f
+	if err := m.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	m.Sync()
+	z.mirrors.Store(cacheTableKey(tableOwnerId, tableId), m)
+	if m.opts.SyncInterval > 0 {
+		go m.syncLoop()
+	} else {
+		close(m.done)
+	}
+	return m, nil
+}
+
+func (m *TableMirror) load() error {
+	good := readChecksummedRecords(m.f, func(payload []byte) bool {
+		var r mirrorRecord
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return false
+		}
+		m.applyLocked(&r)
+		return true
+	})
+	m.fileBytes = good
+	if err := m.f.Truncate(good); err != nil {
+		return err
+	}
+	_, err := m.f.Seek(good, io.SeekStart)
+	return err
+}
+
+// Requires lock
+func (m *TableMirror) applyLocked(r *mirrorRecord) {
+	if !r.Synced.IsZero() {
+		m.lastSync = r.Synced
+		return
+	}
+	if old, ok := m.data[r.Key]; ok {
+		m.liveBytes -= int64(len(r.Key) + len(old))
+		delete(m.data, r.Key)
+	}
+	if !r.Deleted {
+		m.data[r.Key] = r.Value
+		m.liveBytes += int64(len(r.Key) + len(r.Value))
+	}
+}
+
+// Requires lock
+func (m *TableMirror) writeLocked(recs []*mirrorRecord) error {
+	var buf []byte
+	for _, r := range recs {
+		payload, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, checksummedRecord(payload)...)
+	}
+	if _, err := m.f.Write(buf); err != nil {
+		return err
+	}
+	if err := m.f.Sync(); err != nil {
+		return err
+	}
+	m.fileBytes += int64(len(buf))
+	for _, r := range recs {
+		m.applyLocked(r)
+	}
+	return nil
+}
+
+// Rewrite the mirror file with only the live entries. Requires lock.
+func (m *TableMirror) compactLocked() error {
+	var buf []byte
+	keys := make([]string, 0, len(m.data))
+	for k := range m.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	recs := make([]*mirrorRecord, 0, len(keys)+1)
+	for _, k := range keys {
+		recs = append(recs, &mirrorRecord{Key: k, Value: m.data[k]})
+	}
+	recs = append(recs, &mirrorRecord{Synced: m.lastSync})
+	for _, r := range recs {
+		payload, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, checksummedRecord(payload)...)
+	}
+	if err := WriteFileAtomic(m.fn, buf, 0600); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(m.fn, os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return err
+	}
+	m.f.Close()
+	m.f = f
+	m.fileBytes = int64(len(buf))
+	return nil
+}
+
+// Method Sync brings the mirror up to date: keys added on the server, and keys written through the
+// client, are fetched and keys deleted there are dropped. Values of other existing keys are only
+// refreshed by full syncs (see MirrorOptions.FullSyncEvery).
+func (m *TableMirror) Sync() error {
+	m.syncLock.Lock()
+	defer m.syncLock.Unlock()
+	err := m.sync()
+	m.lock.Lock()
+	m.lastErr = err
+	m.lock.Unlock()
+	return err
+}
+
+func (m *TableMirror) sync() error {
+	started := time.Now()
+	defn, err := m.z.GetTableDefinition(m.tableOwnerId, m.tableId)
+	if err != nil {
+		return err
+	}
+	remote, err := m.z.listKeysRemote(m.tableOwnerId, m.tableId, "").KeysAll()
+	if err != nil {
+		return err
+	}
+	sort.Strings(remote)
+
+	// Writes noted from here on are fetched by the next sync
+	m.lock.Lock()
+	dirty, allDirty := m.dirty, m.allDirty
+	m.dirty, m.allDirty = map[string]bool{}, false
+	full := allDirty || (m.opts.FullSyncEvery > 0 && (m.syncs+1)%m.opts.FullSyncEvery == 0)
+	inRemote := map[string]bool{}
+	var fetch []string
+	for _, k := range remote {
+		inRemote[k] = true
+		if _, ok := m.data[k]; !ok || full || dirty[k] {
+			fetch = append(fetch, k)
+		}
+	}
+	var recs []*mirrorRecord
+	for k := range m.data {
+		if !inRemote[k] {
+			recs = append(recs, &mirrorRecord{Key: k, Deleted: true})
+		}
+	}
+	// Until the fetched values are written, reads of the keys being fetched still go to the server
+	m.syncing, m.syncingAll = dirty, allDirty
+	m.lock.Unlock()
+	ok := false
+	defer func() {
+		m.lock.Lock()
+		m.syncing, m.syncingAll = nil, false
+		m.lock.Unlock()
+		if !ok {
+			m.markStale(dirty, allDirty)
+		}
+	}()
+
+	for i := 0; i < len(fetch); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(fetch) {
+			j = len(fetch)
+		}
+		data, err := m.z.getPag(m.tableOwnerId, m.tableId, fetch[i:j]).DataAll()
+		if err != nil {
+			return err
+		}
+		for _, k := range fetch[i:j] {
+			if v, ok := data[k]; ok {
+				recs = append(recs, &mirrorRecord{Key: k, Value: v})
+			}
+		}
+	}
+	recs = append(recs, &mirrorRecord{Synced: started})
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.f == nil {
+		return errors.New("MirrorClosed")
+	}
+	m.orderings = map[string]zbprotocol.QueryOrdering{}
+	for _, f := range defn.GetIndices().GetFields() {
+		m.orderings[f.GetField()] = f.GetOrdering()
+	}
+	if err := m.writeLocked(recs); err != nil {
+		return err
+	}
+	ok = true
+	m.syncs++
+	if m.fileBytes > mirrorCompactionRatio*m.liveBytes+mirrorCompactionSlack {
+		return m.compactLocked()
+	}
+	return nil
+}
+
+func (m *TableMirror) syncLoop() {
+	defer close(m.done)
+	t := time.NewTicker(m.opts.SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-t.C:
+			m.Sync()
+		}
+	}
+}
+
+// Method Fresh returns true if the mirror was synced within its staleness bound. Keys written
+// through the client since the last sync are not served from the mirror even while it is fresh.
+func (m *TableMirror) Fresh() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.f != nil && !m.allDirty && !m.syncingAll && !m.lastSync.IsZero() && time.Since(m.lastSync) <= m.opts.MaxStaleness
+}
+
+// Whether keys were written through the client since the last sync
+func (m *TableMirror) hasDirtyKeys() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.dirty) > 0 || len(m.syncing) > 0 || m.allDirty || m.syncingAll
+}
+
+// Split keys into those the mirror can serve and those written through the client since the last
+// sync, which must be read from the server
+func (m *TableMirror) splitDirty(keys []string) ([]string, []string) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var clean, dirty []string
+	for _, k := range keys {
+		if m.dirty[k] || m.syncing[k] {
+			dirty = append(dirty, k)
+		} else {
+			clean = append(clean, k)
+		}
+	}
+	return clean, dirty
+}
+
+// Method LastSync returns the time the last successful sync started.
+func (m *TableMirror) LastSync() time.Time {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.lastSync
+}
+
+// Method LastError returns the error of the last sync, or nil if it succeeded.
+func (m *TableMirror) LastError() error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.lastErr
+}
+
+// Method Len returns the number of keys in the mirror.
+func (m *TableMirror) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.data)
+}
+
+// Writes through the client changed the given keys (or, if all, any key), so reads of them go to
+// the server until the next sync has fetched them again
+func (m *TableMirror) markStale(keys map[string]bool, all bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.allDirty = m.allDirty || all
+	for k := range keys {
+		m.dirty[k] = true
+	}
+}
+
+// Method Close stops syncing the mirror, unregisters it from the client and closes its file (which
+// can be reopened later).
+func (m *TableMirror) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		m.z.mirrors.Delete(cacheTableKey(m.tableOwnerId, m.tableId))
+		if m.opts.SyncInterval > 0 {
+			close(m.stop)
+		}
+		<-m.done
+		m.syncLock.Lock()
+		defer m.syncLock.Unlock()
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		err = m.f.Close()
+		m.f = nil
+	})
+	return err
+}
+
+// Method Get returns the mirrored values of keys (regardless of staleness).
+func (m *TableMirror) Get(keys []string) map[string][]byte {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	res := map[string][]byte{}
+	for _, k := range keys {
+		if v, ok := m.data[k]; ok {
+			res[k] = append([]byte{}, v...)
+		}
+	}
+	return res
+}
+
+// Key patterns use % as a wildcard
+func keyPatternRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "%")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// Method ListKeysWithPattern returns the mirrored keys matching pattern (% is the wildcard; empty
+// for all keys), sorted.
+func (m *TableMirror) ListKeysWithPattern(pattern string) []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var keys []string
+	re := keyPatternRegexp(pattern)
+	for k := range m.data {
+		if len(pattern) == 0 || re.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Method Query evaluates qry against the mirrored (JSON) values, comparing fields according to the
+// table's index definitions, and returns the matching keys, sorted.
+func (m *TableMirror) Query(qry SubQueryConvertible) ([]string, error) {
+	sq := qry.ToSubQuery(m.tableOwnerId, m.tableId)
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var keys []string
+	for k, v := range m.data {
+		doc, err := decodeJsonObject(v)
+		if err != nil {
+			continue
+		}
+		ok, err := m.eval(sq, doc)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Requires lock
+func (m *TableMirror) eval(q *zbprotocol.TableSubQuery, doc map[string]interface{}) (bool, error) {
+	if q.GetIsCompound() {
+		l, err := m.eval(q.GetCompoundLeft(), doc)
+		if err != nil {
+			return false, err
+		}
+		r, err := m.eval(q.GetCompoundRight(), doc)
+		if err != nil {
+			return false, err
+		}
+		if q.GetCompoundOperator() == zbprotocol.QueryLogicalOperator_LOGICAL_AND {
+			return l && r, nil
+		}
+		return l || r, nil
+	}
+	c := q.GetComparison()
+	ordering, ok := m.orderings[c.GetField()]
+	if !ok {
+		return false, fmt.Errorf("FieldNotIndexed: %s", c.GetField())
+	}
+	valu, ok := jsonFieldValue(doc, c.GetField())
+	if !ok {
+		return false, nil
+	}
+	if c.GetOp() == zbprotocol.QueryOperator_TEXT_SEARCH {
+		text := strings.ToLower(fmt.Sprint(valu))
+		for _, w := range strings.Fields(strings.ToLower(c.GetValue())) {
+			if !strings.Contains(text, w) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	var cmp int
+	switch ordering {
+	case zbprotocol.QueryOrdering_REAL_NUMBERS, zbprotocol.QueryOrdering_INTEGRAL_NUMBERS:
+		a, err1 := strconv.ParseFloat(fmt.Sprint(valu), 64)
+		b, err2 := strconv.ParseFloat(c.GetValue(), 64)
+		if err1 != nil || err2 != nil {
+			return false, nil
+		}
+		if a < b {
+			cmp = -1
+		} else if a > b {
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(fmt.Sprint(valu), c.GetValue())
+	}
+	switch c.GetOp() {
+	case zbprotocol.QueryOperator_EQUALS:
+		return cmp == 0, nil
+	case zbprotocol.QueryOperator_NOT_EQUALS:
+		return cmp != 0, nil
+	case zbprotocol.QueryOperator_GREATER_THAN:
+		return cmp > 0, nil
+	case zbprotocol.QueryOperator_GREATER_THAN_EQ:
+		return cmp >= 0, nil
+	case zbprotocol.QueryOperator_LESS_THAN:
+		return cmp < 0, nil
+	case zbprotocol.QueryOperator_LESS_THAN_EQ:
+		return cmp <= 0, nil
+	}
+	return false, errors.New("UnsupportedQueryOperator")
+}
+
+// The mirror of a table, if one is open and fresh (for reads of keys, see splitDirty)
+func (z *ZetabaseClient) freshMirror(tableOwnerId, tableId string) *TableMirror {
+	v, ok := z.mirrors.Load(cacheTableKey(tableOwnerId, tableId))
+	if !ok {
+		return nil
+	}
+	m := v.(*TableMirror)
+	if !m.Fresh() {
+		return nil
+	}
+	return m
+}
+
+// The mirror of a table, if one is open and fresh and no key was written through the client since
+// its last sync (for listings and queries, which any written key may change)
+func (z *ZetabaseClient) cleanMirror(tableOwnerId, tableId string) *TableMirror {
+	m := z.freshMirror(tableOwnerId, tableId)
+	if m == nil || m.hasDirtyKeys() {
+		return nil
+	}
+	return m
+}
+
+// Bookkeeping after a write through this client: cached values of the keys, and any mirror of the
+// table, can no longer be trusted
+func (z *ZetabaseClient) noteWrite(tableOwnerId, tableId string, keys ...string) {
+	z.InvalidateCache(tableOwnerId, tableId, keys...)
+	if v, ok := z.mirrors.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		dirty := map[string]bool{}
+		for _, k := range keys {
+			dirty[k] = true
+		}
+		v.(*TableMirror).markStale(dirty, len(keys) == 0)
+	}
+}
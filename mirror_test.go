@@ -0,0 +1,119 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_MirrorServesReadsLocally(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbmirror")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "src.mirror")
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, map[string]string{
+		"u/1": `{"name": "ann", "age": 31}`,
+		"u/2": `{"name": "bob", "age": 5}`,
+		"x/3": `{"name": "cat", "age": 12}`,
+	})
+	m, err := z.OpenMirror(testOwnerId, "src", fn, &MirrorOptions{SyncInterval: -1})
+	if err != nil || m.Len() != 3 || !m.Fresh() {
+		t.Fatalf("Failed to open mirror: %v (%d keys)", err, m.Len())
+	}
+
+	calls := srv.GetCalls
+	data, _ := z.Get(testOwnerId, "src", []string{"u/1", "missing"}).DataAll()
+	keys, _ := z.ListKeysWithPattern(testOwnerId, "src", "u/%").KeysAll()
+	pgs, err := z.QueryData(testOwnerId, "src", QAnd(QGt("age", 10), QNEq("age", 99)))
+	if err != nil {
+		t.Fatalf("Local query failed: %s", err.Error())
+	}
+	found, _ := pgs.DataAll()
+	if srv.GetCalls != calls || len(data) != 1 || len(keys) != 2 || len(found) != 2 || found["x/3"] == nil {
+		t.Fatalf("Reads not served locally: %v %v %v (%d server calls)", data, keys, found, srv.GetCalls-calls)
+	}
+	if _, err := m.Query(QEq("name", "ann")); err == nil {
+		t.Fatalf("Expected an error querying a field that is not indexed")
+	}
+
+	// Changes made by others are picked up by the next sync; keys we write are read from the
+	// server until then, while the others are still served locally
+	srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{{Key: "u/4", Value: []byte(`{"age": 1}`)}}, false)
+	delete(srv.Table(testOwnerId, "src").Data, "u/2")
+	if err := m.Sync(); err != nil {
+		t.Fatalf("Sync failed: %s", err.Error())
+	}
+	if keys := m.ListKeysWithPattern("u/%"); !reflect.DeepEqual(keys, []string{"u/1", "u/4"}) {
+		t.Fatalf("Unexpected keys after sync: %v", keys)
+	}
+	synced := m.LastSync()
+	z.PutData(testOwnerId, "src", "u/5", []byte(`{}`), false)
+	srv.GetErr = errors.New("Unavailable")
+	if data, err := z.Get(testOwnerId, "src", []string{"u/1", "u/4"}).DataAll(); err != nil || len(data) != 2 {
+		t.Fatalf("Unwritten keys not served locally after a write: %v %v", data, err)
+	}
+	if _, err := z.Get(testOwnerId, "src", []string{"u/1", "u/5"}).DataAll(); err == nil {
+		t.Fatalf("Written key served from the mirror before a sync")
+	}
+	if keys, _ := z.ListKeysWithPattern(testOwnerId, "src", "u/%").KeysAll(); len(keys) != 3 {
+		t.Fatalf("Listing served from the mirror before a sync: %v", keys)
+	}
+	if !m.Fresh() || !m.LastSync().Equal(synced) {
+		t.Fatalf("Mirror should stay fresh after a write")
+	}
+	srv.GetErr = nil
+	m.Sync()
+	calls = srv.GetCalls
+	if data, _ := z.Get(testOwnerId, "src", []string{"u/5"}).DataAll(); srv.GetCalls != calls || string(data["u/5"]) != "{}" {
+		t.Fatalf("Written key not served locally after a sync: %v", data)
+	}
+	m.Close()
+
+	// Reopening only fetches what changed since
+	calls = srv.GetCalls
+	m, err = z.OpenMirror(testOwnerId, "src", fn, &MirrorOptions{SyncInterval: -1})
+	if err != nil || m.Len() != 4 || srv.GetCalls != calls {
+		t.Fatalf("Mirror not reloaded from file: %v (%d keys, %d fetches)", err, m.Len(), srv.GetCalls-calls)
+	}
+	m.Close()
+}
+
+func Test_MirrorRefreshesOverwrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbmirror")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "old", "b": "old"})
+	m, err := z.OpenMirror(testOwnerId, "src", filepath.Join(dir, "src.mirror"), &MirrorOptions{SyncInterval: -1, FullSyncEvery: 3})
+	if err != nil {
+		t.Fatalf("Failed to open mirror: %s", err.Error())
+	}
+	defer m.Close()
+
+	// Our own overwrite is fetched by the next sync
+	if err := z.PutData(testOwnerId, "src", "a", []byte("new"), true); err != nil {
+		t.Fatalf("PutData failed: %s", err.Error())
+	}
+	if err := m.Sync(); err != nil || !m.Fresh() {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if data, _ := z.Get(testOwnerId, "src", []string{"a"}).DataAll(); string(data["a"]) != "new" {
+		t.Fatalf("Mirror served a stale value: %q", data["a"])
+	}
+
+	// Overwrites by others are picked up by the next full sync (the third)
+	srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{{Key: "b", Value: []byte("theirs")}}, true)
+	m.Sync()
+	if got := m.Get([]string{"b"}); string(got["b"]) != "theirs" {
+		t.Fatalf("Full sync did not refresh the value: %q", got["b"])
+	}
+}
@@ -48,6 +48,9 @@ func (p *PaginationHandler) Keys() ([]string, error) {
 func (p *PaginationHandler) DataAll() (map[string][]byte, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	if p.curError != nil {
+		return nil, p.curError
+	}
 
 	var i int64
 	i = 1
@@ -76,6 +79,9 @@ func (p *PaginationHandler) DataAll() (map[string][]byte, error) {
 func (p *PaginationHandler) KeysAll() ([]string, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	if p.curError != nil {
+		return nil, p.curError
+	}
 
 	// Page 0
 	var ks []string
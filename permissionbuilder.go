@@ -0,0 +1,239 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+)
+
+// Perm is the starting point for building permission entries fluently, e.g.
+//
+//	Perm.Public().Read()
+//	Perm.Users().Append().KeyIsCaller().FieldTimestamp("ts")
+//
+// Combinations the server would reject are reported by Build.
+var Perm = PermBuilderRoot{}
+
+// Type PermBuilderRoot selects the audience of a new permission entry.
+type PermBuilderRoot struct{}
+
+// Type PermBuilder accumulates a permission entry and validates it on Build.
+type PermBuilder struct {
+	level        zbprotocol.PermissionLevel
+	audienceType zbprotocol.PermissionAudienceType
+	audienceId   string
+	keyCons      *PermConstraint
+	fieldCons    []*PermConstraint
+	err          error
+}
+
+// Permission for anyone, including unauthenticated callers
+func (PermBuilderRoot) Public() *PermBuilder {
+	return &PermBuilder{audienceType: zbprotocol.PermissionAudienceType_PUBLIC}
+}
+
+// Permission for any authenticated user of the table owner's account
+func (PermBuilderRoot) Users() *PermBuilder {
+	return &PermBuilder{audienceType: zbprotocol.PermissionAudienceType_USER}
+}
+
+// Permission for the single user uid
+func (PermBuilderRoot) User(uid string) *PermBuilder {
+	return &PermBuilder{audienceType: zbprotocol.PermissionAudienceType_INDIVIDUAL, audienceId: uid}
+}
+
+func (b *PermBuilder) setLevel(lvl zbprotocol.PermissionLevel) *PermBuilder {
+	if b.level != zbprotocol.PermissionLevel_NONE && b.level != lvl {
+		b.fail("ConflictingPermissionLevels")
+	}
+	b.level = lvl
+	return b
+}
+
+func (b *PermBuilder) fail(symbol string) {
+	if b.err == nil {
+		b.err = errors.New(symbol)
+	}
+}
+
+// Grant read access
+func (b *PermBuilder) Read() *PermBuilder {
+	return b.setLevel(zbprotocol.PermissionLevel_READ)
+}
+
+// Grant append access (writing new keys)
+func (b *PermBuilder) Append() *PermBuilder {
+	return b.setLevel(zbprotocol.PermissionLevel_APPEND)
+}
+
+// Grant delete access
+func (b *PermBuilder) Delete() *PermBuilder {
+	return b.setLevel(zbprotocol.PermissionLevel_DELETE)
+}
+
+// Grant administrative access
+func (b *PermBuilder) Administer() *PermBuilder {
+	return b.setLevel(zbprotocol.PermissionLevel_ADMINISTER)
+}
+
+func (b *PermBuilder) keyConstraint() *PermConstraint {
+	if b.keyCons == nil {
+		b.keyCons = &PermConstraint{Field: "@key"}
+	}
+	return b.keyCons
+}
+
+// Require keys to start with prefix. Entries with key prefixes are only sent to servers from
+// KeyPatternPermissionsMinServerVersion on; older ones fail with UnsignedKeyPattern.
+func (b *PermBuilder) KeyPrefix(prefix string) *PermBuilder {
+	kc := b.keyConstraint()
+	if len(kc.Prefix) > 0 && kc.Prefix != prefix {
+		b.fail("ConflictingKeyConstraint")
+	}
+	kc.Prefix = prefix
+	return b
+}
+
+// Require keys to end with suffix (see KeyPrefix for the servers supporting this).
+func (b *PermBuilder) KeySuffix(suffix string) *PermBuilder {
+	kc := b.keyConstraint()
+	if len(kc.Suffix) > 0 && kc.Suffix != suffix {
+		b.fail("ConflictingKeyConstraint")
+	}
+	kc.Suffix = suffix
+	return b
+}
+
+func (b *PermBuilder) setKeyValue(valu string) *PermBuilder {
+	kc := b.keyConstraint()
+	if len(kc.ReqValue) > 0 && kc.ReqValue != valu {
+		b.fail("ConflictingKeyConstraint")
+	}
+	kc.ReqValue = valu
+	return b
+}
+
+// Require the key to be the caller's user ID
+func (b *PermBuilder) KeyIsCaller() *PermBuilder {
+	return b.setKeyValue("@uid")
+}
+
+// Require the key to equal valu
+func (b *PermBuilder) KeyEquals(valu string) *PermBuilder {
+	if len(valu) == 0 || strings.HasPrefix(valu, "@") {
+		b.fail("InvalidConstantConstraint")
+	}
+	return b.setKeyValue(valu)
+}
+
+func (b *PermBuilder) addField(field, reqValue string) *PermBuilder {
+	if len(field) == 0 {
+		b.fail("EmptyFieldName")
+	} else if field == "@key" {
+		b.fail("UseKeyConstraintForKeys")
+	}
+	for _, c := range b.fieldCons {
+		if c.Field == field {
+			b.fail("DuplicateFieldConstraint")
+		}
+	}
+	b.fieldCons = append(b.fieldCons, NewPermissionConstraint(field, reqValue))
+	return b
+}
+
+// Require JSON field field to contain the caller's user ID
+func (b *PermBuilder) FieldIsCaller(field string) *PermBuilder {
+	return b.addField(field, "@uid")
+}
+
+// Require JSON field field to contain the constant valu
+func (b *PermBuilder) FieldEquals(field, valu string) *PermBuilder {
+	if len(valu) == 0 || strings.HasPrefix(valu, "@") {
+		b.fail("InvalidConstantConstraint")
+	}
+	return b.addField(field, valu)
+}
+
+// Have the server set JSON field field to the write timestamp
+func (b *PermBuilder) FieldTimestamp(field string) *PermBuilder {
+	return b.addField(field, "@time")
+}
+
+// Have the server set JSON field field to the next value in the table's natural order
+func (b *PermBuilder) FieldOrder(field string) *PermBuilder {
+	return b.addField(field, "@order")
+}
+
+// Have the server set JSON field field to a random value
+func (b *PermBuilder) FieldRandom(field string) *PermBuilder {
+	return b.addField(field, "@random")
+}
+
+func (b *PermBuilder) constraints() []*PermConstraint {
+	var cs []*PermConstraint
+	if b.keyCons != nil {
+		cs = append(cs, b.keyCons)
+	}
+	return append(cs, b.fieldCons...)
+}
+
+func (b *PermBuilder) validate() error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.level == zbprotocol.PermissionLevel_NONE {
+		return errors.New("NoPermissionLevel")
+	}
+	if b.audienceType == zbprotocol.PermissionAudienceType_INDIVIDUAL && len(b.audienceId) == 0 {
+		return errors.New("NoAudienceId")
+	}
+	cs := b.constraints()
+	if b.level == zbprotocol.PermissionLevel_ADMINISTER && len(cs) > 0 {
+		return errors.New("ConstraintsOnAdminister")
+	}
+	for _, c := range cs {
+		switch c.ReqValue {
+		case "@uid":
+			if b.audienceType == zbprotocol.PermissionAudienceType_PUBLIC {
+				// Public callers may be unauthenticated and so have no user ID
+				return errors.New("CallerConstraintOnPublic")
+			}
+		case "@time", "@order", "@random":
+			if b.level != zbprotocol.PermissionLevel_APPEND {
+				return errors.New("GeneratedValueRequiresAppend")
+			}
+		}
+	}
+	return nil
+}
+
+// Method Build validates the permission and returns the corresponding PermEntry.
+func (b *PermBuilder) Build() (*PermEntry, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	p := NewPermissionEntry(b.level, b.audienceType, b.audienceId)
+	for _, c := range b.constraints() {
+		cc := *c
+		p.AddConstraint(&cc)
+	}
+	return p, nil
+}
+
+// Method MustBuild is like Build but panics if the permission is invalid.
+func (b *PermBuilder) MustBuild() *PermEntry {
+	p, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Describe the permission being built (or the reason it is invalid)
+func (b *PermBuilder) String() string {
+	p, err := b.Build()
+	if err != nil {
+		return "invalid permission: " + err.Error()
+	}
+	return p.String()
+}
@@ -0,0 +1,57 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"log"
+	"testing"
+)
+
+func Test_PermBuilder(t *testing.T) {
+	p, err := Perm.Users().Append().KeyIsCaller().FieldTimestamp("ts").Build()
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if p.Level != zbprotocol.PermissionLevel_APPEND || p.AudienceType != zbprotocol.PermissionAudienceType_USER {
+		t.Fatalf("Wrong level or audience: %v", p)
+	}
+	pe := p.ToProtocol("owner", "tbl")
+	if len(pe.Constraints) != 2 || pe.Constraints[0].GetKeyConstraint().GetValueType() != zbprotocol.FieldConstraintValueType_UID {
+		t.Fatalf("Wrong constraints: %v", pe.Constraints)
+	}
+	if pe.Constraints[1].GetFieldConstraint().GetValueType() != zbprotocol.FieldConstraintValueType_TIMESTAMP {
+		t.Fatalf("Wrong field constraint: %v", pe.Constraints[1])
+	}
+	want := `any authenticated user may append; the key must be the caller's user ID; field "ts" must be the write timestamp (set by the server)`
+	if p.String() != want {
+		t.Fatalf("Wrong description:\n\t%s\nshould be\n\t%s", p.String(), want)
+	}
+	log.Printf("Built: %s\n", Perm.Public().Read())
+
+	p, err = Perm.Users().Append().KeyPrefix("tweet/").FieldIsCaller("uid").Build()
+	if err != nil {
+		t.Fatalf("Error: %s", err.Error())
+	}
+	if kc := p.ToProtocol("owner", "tbl").Constraints[0].GetKeyConstraint(); kc.GetRequiredPrefix() != "tweet/" {
+		t.Fatalf("Wrong key constraint: %v", kc)
+	}
+}
+
+func Test_PermBuilder_invalid(t *testing.T) {
+	invalid := map[string]*PermBuilder{
+		"NoPermissionLevel":            Perm.Public(),
+		"NoAudienceId":                 Perm.User("").Read(),
+		"CallerConstraintOnPublic":     Perm.Public().Read().FieldIsCaller("uid"),
+		"GeneratedValueRequiresAppend": Perm.Users().Read().FieldTimestamp("ts"),
+		"DuplicateFieldConstraint":     Perm.Users().Append().FieldIsCaller("uid").FieldEquals("uid", "x"),
+		"ConflictingPermissionLevels":  Perm.Users().Read().Append(),
+		"ConstraintsOnAdminister":      Perm.User("abc").Administer().KeyEquals("a"),
+		"UseKeyConstraintForKeys":      Perm.Users().Read().FieldEquals("@key", "x"),
+		"ConflictingKeyConstraint":     Perm.Users().Read().KeySuffix(".a").KeySuffix(".b"),
+	}
+	for symbol, b := range invalid {
+		_, err := b.Build()
+		if err == nil || err.Error() != symbol {
+			t.Fatalf("Should have failed with %s, got: %v", symbol, err)
+		}
+	}
+}
@@ -1,13 +1,32 @@
 package zetabase
 
 import (
+	"errors"
+	"fmt"
 	"github.com/zetabase/zetabase-client/zbprotocol"
 	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Servers from this version on include key prefixes and suffixes in the signature of a
+	// permission entry; older ones ignore them there, so they could be altered unnoticed
+	KeyPatternPermissionsMinServerVersion = "0.3.0"
+)
+
+const (
+	keyPatternSupportUnknown int32 = iota
+	keyPatternSupportSigned
+	keyPatternSupportUnsigned
 )
 
 type PermConstraint struct {
 	Field    string
 	ReqValue string
+	// Prefix and Suffix apply to key constraints only (Field "@key"). They can only be sent to
+	// servers from KeyPatternPermissionsMinServerVersion on.
+	Prefix string
+	Suffix string
 }
 
 type PermEntry struct {
@@ -54,8 +73,8 @@ func NewPermConstraintRandom(field string) *PermConstraint {
 
 func NewPermConstraintCustom(field, reqValue string) *PermConstraint {
 	return &PermConstraint{
-		Field:     field,
-		ReqValue:  reqValue,
+		Field:    field,
+		ReqValue: reqValue,
 	}
 }
 
@@ -72,6 +91,73 @@ func (p *PermEntry) AddConstraint(c *PermConstraint) {
 	p.Constraints = append(p.Constraints, c)
 }
 
+func describeConstraintValue(valu string) string {
+	switch strings.ToLower(valu) {
+	case "@uid":
+		return "the caller's user ID"
+	case "@time":
+		return "the write timestamp (set by the server)"
+	case "@order":
+		return "the next natural-order value (set by the server)"
+	case "@random":
+		return "a random value (set by the server)"
+	}
+	return fmt.Sprintf("%q", valu)
+}
+
+func (c *PermConstraint) String() string {
+	if c.Field != "@key" {
+		return fmt.Sprintf("field %q must be %s", c.Field, describeConstraintValue(c.ReqValue))
+	}
+	var parts []string
+	if len(c.Prefix) > 0 {
+		parts = append(parts, fmt.Sprintf("start with %q", c.Prefix))
+	}
+	if len(c.ReqValue) > 0 {
+		if len(c.Prefix) > 0 || len(c.Suffix) > 0 {
+			parts = append(parts, "contain "+describeConstraintValue(c.ReqValue))
+		} else {
+			parts = append(parts, "be "+describeConstraintValue(c.ReqValue))
+		}
+	}
+	if len(c.Suffix) > 0 {
+		parts = append(parts, fmt.Sprintf("end with %q", c.Suffix))
+	}
+	return "the key must " + strings.Join(parts, " and ")
+}
+
+// Describe who the permission applies to, what it allows, and under which constraints, e.g.
+// `any authenticated user may append; the key must be the caller's user ID; field "ts" must be the write timestamp (set by the server)`.
+func (p *PermEntry) String() string {
+	var aud string
+	switch p.AudienceType {
+	case zbprotocol.PermissionAudienceType_PUBLIC:
+		aud = "anyone (public)"
+	case zbprotocol.PermissionAudienceType_USER:
+		aud = "any authenticated user"
+	default:
+		aud = "user " + p.AudienceId
+	}
+	var act string
+	switch p.Level {
+	case zbprotocol.PermissionLevel_READ:
+		act = "may read"
+	case zbprotocol.PermissionLevel_APPEND:
+		act = "may append"
+	case zbprotocol.PermissionLevel_DELETE:
+		act = "may delete"
+	case zbprotocol.PermissionLevel_ADMINISTER:
+		act = "may administer"
+	default:
+		act = "has no access"
+	}
+	s := aud + " " + act
+	for _, c := range p.Constraints {
+		s += "; " + c.String()
+	}
+	return s
+}
+
 func toFieldConstraint(uid, tblId string, cs *PermConstraint) *zbprotocol.PermissionConstraint {
 	fTyp := zbprotocol.FieldConstraintValueType_CONSTANT
 	fVal := cs.ReqValue
@@ -94,6 +180,8 @@ func toFieldConstraint(uid, tblId string, cs *PermConstraint) *zbprotocol.Permis
 			ConstraintType: zbprotocol.PermissionConstraintType_KEY_PATTERN,
 			KeyConstraint: &zbprotocol.KeyPatternConstraint{
 				ConstraintType: zbprotocol.FieldConstraintType_EQUALS_VALUE,
+				RequiredPrefix: cs.Prefix,
+				RequiredSuffix: cs.Suffix,
 				ValueType:      fTyp,
 				RequiredValue:  fVal,
 			},
@@ -110,7 +198,7 @@ func toFieldConstraint(uid, tblId string, cs *PermConstraint) *zbprotocol.Permis
 		}
 
 	}
-	
+
 }
 
 func toFieldConstraints(uid, tblId string, cs []*PermConstraint) []*zbprotocol.PermissionConstraint {
@@ -133,3 +221,79 @@ func (p *PermEntry) ToProtocol(uid, tblId string) *zbprotocol.PermissionsEntry {
 		Constraints:  toFieldConstraints(uid, tblId, p.Constraints),
 	}
 }
+
+func fromFieldConstraintValue(typ zbprotocol.FieldConstraintValueType, valu string) string {
+	switch typ {
+	case zbprotocol.FieldConstraintValueType_UID:
+		return "@uid"
+	case zbprotocol.FieldConstraintValueType_TIMESTAMP:
+		return "@time"
+	case zbprotocol.FieldConstraintValueType_NATURAL_ORDER:
+		return "@order"
+	case zbprotocol.FieldConstraintValueType_RANDOM:
+		return "@random"
+	}
+	return valu
+}
+
+func fromFieldConstraint(c *zbprotocol.PermissionConstraint) *PermConstraint {
+	if c.GetConstraintType() == zbprotocol.PermissionConstraintType_KEY_PATTERN && c.GetKeyConstraint() != nil {
+		kc := c.GetKeyConstraint()
+		return &PermConstraint{
+			Field:    "@key",
+			ReqValue: fromFieldConstraintValue(kc.GetValueType(), kc.GetRequiredValue()),
+			Prefix:   kc.GetRequiredPrefix(),
+			Suffix:   kc.GetRequiredSuffix(),
+		}
+	}
+	fc := c.GetFieldConstraint()
+	return &PermConstraint{
+		Field:    fc.GetFieldKey(),
+		ReqValue: fromFieldConstraintValue(fc.GetValueType(), fc.GetRequiredValue()),
+	}
+}
+
+// Function PermEntryFromProtocol converts a protocol-level permissions entry (e.g. as returned by
+// ListPermissions) back into a PermEntry.
+func PermEntryFromProtocol(p *zbprotocol.PermissionsEntry) *PermEntry {
+	var cs []*PermConstraint
+	for _, c := range p.GetConstraints() {
+		cs = append(cs, fromFieldConstraint(c))
+	}
+	return &PermEntry{
+		Level:        p.GetLevel(),
+		AudienceType: p.GetAudienceType(),
+		AudienceId:   p.GetAudienceId(),
+		Constraints:  cs,
+	}
+}
+
+// Whether the server signs key prefixes and suffixes of permission entries (determined once per
+// client)
+func (z *ZetabaseClient) signedKeyPatterns() bool {
+	switch atomic.LoadInt32(&z.keyPatternSupport) {
+	case keyPatternSupportSigned:
+		return true
+	case keyPatternSupportUnsigned:
+		return false
+	}
+	support := keyPatternSupportUnsigned
+	if _, info, err := z.CheckVersion(); err == nil && IsSemVerVersionAtLeast(info.GetServerVersion(), KeyPatternPermissionsMinServerVersion) {
+		support = keyPatternSupportSigned
+	}
+	atomic.StoreInt32(&z.keyPatternSupport, support)
+	return support == keyPatternSupportSigned
+}
+
+// Fail with UnsignedKeyPattern if perms constrain key prefixes or suffixes and the server would not
+// sign them
+func (z *ZetabaseClient) checkKeyPatterns(perms []*PermEntry) error {
+	for _, p := range perms {
+		for _, c := range p.Constraints {
+			if (len(c.Prefix) > 0 || len(c.Suffix) > 0) && !z.signedKeyPatterns() {
+				return errors.New("UnsignedKeyPattern")
+			}
+		}
+	}
+	return nil
+}
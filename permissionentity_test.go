@@ -0,0 +1,95 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"testing"
+)
+
+func Test_PermEntryFromProtocol(t *testing.T) {
+	perm := NewPermissionEntry(zbprotocol.PermissionLevel_APPEND, zbprotocol.PermissionAudienceType_USER, "")
+	perm.AddConstraint(NewPermConstraintUserId("uid"))
+	perm.AddConstraint(NewPermissionConstraint("type", "tweet"))
+	perm.AddConstraint(NewPermConstraintOrder("@key"))
+
+	pe := perm.ToProtocol("owner", "tbl")
+	back := PermEntryFromProtocol(pe)
+	if back.Level != perm.Level || back.AudienceType != perm.AudienceType || len(back.Constraints) != 3 {
+		t.Fatalf("Wrong entry after round trip: %v", back)
+	}
+	for i, c := range back.Constraints {
+		if c.Field != perm.Constraints[i].Field || c.ReqValue != perm.Constraints[i].ReqValue {
+			t.Fatalf("Wrong constraint %d: %v vs. %v", i, c, perm.Constraints[i])
+		}
+	}
+	if string(PermissionsEntrySigningBytes(back.ToProtocol("owner", "tbl"))) != string(PermissionsEntrySigningBytes(pe)) {
+		t.Fatalf("Signing bytes should match after round trip")
+	}
+
+	// Key patterns are signed
+	prefixed := Perm.Users().Append().KeyPrefix("tweet/").MustBuild().ToProtocol("owner", "tbl")
+	other := Perm.Users().Append().KeyPrefix("note/").MustBuild().ToProtocol("owner", "tbl")
+	if string(PermissionsEntrySigningBytes(prefixed)) == string(PermissionsEntrySigningBytes(other)) {
+		t.Fatalf("Signing bytes should cover key prefixes")
+	}
+}
+
+func Test_ReplaceListedPermissions(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	keyed := NewPermissionEntry(zbprotocol.PermissionLevel_APPEND, zbprotocol.PermissionAudienceType_USER, "")
+	keyed.AddConstraint(&PermConstraint{Field: "@key", ReqValue: "@uid", Prefix: "tweet/", Suffix: "/body"})
+	perms := []*PermEntry{keyed, Perm.Public().Read().MustBuild()}
+
+	// Older servers do not sign key patterns
+	if err := z.CreateTable("tweets", zbprotocol.TableDataFormat_JSON, nil, perms, false); err == nil || err.Error() != "UnsignedKeyPattern" {
+		t.Fatalf("Expected UnsignedKeyPattern, got %v", err)
+	}
+	z, srv = newFakeClient(testOwnerId)
+	srv.Version = KeyPatternPermissionsMinServerVersion
+	if err := z.CreateTable("tweets", zbprotocol.TableDataFormat_JSON, nil, perms, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+
+	listed, err := z.ListPermissions(testOwnerId, "tweets")
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("Unexpected permissions: %v (%v)", listed, err)
+	}
+	want := `any authenticated user may append; the key must start with "tweet/" and contain the caller's user ID and end with "/body"`
+	if listed[0].String() != want {
+		t.Fatalf("Wrong description:\n\t%s\nshould be\n\t%s", listed[0].String(), want)
+	}
+	if err := z.ReplacePermissions(testOwnerId, "tweets", listed[:1]); err != nil {
+		t.Fatalf("Replace failed: %s", err.Error())
+	}
+	got := srv.Table(testOwnerId, "tweets").Defn.Permissions
+	if len(got) != 1 {
+		t.Fatalf("Unexpected permissions after replace: %v", got)
+	}
+	kc := got[0].Constraints[0].GetKeyConstraint()
+	if kc.GetRequiredPrefix() != "tweet/" || kc.GetRequiredSuffix() != "/body" || kc.GetValueType() != zbprotocol.FieldConstraintValueType_UID {
+		t.Fatalf("Key constraint widened by replace: %v", kc)
+	}
+}
+
+func Test_RevokePermission(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	if err := z.CreateTable("tweets", zbprotocol.TableDataFormat_JSON, nil, nil, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	read := Perm.Public().Read().MustBuild()
+	appendOwn := Perm.Users().Append().KeyIsCaller().FieldTimestamp("ts").MustBuild()
+	for _, p := range []*PermEntry{read, appendOwn} {
+		if err := z.AddPermission(testOwnerId, "tweets", p); err != nil {
+			t.Fatalf("AddPermission failed: %s", err.Error())
+		}
+	}
+	if err := z.RevokePermission(testOwnerId, "tweets", read); err != nil {
+		t.Fatalf("RevokePermission failed: %s", err.Error())
+	}
+	listed, err := z.ListPermissions(testOwnerId, "tweets")
+	if err != nil || len(listed) != 1 || listed[0].String() != appendOwn.String() {
+		t.Fatalf("Unexpected permissions after revoking: %v (%v)", listed, err)
+	}
+	if err := z.RevokePermission(testOwnerId, "tweets", read); err == nil {
+		t.Fatalf("Revoking a missing entry should fail")
+	}
+}
@@ -0,0 +1,59 @@
+package zetabase
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Keys beginning with ReservedKeyPrefix hold bookkeeping records written by the client
+	// library itself (e.g. encryption keys) and are skipped when iterating over user data.
+	ReservedKeyPrefix = "__zb/"
+)
+
+// Check whether key k belongs to the client library's reserved namespace
+func IsReservedKey(k string) bool {
+	return strings.HasPrefix(k, ReservedKeyPrefix)
+}
+
+// Build a key in the reserved namespace ns, e.g. reservedKey("dk", id) = "__zb/dk/<id>"
+func reservedKey(ns string, parts ...string) string {
+	return ReservedKeyPrefix + ns + "/" + strings.Join(parts, "/")
+}
+
+// Reserved keys of the form "<ns>/<key>@<stamp>" (e.g. key versions), with stamps written as
+// fixed-width hex so that they sort in order
+const stampDigits = 16
+
+func stampedKeyPrefix(ns, key string) string {
+	return reservedKey(ns, key) + "@"
+}
+
+func stampedKey(ns, key string, stamp int64) string {
+	return fmt.Sprintf("%s%0*x", stampedKeyPrefix(ns, key), stampDigits, stamp)
+}
+
+// Split a stamped key in namespace ns into the key it belongs to and the stamp
+func parseStampedKey(ns, k string) (string, int64, bool) {
+	prefix := reservedKey(ns)
+	i := strings.LastIndex(k, "@")
+	if !strings.HasPrefix(k, prefix) || i < len(prefix) || len(k)-i-1 != stampDigits {
+		return "", 0, false
+	}
+	v, err := strconv.ParseInt(k[i+1:], 16, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return k[len(prefix):i], v, true
+}
+
+func withoutReservedKeys(keys []string) []string {
+	var res []string
+	for _, k := range keys {
+		if !IsReservedKey(k) {
+			res = append(res, k)
+		}
+	}
+	return res
+}
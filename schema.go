@@ -0,0 +1,633 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// How long a client relies on the schema it read for a table before reading it again
+	SchemaRefreshInterval = time.Minute
+	schemaNamespace       = "schema"
+)
+
+// Type Schema is a parsed JSON Schema. The validation keywords of draft 7 are supported except for
+// format and those that need references ($ref, definitions); annotations such as title are ignored.
+type Schema struct {
+	raw  []byte
+	root *schemaNode
+}
+
+type schemaNode struct {
+	always       *bool // boolean schema
+	types        []string
+	properties   map[string]*schemaNode
+	required     []string
+	additional   *schemaNode
+	items        *schemaNode
+	enum         []interface{}
+	constant     interface{}
+	hasConst     bool
+	minimum      *float64
+	maximum      *float64
+	exclMinimum  *float64
+	exclMaximum  *float64
+	minLength    *float64
+	maxLength    *float64
+	minItems     *float64
+	maxItems     *float64
+	pattern      *regexp.Regexp
+	allOf, anyOf []*schemaNode
+	oneOf        []*schemaNode
+	not          *schemaNode
+}
+
+// Type SchemaValidationError lists, by key, the problems that made a table's schema reject
+// documents.
+type SchemaValidationError struct {
+	Problems map[string][]string
+}
+
+func (e *SchemaValidationError) Error() string {
+	var keys []string
+	for k := range e.Problems {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, k+": "+strings.Join(e.Problems[k], ", "))
+	}
+	return "SchemaValidationFailed: " + strings.Join(parts, "; ")
+}
+
+// Function ParseSchema parses a JSON Schema.
+func ParseSchema(bs []byte) (*Schema, error) {
+	var v interface{}
+	if err := json.Unmarshal(bs, &v); err != nil {
+		return nil, err
+	}
+	root, err := parseSchemaNode(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{raw: append([]byte{}, bs...), root: root}, nil
+}
+
+// Method Bytes returns the schema as it was parsed.
+func (s *Schema) Bytes() []byte {
+	return s.raw
+}
+
+func schemaNumber(m map[string]interface{}, kw string) (*float64, error) {
+	v, ok := m[kw]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("InvalidSchema: %s must be a number", kw)
+	}
+	return &f, nil
+}
+
+func schemaNodes(m map[string]interface{}, kw string) ([]*schemaNode, error) {
+	v, ok := m[kw]
+	if !ok {
+		return nil, nil
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("InvalidSchema: %s must be an array", kw)
+	}
+	var res []*schemaNode
+	for _, x := range arr {
+		n, err := parseSchemaNode(x)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+func parseSchemaNode(v interface{}) (*schemaNode, error) {
+	if b, ok := v.(bool); ok {
+		return &schemaNode{always: &b}, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("InvalidSchema: a schema must be an object or a boolean")
+	}
+	for _, kw := range []string{"$ref", "definitions", "$defs", "dependencies", "patternProperties", "if"} {
+		if _, ok := m[kw]; ok {
+			return nil, fmt.Errorf("UnsupportedSchemaKeyword: %s", kw)
+		}
+	}
+	n := &schemaNode{}
+	var err error
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		n.types = []string{t}
+	case []interface{}:
+		for _, x := range t {
+			s, ok := x.(string)
+			if !ok {
+				return nil, errors.New("InvalidSchema: type must be a string or an array of strings")
+			}
+			n.types = append(n.types, s)
+		}
+	default:
+		return nil, errors.New("InvalidSchema: type must be a string or an array of strings")
+	}
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		n.properties = map[string]*schemaNode{}
+		for k, x := range props {
+			if n.properties[k], err = parseSchemaNode(x); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req, ok := m["required"].([]interface{}); ok {
+		for _, x := range req {
+			if s, ok := x.(string); ok {
+				n.required = append(n.required, s)
+			}
+		}
+	}
+	if x, ok := m["additionalProperties"]; ok {
+		if n.additional, err = parseSchemaNode(x); err != nil {
+			return nil, err
+		}
+	}
+	if x, ok := m["items"]; ok {
+		if n.items, err = parseSchemaNode(x); err != nil {
+			return nil, err
+		}
+	}
+	if x, ok := m["not"]; ok {
+		if n.not, err = parseSchemaNode(x); err != nil {
+			return nil, err
+		}
+	}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		n.enum = enum
+	}
+	n.constant, n.hasConst = m["const"]
+	for kw, dst := range map[string]**float64{
+		"minimum": &n.minimum, "maximum": &n.maximum, "exclusiveMinimum": &n.exclMinimum, "exclusiveMaximum": &n.exclMaximum,
+		"minLength": &n.minLength, "maxLength": &n.maxLength, "minItems": &n.minItems, "maxItems": &n.maxItems,
+	} {
+		if *dst, err = schemaNumber(m, kw); err != nil {
+			return nil, err
+		}
+	}
+	if p, ok := m["pattern"].(string); ok {
+		if n.pattern, err = regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("InvalidSchema: pattern: %s", err.Error())
+		}
+	}
+	for kw, dst := range map[string]*[]*schemaNode{"allOf": &n.allOf, "anyOf": &n.anyOf, "oneOf": &n.oneOf} {
+		if *dst, err = schemaNodes(m, kw); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func typeMatches(want, got string) bool {
+	return want == got || (want == "number" && got == "integer")
+}
+
+func jsonPointer(path string) string {
+	if len(path) == 0 {
+		return "/"
+	}
+	return path
+}
+
+// Method Validate checks a document against the schema and returns the problems found, each
+// prefixed with the JSON Pointer of the offending value.
+func (s *Schema) Validate(doc []byte) []string {
+	var v interface{}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+	var problems []string
+	s.root.validate(v, "", &problems)
+	return problems
+}
+
+func (n *schemaNode) validate(v interface{}, path string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, jsonPointer(path)+": "+fmt.Sprintf(format, args...))
+	}
+	if n.always != nil {
+		if !*n.always {
+			fail("not allowed")
+		}
+		return
+	}
+	typ := jsonTypeOf(v)
+	if len(n.types) > 0 {
+		ok := false
+		for _, t := range n.types {
+			ok = ok || typeMatches(t, typ)
+		}
+		if !ok {
+			fail("expected %s, got %s", strings.Join(n.types, " or "), typ)
+			return
+		}
+	}
+	if n.enum != nil {
+		ok := false
+		for _, e := range n.enum {
+			ok = ok || reflect.DeepEqual(e, v)
+		}
+		if !ok {
+			fail("not one of the allowed values")
+		}
+	}
+	if n.hasConst && !reflect.DeepEqual(n.constant, v) {
+		fail("must be %v", n.constant)
+	}
+
+	switch x := v.(type) {
+	case float64:
+		if n.minimum != nil && x < *n.minimum {
+			fail("must be at least %v", *n.minimum)
+		}
+		if n.maximum != nil && x > *n.maximum {
+			fail("must be at most %v", *n.maximum)
+		}
+		if n.exclMinimum != nil && x <= *n.exclMinimum {
+			fail("must be greater than %v", *n.exclMinimum)
+		}
+		if n.exclMaximum != nil && x >= *n.exclMaximum {
+			fail("must be less than %v", *n.exclMaximum)
+		}
+	case string:
+		l := float64(utf8.RuneCountInString(x))
+		if n.minLength != nil && l < *n.minLength {
+			fail("must be at least %v characters long", *n.minLength)
+		}
+		if n.maxLength != nil && l > *n.maxLength {
+			fail("must be at most %v characters long", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(x) {
+			fail("does not match %s", n.pattern.String())
+		}
+	case []interface{}:
+		l := float64(len(x))
+		if n.minItems != nil && l < *n.minItems {
+			fail("must have at least %v items", *n.minItems)
+		}
+		if n.maxItems != nil && l > *n.maxItems {
+			fail("must have at most %v items", *n.maxItems)
+		}
+		if n.items != nil {
+			for i, item := range x {
+				n.items.validate(item, fmt.Sprintf("%s/%d", path, i), problems)
+			}
+		}
+	case map[string]interface{}:
+		for _, r := range n.required {
+			if _, ok := x[r]; !ok {
+				fail("missing required property %s", r)
+			}
+		}
+		var keys []string
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + strings.Replace(strings.Replace(k, "~", "~0", -1), "/", "~1", -1)
+			if prop, ok := n.properties[k]; ok {
+				prop.validate(x[k], p, problems)
+			} else if n.additional != nil {
+				if n.additional.always != nil && !*n.additional.always {
+					fail("unexpected property %s", k)
+				} else {
+					n.additional.validate(x[k], p, problems)
+				}
+			}
+		}
+	}
+
+	for _, sub := range n.allOf {
+		sub.validate(v, path, problems)
+	}
+	if len(n.anyOf) > 0 && n.countMatching(n.anyOf, v) == 0 {
+		fail("does not match any of the allowed schemas")
+	}
+	if len(n.oneOf) > 0 && n.countMatching(n.oneOf, v) != 1 {
+		fail("must match exactly one of the allowed schemas")
+	}
+	if n.not != nil && n.countMatching([]*schemaNode{n.not}, v) == 1 {
+		fail("matches a disallowed schema")
+	}
+}
+
+func (n *schemaNode) countMatching(subs []*schemaNode, v interface{}) int {
+	c := 0
+	for _, sub := range subs {
+		var problems []string
+		sub.validate(v, "", &problems)
+		if len(problems) == 0 {
+			c++
+		}
+	}
+	return c
+}
+
+// Function SchemaForType returns a JSON Schema for the documents encoding/json produces from
+// values like v (a struct or pointer to one). Fields are named and made optional (omitempty)
+// following their json tags; other fields are required. Unknown properties are allowed, as
+// encoding/json ignores them.
+func SchemaForType(v interface{}) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("SchemaRequiresStruct")
+	}
+	return json.Marshal(schemaForType(t, map[reflect.Type]bool{}))
+}
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	jsonMarshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	rawJsonMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func schemaForType(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string"}
+	}
+	if t == rawJsonMessageType || t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := schemaForType(t.Elem(), seen)
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []string{typ, "null"}
+		}
+		return s
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Encoded as base64
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": schemaForType(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": schemaForType(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			// Recursive types are only checked to be objects below the first level
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		props := map[string]interface{}{}
+		var required []string
+		addStructFields(t, seen, props, &required)
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			sort.Strings(required)
+			s["required"] = required
+		}
+		return s
+	}
+	return map[string]interface{}{}
+}
+
+func addStructFields(t reflect.Type, seen map[reflect.Type]bool, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.Index(tag, ","); j >= 0 {
+			name, opts = tag[:j], tag[j:]
+		}
+		if f.Anonymous && len(name) == 0 {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructFields(ft, seen, props, required)
+				continue
+			}
+		}
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		props[name] = schemaForType(f.Type, seen)
+		if !strings.Contains(opts, ",omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+type tableSchema struct {
+	schema   *Schema // nil if the table has none
+	loadedAt time.Time
+	disabled bool // see DisableSchemaValidation, or not a JSON table
+}
+
+func schemaKey() string {
+	return reservedKey(schemaNamespace, "json")
+}
+
+func (z *ZetabaseClient) checkJsonTable(tableOwnerId, tableId string) error {
+	defn, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err != nil {
+		return err
+	} else if defn.GetDataFormat() != zbprotocol.TableDataFormat_JSON {
+		return errors.New("SchemaRequiresJsonTable")
+	}
+	return nil
+}
+
+// Method SetTableSchema attaches a JSON Schema to a JSON table (nil removes it). It is stored in
+// the table itself, so that zb put and every client check documents against it before writing
+// them (unless a client opts out with DisableSchemaValidation). Validation is enabled again for
+// this client.
+func (z *ZetabaseClient) SetTableSchema(tableOwnerId, tableId string, schema []byte) error {
+	if err := z.checkJsonTable(tableOwnerId, tableId); err != nil {
+		return err
+	}
+	if schema == nil {
+		if err := z.deleteKeyRaw(tableOwnerId, tableId, schemaKey()); err != nil {
+			return err
+		}
+		z.schemas.Store(cacheTableKey(tableOwnerId, tableId), &tableSchema{loadedAt: time.Now()})
+		return nil
+	}
+	s, err := ParseSchema(schema)
+	if err != nil {
+		return err
+	}
+	if err := z.PutMulti(tableOwnerId, tableId, []string{schemaKey()}, [][]byte{schema}, true); err != nil {
+		return err
+	}
+	z.schemas.Store(cacheTableKey(tableOwnerId, tableId), &tableSchema{schema: s, loadedAt: time.Now()})
+	return nil
+}
+
+// Method SetTableSchemaFromType attaches the schema of a Go struct type (see SchemaForType) to a
+// JSON table.
+func (z *ZetabaseClient) SetTableSchemaFromType(tableOwnerId, tableId string, v interface{}) error {
+	schema, err := SchemaForType(v)
+	if err != nil {
+		return err
+	}
+	return z.SetTableSchema(tableOwnerId, tableId, schema)
+}
+
+// Method GetTableSchema returns the schema attached to a table, or nil if there is none.
+func (z *ZetabaseClient) GetTableSchema(tableOwnerId, tableId string) (*Schema, error) {
+	bs, ok, err := z.getUncached(tableOwnerId, tableId, schemaKey())
+	if err != nil || !ok {
+		return nil, err
+	}
+	return ParseSchema(bs)
+}
+
+// Method EnableSchemaValidation reads the schema of a JSON table now, and turns validation back on
+// after DisableSchemaValidation. Clients check the documents they write through PutData, PutMulti,
+// CompareAndSwap and batches against the schema of the table (see SetTableSchema), which is read
+// on the first write to the table and again every SchemaRefreshInterval. Writes fail if the table
+// definition or schema cannot be read, so clients that may write to a table but not read it must
+// opt out with DisableSchemaValidation.
+func (z *ZetabaseClient) EnableSchemaValidation(tableOwnerId, tableId string) error {
+	if err := z.checkJsonTable(tableOwnerId, tableId); err != nil {
+		return err
+	}
+	s, err := z.GetTableSchema(tableOwnerId, tableId)
+	if err != nil {
+		return err
+	}
+	z.schemas.Store(cacheTableKey(tableOwnerId, tableId), &tableSchema{schema: s, loadedAt: time.Now()})
+	return nil
+}
+
+// Method DisableSchemaValidation stops checking writes to a table against its schema.
+func (z *ZetabaseClient) DisableSchemaValidation(tableOwnerId, tableId string) {
+	z.schemas.Store(cacheTableKey(tableOwnerId, tableId), &tableSchema{disabled: true})
+}
+
+// The table's schema, as read at most SchemaRefreshInterval ago, and whether validation is enabled
+// for the table. Failed reads are not remembered, so writes keep failing until the schema can be
+// read again.
+func (z *ZetabaseClient) tableSchema(tableOwnerId, tableId string) (*Schema, bool, error) {
+	ts, ok := z.schemas.Load(cacheTableKey(tableOwnerId, tableId))
+	if ok && ts.(*tableSchema).disabled {
+		return nil, false, nil
+	} else if ok && time.Since(ts.(*tableSchema).loadedAt) < SchemaRefreshInterval {
+		return ts.(*tableSchema).schema, true, nil
+	} else if !ok {
+		// First write to the table: only JSON tables have schemas
+		if err := z.checkJsonTable(tableOwnerId, tableId); err != nil && err.Error() == "SchemaRequiresJsonTable" {
+			z.schemas.Store(cacheTableKey(tableOwnerId, tableId), &tableSchema{disabled: true})
+			return nil, false, nil
+		} else if err != nil {
+			return nil, true, err
+		}
+	}
+	s, err := z.GetTableSchema(tableOwnerId, tableId)
+	if err != nil {
+		return nil, true, err
+	}
+	z.schemas.Store(cacheTableKey(tableOwnerId, tableId), &tableSchema{schema: s, loadedAt: time.Now()})
+	return s, true, nil
+}
+
+// Check writes to a table against its schema, unless validation is disabled for it
+func (z *ZetabaseClient) validateWrites(tableOwnerId, tableId string, keys []string, valus [][]byte) error {
+	s, enabled, err := z.tableSchema(tableOwnerId, tableId)
+	if !enabled || err != nil {
+		return err
+	}
+	return validateDocuments(s, keys, valus)
+}
+
+// Method ValidateDocuments checks documents against the schema attached to a table, returning a
+// SchemaValidationError listing the problems of each rejected key. The schema is read from the
+// table if validation is disabled for it or the client's copy is out of date.
+func (z *ZetabaseClient) ValidateDocuments(tableOwnerId, tableId string, keys []string, valus [][]byte) error {
+	s, enabled, err := z.tableSchema(tableOwnerId, tableId)
+	if !enabled {
+		s, err = z.GetTableSchema(tableOwnerId, tableId)
+	}
+	if err != nil {
+		return err
+	}
+	return validateDocuments(s, keys, valus)
+}
+
+func validateDocuments(s *Schema, keys []string, valus [][]byte) error {
+	if s == nil {
+		return nil
+	}
+	problems := map[string][]string{}
+	for i, k := range keys {
+		if IsReservedKey(k) || valus[i] == nil {
+			continue
+		}
+		if p := s.Validate(valus[i]); len(p) > 0 {
+			problems[k] = p
+		}
+	}
+	if len(problems) > 0 {
+		return &SchemaValidationError{Problems: problems}
+	}
+	return nil
+}
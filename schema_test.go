@@ -0,0 +1,187 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["name", "age"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+		"age": {"type": "integer", "minimum": 0},
+		"tags": {"type": "array", "items": {"enum": ["a", "b"]}, "maxItems": 2},
+		"kind": {"anyOf": [{"const": "x"}, {"type": "number"}]}
+	},
+	"additionalProperties": false
+}`
+
+func Test_SchemaValidate(t *testing.T) {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %s", err.Error())
+	}
+	if p := s.Validate([]byte(`{"name": "ann", "age": 3, "tags": ["a"], "kind": 2.5}`)); len(p) != 0 {
+		t.Fatalf("Valid document rejected: %v", p)
+	}
+	for doc, want := range map[string]string{
+		`{"name": "ann"}`:                        "/: missing required property age",
+		`{"name": "Ann", "age": 1}`:              "/name: does not match",
+		`{"name": "ann", "age": 1.5}`:            "/age: expected integer, got number",
+		`{"name": "ann", "age": -1}`:             "/age: must be at least 0",
+		`{"name": "ann", "age": 1, "tags": [1]}`: "/tags/0: not one of the allowed values",
+		`{"name": "ann", "age": 1, "x": 1}`:      "/: unexpected property x",
+		`{"name": "ann", "age": 1, "kind": "y"}`: "/kind: does not match any",
+		`[1]`:                                    "/: expected object, got array",
+		`{"name": `:                              "invalid JSON",
+	} {
+		if p := s.Validate([]byte(doc)); len(p) != 1 || !strings.HasPrefix(p[0], want) {
+			t.Fatalf("Unexpected problems for %s: %v", doc, p)
+		}
+	}
+	if _, err := ParseSchema([]byte(`{"$ref": "#/definitions/x"}`)); err == nil || !strings.HasPrefix(err.Error(), "UnsupportedSchemaKeyword") {
+		t.Fatalf("Expected UnsupportedSchemaKeyword, got %v", err)
+	}
+}
+
+func Test_SchemaEnforcedOnPut(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, map[string]string{"a": `{"age": 1}`})
+	if err := z.SetTableSchema(testOwnerId, "src", []byte(testSchema)); err != nil {
+		t.Fatalf("SetTableSchema failed: %s", err.Error())
+	}
+
+	err := z.PutMulti(testOwnerId, "src", []string{"ok", "bad1", "bad2"},
+		[][]byte{[]byte(`{"name": "bo", "age": 2}`), []byte(`{"name": "bo"}`), []byte(`{"name": 1, "age": 2}`)}, true)
+	verr, ok := err.(*SchemaValidationError)
+	if !ok || len(verr.Problems) != 2 || len(verr.Problems["bad2"]) != 1 || !strings.HasPrefix(err.Error(), "SchemaValidationFailed: bad1: ") {
+		t.Fatalf("Expected per-key validation errors, got %v", err)
+	}
+	if _, ok := srv.Table(testOwnerId, "src").Data["ok"]; ok {
+		t.Fatalf("Documents written despite validation errors")
+	}
+
+	// Another client picks the schema up from the table
+	other := newFakeClientFor(testOwnerId, srv)
+	if err := other.EnableSchemaValidation(testOwnerId, "src"); err != nil {
+		t.Fatalf("EnableSchemaValidation failed: %s", err.Error())
+	}
+	if err := other.PutData(testOwnerId, "src", "bad", []byte(`{}`), true); err == nil {
+		t.Fatalf("Invalid document accepted by another client")
+	}
+	if err := other.CompareAndSwap(testOwnerId, "src", "new", nil, []byte(`{"age": 1}`)); err == nil {
+		t.Fatalf("Invalid document accepted by CompareAndSwap")
+	}
+	if err := other.NewBatch(testOwnerId, "src").Put("b", []byte(`{"name": "x", "age": 1}`)).Delete("a").Commit(); err != nil {
+		t.Fatalf("Valid batch rejected: %s", err.Error())
+	}
+	if err := other.PutWithTTL(testOwnerId, "src", "t", []byte(`{"name": "x", "age": 1}`), time.Hour); err != nil {
+		t.Fatalf("Reserved expiry record rejected: %s", err.Error())
+	}
+
+	if err := z.SetTableSchema(testOwnerId, "src", nil); err != nil {
+		t.Fatalf("Failed to remove schema: %s", err.Error())
+	}
+	if err := z.PutData(testOwnerId, "src", "bad", []byte(`{}`), true); err != nil {
+		t.Fatalf("Document rejected after removing the schema: %s", err.Error())
+	}
+
+	z.CreateTable("txt", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+	if err := z.SetTableSchema(testOwnerId, "txt", []byte(`{}`)); err == nil || err.Error() != "SchemaRequiresJsonTable" {
+		t.Fatalf("Expected SchemaRequiresJsonTable, got %v", err)
+	}
+	if err := z.EnableSchemaValidation(testOwnerId, "txt"); err == nil || err.Error() != "SchemaRequiresJsonTable" {
+		t.Fatalf("Expected SchemaRequiresJsonTable, got %v", err)
+	}
+}
+
+func Test_SchemaLookups(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, map[string]string{"a": `{"age": 1}`})
+	other := newFakeClientFor(testOwnerId, srv)
+	z.SetTableSchema(testOwnerId, "src", []byte(testSchema))
+
+	// Other clients read the schema on their first write to the table, and then rely on it
+	srv.GetCalls = 0
+	if err := other.PutData(testOwnerId, "src", "b", []byte(`{}`), true); err == nil || srv.GetCalls != 1 {
+		t.Fatalf("Invalid document accepted by a client without the schema: %v (%d reads)", err, srv.GetCalls)
+	}
+	if err := other.PutData(testOwnerId, "src", "b", []byte(`{"name": "x", "age": 1}`), true); err != nil || srv.GetCalls != 1 {
+		t.Fatalf("Unexpected write: %v (%d reads)", err, srv.GetCalls)
+	}
+
+	// Unless they opt out
+	other.DisableSchemaValidation(testOwnerId, "src")
+	if err := other.PutData(testOwnerId, "src", "b", []byte(`{}`), true); err != nil || srv.GetCalls != 1 {
+		t.Fatalf("Unexpected write without validation: %v (%d reads)", err, srv.GetCalls)
+	}
+
+	// Tables of other formats have no schema to read
+	z.CreateTable("txt", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+	if err := other.PutData(testOwnerId, "txt", "a", []byte("x"), true); err != nil || srv.GetCalls != 1 {
+		t.Fatalf("Unexpected write to a text table: %v (%d reads)", err, srv.GetCalls)
+	}
+
+	// A schema that cannot be read again fails writes, until it can
+	other.EnableSchemaValidation(testOwnerId, "src")
+	other.schemas.Store(cacheTableKey(testOwnerId, "src"), &tableSchema{loadedAt: time.Now().Add(-2 * SchemaRefreshInterval)})
+	srv.GetErr = errors.New("Unavailable")
+	if err := other.PutData(testOwnerId, "src", "c", []byte(`{"name": "x", "age": 1}`), true); err == nil {
+		t.Fatalf("Write accepted while the schema could not be read")
+	}
+	srv.GetErr = nil
+	if err := other.PutData(testOwnerId, "src", "d", []byte(`{}`), true); err == nil {
+		t.Fatalf("Invalid document accepted after the schema could be read again")
+	}
+}
+
+type schemaTestBase struct {
+	Id string `json:"id"`
+}
+
+type schemaTestDoc struct {
+	schemaTestBase
+	Name    string            `json:"name"`
+	Age     int               `json:"age,omitempty"`
+	Score   *float64          `json:"score"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]int    `json:"attrs,omitempty"`
+	Created time.Time         `json:"created"`
+	Raw     []byte            `json:"raw,omitempty"`
+	Skipped string            `json:"-"`
+	Next    *schemaTestDoc    `json:"next,omitempty"`
+	Extra   map[string]string `json:",omitempty"`
+	hidden  int
+}
+
+func Test_SchemaForType(t *testing.T) {
+	bs, err := SchemaForType(&schemaTestDoc{})
+	if err != nil {
+		t.Fatalf("SchemaForType failed: %s", err.Error())
+	}
+	s, err := ParseSchema(bs)
+	if err != nil {
+		t.Fatalf("Generated schema does not parse: %s", err.Error())
+	}
+	score := 1.5
+	doc, _ := json.Marshal(&schemaTestDoc{schemaTestBase: schemaTestBase{Id: "x"}, Name: "n", Score: &score,
+		Next: &schemaTestDoc{Name: "m"}, Extra: map[string]string{"k": "v"}})
+	if p := s.Validate(doc); len(p) != 0 {
+		t.Fatalf("Marshalled value rejected: %v (%s)", p, bs)
+	}
+	if p := s.Validate([]byte(`{"id": "x", "name": "n", "score": null, "tags": null}`)); len(p) != 1 || p[0] != "/: missing required property created" {
+		t.Fatalf("Unexpected problems: %v", p)
+	}
+	if p := s.Validate([]byte(`{"id": 1, "name": "n", "score": "high", "tags": [], "created": "2020-01-01T00:00:00Z"}`)); len(p) != 2 {
+		t.Fatalf("Unexpected problems: %v", p)
+	}
+	if _, err := SchemaForType(3); err == nil || err.Error() != "SchemaRequiresStruct" {
+		t.Fatalf("Expected SchemaRequiresStruct, got %v", err)
+	}
+}
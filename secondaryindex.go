@@ -0,0 +1,386 @@
+package zetabase
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"regexp"
+	"sort"
+)
+
+const (
+	// Secondary indexes of a table are kept in the table named with this suffix
+	SecondaryIndexTableSuffix = "_zbidx"
+	indexEntryPrefix          = "idx"
+	indexReversePrefix        = "pk"
+)
+
+// Type SecondaryIndex is an index maintained by the client, for tables of any data format. Extract
+// returns the values a key is indexed under (none to leave it out of the index); each value is a
+// Key, so integers and timestamps can be indexed in order with AppendInt and AppendTime.
+type SecondaryIndex struct {
+	Field   string
+	Extract func(key string, valu []byte) ([]Key, error)
+}
+
+// Function IndexWholeValue returns an extractor that indexes each key under its whole value, as a
+// string (for TEXT tables).
+func IndexWholeValue() func(string, []byte) ([]Key, error) {
+	return func(_ string, valu []byte) ([]Key, error) {
+		return []Key{NewKey(string(valu))}, nil
+	}
+}
+
+// Function IndexRegexp returns an extractor that indexes each key under every match of re in its
+// value: the first subgroup of the match if re has one, or else the whole match.
+func IndexRegexp(re *regexp.Regexp) func(string, []byte) ([]Key, error) {
+	return func(_ string, valu []byte) ([]Key, error) {
+		var res []Key
+		for _, m := range re.FindAllSubmatch(valu, -1) {
+			if len(m) > 1 {
+				res = append(res, NewKey(string(m[1])))
+			} else {
+				res = append(res, NewKey(string(m[0])))
+			}
+		}
+		return res, nil
+	}
+}
+
+// Function SecondaryIndexTable returns the name of the table holding the secondary indexes of
+// tableId.
+func SecondaryIndexTable(tableId string) string {
+	return tableId + SecondaryIndexTableSuffix
+}
+
+func indexEntryKey(field string, value Key, pk string) string {
+	return NewKey(indexEntryPrefix, field).Append(value...).Append(pk).String()
+}
+
+// Field and primary key of an index entry
+func parseIndexEntryKey(s string) (string, string, bool) {
+	k, err := ParseKey(s)
+	if err != nil || len(k) < 3 || k[0] != indexEntryPrefix {
+		return "", "", false
+	}
+	return k[1], k[len(k)-1], true
+}
+
+// Reverse records list the index entries of a primary key, so that they can be updated without
+// reading the previous value
+func indexReverseKey(pk string) string {
+	return NewKey(indexReversePrefix, pk).String()
+}
+
+// Method AddSecondaryIndex makes the client maintain idx for a table: PutData, PutMulti,
+// CompareAndSwap, DeleteKey and batches keep the index up to date, and LookupBy and LookupRange
+// query it. The index table (see SecondaryIndexTable) is created with the table's permissions if
+// it does not exist yet; only the owner can do that. Keys written before the index was added, or
+// by clients that do not maintain it, are indexed by RebuildSecondaryIndex.
+func (z *ZetabaseClient) AddSecondaryIndex(tableOwnerId, tableId string, idx *SecondaryIndex) error {
+	if len(idx.Field) == 0 || idx.Extract == nil {
+		return errors.New("InvalidSecondaryIndex")
+	}
+	if err := z.ensureIndexTable(tableOwnerId, tableId); err != nil {
+		return err
+	}
+	var idxs []*SecondaryIndex
+	if prev, ok := z.secondaryIndexes.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		for _, i := range prev.([]*SecondaryIndex) {
+			if i.Field != idx.Field {
+				idxs = append(idxs, i)
+			}
+		}
+	}
+	z.secondaryIndexes.Store(cacheTableKey(tableOwnerId, tableId), append(idxs, idx))
+	return nil
+}
+
+// Method RemoveSecondaryIndex stops maintaining the index on field. Its entries are kept.
+func (z *ZetabaseClient) RemoveSecondaryIndex(tableOwnerId, tableId, field string) {
+	var idxs []*SecondaryIndex
+	for _, i := range z.tableIndexes(tableOwnerId, tableId) {
+		if i.Field != field {
+			idxs = append(idxs, i)
+		}
+	}
+	if len(idxs) == 0 {
+		z.secondaryIndexes.Delete(cacheTableKey(tableOwnerId, tableId))
+	} else {
+		z.secondaryIndexes.Store(cacheTableKey(tableOwnerId, tableId), idxs)
+	}
+}
+
+func (z *ZetabaseClient) tableIndexes(tableOwnerId, tableId string) []*SecondaryIndex {
+	if idxs, ok := z.secondaryIndexes.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		return idxs.([]*SecondaryIndex)
+	}
+	return nil
+}
+
+func (z *ZetabaseClient) ensureIndexTable(tableOwnerId, tableId string) error {
+	if _, err := z.GetTableDefinition(tableOwnerId, SecondaryIndexTable(tableId)); err == nil {
+		return nil
+	}
+	if tableOwnerId != z.Id() {
+		return ErrCannotCreateTableForOtherOwner
+	}
+	defn, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err != nil {
+		return err
+	}
+	var perms []*PermEntry
+	for _, p := range defn.GetPermissions() {
+		perm := PermEntryFromProtocol(p)
+		// Field constraints do not apply to index entries
+		perm.Constraints = nil
+		perms = append(perms, perm)
+	}
+	return z.CreateTable(SecondaryIndexTable(tableId), zbprotocol.TableDataFormat_PLAIN_TEXT, nil, perms, defn.GetAllowTokenAuth())
+}
+
+// Index entries the given writes should leave, by primary key (nil valus are deletes). Returns
+// nil if the table has no secondary indexes.
+func (z *ZetabaseClient) indexEntries(tableOwnerId, tableId string, keys []string, valus [][]byte) (map[string][]string, error) {
+	idxs := z.tableIndexes(tableOwnerId, tableId)
+	if len(idxs) == 0 {
+		return nil, nil
+	}
+	res := map[string][]string{}
+	for i, k := range keys {
+		if IsReservedKey(k) {
+			continue
+		}
+		res[k] = []string{}
+		if valus[i] == nil {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, idx := range idxs {
+			values, err := idx.Extract(k, valus[i])
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range values {
+				if e := indexEntryKey(idx.Field, v, k); !seen[e] {
+					seen[e] = true
+					res[k] = append(res[k], e)
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// Bring the index entries of the given primary keys in line with want, for the fields indexed by
+// this client. Entries of other fields are left alone.
+func (z *ZetabaseClient) applyIndexEntries(tableOwnerId, tableId string, want map[string][]string) error {
+	if len(want) == 0 {
+		return nil
+	}
+	fields := map[string]bool{}
+	for _, idx := range z.tableIndexes(tableOwnerId, tableId) {
+		fields[idx.Field] = true
+	}
+	return z.replaceIndexEntries(tableOwnerId, tableId, fields, want)
+}
+
+func (z *ZetabaseClient) replaceIndexEntries(tableOwnerId, tableId string, fields map[string]bool, want map[string][]string) error {
+	idxTbl := SecondaryIndexTable(tableId)
+	var revKeys []string
+	for pk := range want {
+		revKeys = append(revKeys, indexReverseKey(pk))
+	}
+	revs := map[string][]byte{}
+	for i := 0; i < len(revKeys); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(revKeys) {
+			j = len(revKeys)
+		}
+		m, err := z.getPag(tableOwnerId, idxTbl, revKeys[i:j]).DataAll()
+		if err != nil {
+			return err
+		}
+		addData(revs, m)
+	}
+
+	var putKeys []string
+	var putValus [][]byte
+	var dels []string
+	for pk, entries := range want {
+		var old []string
+		if bs, ok := revs[indexReverseKey(pk)]; ok {
+			json.Unmarshal(bs, &old)
+		}
+		wanted := map[string]bool{}
+		for _, e := range entries {
+			wanted[e] = true
+		}
+		had := map[string]bool{}
+		rev := append([]string{}, entries...)
+		for _, e := range old {
+			had[e] = true
+			if f, _, ok := parseIndexEntryKey(e); ok && !fields[f] {
+				rev = append(rev, e)
+			} else if !wanted[e] {
+				dels = append(dels, e)
+			}
+		}
+		for _, e := range entries {
+			if !had[e] {
+				putKeys = append(putKeys, e)
+				putValus = append(putValus, []byte(pk))
+			}
+		}
+		if len(rev) == 0 {
+			if len(old) > 0 {
+				dels = append(dels, indexReverseKey(pk))
+			}
+			continue
+		}
+		sort.Strings(rev)
+		if bs, err := json.Marshal(rev); err == nil && string(bs) != string(revs[indexReverseKey(pk)]) {
+			putKeys = append(putKeys, indexReverseKey(pk))
+			putValus = append(putValus, bs)
+		}
+	}
+	if len(putKeys) > 0 {
+		if err := z.PutMulti(tableOwnerId, idxTbl, putKeys, putValus, true); err != nil {
+			return err
+		}
+	}
+	for _, k := range dels {
+		if err := z.deleteKeyRaw(tableOwnerId, idxTbl, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method LookupBy returns the keys of a table indexed under value in the secondary index on field.
+func (z *ZetabaseClient) LookupBy(tableOwnerId, tableId, field string, value Key) ([]string, error) {
+	prefix := NewKey(indexEntryPrefix, field).Append(value...)
+	entries, err := z.ListKeysWithPattern(tableOwnerId, SecondaryIndexTable(tableId), prefix.Pattern()).KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, e := range entries {
+		// Skip longer values that merely start with value
+		if k, err := ParseKey(e); err == nil && len(k) == len(prefix)+1 {
+			res = append(res, k[len(k)-1])
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// Method LookupRange returns the keys of a table indexed under values from from (inclusive) to to
+// (exclusive) in the secondary index on field, in index order. Either bound may be nil. Values
+// compare as stored, which is numeric order for AppendInt and AppendTime values.
+func (z *ZetabaseClient) LookupRange(tableOwnerId, tableId, field string, from, to Key) ([]string, error) {
+	r := z.KeyRange(tableOwnerId, SecondaryIndexTable(tableId), NewKey(indexEntryPrefix, field), from, to)
+	var res []string
+	for r.Next() {
+		k := r.Key()
+		res = append(res, k[len(k)-1])
+	}
+	return res, r.Err()
+}
+
+// Method RebuildSecondaryIndex recomputes the index on field from every key of the table, adding
+// missing entries and removing stale ones. Returns the number of entries added and removed.
+func (z *ZetabaseClient) RebuildSecondaryIndex(tableOwnerId, tableId, field string) (int, int, error) {
+	var idx *SecondaryIndex
+	for _, i := range z.tableIndexes(tableOwnerId, tableId) {
+		if i.Field == field {
+			idx = i
+		}
+	}
+	if idx == nil {
+		return 0, 0, errors.New("SecondaryIndexNotFound")
+	}
+	idxTbl := SecondaryIndexTable(tableId)
+	keys, err := z.listKeysRemote(tableOwnerId, tableId, "").KeysAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	keys = withoutReservedKeys(keys)
+	want := map[string][]string{}
+	for i := 0; i < len(keys); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(keys) {
+			j = len(keys)
+		}
+		data, err := z.getPag(tableOwnerId, tableId, keys[i:j]).DataAll()
+		if err != nil {
+			return 0, 0, err
+		}
+		for k, v := range data {
+			values, err := idx.Extract(k, v)
+			if err != nil {
+				return 0, 0, err
+			}
+			want[k] = []string{}
+			for _, val := range values {
+				want[k] = append(want[k], indexEntryKey(field, val, k))
+			}
+		}
+	}
+
+	existing, err := z.listKeysRemote(tableOwnerId, idxTbl, NewKey(indexEntryPrefix, field).Pattern()).KeysAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	have := map[string]bool{}
+	for _, e := range existing {
+		have[e] = true
+		// Entries of deleted keys
+		if _, pk, ok := parseIndexEntryKey(e); ok && want[pk] == nil {
+			want[pk] = []string{}
+		}
+	}
+	added, removed := 0, 0
+	wanted := map[string]bool{}
+	for _, entries := range want {
+		for _, e := range entries {
+			wanted[e] = true
+			if !have[e] {
+				added++
+			}
+		}
+	}
+	for e := range have {
+		if !wanted[e] {
+			removed++
+		}
+	}
+	if err := z.replaceIndexEntries(tableOwnerId, tableId, map[string]bool{field: true}, want); err != nil {
+		return 0, 0, err
+	}
+	// Reverse records may have drifted too: put back entries they list but that are missing, and
+	// delete entries they do not list
+	var putKeys []string
+	var putValus [][]byte
+	for pk, entries := range want {
+		for _, e := range entries {
+			if !have[e] {
+				putKeys = append(putKeys, e)
+				putValus = append(putValus, []byte(pk))
+			}
+		}
+	}
+	if len(putKeys) > 0 {
+		if err := z.PutMulti(tableOwnerId, idxTbl, putKeys, putValus, true); err != nil {
+			return added, removed, err
+		}
+	}
+	for e := range have {
+		if !wanted[e] {
+			if err := z.deleteKeyRaw(tableOwnerId, idxTbl, e); err != nil {
+				return added, removed, err
+			}
+		}
+	}
+	return added, removed, nil
+}
@@ -0,0 +1,84 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func makeIndexTestTable(t *testing.T, z *ZetabaseClient) {
+	if err := z.CreateTable("src", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	// Values look like "<city>;<age>"
+	err := z.AddSecondaryIndex(testOwnerId, "src", &SecondaryIndex{Field: "city", Extract: IndexRegexp(regexp.MustCompile(`^([^;]*);`))})
+	if err != nil {
+		t.Fatalf("AddSecondaryIndex failed: %s", err.Error())
+	}
+	z.AddSecondaryIndex(testOwnerId, "src", &SecondaryIndex{Field: "age", Extract: func(_ string, valu []byte) ([]Key, error) {
+		n, err := strconv.ParseInt(strings.SplitN(string(valu), ";", 2)[1], 10, 64)
+		return []Key{NewKey().AppendInt(n)}, err
+	}})
+}
+
+func Test_SecondaryIndexMaintained(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	srv.Version = CompareAndSwapMinServerVersion
+	makeIndexTestTable(t, z)
+
+	z.PutData(testOwnerId, "src", "ann", []byte("Paris;31"), true)
+	z.PutMulti(testOwnerId, "src", []string{"bob", "cy/1"}, [][]byte{[]byte("Oslo;-4"), []byte("Paris;100")}, true)
+	z.NewBatch(testOwnerId, "src").Put("dee", []byte("Rome;9")).Commit()
+	if got, err := z.LookupBy(testOwnerId, "src", "city", NewKey("Paris")); err != nil || strings.Join(got, ",") != "ann,cy/1" {
+		t.Fatalf("Unexpected lookup: %v (%v)", got, err)
+	}
+	if got, _ := z.LookupRange(testOwnerId, "src", "age", NewKey().AppendInt(-10), NewKey().AppendInt(100)); strings.Join(got, ",") != "bob,dee,ann" {
+		t.Fatalf("Unexpected range lookup: %v", got)
+	}
+
+	old, _, _ := z.getUncached(testOwnerId, "src", "ann")
+	if err := z.CompareAndSwap(testOwnerId, "src", "ann", ValueHash(old), []byte("Oslo;31")); err != nil {
+		t.Fatalf("CompareAndSwap failed: %s", err.Error())
+	}
+	z.DeleteKey(testOwnerId, "src", "bob")
+	if got, _ := z.LookupBy(testOwnerId, "src", "city", NewKey("Oslo")); strings.Join(got, ",") != "ann" {
+		t.Fatalf("Index not updated: %v", got)
+	}
+	if got, _ := z.LookupBy(testOwnerId, "src", "city", NewKey("Paris")); strings.Join(got, ",") != "cy/1" {
+		t.Fatalf("Stale index entry left: %v", got)
+	}
+	if got, _ := z.LookupRange(testOwnerId, "src", "age", nil, nil); len(got) != 3 {
+		t.Fatalf("Unexpected age index: %v", got)
+	}
+}
+
+func Test_SecondaryIndexRebuild(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeIndexTestTable(t, z)
+	z.PutData(testOwnerId, "src", "ann", []byte("Paris;31"), true)
+	z.PutData(testOwnerId, "src", "bob", []byte("Oslo;4"), true)
+
+	// Drift: writes by a client without the index, and a lost index entry
+	srv.Put(testOwnerId, "src", []*zbprotocol.DataPair{{Key: "ann", Value: []byte("Rome;31")}, {Key: "eve", Value: []byte("Paris;50")}}, true)
+	delete(srv.Table(testOwnerId, SecondaryIndexTable("src")).Data, indexEntryKey("city", NewKey("Oslo"), "bob"))
+
+	added, removed, err := z.RebuildSecondaryIndex(testOwnerId, "src", "city")
+	if err != nil || added != 3 || removed != 1 {
+		t.Fatalf("Unexpected rebuild: %v (%d added, %d removed)", err, added, removed)
+	}
+	for city, want := range map[string]string{"Paris": "eve", "Rome": "ann", "Oslo": "bob"} {
+		if got, _ := z.LookupBy(testOwnerId, "src", "city", NewKey(city)); strings.Join(got, ",") != want {
+			t.Fatalf("Unexpected lookup for %s after rebuild: %v", city, got)
+		}
+	}
+	// The age index is untouched, and still maintained on later writes
+	z.DeleteKey(testOwnerId, "src", "eve")
+	if got, _ := z.LookupRange(testOwnerId, "src", "age", nil, nil); strings.Join(got, ",") != "bob,ann" {
+		t.Fatalf("Unexpected age index: %v", got)
+	}
+	if _, _, err := z.RebuildSecondaryIndex(testOwnerId, "src", "nope"); err == nil || err.Error() != "SecondaryIndexNotFound" {
+		t.Fatalf("Expected SecondaryIndexNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"sync"
+)
+
+const (
+	DefaultCopyPageSize    = 200
+	DefaultCopyParallelism = 4
+)
+
+// Type CopyTransform rewrites a record while it is copied. Returning keep=false skips the record.
+type CopyTransform func(key string, valu []byte) (newKey string, newValu []byte, keep bool, err error)
+
+// Type CopyOptions configures CopyTable.
+type CopyOptions struct {
+	KeyPattern      string                  // only copy keys matching this pattern (% is the wildcard)
+	CopyPermissions bool                    // copy the source table's permissions
+	Overwrite       bool                    // overwrite keys that already exist in the destination
+	Transform       CopyTransform           // optional rewrite of each record
+	PageSize        int                     // records fetched per request
+	Parallelism     int                     // pages copied concurrently
+	Progress        func(copied, total int) // called after each page
+}
+
+// Function CopyTable copies table srcTbl of srcOwner (read with client src) to table dstTbl of
+// dstOwner (written with client dst), so the two sides may use different identities. The
+// destination table is created from the source's definition if it does not exist (permissions are
+// only carried over with opts.CopyPermissions). Returns the number of records written.
+func CopyTable(src *ZetabaseClient, srcOwner, srcTbl string, dst *ZetabaseClient, dstOwner, dstTbl string, opts *CopyOptions) (int, error) {
+	var o CopyOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = DefaultCopyPageSize
+	}
+	if o.Parallelism <= 0 {
+		o.Parallelism = DefaultCopyParallelism
+	}
+	if src == nil || dst == nil {
+		return 0, errors.New("NotReady")
+	}
+
+	defn, err := src.GetTableDefinition(srcOwner, srcTbl)
+	if err != nil {
+		return 0, err
+	}
+	if !o.CopyPermissions {
+		defn = proto.Clone(defn).(*zbprotocol.TableCreate)
+		defn.Permissions = nil
+	}
+	_, err = dst.GetTableDefinition(dstOwner, dstTbl)
+	if err != nil && !errors.Is(err, ErrTableNotFound) {
+		return 0, err
+	} else if err != nil {
+		if dstOwner != dst.Id() {
+			return 0, ErrCannotCreateTableForOtherOwner
+		}
+		err = dst.CreateTableFromDefinition(dstTbl, defn)
+	} else if o.CopyPermissions {
+		var perms []*PermEntry
+		for _, p := range defn.GetPermissions() {
+			perms = append(perms, PermEntryFromProtocol(p))
+		}
+		err = dst.ReplacePermissions(dstOwner, dstTbl, perms)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := src.ListKeysWithPattern(srcOwner, srcTbl, o.KeyPattern).KeysAll()
+	if err != nil {
+		return 0, err
+	}
+	var pages [][]string
+	for i := 0; i < len(keys); i += o.PageSize {
+		j := i + o.PageSize
+		if j > len(keys) {
+			j = len(keys)
+		}
+		pages = append(pages, keys[i:j])
+	}
+
+	var lock sync.Mutex
+	var firstErr error
+	copied := 0
+	work := make(chan []string)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range work {
+				n, err := copyPage(src, srcOwner, srcTbl, dst, dstOwner, dstTbl, page, &o)
+				lock.Lock()
+				copied += n
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if o.Progress != nil {
+					o.Progress(copied, len(keys))
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, page := range pages {
+		lock.Lock()
+		failed := firstErr != nil
+		lock.Unlock()
+		if failed {
+			break
+		}
+		work <- page
+	}
+	close(work)
+	wg.Wait()
+	return copied, firstErr
+}
+
+func copyPage(src *ZetabaseClient, srcOwner, srcTbl string, dst *ZetabaseClient, dstOwner, dstTbl string, page []string, o *CopyOptions) (int, error) {
+	data, err := src.GetUncached(srcOwner, srcTbl, page).DataAll()
+	if err != nil {
+		return 0, err
+	}
+	var ks []string
+	var vs [][]byte
+	for _, k := range page {
+		v, ok := data[k]
+		if !ok {
+			continue
+		}
+		if o.Transform != nil {
+			nk, nv, keep, err := o.Transform(k, v)
+			if err != nil {
+				return 0, err
+			} else if !keep {
+				continue
+			}
+			k, v = nk, nv
+		}
+		ks = append(ks, k)
+		vs = append(vs, v)
+	}
+	if len(ks) == 0 {
+		return 0, nil
+	}
+	err = dst.PutMulti(dstOwner, dstTbl, ks, vs, o.Overwrite)
+	if err != nil {
+		return 0, err
+	}
+	return len(ks), nil
+}
@@ -0,0 +1,45 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"testing"
+)
+
+func Test_CopyTableAcrossIdentities(t *testing.T) {
+	src, _ := newFakeClient(testOwnerId)
+	data := map[string]string{"keep/1": "a", "keep/2": "b", "skip/1": "c"}
+	makeExportTestTable(t, src, zbprotocol.TableDataFormat_PLAIN_TEXT, data)
+
+	otherId := "66666666-2222-3333-4444-555555555555"
+	dst, srv := newFakeClient(otherId)
+	progressCalls := 0
+	n, err := CopyTable(src, testOwnerId, "src", dst, otherId, "copy", &CopyOptions{
+		KeyPattern:      "keep/%",
+		CopyPermissions: true,
+		PageSize:        1,
+		Transform: func(k string, v []byte) (string, []byte, bool, error) {
+			return k, []byte(strings.ToUpper(string(v))), k != "keep/2", nil
+		},
+		Progress: func(copied, total int) {
+			progressCalls++
+		},
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("Copy failed: %v (%d records)", err, n)
+	}
+	checkTableContents(t, srv, otherId, "copy", map[string]string{"keep/1": "A"}, false)
+	if len(srv.Table(otherId, "copy").Defn.Permissions) != 1 || progressCalls != 2 {
+		t.Fatalf("Permissions or progress not carried over")
+	}
+
+	// Without permissions, and into a table for which we have no create rights
+	_, err = CopyTable(src, testOwnerId, "src", dst, testOwnerId, "copy", nil)
+	if err == nil || err.Error() != "CannotCreateTableForOtherOwner" {
+		t.Fatalf("Expected an error creating another owner's table, got %v", err)
+	}
+	n, err = CopyTable(src, testOwnerId, "src", dst, otherId, "noperms", nil)
+	if err != nil || n != 3 || len(srv.Table(otherId, "noperms").Defn.Permissions) != 0 {
+		t.Fatalf("Copy without permissions failed: %v (%d records)", err, n)
+	}
+}
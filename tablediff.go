@@ -0,0 +1,192 @@
+package zetabase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// Type TableDiff lists the differences between a source and a target table.
+type TableDiff struct {
+	Added   []string // keys in the source but not the target
+	Removed []string // keys in the target but not the source
+	Changed []string // keys in both tables with different values
+}
+
+// Type DiffOptions configures DiffTables and SyncTables.
+type DiffOptions struct {
+	KeyPattern string // only compare keys matching this pattern (% is the wildcard)
+	PageSize   int    // records fetched per request
+}
+
+// Method Empty returns true if the tables were found to be identical.
+func (d *TableDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Function DiffTables compares table srcTbl of srcOwner (read with client src) to table dstTbl of
+// dstOwner (read with client dst). Keys are compared first; values of keys present in both tables
+// are then compared by hash. Both tables must exist. A key listed in both tables whose source value cannot be read back
+// fails the diff rather than being reported as removed.
+func DiffTables(src *ZetabaseClient, srcOwner, srcTbl string, dst *ZetabaseClient, dstOwner, dstTbl string, opts *DiffOptions) (*TableDiff, error) {
+	var o DiffOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = DefaultCopyPageSize
+	}
+	if _, err := src.GetTableDefinition(srcOwner, srcTbl); err != nil {
+		return nil, err
+	}
+	if _, err := dst.GetTableDefinition(dstOwner, dstTbl); err != nil {
+		return nil, err
+	}
+	srcKeys, err := src.ListKeysWithPattern(srcOwner, srcTbl, o.KeyPattern).KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	dstKeys, err := dst.ListKeysWithPattern(dstOwner, dstTbl, o.KeyPattern).KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(srcKeys)
+	sort.Strings(dstKeys)
+
+	d := &TableDiff{}
+	var common []string
+	i, j := 0, 0
+	for i < len(srcKeys) || j < len(dstKeys) {
+		switch {
+		case j >= len(dstKeys) || (i < len(srcKeys) && srcKeys[i] < dstKeys[j]):
+			d.Added = append(d.Added, srcKeys[i])
+			i++
+		case i >= len(srcKeys) || dstKeys[j] < srcKeys[i]:
+			d.Removed = append(d.Removed, dstKeys[j])
+			j++
+		default:
+			common = append(common, srcKeys[i])
+			i++
+			j++
+		}
+	}
+
+	for i := 0; i < len(common); i += o.PageSize {
+		k := i + o.PageSize
+		if k > len(common) {
+			k = len(common)
+		}
+		page := common[i:k]
+		srcHashes, err := valueHashes(src, srcOwner, srcTbl, page)
+		if err != nil {
+			return nil, err
+		}
+		dstHashes, err := valueHashes(dst, dstOwner, dstTbl, page)
+		if err != nil {
+			return nil, err
+		}
+		if err := refetchMissing(src, srcOwner, srcTbl, page, srcHashes, dstHashes); err != nil {
+			return nil, err
+		}
+		for _, key := range page {
+			a, aok := srcHashes[key]
+			b, bok := dstHashes[key]
+			if aok && !bok {
+				d.Added = append(d.Added, key)
+			} else if aok && !bytes.Equal(a, b) {
+				d.Changed = append(d.Changed, key)
+			}
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	return d, nil
+}
+
+// Hashes of the values of keys (keys deleted since they were listed are left out)
+func valueHashes(z *ZetabaseClient, tableOwnerId, tableId string, keys []string) (map[string][]byte, error) {
+	data, err := z.GetUncached(tableOwnerId, tableId, keys).DataAll()
+	if err != nil {
+		return nil, err
+	}
+	hashes := map[string][]byte{}
+	for k, v := range data {
+		h := sha256.Sum256(v)
+		hashes[k] = h[:]
+	}
+	return hashes, nil
+}
+
+// Fetch again the source values of keys listed in both tables but missing from srcHashes. A key
+// still missing was deleted from the source after it was listed: the diff fails, and the key is
+// left for the next one rather than deleted from the target on a single read.
+func refetchMissing(src *ZetabaseClient, srcOwner, srcTbl string, page []string, srcHashes, dstHashes map[string][]byte) error {
+	var missing []string
+	for _, key := range page {
+		_, aok := srcHashes[key]
+		_, bok := dstHashes[key]
+		if bok && !aok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	again, err := valueHashes(src, srcOwner, srcTbl, missing)
+	if err != nil {
+		return err
+	}
+	for _, key := range missing {
+		h, ok := again[key]
+		if !ok {
+			return fmt.Errorf("SourceChangedDuringDiff: %s", key)
+		}
+		srcHashes[key] = h
+	}
+	return nil
+}
+
+// Function SyncTables makes the target table match the source table (see DiffTables): added and
+// changed keys are written with overwrite, and removed keys are deleted. Returns the differences
+// that were found.
+func SyncTables(src *ZetabaseClient, srcOwner, srcTbl string, dst *ZetabaseClient, dstOwner, dstTbl string, opts *DiffOptions) (*TableDiff, error) {
+	d, err := DiffTables(src, srcOwner, srcTbl, dst, dstOwner, dstTbl, opts)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := DefaultCopyPageSize
+	if opts != nil && opts.PageSize > 0 {
+		pageSize = opts.PageSize
+	}
+	toPut := append(append([]string{}, d.Added...), d.Changed...)
+	for i := 0; i < len(toPut); i += pageSize {
+		k := i + pageSize
+		if k > len(toPut) {
+			k = len(toPut)
+		}
+		data, err := src.GetUncached(srcOwner, srcTbl, toPut[i:k]).DataAll()
+		if err != nil {
+			return d, err
+		}
+		var ks []string
+		var vs [][]byte
+		for _, key := range toPut[i:k] {
+			if v, ok := data[key]; ok {
+				ks = append(ks, key)
+				vs = append(vs, v)
+			}
+		}
+		if len(ks) > 0 {
+			if err := dst.PutMulti(dstOwner, dstTbl, ks, vs, true); err != nil {
+				return d, err
+			}
+		}
+	}
+	for _, key := range d.Removed {
+		if err := dst.DeleteKey(dstOwner, dstTbl, key); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,71 @@
+package zetabase
+
+import (
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"reflect"
+	"testing"
+)
+
+func Test_DiffAndSyncTables(t *testing.T) {
+	src, _ := newFakeClient(testOwnerId)
+	makeExportTestTable(t, src, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2", "c": "3"})
+
+	otherId := "66666666-2222-3333-4444-555555555555"
+	dst, srv := newFakeClient(otherId)
+	dst.CreateTable("replica", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+	dst.PutMulti(otherId, "replica", []string{"b", "c", "d"}, [][]byte{[]byte("2"), []byte("x"), []byte("4")}, false)
+
+	d, err := DiffTables(src, testOwnerId, "src", dst, otherId, "replica", &DiffOptions{PageSize: 1})
+	if err != nil {
+		t.Fatalf("Diff failed: %s", err.Error())
+	}
+	want := &TableDiff{Added: []string{"a"}, Removed: []string{"d"}, Changed: []string{"c"}}
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("Unexpected diff: %+v", d)
+	}
+
+	_, err = SyncTables(src, testOwnerId, "src", dst, otherId, "replica", nil)
+	if err != nil {
+		t.Fatalf("Sync failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, otherId, "replica", map[string]string{"a": "1", "b": "2", "c": "3"}, false)
+	d, _ = DiffTables(src, testOwnerId, "src", dst, otherId, "replica", nil)
+	if !d.Empty() {
+		t.Fatalf("Tables still differ after sync: %+v", d)
+	}
+}
+
+func Test_SyncTablesKeepsTargetOnSourceReadFailure(t *testing.T) {
+	src, srcSrv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, src, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+
+	otherId := "66666666-2222-3333-4444-555555555555"
+	dst, srv := newFakeClient(otherId)
+	dst.CreateTable("replica", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+	dst.PutMulti(otherId, "replica", []string{"a", "b"}, [][]byte{[]byte("1"), []byte("x")}, false)
+
+	srcSrv.GetErr = errors.New("Unavailable")
+	if d, err := SyncTables(src, testOwnerId, "src", dst, otherId, "replica", nil); err == nil {
+		t.Fatalf("Expected sync to fail, got %+v", d)
+	}
+	checkTableContents(t, srv, otherId, "replica", map[string]string{"a": "1", "b": "x"}, false)
+}
+
+func Test_SyncTablesKeepsTargetWithoutSource(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	z.CreateTable("dst", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false)
+	z.PutMulti(testOwnerId, "dst", []string{"a", "b"}, [][]byte{[]byte("1"), []byte("2")}, false)
+	if d, err := SyncTables(z, testOwnerId, "nosuchtable", z, testOwnerId, "dst", nil); err == nil {
+		t.Fatalf("Expected sync from a missing table to fail, got %+v", d)
+	}
+	checkTableContents(t, srv, testOwnerId, "dst", map[string]string{"a": "1", "b": "2"}, false)
+
+	// A listing that fails on the server is an error, not an empty table
+	if _, err := z.ListKeysWithPattern(testOwnerId, "nosuchtable", "").KeysAll(); err == nil {
+		t.Fatalf("Expected listing a missing table to fail")
+	}
+	if _, err := z.GetUncached(testOwnerId, "nosuchtable", []string{"a"}).DataAll(); err == nil {
+		t.Fatalf("Expected reading a missing table to fail")
+	}
+}
@@ -0,0 +1,655 @@
+package zetabase
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Type ExportFormat selects the serialization used by ExportTable and ImportTable.
+type ExportFormat int
+
+const (
+	ExportNdjson ExportFormat = iota // one JSON object per line: {"key": ..., "json"|"text"|"base64": ...}
+	ExportCsv                        // CSV with a header row, optionally mapping columns to JSON fields
+	ExportTar                        // tar snapshot: table definition (table.json) plus NDJSON data files
+)
+
+const (
+	DefaultExportBatchSize = 500
+	snapshotDefinitionFile = "table.json"
+	snapshotDataDir        = "data"
+	maxNdjsonLineBytes     = 64 * 1024 * 1024
+)
+
+// Parse an export format name: one of ndjson (or jsonl), csv, tar
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(s) {
+	case "ndjson", "jsonl":
+		return ExportNdjson, nil
+	case "csv":
+		return ExportCsv, nil
+	case "tar":
+		return ExportTar, nil
+	}
+	return 0, errors.New("UnknownExportFormat")
+}
+
+// Guess the export format from a file name's extension
+func ExportFormatForFilename(fn string) (ExportFormat, bool) {
+	switch strings.ToLower(path.Ext(fn)) {
+	case ".ndjson", ".jsonl":
+		return ExportNdjson, true
+	case ".csv":
+		return ExportCsv, true
+	case ".tar":
+		return ExportTar, true
+	}
+	return 0, false
+}
+
+// Type CsvColumn maps a CSV column to part of a record. Path is a (dotted) JSON field path, or
+// one of the special paths @key (the record's key) and @value (the whole value).
+type CsvColumn struct {
+	Header string
+	Path   string
+	// For JSON fields: string (default), number, bool, or json. For @value: text (default) or base64.
+	Type string
+}
+
+// Parse a column mapping of the form "header=path:type,..." (e.g. "id=@key,name=user.name,age=age:number").
+// The path defaults to the header and the type to string.
+func ParseCsvColumns(spec string) ([]CsvColumn, error) {
+	var cols []CsvColumn
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		col := CsvColumn{}
+		if i := strings.Index(part, "="); i >= 0 {
+			col.Header, part = part[:i], part[i+1:]
+		}
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			part, col.Type = part[:i], part[i+1:]
+		}
+		col.Path = part
+		if len(col.Header) == 0 {
+			col.Header = col.Path
+		}
+		if len(col.Path) == 0 {
+			return nil, errors.New("EmptyCsvColumnPath")
+		}
+		switch col.Type {
+		case "", "string", "number", "bool", "json", "text", "base64":
+		default:
+			return nil, errors.New("UnknownCsvColumnType")
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func defaultCsvColumns(defn *zbprotocol.TableCreate) []CsvColumn {
+	typ := "text"
+	if defn != nil && defn.GetDataFormat() == zbprotocol.TableDataFormat_BINARY {
+		typ = "base64"
+	}
+	return []CsvColumn{{Header: "key", Path: "@key"}, {Header: "value", Path: "@value", Type: typ}}
+}
+
+// Type ExportOptions configures ExportTable.
+type ExportOptions struct {
+	Format    ExportFormat
+	Columns   []CsvColumn // CSV only; defaults to key and value columns
+	BatchSize int         // keys fetched per request
+}
+
+// Type ImportOptions configures ImportTable.
+type ImportOptions struct {
+	Format    ExportFormat
+	Columns   []CsvColumn // CSV only; defaults to key and value columns
+	BatchSize int         // records written per request
+	Overwrite bool
+	// Tar only: create the table from the snapshot's definition if it does not exist yet
+	CreateTable bool
+}
+
+type ndjsonRecord struct {
+	Key    string          `json:"key"`
+	Json   json.RawMessage `json:"json,omitempty"`
+	Text   *string         `json:"text,omitempty"`
+	Base64 []byte          `json:"base64,omitempty"`
+}
+
+// Encode a key-value pair as an NDJSON line, choosing the most readable lossless representation
+// for the table's data format (defn may be nil if the definition is not available).
+func encodeNdjsonRecord(key string, valu []byte, defn *zbprotocol.TableCreate) ([]byte, error) {
+	rec := ndjsonRecord{Key: key}
+	format := zbprotocol.TableDataFormat_PLAIN_TEXT
+	if defn != nil {
+		format = defn.GetDataFormat()
+	}
+	var compact bytes.Buffer
+	if format == zbprotocol.TableDataFormat_JSON && json.Compact(&compact, valu) == nil {
+		rec.Json = compact.Bytes()
+	} else if format != zbprotocol.TableDataFormat_BINARY && utf8.Valid(valu) {
+		s := string(valu)
+		rec.Text = &s
+	} else {
+		rec.Base64 = valu
+	}
+	bs, err := json.Marshal(&rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(bs, '\n'), nil
+}
+
+func decodeNdjsonRecord(line []byte) (string, []byte, error) {
+	var rec ndjsonRecord
+	err := json.Unmarshal(line, &rec)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(rec.Key) == 0 {
+		return "", nil, errors.New("MissingKey")
+	}
+	if rec.Json != nil {
+		return rec.Key, []byte(rec.Json), nil
+	} else if rec.Text != nil {
+		return rec.Key, []byte(*rec.Text), nil
+	}
+	if rec.Base64 == nil {
+		return rec.Key, []byte{}, nil
+	}
+	return rec.Key, rec.Base64, nil
+}
+
+// Method ScanTable iterates over every key-value pair of a table (including reserved keys) in key
+// order, calling f with batches of up to batchSize keys and their values. Keys deleted while the
+// scan is running are skipped; a batch that cannot be read fails the scan.
+func (z *ZetabaseClient) ScanTable(tableOwnerId, tableId string, batchSize int, f func(keys []string, data map[string][]byte) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultExportBatchSize
+	}
+	keys, err := z.ListKeys(tableOwnerId, tableId).KeysAll()
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+	for i := 0; i < len(keys); i += batchSize {
+		j := i + batchSize
+		if j > len(keys) {
+			j = len(keys)
+		}
+		data, err := z.GetUncached(tableOwnerId, tableId, keys[i:j]).DataAll()
+		if err != nil {
+			return err
+		}
+		var present []string
+		for _, k := range keys[i:j] {
+			if _, ok := data[k]; ok {
+				present = append(present, k)
+			}
+		}
+		err = f(present, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy of a table definition without request-specific fields (credentials, nonces, caller IDs)
+func sanitizedTableDefinition(defn *zbprotocol.TableCreate) *zbprotocol.TableCreate {
+	d := proto.Clone(defn).(*zbprotocol.TableCreate)
+	d.Id = ""
+	d.Nonce = 0
+	d.Credential = nil
+	for _, p := range d.Permissions {
+		p.Id = ""
+		p.Nonce = 0
+		p.Credential = nil
+	}
+	return d
+}
+
+func marshalTableDefinition(defn *zbprotocol.TableCreate) ([]byte, error) {
+	m := jsonpb.Marshaler{Indent: " "}
+	s, err := m.MarshalToString(sanitizedTableDefinition(defn))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s + "\n"), nil
+}
+
+func unmarshalTableDefinition(bs []byte) (*zbprotocol.TableCreate, error) {
+	var defn zbprotocol.TableCreate
+	err := jsonpb.Unmarshal(bytes.NewReader(bs), &defn)
+	if err != nil {
+		return nil, err
+	}
+	return &defn, nil
+}
+
+// Method ExportTable writes the whole contents of a table to w in the format given by opts and
+// returns the number of records written.
+func (z *ZetabaseClient) ExportTable(tableOwnerId, tableId string, w io.Writer, opts *ExportOptions) (int, error) {
+	var o ExportOptions
+	if opts != nil {
+		o = *opts
+	}
+	// The definition is only required for snapshots; other formats just use it to pick encodings
+	defn, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err != nil && o.Format == ExportTar {
+		return 0, err
+	}
+	switch o.Format {
+	case ExportNdjson:
+		return z.exportNdjson(tableOwnerId, tableId, w, defn, o.BatchSize)
+	case ExportCsv:
+		return z.exportCsv(tableOwnerId, tableId, w, defn, o)
+	case ExportTar:
+		return z.exportTar(tableOwnerId, tableId, w, defn, o.BatchSize)
+	}
+	return 0, errors.New("UnknownExportFormat")
+}
+
+func (z *ZetabaseClient) exportNdjson(tableOwnerId, tableId string, w io.Writer, defn *zbprotocol.TableCreate, batchSize int) (int, error) {
+	bw := bufio.NewWriter(w)
+	n := 0
+	err := z.ScanTable(tableOwnerId, tableId, batchSize, func(keys []string, data map[string][]byte) error {
+		for _, k := range keys {
+			line, err := encodeNdjsonRecord(k, data[k], defn)
+			if err != nil {
+				return err
+			}
+			if _, err := bw.Write(line); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+func csvCell(col CsvColumn, key string, valu []byte, doc map[string]interface{}) (string, error) {
+	switch col.Path {
+	case "@key":
+		return key, nil
+	case "@value":
+		if col.Type == "base64" {
+			return base64.StdEncoding.EncodeToString(valu), nil
+		} else if !utf8.Valid(valu) {
+			return "", errors.New("NonTextValueUseBase64Column")
+		}
+		return string(valu), nil
+	}
+	v, ok := jsonFieldValue(doc, col.Path)
+	if !ok || v == nil {
+		return "", nil
+	}
+	if s, isStr := v.(string); isStr && col.Type != "json" {
+		return s, nil
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+func csvColumnsUseFields(cols []CsvColumn) bool {
+	for _, c := range cols {
+		if c.Path != "@key" && c.Path != "@value" {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *ZetabaseClient) exportCsv(tableOwnerId, tableId string, w io.Writer, defn *zbprotocol.TableCreate, o ExportOptions) (int, error) {
+	cols := o.Columns
+	if len(cols) == 0 {
+		cols = defaultCsvColumns(defn)
+	}
+	useFields := csvColumnsUseFields(cols)
+	cw := csv.NewWriter(w)
+	var header []string
+	for _, c := range cols {
+		header = append(header, c.Header)
+	}
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := z.ScanTable(tableOwnerId, tableId, o.BatchSize, func(keys []string, data map[string][]byte) error {
+		for _, k := range keys {
+			if IsReservedKey(k) && useFields {
+				// Bookkeeping records have no meaningful JSON columns
+				continue
+			}
+			var doc map[string]interface{}
+			if useFields {
+				d, err := decodeJsonObject(data[k])
+				if err != nil {
+					return fmt.Errorf("NotJsonObject: %s", k)
+				}
+				doc = d
+			}
+			var row []string
+			for _, c := range cols {
+				cell, err := csvCell(c, k, data[k], doc)
+				if err != nil {
+					return err
+				}
+				row = append(row, cell)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	cw.Flush()
+	if err != nil {
+		return n, err
+	}
+	return n, cw.Error()
+}
+
+func writeTarEntry(tw *tar.Writer, name string, bs []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(bs)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(bs)
+	return err
+}
+
+func (z *ZetabaseClient) exportTar(tableOwnerId, tableId string, w io.Writer, defn *zbprotocol.TableCreate, batchSize int) (int, error) {
+	tw := tar.NewWriter(w)
+	defnBs, err := marshalTableDefinition(defn)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeTarEntry(tw, snapshotDefinitionFile, defnBs); err != nil {
+		return 0, err
+	}
+	n, batchNo := 0, 0
+	err = z.ScanTable(tableOwnerId, tableId, batchSize, func(keys []string, data map[string][]byte) error {
+		var buf bytes.Buffer
+		for _, k := range keys {
+			line, err := encodeNdjsonRecord(k, data[k], defn)
+			if err != nil {
+				return err
+			}
+			buf.Write(line)
+		}
+		batchNo++
+		n += len(keys)
+		return writeTarEntry(tw, fmt.Sprintf("%s/%06d.ndjson", snapshotDataDir, batchNo), buf.Bytes())
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, tw.Close()
+}
+
+// Accumulates records and writes them with PutMulti in batches
+type importBatcher struct {
+	z            *ZetabaseClient
+	tableOwnerId string
+	tableId      string
+	batchSize    int
+	overwrite    bool
+	keys         []string
+	valus        [][]byte
+	n            int
+}
+
+func (b *importBatcher) add(key string, valu []byte) error {
+	b.keys = append(b.keys, key)
+	b.valus = append(b.valus, valu)
+	if len(b.keys) >= b.batchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *importBatcher) flush() error {
+	if len(b.keys) == 0 {
+		return nil
+	}
+	err := b.z.PutMulti(b.tableOwnerId, b.tableId, b.keys, b.valus, b.overwrite)
+	if err != nil {
+		return err
+	}
+	b.n += len(b.keys)
+	b.keys, b.valus = nil, nil
+	return nil
+}
+
+// Method ImportTable reads records in the format given by opts from r and writes them to a table,
+// returning the number of records written.
+func (z *ZetabaseClient) ImportTable(tableOwnerId, tableId string, r io.Reader, opts *ImportOptions) (int, error) {
+	var o ImportOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultExportBatchSize
+	}
+	b := &importBatcher{
+		z:            z,
+		tableOwnerId: tableOwnerId,
+		tableId:      tableId,
+		batchSize:    o.BatchSize,
+		overwrite:    o.Overwrite,
+	}
+	var err error
+	switch o.Format {
+	case ExportNdjson:
+		err = readNdjson(r, b.add)
+	case ExportCsv:
+		err = readCsv(r, o.Columns, b.add)
+	case ExportTar:
+		err = z.importTar(tableOwnerId, tableId, r, o, b)
+	default:
+		err = errors.New("UnknownExportFormat")
+	}
+	if err == nil {
+		err = b.flush()
+	}
+	return b.n, err
+}
+
+func readNdjson(r io.Reader, f func(string, []byte) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxNdjsonLineBytes)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		k, v, err := decodeNdjsonRecord(line)
+		if err != nil {
+			return fmt.Errorf("InvalidRecord: line %d: %s", lineNo, err.Error())
+		}
+		if err := f(k, v); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func csvFieldValue(col CsvColumn, cell string) (interface{}, error) {
+	switch col.Type {
+	case "number":
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			return nil, err
+		}
+		return json.Number(cell), nil
+	case "bool":
+		return strconv.ParseBool(cell)
+	case "json":
+		if !json.Valid([]byte(cell)) {
+			return nil, errors.New("InvalidJson")
+		}
+		return json.RawMessage(cell), nil
+	}
+	return cell, nil
+}
+
+func readCsv(r io.Reader, cols []CsvColumn, f func(string, []byte) error) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		cols = defaultCsvColumns(nil)
+	}
+	idx := map[string]int{}
+	for i, h := range header {
+		idx[h] = i
+	}
+	keyCol, valuCol := -1, -1
+	for i, c := range cols {
+		if _, ok := idx[c.Header]; !ok {
+			return fmt.Errorf("CsvColumnMissing: %s", c.Header)
+		}
+		switch c.Path {
+		case "@key":
+			keyCol = i
+		case "@value":
+			valuCol = i
+		}
+	}
+	if keyCol < 0 {
+		return errors.New("CsvKeyColumnMissing")
+	}
+	rowNo := 1
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		rowNo++
+		cell := func(i int) string {
+			return row[idx[cols[i].Header]]
+		}
+		key := cell(keyCol)
+		var valu []byte
+		if valuCol >= 0 {
+			valu = []byte(cell(valuCol))
+			if cols[valuCol].Type == "base64" {
+				valu, err = base64.StdEncoding.DecodeString(cell(valuCol))
+				if err != nil {
+					return fmt.Errorf("InvalidRecord: row %d: %s", rowNo, err.Error())
+				}
+			}
+		} else {
+			doc := map[string]interface{}{}
+			for i, c := range cols {
+				if i == keyCol || len(cell(i)) == 0 {
+					continue
+				}
+				v, err := csvFieldValue(c, cell(i))
+				if err != nil {
+					return fmt.Errorf("InvalidRecord: row %d, column %s: %s", rowNo, c.Header, err.Error())
+				}
+				setJsonFieldValue(doc, c.Path, v)
+			}
+			valu, err = json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+		}
+		if err := f(key, valu); err != nil {
+			return err
+		}
+	}
+}
+
+func (z *ZetabaseClient) importTar(tableOwnerId, tableId string, r io.Reader, o ImportOptions, b *importBatcher) error {
+	tr := tar.NewReader(r)
+	var defn *zbprotocol.TableCreate
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case hdr.Name == snapshotDefinitionFile:
+			bs, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			defn, err = unmarshalTableDefinition(bs)
+			if err != nil {
+				return err
+			}
+			if o.CreateTable {
+				err = z.ensureTableFromDefinition(tableOwnerId, tableId, defn)
+				if err != nil {
+					return err
+				}
+			}
+		case strings.HasPrefix(hdr.Name, snapshotDataDir+"/"):
+			if defn == nil && o.CreateTable {
+				return errors.New("SnapshotMissingDefinition")
+			}
+			err = readNdjson(tr, b.add)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Create table tableId from defn unless it already exists
+func (z *ZetabaseClient) ensureTableFromDefinition(tableOwnerId, tableId string, defn *zbprotocol.TableCreate) error {
+	_, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, ErrTableNotFound) {
+		return err
+	} else if tableOwnerId != z.Id() {
+		return ErrCannotCreateTableForOtherOwner
+	}
+	return z.CreateTableFromDefinition(tableId, defn)
+}
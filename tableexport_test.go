@@ -0,0 +1,163 @@
+package zetabase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testOwnerId = "11111111-2222-3333-4444-555555555555"
+
+func makeExportTestTable(t *testing.T, z *ZetabaseClient, format zbprotocol.TableDataFormat, data map[string]string) {
+	perms := []*PermEntry{Perm.Users().Read().MustBuild()}
+	idx := []*IndexedField{NewIndexedField("age", zbprotocol.QueryOrdering_INTEGRAL_NUMBERS)}
+	err := z.CreateTable("src", format, idx, perms, false)
+	if err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	for k, v := range data {
+		err := z.PutData(testOwnerId, "src", k, []byte(v), false)
+		if err != nil {
+			t.Fatalf("Failed to put data: %s", err.Error())
+		}
+	}
+}
+
+func checkTableContents(t *testing.T, srv *fakeServer, owner, tbl string, want map[string]string, jsonCompare bool) {
+	ft := srv.Table(owner, tbl)
+	if ft == nil || len(ft.Data) != len(want) {
+		t.Fatalf("Unexpected table contents: %v", ft)
+	}
+	for k, v := range want {
+		got := string(ft.Data[k])
+		if jsonCompare {
+			var a, b interface{}
+			json.Unmarshal([]byte(v), &a)
+			json.Unmarshal(ft.Data[k], &b)
+			ab, _ := json.Marshal(a)
+			bb, _ := json.Marshal(b)
+			got, v = string(bb), string(ab)
+		}
+		if got != v {
+			t.Fatalf("Value mismatch for key %q: %q vs %q", k, got, v)
+		}
+	}
+}
+
+func Test_ExportImportNdjson(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	data := map[string]string{
+		"a\"quoted\" key": `{"name": "x\ny", "age": 3}`,
+		"b/ünïcode":       `{"name": "z", "age": 40}`,
+	}
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, data)
+	var buf bytes.Buffer
+	n, err := z.ExportTable(testOwnerId, "src", &buf, &ExportOptions{Format: ExportNdjson})
+	if err != nil || n != 2 {
+		t.Fatalf("Export failed: %v (%d records)", err, n)
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("Expected one line per record: %s", buf.String())
+	}
+	z.CreateTable("dst", zbprotocol.TableDataFormat_JSON, nil, nil, false)
+	n, err = z.ImportTable(testOwnerId, "dst", &buf, &ImportOptions{Format: ExportNdjson})
+	if err != nil || n != 2 {
+		t.Fatalf("Import failed: %v (%d records)", err, n)
+	}
+	checkTableContents(t, srv, testOwnerId, "dst", data, true)
+}
+
+func Test_ExportImportNdjsonBinary(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	data := map[string]string{"k1": "\x00\xff\xfe", "k2": ""}
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_BINARY, data)
+	var buf bytes.Buffer
+	z.ExportTable(testOwnerId, "src", &buf, &ExportOptions{Format: ExportNdjson})
+	z.CreateTable("dst", zbprotocol.TableDataFormat_BINARY, nil, nil, false)
+	_, err := z.ImportTable(testOwnerId, "dst", &buf, &ImportOptions{Format: ExportNdjson})
+	if err != nil {
+		t.Fatalf("Import failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, testOwnerId, "dst", data, false)
+}
+
+func Test_ExportImportCsv(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	data := map[string]string{
+		"u1": `{"user": {"name": "Ann, Jr."}, "age": 31, "admin": true}`,
+		"u2": `{"user": {"name": "Bob"}, "age": 5, "admin": false}`,
+	}
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, data)
+	cols, err := ParseCsvColumns("id=@key,name=user.name,age:number,admin:bool")
+	if err != nil {
+		t.Fatalf("Failed to parse columns: %s", err.Error())
+	}
+	var buf bytes.Buffer
+	_, err = z.ExportTable(testOwnerId, "src", &buf, &ExportOptions{Format: ExportCsv, Columns: cols})
+	if err != nil {
+		t.Fatalf("Export failed: %s", err.Error())
+	}
+	want := "id,name,age,admin\nu1,\"Ann, Jr.\",31,true\nu2,Bob,5,false\n"
+	if buf.String() != want {
+		t.Fatalf("Unexpected CSV:\n%s", buf.String())
+	}
+	z.CreateTable("dst", zbprotocol.TableDataFormat_JSON, nil, nil, false)
+	_, err = z.ImportTable(testOwnerId, "dst", &buf, &ImportOptions{Format: ExportCsv, Columns: cols})
+	if err != nil {
+		t.Fatalf("Import failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, testOwnerId, "dst", data, true)
+}
+
+func Test_ExportImportTarSnapshot(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	data := map[string]string{"k1": "hello", "k2": "world"}
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, data)
+	var buf bytes.Buffer
+	_, err := z.ExportTable(testOwnerId, "src", &buf, &ExportOptions{Format: ExportTar, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Export failed: %s", err.Error())
+	}
+
+	// Restore into a different account
+	otherId := "66666666-2222-3333-4444-555555555555"
+	z2, srv2 := newFakeClient(otherId)
+	snapshot := bytes.NewReader(buf.Bytes())
+	n, err := z2.ImportTable(otherId, "restored", snapshot, &ImportOptions{Format: ExportTar, CreateTable: true})
+	if err != nil || n != 2 {
+		t.Fatalf("Import failed: %v (%d records)", err, n)
+	}
+	checkTableContents(t, srv2, otherId, "restored", data, false)
+	defn := srv2.Table(otherId, "restored").Defn
+	if defn.DataFormat != zbprotocol.TableDataFormat_PLAIN_TEXT || len(defn.GetIndices().GetFields()) != 1 || len(defn.Permissions) != 1 {
+		t.Fatalf("Table definition not restored: %v", defn)
+	}
+	if defn.Permissions[0].Level != zbprotocol.PermissionLevel_READ || defn.Permissions[0].AudienceType != zbprotocol.PermissionAudienceType_USER {
+		t.Fatalf("Permission not restored: %v", defn.Permissions[0])
+	}
+	snapshot.Seek(0, io.SeekStart)
+	if _, err := z2.ImportTable(otherId, "restored", snapshot, &ImportOptions{Format: ExportTar, CreateTable: true, Overwrite: true}); err != nil {
+		t.Fatalf("Import into an existing table failed: %s", err.Error())
+	}
+
+	// Tables are only created for the client's own account
+	snapshot.Seek(0, io.SeekStart)
+	_, err = z2.ImportTable(testOwnerId, "restored", snapshot, &ImportOptions{Format: ExportTar, CreateTable: true})
+	if !errors.Is(err, ErrCannotCreateTableForOtherOwner) || srv2.Table(otherId, "restored") == nil {
+		t.Fatalf("Expected CannotCreateTableForOtherOwner, got: %v", err)
+	}
+}
+
+func Test_ExportFailsOnUnreadableRecords(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"k1": "a", "k2": "b"})
+	srv.GetErr = errors.New("Unavailable")
+	var buf bytes.Buffer
+	if n, err := z.ExportTable(testOwnerId, "src", &buf, &ExportOptions{Format: ExportNdjson}); err == nil {
+		t.Fatalf("Export of unreadable records succeeded (%d records)", n)
+	}
+}
@@ -0,0 +1,254 @@
+package zetabase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Separator between the segments of a Key
+	KeySeparator = "/"
+	// Characters in segments that would clash with the separator or with key patterns are
+	// written as keyEscape followed by two hex digits
+	keyEscape      = '~'
+	keyEscapeChars = "%/~"
+	// Width of the order-preserving integer segments
+	keyIntDigits = 16
+)
+
+// Type Key is a table key made of segments, like tweet/<uid>/<ts>. Segments are escaped when the
+// key is written out, so they may contain any character, and AppendInt and AppendTime encode
+// numbers so that keys sort in numeric order. A Key is never modified in place.
+type Key []string
+
+// Function NewKey returns a key made of the given segments.
+func NewKey(segments ...string) Key {
+	return Key(append([]string{}, segments...))
+}
+
+// Function ParseKey splits a key written by Key.String back into its segments.
+func ParseKey(s string) (Key, error) {
+	if len(s) == 0 {
+		return Key{}, nil
+	}
+	parts := strings.Split(s, KeySeparator)
+	res := make(Key, len(parts))
+	for i, p := range parts {
+		seg, err := unescapeKeySegment(p)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = seg
+	}
+	return res, nil
+}
+
+func escapeKeySegment(seg string) string {
+	if !strings.ContainsAny(seg, keyEscapeChars) {
+		return seg
+	}
+	var sb strings.Builder
+	for i := 0; i < len(seg); i++ {
+		if strings.IndexByte(keyEscapeChars, seg[i]) >= 0 {
+			fmt.Fprintf(&sb, "%c%02X", keyEscape, seg[i])
+		} else {
+			sb.WriteByte(seg[i])
+		}
+	}
+	return sb.String()
+}
+
+func unescapeKeySegment(s string) (string, error) {
+	if strings.IndexByte(s, keyEscape) < 0 {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != keyEscape {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", errors.New("InvalidKeyEscape")
+		}
+		b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", errors.New("InvalidKeyEscape")
+		}
+		sb.WriteByte(byte(b))
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+// Method Append returns the key extended with the given segments.
+func (k Key) Append(segments ...string) Key {
+	res := make(Key, 0, len(k)+len(segments))
+	return append(append(res, k...), segments...)
+}
+
+// Method AppendInt returns the key extended with an integer segment that sorts in numeric order,
+// negative numbers included.
+func (k Key) AppendInt(n int64) Key {
+	return k.Append(fmt.Sprintf("%0*x", keyIntDigits, uint64(n)^(1<<63)))
+}
+
+// Method AppendTime returns the key extended with a timestamp segment that sorts in time order.
+func (k Key) AppendTime(t time.Time) Key {
+	return k.AppendInt(t.UnixNano())
+}
+
+// Method Parent returns the key without its last segment.
+func (k Key) Parent() Key {
+	if len(k) == 0 {
+		return k
+	}
+	return NewKey(k[:len(k)-1]...)
+}
+
+// Method Segment returns the i-th segment, or an empty string if there is none.
+func (k Key) Segment(i int) string {
+	if i < 0 || i >= len(k) {
+		return ""
+	}
+	return k[i]
+}
+
+// Method Int decodes the i-th segment as written by AppendInt.
+func (k Key) Int(i int) (int64, error) {
+	seg := k.Segment(i)
+	if len(seg) != keyIntDigits {
+		return 0, errors.New("NotAnIntSegment")
+	}
+	n, err := strconv.ParseUint(seg, 16, 64)
+	if err != nil {
+		return 0, errors.New("NotAnIntSegment")
+	}
+	return int64(n ^ (1 << 63)), nil
+}
+
+// Method Time decodes the i-th segment as written by AppendTime.
+func (k Key) Time(i int) (time.Time, error) {
+	n, err := k.Int(i)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, n), nil
+}
+
+// Method HasPrefix returns whether the key starts with the segments of prefix.
+func (k Key) HasPrefix(prefix Key) bool {
+	if len(prefix) > len(k) {
+		return false
+	}
+	for i := range prefix {
+		if k[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Method String returns the key as stored in a table, with its segments escaped.
+func (k Key) String() string {
+	parts := make([]string, len(k))
+	for i, seg := range k {
+		parts[i] = escapeKeySegment(seg)
+	}
+	return strings.Join(parts, KeySeparator)
+}
+
+// Method Pattern returns the pattern for ListKeysWithPattern that matches every key below this
+// one (but not the key itself). The empty key matches every key.
+func (k Key) Pattern() string {
+	if len(k) == 0 {
+		return "%"
+	}
+	return k.String() + KeySeparator + "%"
+}
+
+// Type KeyRange iterates in order over the keys of a table below a prefix, from a start key
+// (inclusive) to an end key (exclusive). Use it like:
+//
+//	r := z.KeyRange(owner, "tweets", NewKey("tweet", uid), NewKey().AppendTime(since), nil)
+//	for r.Next() {
+//		fmt.Println(r.Key())
+//	}
+//	if r.Err() != nil { ... }
+type KeyRange struct {
+	pages *PaginationHandler
+	from  string
+	to    string
+	keys  []string
+	pos   int
+	err   error
+}
+
+// Method KeyRange returns an iterator over the keys of a table below prefix, starting from
+// prefix+from and stopping before prefix+to. Either bound may be nil to leave that end open. Keys
+// are compared in their stored form, which is numeric order for AppendInt and AppendTime segments.
+func (z *ZetabaseClient) KeyRange(tableOwnerId, tableId string, prefix, from, to Key) *KeyRange {
+	r := &KeyRange{pages: z.ListKeysWithPattern(tableOwnerId, tableId, prefix.Pattern()), pos: -1}
+	if from != nil {
+		r.from = prefix.Append(from...).String()
+	}
+	if to != nil {
+		r.to = prefix.Append(to...).String()
+	}
+	return r
+}
+
+func (r *KeyRange) load() {
+	keys, err := r.pages.KeysAll()
+	if err != nil {
+		r.err = err
+		return
+	}
+	for _, k := range withoutReservedKeys(keys) {
+		if k >= r.from && (len(r.to) == 0 || k < r.to) {
+			r.keys = append(r.keys, k)
+		}
+	}
+	sort.Strings(r.keys)
+}
+
+// Method Next moves to the next key in the range, returning false when there are none left or
+// listing the keys failed (see Err).
+func (r *KeyRange) Next() bool {
+	if r.pos < 0 {
+		r.load()
+	}
+	if r.err != nil || r.pos+1 >= len(r.keys) {
+		r.pos = len(r.keys)
+		return false
+	}
+	r.pos++
+	return true
+}
+
+// Method Key returns the current key, parsed into segments. Keys not written with Key come back
+// as best parsed, unescaped where possible.
+func (r *KeyRange) Key() Key {
+	k, err := ParseKey(r.RawKey())
+	if err != nil {
+		return Key(strings.Split(r.RawKey(), KeySeparator))
+	}
+	return k
+}
+
+// Method RawKey returns the current key as stored in the table.
+func (r *KeyRange) RawKey() string {
+	if r.pos < 0 || r.pos >= len(r.keys) {
+		return ""
+	}
+	return r.keys[r.pos]
+}
+
+// Method Err returns the error that stopped the iteration, if any.
+func (r *KeyRange) Err() error {
+	return r.err
+}
@@ -0,0 +1,81 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"sort"
+	"testing"
+	"time"
+)
+
+func Test_KeyEscaping(t *testing.T) {
+	k := NewKey("tweet", "50%/off~", "")
+	s := k.String()
+	if s != "tweet/50~25~2Foff~7E/" {
+		t.Fatalf("Unexpected escaped key: %s", s)
+	}
+	back, err := ParseKey(s)
+	if err != nil || len(back) != 3 || back[1] != "50%/off~" || back[2] != "" {
+		t.Fatalf("Key round trip failed: %q (%v)", back, err)
+	}
+	if _, err := ParseKey("a/b~2"); err == nil {
+		t.Fatalf("Expected an error for a truncated escape")
+	}
+	if p := NewKey("a%").Pattern(); p != "a~25/%" {
+		t.Fatalf("Unexpected pattern: %s", p)
+	}
+}
+
+func Test_KeyOrderedSegments(t *testing.T) {
+	nums := []int64{-1 << 63, -1000, -1, 0, 1, 9, 10, 1000, 1<<63 - 1}
+	var keys []string
+	for i := len(nums) - 1; i >= 0; i-- {
+		keys = append(keys, NewKey("n").AppendInt(nums[i]).String())
+	}
+	sort.Strings(keys)
+	for i, s := range keys {
+		k, _ := ParseKey(s)
+		if n, err := k.Int(1); err != nil || n != nums[i] {
+			t.Fatalf("Integer segments out of order at %d: %d (%v)", i, n, err)
+		}
+	}
+	now := time.Now()
+	if tm, err := NewKey().AppendTime(now).Time(0); err != nil || !tm.Equal(now) {
+		t.Fatalf("Time round trip failed: %v (%v)", tm, err)
+	}
+	if _, err := NewKey("x").Int(0); err == nil || err.Error() != "NotAnIntSegment" {
+		t.Fatalf("Expected NotAnIntSegment, got %v", err)
+	}
+}
+
+func Test_KeyRange(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	if err := z.CreateTable("src", zbprotocol.TableDataFormat_PLAIN_TEXT, nil, nil, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	user := NewKey("tweet", "u%1")
+	for _, ts := range []int64{5, 100, 20, -3, 7} {
+		z.PutData(testOwnerId, "src", user.AppendInt(ts).String(), []byte("x"), true)
+	}
+	z.PutData(testOwnerId, "src", NewKey("tweet", "u%10").AppendInt(6).String(), []byte("x"), true)
+	z.PutData(testOwnerId, "src", user.String(), []byte("x"), true)
+
+	r := z.KeyRange(testOwnerId, "src", user, NewKey().AppendInt(0), NewKey().AppendInt(100))
+	var got []int64
+	for r.Next() {
+		n, err := r.Key().Int(2)
+		if err != nil || r.Key().Segment(1) != "u%1" {
+			t.Fatalf("Unexpected key in range: %s", r.RawKey())
+		}
+		got = append(got, n)
+	}
+	if r.Err() != nil || len(got) != 3 || got[0] != 5 || got[1] != 7 || got[2] != 20 {
+		t.Fatalf("Unexpected range: %v (%v)", got, r.Err())
+	}
+
+	n := 0
+	for r = z.KeyRange(testOwnerId, "src", NewKey("tweet"), nil, nil); r.Next(); n++ {
+	}
+	if n != 7 {
+		t.Fatalf("Expected 7 keys below tweet, got %d", n)
+	}
+}
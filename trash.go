@@ -0,0 +1,349 @@
+package zetabase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Deleted tables are kept as tables named TrashTablePrefix + "<deletion time>_<table>"
+	TrashTablePrefix          = "zbtrash_"
+	DefaultTrashPurgeInterval = time.Hour
+	trashNamespace            = "trash"
+)
+
+// Type SoftDeleteOptions configures soft deletes (see SetSoftDelete).
+type SoftDeleteOptions struct {
+	Retention     time.Duration // trash older than this is purged (0: kept until purged by hand)
+	PurgeInterval time.Duration // how often to purge in the background (negative: never)
+}
+
+// Type TrashEntry is a deleted key or table in the trash.
+type TrashEntry struct {
+	TableId   string // the table the key was deleted from, or the deleted table
+	Key       string // empty for a deleted table
+	DeletedAt time.Time
+	Value     []byte // value of a deleted key
+	trashId   string // key of the tombstone record, or the trash table
+}
+
+type softDeleteMode struct {
+	opts      SoftDeleteOptions
+	stop      chan struct{}
+	closeOnce sync.Once
+	// Tables trashed into by this client, purged in the background
+	tables        sync.Map
+	tablesTrashed int32
+}
+
+// Method SetSoftDelete turns on soft deletes for everything the client deletes (nil turns them
+// off again): DeleteKey moves values to the table's trash, and DeleteTable moves the table's
+// contents to a trash table, until they are restored or purged. Trash older than opts.Retention is purged in
+// the background from the tables the client deleted from.
+func (z *ZetabaseClient) SetSoftDelete(opts *SoftDeleteOptions) {
+	mode := &softDeleteMode{stop: make(chan struct{})}
+	if opts != nil {
+		mode.opts = *opts
+		if mode.opts.PurgeInterval == 0 {
+			mode.opts.PurgeInterval = DefaultTrashPurgeInterval
+		}
+	}
+	if prev := z.softDeleteMode(); prev != nil {
+		prev.closeOnce.Do(func() { close(prev.stop) })
+	}
+	if opts == nil {
+		z.softDelete.Store((*softDeleteMode)(nil))
+		return
+	}
+	z.softDelete.Store(mode)
+	if mode.opts.Retention > 0 && mode.opts.PurgeInterval > 0 {
+		go z.purgeLoop(mode)
+	}
+}
+
+func (z *ZetabaseClient) softDeleteMode() *softDeleteMode {
+	mode, _ := z.softDelete.Load().(*softDeleteMode)
+	return mode
+}
+
+func tombstoneKey(key string, deletedAt int64) string {
+	return stampedKey(trashNamespace, key, deletedAt)
+}
+
+// Tombstone puts that move the current values of deleted keys to the trash
+func (z *ZetabaseClient) withTombstones(tableOwnerId, tableId string, puts []*zbprotocol.DataPair, deletes []string) ([]*zbprotocol.DataPair, error) {
+	mode := z.softDeleteMode()
+	if mode == nil {
+		return puts, nil
+	}
+	var keys []string
+	for _, k := range deletes {
+		if !IsReservedKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return puts, nil
+	}
+	cur, err := z.getPag(tableOwnerId, tableId, keys).DataAll()
+	if err != nil {
+		return nil, err
+	}
+	res := append([]*zbprotocol.DataPair{}, puts...)
+	for _, k := range keys {
+		if v, ok := cur[k]; ok {
+			res = append(res, &zbprotocol.DataPair{Key: tombstoneKey(k, z.nextVersion()), Value: v})
+		}
+	}
+	mode.tables.Store(cacheTableKey(tableOwnerId, tableId), [2]string{tableOwnerId, tableId})
+	return res, nil
+}
+
+func (z *ZetabaseClient) trashKey(tableOwnerId, tableId, key string) error {
+	tomb, err := z.withTombstones(tableOwnerId, tableId, nil, []string{key})
+	if err != nil {
+		return err
+	}
+	if len(tomb) > 0 {
+		if err := z.PutMulti(tableOwnerId, tableId, []string{tomb[0].Key}, [][]byte{tomb[0].Value}, true); err != nil {
+			return err
+		}
+	}
+	return z.deleteKeyRaw(tableOwnerId, tableId, key)
+}
+
+// Method ListTrash lists the keys deleted from a table that are in its trash, oldest first.
+func (z *ZetabaseClient) ListTrash(tableOwnerId, tableId string) ([]*TrashEntry, error) {
+	keys, err := z.listKeysRemote(tableOwnerId, tableId, reservedKey(trashNamespace)+"%").KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	var res []*TrashEntry
+	var fetch []string
+	for _, k := range keys {
+		if key, stamp, ok := parseStampedKey(trashNamespace, k); ok {
+			res = append(res, &TrashEntry{TableId: tableId, Key: key, DeletedAt: time.Unix(0, stamp), trashId: k})
+			fetch = append(fetch, k)
+		}
+	}
+	data := map[string][]byte{}
+	for i := 0; i < len(fetch); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(fetch) {
+			j = len(fetch)
+		}
+		m, err := z.getPag(tableOwnerId, tableId, fetch[i:j]).DataAll()
+		if err != nil {
+			return nil, err
+		}
+		addData(data, m)
+	}
+	for _, e := range res {
+		e.Value = data[e.trashId]
+	}
+	sortTrash(res)
+	return res, nil
+}
+
+func sortTrash(entries []*TrashEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].DeletedAt.Before(entries[j].DeletedAt) })
+}
+
+// Method RestoreKey puts back the most recently deleted value of key from the table's trash. It
+// fails with KeyAlreadyExists if the key has been written since.
+func (z *ZetabaseClient) RestoreKey(tableOwnerId, tableId, key string) error {
+	entries, err := z.ListTrash(tableOwnerId, tableId)
+	if err != nil {
+		return err
+	}
+	var found *TrashEntry
+	for _, e := range entries {
+		if e.Key == key {
+			found = e
+		}
+	}
+	if found == nil {
+		return errors.New("NotInTrash")
+	}
+	if err := z.CompareAndSwap(tableOwnerId, tableId, key, nil, found.Value); err != nil {
+		if errors.Is(err, ErrCompareAndSwapFailed) {
+			return ErrKeyAlreadyExists
+		}
+		return err
+	}
+	return z.deleteKeyRaw(tableOwnerId, tableId, found.trashId)
+}
+
+// Method PurgeTrash permanently deletes the keys in a table's trash that were deleted longer than
+// olderThan ago. Returns the number of keys purged.
+func (z *ZetabaseClient) PurgeTrash(tableOwnerId, tableId string, olderThan time.Duration) (int, error) {
+	keys, err := z.listKeysRemote(tableOwnerId, tableId, reservedKey(trashNamespace)+"%").KeysAll()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan).UnixNano()
+	n := 0
+	for _, k := range keys {
+		if _, stamp, ok := parseStampedKey(trashNamespace, k); ok && stamp <= cutoff {
+			if err := z.deleteKeyRaw(tableOwnerId, tableId, k); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func trashTableName(tableId string, deletedAt int64) string {
+	return fmt.Sprintf("%s%0*x_%s", TrashTablePrefix, stampDigits, deletedAt, tableId)
+}
+
+func parseTrashTableName(name string) (string, int64, bool) {
+	rest := strings.TrimPrefix(name, TrashTablePrefix)
+	if len(rest) == len(name) || len(rest) < stampDigits+2 || rest[stampDigits] != '_' {
+		return "", 0, false
+	}
+	stamp, err := strconv.ParseInt(rest[:stampDigits], 16, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return rest[stampDigits+1:], stamp, true
+}
+
+func trashedDefinitionKey() string {
+	return reservedKey(trashNamespace, "table")
+}
+
+// Copy a table into a new trash table, with its full definition kept for restoring it. Only the
+// client's own tables can be moved to the trash.
+func (z *ZetabaseClient) trashTable(tableOwnerId, tableId string) error {
+	if tableOwnerId != z.Id() {
+		return ErrCannotCreateTableForOtherOwner
+	}
+	defn, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err != nil {
+		return err
+	}
+	bs, err := marshalTableDefinition(defn)
+	if err != nil {
+		return err
+	}
+	trashId := trashTableName(tableId, z.nextVersion())
+	if _, err := CopyTable(z, tableOwnerId, tableId, z, tableOwnerId, trashId, &CopyOptions{Overwrite: true}); err != nil {
+		return err
+	}
+	if err := z.PutMulti(tableOwnerId, trashId, []string{trashedDefinitionKey()}, [][]byte{bs}, true); err != nil {
+		return err
+	}
+	if mode := z.softDeleteMode(); mode != nil {
+		atomic.StoreInt32(&mode.tablesTrashed, 1)
+	}
+	return z.deleteTableRaw(tableOwnerId, tableId)
+}
+
+// Method ListTrashedTables lists the client's deleted tables that are in the trash, oldest first.
+func (z *ZetabaseClient) ListTrashedTables() ([]*TrashEntry, error) {
+	names, err := z.ListTables()
+	if err != nil {
+		return nil, err
+	}
+	var res []*TrashEntry
+	for _, name := range names {
+		if tbl, stamp, ok := parseTrashTableName(name); ok {
+			res = append(res, &TrashEntry{TableId: tbl, DeletedAt: time.Unix(0, stamp), trashId: name})
+		}
+	}
+	sortTrash(res)
+	return res, nil
+}
+
+// Method RestoreTable recreates the most recently deleted table named tableId from the trash,
+// with its original definition and permissions.
+func (z *ZetabaseClient) RestoreTable(tableId string) error {
+	tableOwnerId := z.Id()
+	entries, err := z.ListTrashedTables()
+	if err != nil {
+		return err
+	}
+	var found *TrashEntry
+	for _, e := range entries {
+		if e.TableId == tableId {
+			found = e
+		}
+	}
+	if found == nil {
+		return errors.New("NotInTrash")
+	}
+	if _, err := z.GetTableDefinition(tableOwnerId, tableId); err == nil {
+		return ErrTableExists
+	}
+	bs, ok, err := z.getUncached(tableOwnerId, found.trashId, trashedDefinitionKey())
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.New("TrashedTableDefinitionMissing")
+	}
+	defn, err := unmarshalTableDefinition(bs)
+	if err != nil {
+		return err
+	}
+	if err := z.CreateTableFromDefinition(tableId, defn); err != nil {
+		return err
+	}
+	if _, err := CopyTable(z, tableOwnerId, found.trashId, z, tableOwnerId, tableId, &CopyOptions{Overwrite: true}); err != nil {
+		return err
+	}
+	if err := z.deleteKeyRaw(tableOwnerId, tableId, trashedDefinitionKey()); err != nil {
+		return err
+	}
+	return z.deleteTableRaw(tableOwnerId, found.trashId)
+}
+
+// Method PurgeTrashedTables permanently deletes the client's trashed tables that were deleted
+// longer than olderThan ago. Returns the number of tables purged.
+func (z *ZetabaseClient) PurgeTrashedTables(olderThan time.Duration) (int, error) {
+	entries, err := z.ListTrashedTables()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	n := 0
+	for _, e := range entries {
+		if e.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := z.deleteTableRaw(z.Id(), e.trashId); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (z *ZetabaseClient) purgeLoop(mode *softDeleteMode) {
+	tkr := time.NewTicker(mode.opts.PurgeInterval)
+	defer tkr.Stop()
+	for {
+		select {
+		case <-mode.stop:
+			return
+		case <-tkr.C:
+			// Failures are retried at the next interval
+			mode.tables.Range(func(_, v interface{}) bool {
+				t := v.([2]string)
+				z.PurgeTrash(t[0], t[1], mode.opts.Retention)
+				return true
+			})
+			if atomic.LoadInt32(&mode.tablesTrashed) != 0 {
+				z.PurgeTrashedTables(mode.opts.Retention)
+			}
+		}
+	}
+}
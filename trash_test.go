@@ -0,0 +1,74 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"testing"
+	"time"
+)
+
+func Test_SoftDeleteKeys(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2", "c": "3"})
+	z.SetSoftDelete(&SoftDeleteOptions{PurgeInterval: -1})
+	defer z.SetSoftDelete(nil)
+
+	z.DeleteKey(testOwnerId, "src", "a")
+	if err := z.NewBatch(testOwnerId, "src").Delete("b").Put("d", []byte("4")).Commit(); err != nil {
+		t.Fatalf("Batch failed: %s", err.Error())
+	}
+	entries, err := z.ListTrash(testOwnerId, "src")
+	if err != nil || len(entries) != 2 || entries[0].Key != "a" || string(entries[1].Value) != "2" {
+		t.Fatalf("Unexpected trash: %v (%v)", entries, err)
+	}
+	if keys, _ := z.ListKeys(testOwnerId, "src").KeysAll(); len(withoutReservedKeys(keys)) != 2 {
+		t.Fatalf("Deleted keys still present: %v", keys)
+	}
+
+	if err := z.RestoreKey(testOwnerId, "src", "a"); err != nil {
+		t.Fatalf("RestoreKey failed: %s", err.Error())
+	}
+	z.PutData(testOwnerId, "src", "b", []byte("new"), true)
+	if err := z.RestoreKey(testOwnerId, "src", "b"); err == nil || err.Error() != "KeyAlreadyExists" {
+		t.Fatalf("Expected KeyAlreadyExists, got %v", err)
+	}
+	if n, _ := z.PurgeTrash(testOwnerId, "src", time.Hour); n != 0 {
+		t.Fatalf("Purged %d recent keys", n)
+	}
+	if n, _ := z.PurgeTrash(testOwnerId, "src", 0); n != 1 {
+		t.Fatalf("Expected to purge 1 key, purged %d", n)
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"a": "1", "b": "new", "c": "3", "d": "4"}, false)
+}
+
+func Test_SoftDeleteTable(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_JSON, map[string]string{"a": `{"age": 1}`})
+	z.SetSoftDelete(&SoftDeleteOptions{PurgeInterval: -1})
+	defer z.SetSoftDelete(nil)
+
+	if err := z.DeleteTable(testOwnerId, "src"); err != nil {
+		t.Fatalf("DeleteTable failed: %s", err.Error())
+	}
+	entries, err := z.ListTrashedTables()
+	if err != nil || len(entries) != 1 || entries[0].TableId != "src" || srv.Table(testOwnerId, "src") != nil {
+		t.Fatalf("Table not moved to trash: %v (%v)", entries, err)
+	}
+	if err := z.RestoreTable("src"); err != nil {
+		t.Fatalf("RestoreTable failed: %s", err.Error())
+	}
+	checkTableContents(t, srv, testOwnerId, "src", map[string]string{"a": `{"age": 1}`}, true)
+	if perms := srv.Table(testOwnerId, "src").Defn.GetPermissions(); len(perms) != 1 {
+		t.Fatalf("Permissions not restored: %v", perms)
+	}
+	if entries, _ := z.ListTrashedTables(); len(entries) != 0 {
+		t.Fatalf("Trash table not removed after restore: %v", entries)
+	}
+
+	z.DeleteTable(testOwnerId, "src")
+	if n, err := z.PurgeTrashedTables(0); err != nil || n != 1 {
+		t.Fatalf("Unexpected purge: %v (%d tables)", err, n)
+	}
+	if err := z.RestoreTable("src"); err == nil || err.Error() != "NotInTrash" {
+		t.Fatalf("Expected NotInTrash, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package zetabase
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Field of the expiry records written by PutWithTTL, holding the expiry time in milliseconds
+	// since the epoch. Index it (see TTLIndex) so that sweeps can query for expired keys.
+	TTLExpiryField             = "zbExpiresAt"
+	DefaultExpirySweepInterval = time.Minute
+	ttlNamespace               = "ttl"
+)
+
+// Type ExpiryOptions configures how expired keys are swept from a table (see EnableExpiry).
+type ExpiryOptions struct {
+	SweepInterval time.Duration // how often to sweep in the background (negative: never)
+	BatchSize     int           // expired keys handled per round trip
+}
+
+// Expiry record kept beside a key. The hash ties it to the value written with the TTL, so that
+// writing the key again without a TTL makes it permanent.
+type ttlRecord struct {
+	ExpiresAt int64  `json:"zbExpiresAt"`
+	ValueHash string `json:"valueHash"`
+}
+
+type expiringTable struct {
+	opts      ExpiryOptions
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// Function TTLIndex returns the index to include when creating a table for PutWithTTL, which lets
+// SweepExpired find expired keys with a query instead of reading every expiry record.
+func TTLIndex() *IndexedField {
+	return NewIndexedField(TTLExpiryField, zbprotocol.QueryOrdering_INTEGRAL_NUMBERS)
+}
+
+func ttlKey(key string) string {
+	return reservedKey(ttlNamespace, key)
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Method PutWithTTL writes valu to key and makes the key expire after ttl: from then on Get and
+// the other reads of this client hide it, and SweepExpired deletes it.
+func (z *ZetabaseClient) PutWithTTL(tableOwnerId, tableId, key string, valu []byte, ttl time.Duration) error {
+	if IsReservedKey(key) {
+		return errors.New("ReservedKey")
+	}
+	rec, err := json.Marshal(&ttlRecord{
+		ExpiresAt: nowMillis() + int64(ttl/time.Millisecond),
+		ValueHash: hex.EncodeToString(ValueHash(valu)),
+	})
+	if err != nil {
+		return err
+	}
+	z.expiring.LoadOrStore(cacheTableKey(tableOwnerId, tableId), &expiringTable{opts: ExpiryOptions{SweepInterval: -1}, stop: make(chan struct{})})
+	return z.PutMulti(tableOwnerId, tableId, []string{key, ttlKey(key)}, [][]byte{valu, rec}, true)
+}
+
+// Method EnableExpiry makes the client hide expired keys in a table (which PutWithTTL does by
+// itself for the tables it writes to) and sweep them in the background according to opts.
+func (z *ZetabaseClient) EnableExpiry(tableOwnerId, tableId string, opts *ExpiryOptions) {
+	et := &expiringTable{stop: make(chan struct{})}
+	if opts != nil {
+		et.opts = *opts
+	}
+	if et.opts.SweepInterval == 0 {
+		et.opts.SweepInterval = DefaultExpirySweepInterval
+	}
+	if prev, ok := z.expiring.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		prev.(*expiringTable).close()
+	}
+	z.expiring.Store(cacheTableKey(tableOwnerId, tableId), et)
+	if et.opts.SweepInterval > 0 {
+		go z.sweepLoop(tableOwnerId, tableId, et)
+	}
+}
+
+// Method DisableExpiry stops hiding and sweeping expired keys in a table.
+func (z *ZetabaseClient) DisableExpiry(tableOwnerId, tableId string) {
+	if et, ok := z.expiring.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		et.(*expiringTable).close()
+		z.expiring.Delete(cacheTableKey(tableOwnerId, tableId))
+	}
+}
+
+func (et *expiringTable) close() {
+	et.closeOnce.Do(func() { close(et.stop) })
+}
+
+func (z *ZetabaseClient) expiringTable(tableOwnerId, tableId string) *expiringTable {
+	if et, ok := z.expiring.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		return et.(*expiringTable)
+	}
+	return nil
+}
+
+// Whether an expiry record applies to valu and has passed
+func (r *ttlRecord) expired(valu []byte, now int64) bool {
+	return r.ExpiresAt <= now && r.ValueHash == hex.EncodeToString(ValueHash(valu))
+}
+
+// Drop expired keys from data read from a table
+func (z *ZetabaseClient) withoutExpired(tableOwnerId, tableId string, data map[string][]byte) (map[string][]byte, error) {
+	if z.expiringTable(tableOwnerId, tableId) == nil || len(data) == 0 {
+		return data, nil
+	}
+	var ttlKeys []string
+	for k := range data {
+		if !IsReservedKey(k) {
+			ttlKeys = append(ttlKeys, ttlKey(k))
+		}
+	}
+	recs, err := z.getPag(tableOwnerId, tableId, ttlKeys).DataAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return data, nil
+	}
+	now := nowMillis()
+	res := map[string][]byte{}
+	for k, v := range data {
+		var r ttlRecord
+		if bs, ok := recs[ttlKey(k)]; ok && json.Unmarshal(bs, &r) == nil && r.expired(v, now) {
+			continue
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
+func (z *ZetabaseClient) hasTTLIndex(tableOwnerId, tableId string) bool {
+	defn, err := z.GetTableDefinition(tableOwnerId, tableId)
+	if err != nil {
+		return false
+	}
+	for _, f := range defn.GetIndices().GetFields() {
+		if f.GetField() == TTLExpiryField {
+			return true
+		}
+	}
+	return false
+}
+
+// Method SweepExpired deletes the expired keys in a table (with DeleteKey) along with their
+// expiry records. Expired keys are found with a query if the table has TTLIndex, and otherwise by
+// reading all expiry records. Returns the number of keys deleted.
+func (z *ZetabaseClient) SweepExpired(tableOwnerId, tableId string) (int, error) {
+	batchSize := DefaultCopyPageSize
+	if et := z.expiringTable(tableOwnerId, tableId); et != nil && et.opts.BatchSize > 0 {
+		batchSize = et.opts.BatchSize
+	}
+	now := nowMillis()
+	var found []string
+	var err error
+	if z.hasTTLIndex(tableOwnerId, tableId) {
+		found, err = z.Query(tableOwnerId, tableId, QLt(TTLExpiryField, now+1)).KeysAll()
+	} else {
+		found, err = z.listKeysRemote(tableOwnerId, tableId, reservedKey(ttlNamespace)+"%").KeysAll()
+	}
+	if err != nil {
+		return 0, err
+	}
+	prefix := reservedKey(ttlNamespace)
+	var ttlKeys []string
+	for _, k := range found {
+		if strings.HasPrefix(k, prefix) {
+			ttlKeys = append(ttlKeys, k)
+		}
+	}
+
+	n := 0
+	for i := 0; i < len(ttlKeys); i += batchSize {
+		j := i + batchSize
+		if j > len(ttlKeys) {
+			j = len(ttlKeys)
+		}
+		recs, err := z.getPag(tableOwnerId, tableId, ttlKeys[i:j]).DataAll()
+		if err != nil {
+			return n, err
+		}
+		var keys []string
+		for _, k := range ttlKeys[i:j] {
+			keys = append(keys, strings.TrimPrefix(k, prefix))
+		}
+		valus, err := z.getPag(tableOwnerId, tableId, keys).DataAll()
+		if err != nil {
+			return n, err
+		}
+		for _, k := range keys {
+			var r ttlRecord
+			bs, ok := recs[ttlKey(k)]
+			if !ok || json.Unmarshal(bs, &r) != nil || r.ExpiresAt > now {
+				continue
+			}
+			if v, ok := valus[k]; ok && r.expired(v, now) {
+				if err := z.DeleteKey(tableOwnerId, tableId, k); err != nil {
+					return n, err
+				}
+				n++
+			}
+			// Otherwise the key was deleted or rewritten without a TTL, and the record is stale
+			if err := z.deleteKeyRaw(tableOwnerId, tableId, ttlKey(k)); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (z *ZetabaseClient) sweepLoop(tableOwnerId, tableId string, et *expiringTable) {
+	tkr := time.NewTicker(et.opts.SweepInterval)
+	defer tkr.Stop()
+	for {
+		select {
+		case <-et.stop:
+			return
+		case <-tkr.C:
+			// Failures are retried at the next interval
+			z.SweepExpired(tableOwnerId, tableId)
+		}
+	}
+}
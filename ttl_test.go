@@ -0,0 +1,40 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"testing"
+	"time"
+)
+
+func Test_ExpiringKeys(t *testing.T) {
+	for _, indexed := range []bool{true, false} {
+		z, srv := newFakeClient(testOwnerId)
+		idx := []*IndexedField{NewIndexedField("age", zbprotocol.QueryOrdering_INTEGRAL_NUMBERS)}
+		if indexed {
+			idx = append(idx, TTLIndex())
+		}
+		if err := z.CreateTable("src", zbprotocol.TableDataFormat_JSON, idx, nil, false); err != nil {
+			t.Fatalf("Failed to create table: %s", err.Error())
+		}
+		z.PutWithTTL(testOwnerId, "src", "gone", []byte(`{"age": 1}`), -time.Second)
+		z.PutWithTTL(testOwnerId, "src", "rewritten", []byte(`{"age": 2}`), -time.Second)
+		z.PutData(testOwnerId, "src", "rewritten", []byte(`{"age": 3}`), true)
+		z.PutWithTTL(testOwnerId, "src", "live", []byte(`{"age": 4}`), time.Hour)
+		z.PutData(testOwnerId, "src", "plain", []byte(`{"age": 5}`), true)
+
+		data, err := z.Get(testOwnerId, "src", []string{"gone", "rewritten", "live", "plain"}).DataAll()
+		if err != nil || len(data) != 3 || data["gone"] != nil {
+			t.Fatalf("Expired key not hidden: %v (%v)", data, err)
+		}
+		n, err := z.SweepExpired(testOwnerId, "src")
+		if err != nil || n != 1 {
+			t.Fatalf("Unexpected sweep (indexed %v): %v (%d deleted)", indexed, err, n)
+		}
+		if _, ok := srv.Table(testOwnerId, "src").Data["gone"]; ok {
+			t.Fatalf("Expired key not deleted")
+		}
+		if keys, _ := z.ListKeys(testOwnerId, "src").KeysAll(); len(keys) != 4 {
+			t.Fatalf("Unexpected keys after sweep: %v", keys)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package zetabase
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultVersionPruneInterval = time.Hour
+	versionNamespace            = "v"
+)
+
+// Type VersioningOptions sets how long old versions of keys are kept. The most recent version of
+// each key is always kept.
+type VersioningOptions struct {
+	KeepVersions  int           // versions kept per key (0: no limit)
+	KeepFor       time.Duration // versions older than this are pruned (0: no limit)
+	PruneInterval time.Duration // how often to prune in the background (negative: never)
+}
+
+// Type KeyVersion is a past value of a key. Versions are timestamps in nanoseconds.
+type KeyVersion struct {
+	Version int64
+	Value   []byte
+}
+
+// Method Time returns when the version was written.
+func (v *KeyVersion) Time() time.Time {
+	return time.Unix(0, v.Version)
+}
+
+type versionedTable struct {
+	opts      VersioningOptions
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func (vt *versionedTable) close() {
+	vt.closeOnce.Do(func() { close(vt.stop) })
+}
+
+func versionKey(key string, v int64) string {
+	return stampedKey(versionNamespace, key, v)
+}
+
+// Method EnableVersioning makes every value the client writes to a table also be stored as an
+// immutable version record, so earlier values can be read back with History and GetVersion and
+// restored with Rollback. Old versions are pruned in the background according to opts.
+func (z *ZetabaseClient) EnableVersioning(tableOwnerId, tableId string, opts *VersioningOptions) {
+	vt := &versionedTable{stop: make(chan struct{})}
+	if opts != nil {
+		vt.opts = *opts
+	}
+	if vt.opts.PruneInterval == 0 {
+		vt.opts.PruneInterval = DefaultVersionPruneInterval
+	}
+	if prev, ok := z.versioned.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		prev.(*versionedTable).close()
+	}
+	z.versioned.Store(cacheTableKey(tableOwnerId, tableId), vt)
+	if vt.opts.PruneInterval > 0 && (vt.opts.KeepVersions > 0 || vt.opts.KeepFor > 0) {
+		go z.pruneLoop(tableOwnerId, tableId, vt)
+	}
+}
+
+// Method DisableVersioning stops recording versions for a table. Existing versions are kept.
+func (z *ZetabaseClient) DisableVersioning(tableOwnerId, tableId string) {
+	if vt, ok := z.versioned.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		vt.(*versionedTable).close()
+		z.versioned.Delete(cacheTableKey(tableOwnerId, tableId))
+	}
+}
+
+func (z *ZetabaseClient) versionedTable(tableOwnerId, tableId string) *versionedTable {
+	if vt, ok := z.versioned.Load(cacheTableKey(tableOwnerId, tableId)); ok {
+		return vt.(*versionedTable)
+	}
+	return nil
+}
+
+// Next version number: the current time, kept strictly increasing within this client
+func (z *ZetabaseClient) nextVersion() int64 {
+	for {
+		last := atomic.LoadInt64(&z.lastVersion)
+		v := time.Now().UnixNano()
+		if v <= last {
+			v = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&z.lastVersion, last, v) {
+			return v
+		}
+	}
+}
+
+// Add version records for the given writes, if the table is versioned
+func (z *ZetabaseClient) withVersionRecords(tableOwnerId, tableId string, keys []string, valus [][]byte) ([]string, [][]byte) {
+	if z.versionedTable(tableOwnerId, tableId) == nil {
+		return keys, valus
+	}
+	allKeys := append([]string{}, keys...)
+	allValus := append([][]byte{}, valus...)
+	for i, k := range keys {
+		if !IsReservedKey(k) {
+			allKeys = append(allKeys, versionKey(k, z.nextVersion()))
+			allValus = append(allValus, valus[i])
+		}
+	}
+	return allKeys, allValus
+}
+
+// Write a version record for a value written other than through PutMulti
+func (z *ZetabaseClient) recordVersion(tableOwnerId, tableId, key string, valu []byte) error {
+	if z.versionedTable(tableOwnerId, tableId) == nil || IsReservedKey(key) {
+		return nil
+	}
+	return z.PutMulti(tableOwnerId, tableId, []string{versionKey(key, z.nextVersion())}, [][]byte{valu}, true)
+}
+
+// Method History returns the recorded versions of a key, oldest first.
+func (z *ZetabaseClient) History(tableOwnerId, tableId, key string) ([]*KeyVersion, error) {
+	vkeys, err := z.listKeysRemote(tableOwnerId, tableId, stampedKeyPrefix(versionNamespace, key)+"%").KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	var res []*KeyVersion
+	var fetch []string
+	for _, k := range vkeys {
+		if kk, v, ok := parseStampedKey(versionNamespace, k); ok && kk == key {
+			res = append(res, &KeyVersion{Version: v})
+			fetch = append(fetch, k)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	data := map[string][]byte{}
+	for i := 0; i < len(fetch); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(fetch) {
+			j = len(fetch)
+		}
+		m, err := z.getPag(tableOwnerId, tableId, fetch[i:j]).DataAll()
+		if err != nil {
+			return nil, err
+		}
+		addData(data, m)
+	}
+	for _, kv := range res {
+		kv.Value = data[versionKey(key, kv.Version)]
+	}
+	return res, nil
+}
+
+// Method GetVersion returns the value of key as of version v.
+func (z *ZetabaseClient) GetVersion(tableOwnerId, tableId, key string, v int64) ([]byte, error) {
+	valu, ok, err := z.getUncached(tableOwnerId, tableId, versionKey(key, v))
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("VersionNotFound")
+	}
+	return valu, nil
+}
+
+// Method Rollback restores key to its value as of version v. On a versioned table this is
+// recorded as a new version.
+func (z *ZetabaseClient) Rollback(tableOwnerId, tableId, key string, v int64) error {
+	valu, err := z.GetVersion(tableOwnerId, tableId, key, v)
+	if err != nil {
+		return err
+	}
+	return z.PutMulti(tableOwnerId, tableId, []string{key}, [][]byte{valu}, true)
+}
+
+// Method PruneVersions deletes the versions in a table that the retention settings given to
+// EnableVersioning no longer keep. Returns the number of versions deleted.
+func (z *ZetabaseClient) PruneVersions(tableOwnerId, tableId string) (int, error) {
+	vt := z.versionedTable(tableOwnerId, tableId)
+	if vt == nil {
+		return 0, errors.New("TableNotVersioned")
+	}
+	vkeys, err := z.listKeysRemote(tableOwnerId, tableId, reservedKey(versionNamespace)+"%").KeysAll()
+	if err != nil {
+		return 0, err
+	}
+	byKey := map[string][]int64{}
+	for _, k := range vkeys {
+		if kk, v, ok := parseStampedKey(versionNamespace, k); ok {
+			byKey[kk] = append(byKey[kk], v)
+		}
+	}
+	cutoff := time.Now().Add(-vt.opts.KeepFor).UnixNano()
+	n := 0
+	for k, vs := range byKey {
+		sort.Slice(vs, func(i, j int) bool { return vs[i] > vs[j] })
+		for i, v := range vs {
+			if i == 0 {
+				continue
+			}
+			if (vt.opts.KeepVersions > 0 && i >= vt.opts.KeepVersions) || (vt.opts.KeepFor > 0 && v < cutoff) {
+				if err := z.deleteKeyRaw(tableOwnerId, tableId, versionKey(k, v)); err != nil {
+					return n, err
+				}
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (z *ZetabaseClient) pruneLoop(tableOwnerId, tableId string, vt *versionedTable) {
+	tkr := time.NewTicker(vt.opts.PruneInterval)
+	defer tkr.Stop()
+	for {
+		select {
+		case <-vt.stop:
+			return
+		case <-tkr.C:
+			// Failures are retried at the next interval
+			z.PruneVersions(tableOwnerId, tableId)
+		}
+	}
+}
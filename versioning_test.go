@@ -0,0 +1,61 @@
+package zetabase
+
+import (
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"testing"
+)
+
+func Test_VersionHistoryAndRollback(t *testing.T) {
+	z, srv := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"cfg": "v0"})
+	z.EnableVersioning(testOwnerId, "src", &VersioningOptions{PruneInterval: -1})
+	defer z.DisableVersioning(testOwnerId, "src")
+
+	z.PutData(testOwnerId, "src", "cfg", []byte("v1"), true)
+	z.PutMulti(testOwnerId, "src", []string{"cfg", "cfg@other"}, [][]byte{[]byte("v2"), []byte("x")}, true)
+	if err := z.CompareAndSwap(testOwnerId, "src", "cfg", ValueHash([]byte("v2")), []byte("v3")); err != nil {
+		t.Fatalf("CompareAndSwap failed: %s", err.Error())
+	}
+	hist, err := z.History(testOwnerId, "src", "cfg")
+	if err != nil || len(hist) != 3 {
+		t.Fatalf("Unexpected history: %v (%d versions)", err, len(hist))
+	}
+	for i, want := range []string{"v1", "v2", "v3"} {
+		if string(hist[i].Value) != want {
+			t.Fatalf("Version %d is %q, expected %q", i, hist[i].Value, want)
+		}
+	}
+	if v, err := z.GetVersion(testOwnerId, "src", "cfg", hist[0].Version); err != nil || string(v) != "v1" {
+		t.Fatalf("GetVersion failed: %v", err)
+	}
+	if _, err := z.GetVersion(testOwnerId, "src", "cfg", 1); err == nil || err.Error() != "VersionNotFound" {
+		t.Fatalf("Expected VersionNotFound, got %v", err)
+	}
+	if err := z.Rollback(testOwnerId, "src", "cfg", hist[0].Version); err != nil {
+		t.Fatalf("Rollback failed: %s", err.Error())
+	}
+	if v := srv.Table(testOwnerId, "src").Data["cfg"]; string(v) != "v1" {
+		t.Fatalf("Rollback not applied: %q", v)
+	}
+	if hist, _ = z.History(testOwnerId, "src", "cfg"); len(hist) != 4 {
+		t.Fatalf("Rollback not recorded as a version (%d versions)", len(hist))
+	}
+}
+
+func Test_PruneVersions(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, nil)
+	z.EnableVersioning(testOwnerId, "src", &VersioningOptions{KeepVersions: 2, PruneInterval: -1})
+	defer z.DisableVersioning(testOwnerId, "src")
+	for _, v := range []string{"1", "2", "3", "4"} {
+		z.PutData(testOwnerId, "src", "a", []byte(v), true)
+	}
+	z.PutData(testOwnerId, "src", "b", []byte("1"), true)
+	if n, err := z.PruneVersions(testOwnerId, "src"); err != nil || n != 2 {
+		t.Fatalf("Unexpected pruning: %v (%d pruned)", err, n)
+	}
+	hist, _ := z.History(testOwnerId, "src", "a")
+	if len(hist) != 2 || string(hist[0].Value) != "3" || string(hist[1].Value) != "4" {
+		t.Fatalf("Wrong versions kept: %v", hist)
+	}
+}
@@ -0,0 +1,153 @@
+package zetabase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultWatchInterval = 2 * time.Second
+	// Idle watches slow down to at most this multiple of the requested interval
+	maxWatchBackoff = 16
+)
+
+type ChangeType int
+
+const (
+	ChangeAdded ChangeType = iota
+	ChangeModified
+	ChangeDeleted
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeAdded:
+		return "added"
+	case ChangeModified:
+		return "modified"
+	case ChangeDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// Type ChangeEvent is a change seen by Watch. Value is empty for deletions. If polling failed (e.g.
+// because the table was deleted or access to it revoked), Err is set, no deletions are reported,
+// and the watch keeps polling.
+type ChangeEvent struct {
+	Type  ChangeType
+	Key   string
+	Value []byte
+	Err   error
+}
+
+// State of a watch between polls
+type tableWatcher struct {
+	z            *ZetabaseClient
+	tableOwnerId string
+	tableId      string
+	pattern      string
+	digests      map[string][]byte
+}
+
+// Poll the table, returning changes since the last poll (none on the first poll)
+func (w *tableWatcher) poll() ([]*ChangeEvent, error) {
+	keys, err := w.z.ListKeysWithPattern(w.tableOwnerId, w.tableId, w.pattern).KeysAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	digests := map[string][]byte{}
+	var evts []*ChangeEvent
+	for i := 0; i < len(keys); i += DefaultCopyPageSize {
+		j := i + DefaultCopyPageSize
+		if j > len(keys) {
+			j = len(keys)
+		}
+		data, err := w.z.GetUncached(w.tableOwnerId, w.tableId, keys[i:j]).DataAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys[i:j] {
+			v, ok := data[k]
+			if !ok {
+				continue
+			}
+			h := sha256.Sum256(v)
+			digests[k] = h[:]
+			if w.digests == nil {
+				continue
+			} else if old, ok := w.digests[k]; !ok {
+				evts = append(evts, &ChangeEvent{Type: ChangeAdded, Key: k, Value: v})
+			} else if !bytes.Equal(old, h[:]) {
+				evts = append(evts, &ChangeEvent{Type: ChangeModified, Key: k, Value: v})
+			}
+		}
+	}
+	var deleted []string
+	for k := range w.digests {
+		if _, ok := digests[k]; !ok {
+			deleted = append(deleted, k)
+		}
+	}
+	sort.Strings(deleted)
+	for _, k := range deleted {
+		evts = append(evts, &ChangeEvent{Type: ChangeDeleted, Key: k})
+	}
+	w.digests = digests
+	return evts, nil
+}
+
+// Method Watch polls table tableId of tableOwnerId for changes to keys matching pattern (% is the
+// wildcard; empty for all keys), comparing key listings and value digests between polls. Changes
+// after the first poll are sent on the returned channel, which is closed when ctx is done. Polls
+// are interval apart while the table is changing, and slow down (up to 16x) while it is idle or
+// polls fail.
+func (z *ZetabaseClient) Watch(ctx context.Context, tableOwnerId, tableId, pattern string, interval time.Duration) (<-chan *ChangeEvent, error) {
+	if !z.checkReady() {
+		return nil, errors.New("NotReady")
+	}
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	if _, err := z.GetTableDefinition(tableOwnerId, tableId); err != nil {
+		return nil, err
+	}
+	w := &tableWatcher{z: z, tableOwnerId: tableOwnerId, tableId: tableId, pattern: pattern}
+	if _, err := w.poll(); err != nil {
+		return nil, err
+	}
+	ch := make(chan *ChangeEvent)
+	go func() {
+		defer close(ch)
+		wait := interval
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			evts, err := w.poll()
+			if err != nil {
+				evts = []*ChangeEvent{{Err: err}}
+			}
+			for _, e := range evts {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(evts) > 0 && err == nil {
+				wait = interval
+			} else if wait < interval*maxWatchBackoff {
+				wait *= 2
+			}
+		}
+	}()
+	return ch, nil
+}
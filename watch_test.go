@@ -0,0 +1,72 @@
+package zetabase
+
+import (
+	"context"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"testing"
+	"time"
+)
+
+func Test_WatchReportsChanges(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"log/1": "a", "log/2": "b", "other": "x"})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := z.Watch(ctx, testOwnerId, "src", "log/%", time.Millisecond)
+	if err != nil {
+		t.Fatalf("Watch failed: %s", err.Error())
+	}
+
+	z.PutData(testOwnerId, "src", "log/3", []byte("c"), false)
+	z.PutData(testOwnerId, "src", "log/1", []byte("A"), true)
+	z.PutData(testOwnerId, "src", "other/1", []byte("ignored"), false)
+	z.DeleteKey(testOwnerId, "src", "log/2")
+
+	// The changes may be picked up by different polls
+	want := map[string]ChangeEvent{
+		"log/1": {Type: ChangeModified, Value: []byte("A")},
+		"log/3": {Type: ChangeAdded, Value: []byte("c")},
+		"log/2": {Type: ChangeDeleted},
+	}
+	for len(want) > 0 {
+		select {
+		case e := <-ch:
+			w, ok := want[e.Key]
+			if !ok || e.Err != nil || e.Type != w.Type || string(e.Value) != string(w.Value) {
+				t.Fatalf("Unexpected event %+v", e)
+			}
+			delete(want, e.Key)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %v", want)
+		}
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func Test_WatchReportsFailedPolls(t *testing.T) {
+	z, _ := newFakeClient(testOwnerId)
+	if _, err := z.Watch(context.Background(), testOwnerId, "nosuchtable", "", time.Millisecond); err == nil {
+		t.Fatalf("Expected watching a missing table to fail")
+	}
+	makeExportTestTable(t, z, zbprotocol.TableDataFormat_PLAIN_TEXT, map[string]string{"a": "1", "b": "2"})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := z.Watch(ctx, testOwnerId, "src", "", time.Millisecond)
+	if err != nil {
+		t.Fatalf("Watch failed: %s", err.Error())
+	}
+
+	// Polls of a deleted table fail rather than report its keys as deleted
+	z.DeleteTable(testOwnerId, "src")
+	select {
+	case e := <-ch:
+		if e.Err == nil {
+			t.Fatalf("Unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a failed poll")
+	}
+	cancel()
+	for range ch {
+	}
+}
@@ -55,6 +55,43 @@ const (
 	ConfigKeyLoginParentId = "loginparentid"
 
 	ConfigKeyExportDataMode = "mode.export"
+
+	ConfigKeyKeyFormat     = "keyformat"
+	ConfigKeyKeyPublicOnly = "public"
+	ConfigKeyOutputFile    = "out"
+
+	ConfigKeyDumpFormat   = "format"
+	ConfigKeyCsvColumns   = "columns"
+	ConfigKeyImportCreate = "create"
+
+	ConfigKeyBackupParent       = "incremental-from"
+	ConfigKeyRestoreAs          = "as"
+	ConfigKeyRestoreReapplyPerm = "reapply-perms"
+	ConfigKeyRestoreFresh       = "fresh"
+
+	ConfigKeyCopyFrom         = "from"
+	ConfigKeyCopyTo           = "to"
+	ConfigKeyCopyFromIdentity = "from-identity"
+	ConfigKeyCopyToIdentity   = "to-identity"
+	ConfigKeyCopyPermissions  = "perms"
+	ConfigKeyCopyParallelism  = "parallel"
+	ConfigKeyDiffSync         = "sync"
+
+	ConfigKeyTailInterval = "interval"
+
+	ConfigKeyForce          = "force"
+	ConfigKeySoftDelete     = "soft"
+	ConfigKeyTrashOlderThan = "older-than"
+
+	ConfigKeyIndexField  = "field"
+	ConfigKeyIndexRegexp = "regexp"
+
+	ConfigKeyMigrationsFile = "migrations"
+	ConfigKeyMigrateTarget  = "target"
+
+	ConfigKeySchemaFile = "schema"
+
+	ConfigKeyRotateTables = "encrypted-tables"
 )
 
 var (
@@ -88,6 +125,33 @@ var (
 	parentUid           = ""
 	putOverwrite        = false
 	exportDataMode      = ""
+	keyFormat           = ""
+	keyPublicOnly       = false
+	outputFile          = ""
+	dumpFormat          = ""
+	csvColumns          = ""
+	importCreate        = true
+	backupParent        = ""
+	restoreAs           = ""
+	restoreReapplyPerm  = false
+	restoreFresh        = false
+	copyFrom            = ""
+	copyTo              = ""
+	copyFromIdentity    = ""
+	copyToIdentity      = ""
+	copyPermissions     = false
+	copyParallelism     = zetabase.DefaultCopyParallelism
+	diffSync            = false
+	tailInterval        = ""
+	forceDelete         = false
+	softDeleteFlag      = false
+	trashOlderThan      = ""
+	indexField          = ""
+	indexRegexp         = ""
+	migrationsFile      = ""
+	migrateTarget       = int64(-1)
+	schemaFile          = ""
+	rotateTables        = ""
 )
 
 type IdentityDefinition struct {
@@ -98,6 +162,14 @@ type IdentityDefinition struct {
 	PrivKeyEnc string `json:"priv_key"`
 }
 
+// An identity key retired by `zb identity rotate`
+type ArchivedIdentityKey struct {
+	Id         string `json:"id"`
+	RetiredAt  string `json:"retired_at"`
+	PubKeyEnc  string `json:"pub_key"`
+	PrivKeyEnc string `json:"priv_key"`
+}
+
 func (d *IdentityDefinition) ToUserIdentity() (*UserIdentity, error) {
 	pub, err := zetabase.DecodeEcdsaPublicKey(d.PubKeyEnc)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/jedib0t/go-pretty/table"
+	"github.com/zetabase/zetabase-client"
 	"github.com/zetabase/zetabase-client/zbprotocol"
 	"os"
 	"strings"
@@ -83,6 +84,19 @@ func stringifyPermissions(perms []*zbprotocol.PermissionsEntry) string {
 	return strings.Join(ps, ", ")
 }
 
+func PrintPermissionEntries(perms []*zetabase.PermEntry, tblOwnerId, tblId string) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Permission"})
+	var rows []table.Row
+	for i, x := range perms {
+		rows = append(rows, table.Row{i+1, stringifyPermEntry(x.ToProtocol(tblOwnerId, tblId))})
+	}
+	t.AppendRows(rows)
+	t.SetStyle(getStyleForOs())
+	t.Render()
+}
+
 func PrintTableDefinitions(defns []*zbprotocol.TableCreate) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
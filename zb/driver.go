@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/zetabase/zetabase-client"
 	"math/rand"
 	"time"
 )
@@ -85,6 +86,204 @@ func main() {
 	cmdDelete.Flags().StringVarP(&tableKey, ConfigKeyTableKey, "k", "", "path/to/key/0")
 	viper.BindPFlag(ConfigKeyTableKey, cmdDelete.Flags().Lookup(ConfigKeyTableKey))
 
+	cmdDelete.Flags().BoolVarP(&forceDelete, ConfigKeyForce, "", false, "delete tables without asking for confirmation")
+	viper.BindPFlag(ConfigKeyForce, cmdDelete.Flags().Lookup(ConfigKeyForce))
+
+	cmdDelete.Flags().BoolVarP(&softDeleteFlag, ConfigKeySoftDelete, "", false, "move the key or table to the trash instead")
+	viper.BindPFlag(ConfigKeySoftDelete, cmdDelete.Flags().Lookup(ConfigKeySoftDelete))
+
+	// Trash flags
+	cmdTrash.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdTrash.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdTrash.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdTrash.Flags().Lookup(ConfigKeyTableId))
+
+	cmdTrash.Flags().StringVarP(&tableKey, ConfigKeyTableKey, "k", "", "path/to/key/0")
+	viper.BindPFlag(ConfigKeyTableKey, cmdTrash.Flags().Lookup(ConfigKeyTableKey))
+
+	cmdTrash.Flags().StringVarP(&trashOlderThan, ConfigKeyTrashOlderThan, "", "720h", "only purge trash deleted longer ago than this")
+	viper.BindPFlag(ConfigKeyTrashOlderThan, cmdTrash.Flags().Lookup(ConfigKeyTrashOlderThan))
+
+
+	// Perms flags
+	cmdPerms.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdPerms.Flags().Lookup(ConfigKeyTableId))
+
+	cmdPerms.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdPerms.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdPerms.Flags().StringVarP(&createPermissions, ConfigKeyCreatePermissions, "p", "", "see docs for usage")
+	viper.BindPFlag(ConfigKeyCreatePermissions, cmdPerms.Flags().Lookup(ConfigKeyCreatePermissions))
+
+	// Identity flags
+	cmdIdentity.Flags().StringVarP(&rotateTables, ConfigKeyRotateTables, "", "", "123f-.../mytable,... (encrypted tables of other owners to rewrap)")
+	viper.BindPFlag(ConfigKeyRotateTables, cmdIdentity.Flags().Lookup(ConfigKeyRotateTables))
+
+	// Keys flags
+	cmdKeys.Flags().StringVarP(&keyFormat, ConfigKeyKeyFormat, "T", "", "one of: sec1, pkcs8, der, jwk, openssh")
+	viper.BindPFlag(ConfigKeyKeyFormat, cmdKeys.Flags().Lookup(ConfigKeyKeyFormat))
+
+	cmdKeys.Flags().BoolVarP(&keyPublicOnly, ConfigKeyKeyPublicOnly, "", false, "convert only the public key")
+	viper.BindPFlag(ConfigKeyKeyPublicOnly, cmdKeys.Flags().Lookup(ConfigKeyKeyPublicOnly))
+
+	cmdKeys.Flags().StringVarP(&outputFile, ConfigKeyOutputFile, "", "", "key.jwk (default: STDOUT)")
+	viper.BindPFlag(ConfigKeyOutputFile, cmdKeys.Flags().Lookup(ConfigKeyOutputFile))
+
+	// Export flags
+	cmdExport.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdExport.Flags().Lookup(ConfigKeyTableId))
+
+	cmdExport.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdExport.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdExport.Flags().StringVarP(&dumpFormat, ConfigKeyDumpFormat, "F", "", "one of: ndjson, csv, tar (default: from file extension)")
+	viper.BindPFlag(ConfigKeyDumpFormat, cmdExport.Flags().Lookup(ConfigKeyDumpFormat))
+
+	cmdExport.Flags().StringVarP(&csvColumns, ConfigKeyCsvColumns, "", "", "id=@key,name=user.name,age:number")
+	viper.BindPFlag(ConfigKeyCsvColumns, cmdExport.Flags().Lookup(ConfigKeyCsvColumns))
+
+	cmdExport.Flags().StringVarP(&outputFile, ConfigKeyOutputFile, "", "", "mytable.tar (default: STDOUT)")
+	viper.BindPFlag(ConfigKeyOutputFile, cmdExport.Flags().Lookup(ConfigKeyOutputFile))
+
+	// Import flags
+	cmdImport.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdImport.Flags().Lookup(ConfigKeyTableId))
+
+	cmdImport.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdImport.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdImport.Flags().StringVarP(&dumpFormat, ConfigKeyDumpFormat, "F", "", "one of: ndjson, csv, tar (default: from file extension)")
+	viper.BindPFlag(ConfigKeyDumpFormat, cmdImport.Flags().Lookup(ConfigKeyDumpFormat))
+
+	cmdImport.Flags().StringVarP(&csvColumns, ConfigKeyCsvColumns, "", "", "id=@key,name=user.name,age:number")
+	viper.BindPFlag(ConfigKeyCsvColumns, cmdImport.Flags().Lookup(ConfigKeyCsvColumns))
+
+	cmdImport.Flags().BoolVarP(&putOverwrite, ConfigKeyPutOverwrite, "O", false, "overwrite existing keys")
+	viper.BindPFlag(ConfigKeyPutOverwrite, cmdImport.Flags().Lookup(ConfigKeyPutOverwrite))
+
+	cmdImport.Flags().BoolVarP(&importCreate, ConfigKeyImportCreate, "", true, "create the table from a tar snapshot's definition if needed")
+	viper.BindPFlag(ConfigKeyImportCreate, cmdImport.Flags().Lookup(ConfigKeyImportCreate))
+
+	// Backup flags
+	cmdBackup.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable,othertable (default: all tables)")
+	viper.BindPFlag(ConfigKeyTableId, cmdBackup.Flags().Lookup(ConfigKeyTableId))
+
+	cmdBackup.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdBackup.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdBackup.Flags().StringVarP(&backupParent, ConfigKeyBackupParent, "", "", "backups/monday")
+	viper.BindPFlag(ConfigKeyBackupParent, cmdBackup.Flags().Lookup(ConfigKeyBackupParent))
+
+	// Restore flags
+	cmdRestore.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable,othertable (default: all tables)")
+	viper.BindPFlag(ConfigKeyTableId, cmdRestore.Flags().Lookup(ConfigKeyTableId))
+
+	cmdRestore.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "owner to restore into (default: self)")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdRestore.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdRestore.Flags().StringVarP(&restoreAs, ConfigKeyRestoreAs, "", "", "restore a single table under a new name")
+	viper.BindPFlag(ConfigKeyRestoreAs, cmdRestore.Flags().Lookup(ConfigKeyRestoreAs))
+
+	cmdRestore.Flags().BoolVarP(&restoreReapplyPerm, ConfigKeyRestoreReapplyPerm, "", false, "replace permissions of existing tables with those from the backup")
+	viper.BindPFlag(ConfigKeyRestoreReapplyPerm, cmdRestore.Flags().Lookup(ConfigKeyRestoreReapplyPerm))
+
+	cmdRestore.Flags().BoolVarP(&restoreFresh, ConfigKeyRestoreFresh, "", false, "start over instead of resuming an interrupted restore")
+	viper.BindPFlag(ConfigKeyRestoreFresh, cmdRestore.Flags().Lookup(ConfigKeyRestoreFresh))
+
+	// Copy flags
+	cmdCopy.Flags().StringVarP(&copyFrom, ConfigKeyCopyFrom, "", "", "owner/table (owner defaults as for other commands)")
+	viper.BindPFlag(ConfigKeyCopyFrom, cmdCopy.Flags().Lookup(ConfigKeyCopyFrom))
+
+	cmdCopy.Flags().StringVarP(&copyTo, ConfigKeyCopyTo, "", "", "owner/table")
+	viper.BindPFlag(ConfigKeyCopyTo, cmdCopy.Flags().Lookup(ConfigKeyCopyTo))
+
+	cmdCopy.Flags().StringVarP(&copyFromIdentity, ConfigKeyCopyFromIdentity, "", "", "staging.zbid (default: the configured identity)")
+	viper.BindPFlag(ConfigKeyCopyFromIdentity, cmdCopy.Flags().Lookup(ConfigKeyCopyFromIdentity))
+
+	cmdCopy.Flags().StringVarP(&copyToIdentity, ConfigKeyCopyToIdentity, "", "", "production.zbid (default: the configured identity)")
+	viper.BindPFlag(ConfigKeyCopyToIdentity, cmdCopy.Flags().Lookup(ConfigKeyCopyToIdentity))
+
+	cmdCopy.Flags().StringVarP(&keyPattern, ConfigKeyKeyPattern, "K", "", "prefix/%")
+	viper.BindPFlag(ConfigKeyKeyPattern, cmdCopy.Flags().Lookup(ConfigKeyKeyPattern))
+
+	cmdCopy.Flags().BoolVarP(&copyPermissions, ConfigKeyCopyPermissions, "", false, "copy the table's permissions")
+	viper.BindPFlag(ConfigKeyCopyPermissions, cmdCopy.Flags().Lookup(ConfigKeyCopyPermissions))
+
+	cmdCopy.Flags().BoolVarP(&putOverwrite, ConfigKeyPutOverwrite, "O", false, "overwrite existing keys")
+	viper.BindPFlag(ConfigKeyPutOverwrite, cmdCopy.Flags().Lookup(ConfigKeyPutOverwrite))
+
+	cmdCopy.Flags().IntVarP(&copyParallelism, ConfigKeyCopyParallelism, "", zetabase.DefaultCopyParallelism, "pages copied concurrently")
+	viper.BindPFlag(ConfigKeyCopyParallelism, cmdCopy.Flags().Lookup(ConfigKeyCopyParallelism))
+
+	// Diff flags
+	cmdDiff.Flags().StringVarP(&copyFrom, ConfigKeyCopyFrom, "", "", "owner/table (owner defaults as for other commands)")
+	viper.BindPFlag(ConfigKeyCopyFrom, cmdDiff.Flags().Lookup(ConfigKeyCopyFrom))
+
+	cmdDiff.Flags().StringVarP(&copyTo, ConfigKeyCopyTo, "", "", "owner/table")
+	viper.BindPFlag(ConfigKeyCopyTo, cmdDiff.Flags().Lookup(ConfigKeyCopyTo))
+
+	cmdDiff.Flags().StringVarP(&copyFromIdentity, ConfigKeyCopyFromIdentity, "", "", "staging.zbid (default: the configured identity)")
+	viper.BindPFlag(ConfigKeyCopyFromIdentity, cmdDiff.Flags().Lookup(ConfigKeyCopyFromIdentity))
+
+	cmdDiff.Flags().StringVarP(&copyToIdentity, ConfigKeyCopyToIdentity, "", "", "production.zbid (default: the configured identity)")
+	viper.BindPFlag(ConfigKeyCopyToIdentity, cmdDiff.Flags().Lookup(ConfigKeyCopyToIdentity))
+
+	cmdDiff.Flags().StringVarP(&keyPattern, ConfigKeyKeyPattern, "K", "", "prefix/%")
+	viper.BindPFlag(ConfigKeyKeyPattern, cmdDiff.Flags().Lookup(ConfigKeyKeyPattern))
+
+	cmdDiff.Flags().BoolVarP(&diffSync, ConfigKeyDiffSync, "", false, "make the target table match the source")
+	viper.BindPFlag(ConfigKeyDiffSync, cmdDiff.Flags().Lookup(ConfigKeyDiffSync))
+
+	// Tail flags
+	cmdTail.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdTail.Flags().Lookup(ConfigKeyTableId))
+
+	cmdTail.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdTail.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdTail.Flags().StringVarP(&keyPattern, ConfigKeyKeyPattern, "K", "", "log/%")
+	viper.BindPFlag(ConfigKeyKeyPattern, cmdTail.Flags().Lookup(ConfigKeyKeyPattern))
+
+	cmdTail.Flags().StringVarP(&tailInterval, ConfigKeyTailInterval, "", "2s", "time between polls while the table is changing")
+	viper.BindPFlag(ConfigKeyTailInterval, cmdTail.Flags().Lookup(ConfigKeyTailInterval))
+
+	// Sweep flags
+	cmdSweep.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdSweep.Flags().Lookup(ConfigKeyTableId))
+
+	cmdSweep.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdSweep.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	// Reindex flags
+	cmdReindex.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdReindex.Flags().Lookup(ConfigKeyTableId))
+
+	cmdReindex.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdReindex.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdReindex.Flags().StringVarP(&indexField, ConfigKeyIndexField, "f", "", "name of the secondary index")
+	viper.BindPFlag(ConfigKeyIndexField, cmdReindex.Flags().Lookup(ConfigKeyIndexField))
+
+	cmdReindex.Flags().StringVarP(&indexRegexp, ConfigKeyIndexRegexp, "", "", "index values by matches of this regexp (default: whole value)")
+	viper.BindPFlag(ConfigKeyIndexRegexp, cmdReindex.Flags().Lookup(ConfigKeyIndexRegexp))
+
+	// Migrate flags
+	cmdMigrate.Flags().StringVarP(&migrationsFile, ConfigKeyMigrationsFile, "f", "", "migrations.json")
+	viper.BindPFlag(ConfigKeyMigrationsFile, cmdMigrate.Flags().Lookup(ConfigKeyMigrationsFile))
+
+	cmdMigrate.Flags().Int64VarP(&migrateTarget, ConfigKeyMigrateTarget, "", -1, "version to migrate up or down to (default: up to the latest, down by one)")
+	viper.BindPFlag(ConfigKeyMigrateTarget, cmdMigrate.Flags().Lookup(ConfigKeyMigrateTarget))
+
+	// Schema flags
+	cmdSchema.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
+	viper.BindPFlag(ConfigKeyTableId, cmdSchema.Flags().Lookup(ConfigKeyTableId))
+
+	cmdSchema.Flags().StringVarP(&tableOwnerId, ConfigKeyTableOwnerId, "o", "", "123f-...")
+	viper.BindPFlag(ConfigKeyTableOwnerId, cmdSchema.Flags().Lookup(ConfigKeyTableOwnerId))
+
+	cmdSchema.Flags().StringVarP(&schemaFile, ConfigKeySchemaFile, "f", "", "schema.json")
+	viper.BindPFlag(ConfigKeySchemaFile, cmdSchema.Flags().Lookup(ConfigKeySchemaFile))
 
 	// Put flags
 	cmdPut.Flags().StringVarP(&tableId, ConfigKeyTableId, "t", "", "mytable")
@@ -145,6 +344,21 @@ func main() {
 	rootCmd.AddCommand(cmdList)
 	rootCmd.AddCommand(cmdDelete)
 	rootCmd.AddCommand(cmdCreate)
+	rootCmd.AddCommand(cmdPerms)
+	rootCmd.AddCommand(cmdIdentity)
+	rootCmd.AddCommand(cmdKeys)
+	rootCmd.AddCommand(cmdExport)
+	rootCmd.AddCommand(cmdImport)
+	rootCmd.AddCommand(cmdBackup)
+	rootCmd.AddCommand(cmdRestore)
+	rootCmd.AddCommand(cmdCopy)
+	rootCmd.AddCommand(cmdDiff)
+	rootCmd.AddCommand(cmdTail)
+	rootCmd.AddCommand(cmdTrash)
+	rootCmd.AddCommand(cmdSweep)
+	rootCmd.AddCommand(cmdReindex)
+	rootCmd.AddCommand(cmdMigrate)
+	rootCmd.AddCommand(cmdSchema)
 	rootCmd.AddCommand(cmdShell)
 	rootCmd.Execute()
 
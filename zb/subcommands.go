@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"crypto/ecdsa"
 	"crypto/tls"
@@ -12,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/zetabase/zetabase-client"
+	"github.com/zetabase/zetabase-client/migrate"
 	"github.com/zetabase/zetabase-client/zbprotocol"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -20,6 +22,7 @@ import (
 	"math/rand"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -118,7 +121,7 @@ func deleteTable(identity *UserIdentity, tblOwnerId, tbl string, nonce int64, po
 var cmdDelete = &cobra.Command{
 	Use:   "rm",
 	Short: "Delete keys, users, and tables",
-	Long:  `Delete any system object with rm key <key>, rm table <table>, rm subuser <userid>.`,
+	Long:  `Delete any system object with rm key <key>, rm table <table>, rm subuser <userid>. Keys and tables can be moved to the trash instead with --soft (see trash).`,
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		identity := loadIdentityFromConfigs()
@@ -134,6 +137,15 @@ var cmdDelete = &cobra.Command{
 		var extraBytes []byte
 		var req *zbprotocol.DeleteSystemObjectRequest
 
+		if viper.GetBool(ConfigKeySoftDelete) {
+			softDelete(identity, strings.ToLower(args[0]), tbl)
+			return
+		}
+		// Trashed tables can be restored, so only hard deletes ask for confirmation
+		if strings.ToLower(args[0]) == "table" && !viper.GetBool(ConfigKeyForce) {
+			confirmTableDeletion(tbl)
+		}
+
 		switch strings.ToLower(args[0]) {
 		case "key":
 			key := viper.GetString(ConfigKeyTableKey)
@@ -194,6 +206,102 @@ var cmdDelete = &cobra.Command{
 	},
 }
 
+// Ask the user to type the table name before deleting it
+func confirmTableDeletion(tbl string) {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		PrintErrorStringAndQuit(fmt.Sprintf("Cannot ask for confirmation to delete table '%s' (standard input is not a terminal); use --force to delete it without asking.", tbl))
+	}
+	fmt.Printf("This will delete table '%s' and everything in it. Type the table name to confirm: ", tbl)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(line) != tbl {
+		PrintErrorStringAndQuit("Table name did not match; nothing was deleted.")
+	}
+}
+
+// Move a key or table to the trash instead of deleting it (see `zb trash`)
+func softDelete(identity *UserIdentity, objType, tbl string) {
+	cli, tblOwnerId := connectForTable(identity)
+	cli.SetSoftDelete(&zetabase.SoftDeleteOptions{PurgeInterval: -1})
+	var err error
+	switch objType {
+	case "key":
+		err = cli.DeleteKey(tblOwnerId, tbl, viper.GetString(ConfigKeyTableKey))
+	case "table":
+		err = cli.DeleteTable(tblOwnerId, tbl)
+	default:
+		PrintErrorStringAndQuit("Only keys and tables can be moved to the trash.")
+	}
+	if err != nil {
+		PrintErrorAndQuit(err)
+	}
+	Logf("Moved to trash (see `zb trash ls`).")
+}
+
+var cmdTrash = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore and purge deleted keys and tables",
+	Long: `Manage keys and tables deleted with rm --soft: trash ls lists the trash of a table (-t), or trashed tables
+without -t; trash restore puts back a key (-t and -k) or a table (-t); trash purge permanently deletes trash older
+than --older-than from a table (-t), or trashed tables without -t.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		key := viper.GetString(ConfigKeyTableKey)
+		cli, tblOwnerId := connectForTable(identity)
+		switch strings.ToLower(args[0]) {
+		case "ls":
+			var entries []*zetabase.TrashEntry
+			var err error
+			if len(tbl) > 0 {
+				entries, err = cli.ListTrash(tblOwnerId, tbl)
+			} else {
+				entries, err = cli.ListTrashedTables()
+			}
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			for _, e := range entries {
+				if len(e.Key) > 0 {
+					fmt.Printf("%s\t%s\t%d bytes\n", e.DeletedAt.Format(time.RFC3339), e.Key, len(e.Value))
+				} else {
+					fmt.Printf("%s\t%s\n", e.DeletedAt.Format(time.RFC3339), e.TableId)
+				}
+			}
+		case "restore":
+			var err error
+			if len(tbl) == 0 {
+				PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+			} else if len(key) > 0 {
+				err = cli.RestoreKey(tblOwnerId, tbl, key)
+			} else {
+				err = cli.RestoreTable(tbl)
+			}
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Restored.")
+		case "purge":
+			olderThan, err := time.ParseDuration(viper.GetString(ConfigKeyTrashOlderThan))
+			if err != nil {
+				PrintErrorStringAndQuit("Please specify --older-than as a duration (e.g. 720h).")
+			}
+			var n int
+			if len(tbl) > 0 {
+				n, err = cli.PurgeTrash(tblOwnerId, tbl, olderThan)
+			} else {
+				n, err = cli.PurgeTrashedTables(olderThan)
+			}
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Purged %d.", n)
+		default:
+			PrintErrorStringAndQuit("Unknown trash command (use ls, restore or purge).")
+		}
+	},
+}
+
 var cmdList = &cobra.Command{
 	Use:   "list",
 	Short: "List owned tables",
@@ -287,6 +395,852 @@ var cmdList = &cobra.Command{
 	},
 }
 
+var cmdPerms = &cobra.Command{
+	Use:   "perms",
+	Short: "List, add, and revoke table permissions",
+	Long:  `Manage the permissions of a table with perms ls, perms add -p <permission>, perms rm (-p <permission> | <#>), where # is the entry number shown by perms ls.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		if len(tbl) == 0 {
+			PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+		}
+		cli := makeNewClient(identity.Id, identity.PrivKey, identity.PubKey)
+		if cli == nil {
+			PrintErrorStringAndQuit("Failed to connect to server.")
+		}
+		tblOwnerId := chooseDefaultTableOwnerId(identity)
+
+		// Login (if needed) so that the client knows its own ID
+		_, _, err := cli.CheckVersion()
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		if len(tblOwnerId) == 0 {
+			identity.Id = cli.Id()
+			tblOwnerId = identity.Id
+		}
+
+		parsePerms := func() []*zetabase.PermEntry {
+			permsRaw := viper.GetString(ConfigKeyCreatePermissions)
+			if len(permsRaw) == 0 {
+				PrintErrorStringAndQuit("Please specify a permission (e.g. with `-p \"user read _\"`).")
+			}
+			pEntries, ok := parsePermissionsString(permsRaw, identity, tbl, 0, nil)
+			if !ok {
+				PrintErrorStringAndQuit("Invalid permissions specification.")
+			}
+			var perms []*zetabase.PermEntry
+			for _, p := range pEntries {
+				perms = append(perms, zetabase.PermEntryFromProtocol(p))
+			}
+			return perms
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "ls", "list":
+			perms, err := cli.ListPermissions(tblOwnerId, tbl)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			PrintPermissionEntries(perms, tblOwnerId, tbl)
+		case "add":
+			for _, p := range parsePerms() {
+				err := cli.AddPermission(tblOwnerId, tbl, p)
+				if err != nil {
+					PrintErrorAndQuit(err)
+				}
+			}
+			Logf("Success.")
+		case "rm":
+			if len(args) > 1 {
+				n, err := strconv.Atoi(args[1])
+				if err != nil {
+					PrintErrorStringAndQuit("Usage is: zb perms rm <#> -t tbl (with # as shown by `zb perms ls`)")
+				}
+				if err := revokeNumberedPermission(cli, tblOwnerId, tbl, n); err != nil {
+					PrintErrorAndQuit(err)
+				}
+			} else {
+				for _, p := range parsePerms() {
+					err := cli.RevokePermission(tblOwnerId, tbl, p)
+					if err != nil {
+						PrintErrorAndQuit(err)
+					}
+				}
+			}
+			Logf("Success.")
+		default:
+			Logf("Usage is: zb perms (ls|add|rm) -t tbl [-o ownerId] [-p permission] [#]")
+		}
+	},
+}
+
+var cmdIdentity = &cobra.Command{
+	Use:   "identity",
+	Short: "Manage the local identity",
+	Long:  `Manage the identity file given with -i. identity rotate generates a new key pair, registers it with the server, rewraps the data keys of encrypted tables that use a key derived from the identity key, and rewrites the identity file; the old key is then kept in <file>.archive. Only the identity's own tables are found: encrypted tables of other owners must be listed with --encrypted-tables owner/table,... or their data keys can no longer be unwrapped.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		switch strings.ToLower(args[0]) {
+		case "rotate":
+			identFn := viper.GetString(ConfigKeyIdentityFile)
+			if len(identFn) == 0 {
+				PrintErrorStringAndQuit("Please specify an identity file (e.g. with `-i zetabase.xxx.identity`).")
+			}
+			tbls, err := parseTableIdList(viper.GetString(ConfigKeyRotateTables))
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			err = rotateIdentityFile(identFn, tbls)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Success.\n\nResult: new key saved to %s (old key archived in %s)", identFn, identFn+".archive")
+		default:
+			Logf("Usage is: zb identity rotate -i zetabase.xxx.identity [--encrypted-tables owner/table,...]")
+		}
+	},
+}
+
+func rotateIdentityFile(identFn string, encryptedTables [][2]string) error {
+	bs, err := ioutil.ReadFile(identFn)
+	if err != nil {
+		return err
+	}
+	var defn IdentityDefinition
+	err = json.Unmarshal(bs, &defn)
+	if err != nil {
+		return err
+	}
+	identity, err := defn.ToUserIdentity()
+	if err != nil {
+		return err
+	}
+	cli := makeNewClient(identity.Id, identity.PrivKey, identity.PubKey)
+	if cli == nil {
+		return errors.New("FailedToConnect")
+	}
+	if identity.ParentId != nil {
+		cli.SetParent(*identity.ParentId)
+	}
+
+	priv, pub := zetabase.GenerateKeyPair()
+	if priv == nil {
+		return errors.New("KeyGenerationFailed")
+	}
+	privEnc, err := zetabase.EncodeEcdsaPrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	pubEnc, err := zetabase.EncodeEcdsaPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	newDefn := defn
+	newDefn.PrivKeyEnc = string(privEnc)
+	newDefn.PubKeyEnc = string(pubEnc)
+	newDat, _ := json.MarshalIndent(newDefn, "", " ")
+
+	// Save the new key before registering it, so that it cannot be lost if we are interrupted
+	pendingFn := identFn + ".pending"
+	err = zetabase.WriteFileAtomic(pendingFn, newDat, 0600)
+	if err != nil {
+		return err
+	}
+	for _, t := range encryptedTables {
+		cli.TrackEncryptedTable(t[0], t[1])
+	}
+	if isVerbose() {
+		Logf("Registering new public key for %s...", identity.Id)
+	}
+	err = cli.ReplaceIdentityKey(priv, pub)
+	if err != nil {
+		os.Remove(pendingFn)
+		return err
+	}
+	// Only archive the old key once the server has retired it
+	err = archiveIdentityKey(identFn, &defn)
+	if err == nil {
+		err = os.Rename(pendingFn, identFn)
+		if err != nil {
+			unarchiveIdentityKey(identFn, &defn)
+		}
+	}
+	if err != nil {
+		// The server has the new key but we could not install it locally: put the old key back
+		rbErr := cli.ReplaceIdentityKey(identity.PrivKey, identity.PubKey)
+		if rbErr != nil {
+			Logf("Failed to restore the old key (%s); the new key is saved in %s", rbErr.Error(), pendingFn)
+			return err
+		}
+		os.Remove(pendingFn)
+		return err
+	}
+	return nil
+}
+
+// Revoke permission entry n (counting from 1, as shown by perms ls) of a table
+func revokeNumberedPermission(cli *zetabase.ZetabaseClient, tblOwnerId, tbl string, n int) error {
+	existing, err := cli.ListPermissions(tblOwnerId, tbl)
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > len(existing) {
+		return fmt.Errorf("No permission entry #%d (table has %d entries).", n, len(existing))
+	}
+	return cli.RevokePermission(tblOwnerId, tbl, existing[n-1])
+}
+
+// Parse a comma-separated list of owner/table pairs
+func parseTableIdList(s string) ([][2]string, error) {
+	var res [][2]string
+	for _, x := range strings.Split(s, ",") {
+		x = strings.TrimSpace(x)
+		if len(x) == 0 {
+			continue
+		}
+		parts := strings.SplitN(x, "/", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("InvalidTableId: %s", x)
+		}
+		res = append(res, [2]string{parts[0], parts[1]})
+	}
+	return res, nil
+}
+
+// Append the key of defn to the identity's key archive
+func archiveIdentityKey(identFn string, defn *IdentityDefinition) error {
+	archiveFn := identFn + ".archive"
+	var entries []ArchivedIdentityKey
+	bs, err := ioutil.ReadFile(archiveFn)
+	if err == nil {
+		err = json.Unmarshal(bs, &entries)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	entries = append(entries, ArchivedIdentityKey{
+		Id:         defn.Id,
+		RetiredAt:  time.Now().UTC().Format(time.RFC3339),
+		PubKeyEnc:  defn.PubKeyEnc,
+		PrivKeyEnc: defn.PrivKeyEnc,
+	})
+	dat, _ := json.MarshalIndent(entries, "", " ")
+	return zetabase.WriteFileAtomic(archiveFn, dat, 0600)
+}
+
+// Remove the key of defn from the identity's key archive, if it is the last entry
+func unarchiveIdentityKey(identFn string, defn *IdentityDefinition) error {
+	archiveFn := identFn + ".archive"
+	var entries []ArchivedIdentityKey
+	bs, err := ioutil.ReadFile(archiveFn)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(bs, &entries)
+	if err != nil {
+		return err
+	}
+	n := len(entries)
+	if n == 0 || entries[n-1].PubKeyEnc != defn.PubKeyEnc {
+		return nil
+	} else if n == 1 {
+		return os.Remove(archiveFn)
+	}
+	dat, _ := json.MarshalIndent(entries[:n-1], "", " ")
+	return zetabase.WriteFileAtomic(archiveFn, dat, 0600)
+}
+
+var cmdKeys = &cobra.Command{
+	Use:   "keys",
+	Short: "Convert identity keys between formats",
+	Long:  `Convert a key with keys convert [file] -T <format>, where format is one of sec1, pkcs8, der, jwk, openssh. The input may be a key in any of these formats or an identity file (defaults to the one given with -i).`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		switch strings.ToLower(args[0]) {
+		case "convert":
+			inFn := viper.GetString(ConfigKeyIdentityFile)
+			if len(args) > 1 {
+				inFn = args[1]
+			}
+			if len(inFn) == 0 {
+				PrintErrorStringAndQuit("Please specify a key or identity file.")
+			}
+			f, err := zetabase.ParseKeyFormat(viper.GetString(ConfigKeyKeyFormat))
+			if err != nil {
+				PrintErrorStringAndQuit("Please specify an output format with -T (one of sec1, pkcs8, der, jwk, openssh).")
+			}
+			out, err := convertKeyFile(inFn, f, viper.GetBool(ConfigKeyKeyPublicOnly))
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			outFn := viper.GetString(ConfigKeyOutputFile)
+			if len(outFn) == 0 {
+				os.Stdout.Write(out)
+				if f != zetabase.KeyFormatDer && len(out) > 0 && out[len(out)-1] != '\n' {
+					fmt.Println()
+				}
+				return
+			}
+			err = zetabase.WriteFileAtomic(outFn, out, 0600)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			if isVerbose() {
+				Logf("Wrote %s key to %s", f.String(), outFn)
+			}
+		default:
+			Logf("Usage is: zb keys convert [file] -T (sec1|pkcs8|der|jwk|openssh) [--public] [--out file]")
+		}
+	},
+}
+
+func convertKeyFile(inFn string, f zetabase.KeyFormat, publicOnly bool) ([]byte, error) {
+	bs, err := ioutil.ReadFile(inFn)
+	if err != nil {
+		return nil, err
+	}
+	// Identity files hold the keys as strings in a JSON document
+	var defn IdentityDefinition
+	if json.Unmarshal(bs, &defn) == nil && (len(defn.PrivKeyEnc) > 0 || len(defn.PubKeyEnc) > 0) {
+		if publicOnly || len(defn.PrivKeyEnc) == 0 {
+			bs = []byte(defn.PubKeyEnc)
+		} else {
+			bs = []byte(defn.PrivKeyEnc)
+		}
+	}
+	if publicOnly {
+		pub, _, err := zetabase.DecodePublicKeyAny(bs)
+		if err != nil {
+			return nil, err
+		}
+		return zetabase.EncodePublicKeyAs(pub, f)
+	}
+	priv, _, err := zetabase.DecodePrivateKeyAny(bs)
+	if err != nil {
+		if _, _, perr := zetabase.DecodePublicKeyAny(bs); perr == nil {
+			return nil, errors.New("InputIsPublicKeyUsePublicFlag")
+		}
+		return nil, err
+	}
+	return zetabase.EncodePrivateKeyAs(priv, f)
+}
+
+// Connect and log in (if needed), returning the client and the table owner to use
+func connectForTable(identity *UserIdentity) (*zetabase.ZetabaseClient, string) {
+	cli := makeNewClient(identity.Id, identity.PrivKey, identity.PubKey)
+	if cli == nil {
+		PrintErrorStringAndQuit("Failed to connect to server.")
+	}
+	tblOwnerId := chooseDefaultTableOwnerId(identity)
+	_, _, err := cli.CheckVersion()
+	if err != nil {
+		PrintErrorAndQuit(err)
+	}
+	if len(tblOwnerId) == 0 {
+		identity.Id = cli.Id()
+		tblOwnerId = identity.Id
+	}
+	return cli, tblOwnerId
+}
+
+func dumpFormatFromConfig(fn string) zetabase.ExportFormat {
+	fmtName := viper.GetString(ConfigKeyDumpFormat)
+	if len(fmtName) > 0 {
+		f, err := zetabase.ParseExportFormat(fmtName)
+		if err != nil {
+			PrintErrorStringAndQuit("Unknown format (use one of ndjson, csv, tar).")
+		}
+		return f
+	}
+	if f, ok := zetabase.ExportFormatForFilename(fn); ok {
+		return f
+	}
+	PrintErrorStringAndQuit("Please specify a format with -F (one of ndjson, csv, tar).")
+	return 0
+}
+
+func csvColumnsFromConfig() []zetabase.CsvColumn {
+	cols, err := zetabase.ParseCsvColumns(viper.GetString(ConfigKeyCsvColumns))
+	if err != nil {
+		PrintErrorAndQuit(err)
+	}
+	return cols
+}
+
+var cmdExport = &cobra.Command{
+	Use:   "export",
+	Short: "Export a table",
+	Long:  `Export a whole table as NDJSON, CSV (optionally mapping columns to JSON fields with --columns "header=field:type,...") or a tar snapshot that includes the table definition.`,
+	Args:  cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		if len(tbl) == 0 {
+			PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+		}
+		outFn := viper.GetString(ConfigKeyOutputFile)
+		format := dumpFormatFromConfig(outFn)
+		cli, tblOwnerId := connectForTable(identity)
+
+		out := os.Stdout
+		if len(outFn) > 0 {
+			f, err := os.Create(outFn)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			defer f.Close()
+			out = f
+		}
+		n, err := cli.ExportTable(tblOwnerId, tbl, out, &zetabase.ExportOptions{
+			Format:  format,
+			Columns: csvColumnsFromConfig(),
+		})
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		if len(outFn) > 0 {
+			Logf("Exported %d records to %s", n, outFn)
+		}
+	},
+}
+
+var cmdImport = &cobra.Command{
+	Use:   "import",
+	Short: "Import data into a table",
+	Long:  `Import NDJSON, CSV or a tar snapshot (from a file, or STDIN) into a table. Tables are created from a snapshot's definition if they do not exist.`,
+	Args:  cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		if len(tbl) == 0 {
+			PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+		}
+		inFn := ""
+		if len(args) > 0 {
+			inFn = args[0]
+		}
+		format := dumpFormatFromConfig(inFn)
+		cli, tblOwnerId := connectForTable(identity)
+
+		in := os.Stdin
+		if len(inFn) > 0 {
+			f, err := os.Open(inFn)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			defer f.Close()
+			in = f
+		}
+		n, err := cli.ImportTable(tblOwnerId, tbl, in, &zetabase.ImportOptions{
+			Format:      format,
+			Columns:     csvColumnsFromConfig(),
+			Overwrite:   viper.GetBool(ConfigKeyPutOverwrite),
+			CreateTable: viper.GetBool(ConfigKeyImportCreate),
+		})
+		if err != nil {
+			Logf("Imported %d records before failing.", n)
+			PrintErrorAndQuit(err)
+		}
+		Logf("Imported %d records into %s.", n, tbl)
+	},
+}
+
+func tableListFromConfig() []string {
+	var tbls []string
+	for _, t := range strings.Split(viper.GetString(ConfigKeyTableId), ",") {
+		if t = strings.TrimSpace(t); len(t) > 0 {
+			tbls = append(tbls, t)
+		}
+	}
+	return tbls
+}
+
+var cmdBackup = &cobra.Command{
+	Use:   "backup [directory]",
+	Short: "Back up tables to a directory",
+	Long:  `Back up tables (all of the owner's tables, or those given with -t) to a new directory. With --incremental-from, only keys added since the given earlier backup are stored.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		cli, tblOwnerId := connectForTable(identity)
+		m, err := cli.Backup(tblOwnerId, args[0], &zetabase.BackupOptions{
+			Tables: tableListFromConfig(),
+			Parent: viper.GetString(ConfigKeyBackupParent),
+		})
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		for _, t := range m.Tables {
+			Logf("Backed up %s: %d keys (%d new, %d deleted).", t.TableId, t.KeyCount, t.NewKeyCount, t.DeletedKeyCount)
+		}
+	},
+}
+
+var cmdRestore = &cobra.Command{
+	Use:   "restore [directory]",
+	Short: "Restore tables from a backup",
+	Long:  `Restore tables from a backup directory (and the backups it is incremental to) after verifying their checksums. An interrupted restore resumes where it stopped.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbls := tableListFromConfig()
+		names := map[string]string{}
+		if as := viper.GetString(ConfigKeyRestoreAs); len(as) > 0 {
+			if len(tbls) != 1 {
+				PrintErrorStringAndQuit("Please specify the single table to rename with `-t tablename`.")
+			}
+			names[tbls[0]] = as
+		}
+		cli, tblOwnerId := connectForTable(identity)
+		n, err := cli.Restore(args[0], &zetabase.RestoreOptions{
+			Tables:             tbls,
+			TableOwnerId:       tblOwnerId,
+			TableNames:         names,
+			ReapplyPermissions: viper.GetBool(ConfigKeyRestoreReapplyPerm),
+			NoResume:           viper.GetBool(ConfigKeyRestoreFresh),
+		})
+		if err != nil {
+			Logf("Restored %d records before failing; run again to resume.", n)
+			PrintErrorAndQuit(err)
+		}
+		Logf("Restored %d records.", n)
+	},
+}
+
+// Connect with the identity in file fn (or the configured identity), returning the owner ID named in
+// spec ("owner/table" or "table") and the table ID
+func connectForTableSpec(fn, spec string) (*zetabase.ZetabaseClient, string, string) {
+	identity := loadIdentityFromConfigs()
+	if len(fn) > 0 {
+		identity = parseIdentFromFile(fn)
+		if identity == nil {
+			PrintErrorAndQuit(errors.New("FailedToLoadIdentity"))
+		}
+	}
+	owner, tbl := "", spec
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		owner, tbl = spec[:i], spec[i+1:]
+	}
+	if len(tbl) == 0 {
+		PrintErrorStringAndQuit("Please specify tables as owner/table or table.")
+	}
+	cli := makeNewClient(identity.Id, identity.PrivKey, identity.PubKey)
+	if cli == nil {
+		PrintErrorStringAndQuit("Failed to connect to server.")
+	}
+	if len(owner) == 0 {
+		if identity.ParentId != nil {
+			owner = *identity.ParentId
+		} else {
+			owner = cli.Id()
+		}
+	}
+	return cli, owner, tbl
+}
+
+var cmdCopy = &cobra.Command{
+	Use:   "copy",
+	Short: "Copy a table",
+	Long:  `Copy a table's definition and data (and optionally its permissions) to another table, which may belong to a different owner and be written with a different identity (--from-identity, --to-identity).`,
+	Args:  cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to := viper.GetString(ConfigKeyCopyFrom), viper.GetString(ConfigKeyCopyTo)
+		if len(from) == 0 || len(to) == 0 {
+			PrintErrorStringAndQuit("Please specify the tables with `--from owner/table --to owner/table`.")
+		}
+		src, srcOwner, srcTbl := connectForTableSpec(viper.GetString(ConfigKeyCopyFromIdentity), from)
+		dst, dstOwner, dstTbl := connectForTableSpec(viper.GetString(ConfigKeyCopyToIdentity), to)
+		lastDecile := -1
+		n, err := zetabase.CopyTable(src, srcOwner, srcTbl, dst, dstOwner, dstTbl, &zetabase.CopyOptions{
+			KeyPattern:      viper.GetString(ConfigKeyKeyPattern),
+			CopyPermissions: viper.GetBool(ConfigKeyCopyPermissions),
+			Overwrite:       viper.GetBool(ConfigKeyPutOverwrite),
+			Parallelism:     viper.GetInt(ConfigKeyCopyParallelism),
+			Progress: func(copied, total int) {
+				if d := copied * 10 / total; d > lastDecile {
+					lastDecile = d
+					Logf("Copied %d of %d records...", copied, total)
+				}
+			},
+		})
+		if err != nil {
+			Logf("Copied %d records before failing.", n)
+			PrintErrorAndQuit(err)
+		}
+		Logf("Copied %d records from %s/%s to %s/%s.", n, srcOwner, srcTbl, dstOwner, dstTbl)
+	},
+}
+
+var cmdDiff = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two tables",
+	Long:  `Compare a source table (--from) with a target table (--to), listing keys added (+), removed (-) and changed (~) in the source relative to the target. Exits with status 1 if the tables differ, unless --sync is given to make the target match.`,
+	Args:  cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to := viper.GetString(ConfigKeyCopyFrom), viper.GetString(ConfigKeyCopyTo)
+		if len(from) == 0 || len(to) == 0 {
+			PrintErrorStringAndQuit("Please specify the tables with `--from owner/table --to owner/table`.")
+		}
+		src, srcOwner, srcTbl := connectForTableSpec(viper.GetString(ConfigKeyCopyFromIdentity), from)
+		dst, dstOwner, dstTbl := connectForTableSpec(viper.GetString(ConfigKeyCopyToIdentity), to)
+		opts := &zetabase.DiffOptions{KeyPattern: viper.GetString(ConfigKeyKeyPattern)}
+		sync := viper.GetBool(ConfigKeyDiffSync)
+		var d *zetabase.TableDiff
+		var err error
+		if sync {
+			d, err = zetabase.SyncTables(src, srcOwner, srcTbl, dst, dstOwner, dstTbl, opts)
+		} else {
+			d, err = zetabase.DiffTables(src, srcOwner, srcTbl, dst, dstOwner, dstTbl, opts)
+		}
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		for _, k := range d.Added {
+			fmt.Printf("+ %s\n", k)
+		}
+		for _, k := range d.Removed {
+			fmt.Printf("- %s\n", k)
+		}
+		for _, k := range d.Changed {
+			fmt.Printf("~ %s\n", k)
+		}
+		Logf("%d added, %d removed, %d changed.", len(d.Added), len(d.Removed), len(d.Changed))
+		if sync {
+			Logf("Target table %s/%s synced.", dstOwner, dstTbl)
+		} else if !d.Empty() {
+			os.Exit(1)
+		}
+	},
+}
+
+var cmdTail = &cobra.Command{
+	Use:   "tail",
+	Short: "Follow changes to a table",
+	Long:  `Poll a table (optionally only keys matching --pattern) and print records as they are added (+), modified (~) or deleted (-).`,
+	Args:  cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		if len(tbl) == 0 {
+			PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+		}
+		interval, err := time.ParseDuration(viper.GetString(ConfigKeyTailInterval))
+		if err != nil {
+			PrintErrorStringAndQuit("Please specify the interval as a duration (e.g. 500ms or 5s).")
+		}
+		cli, tblOwnerId := connectForTable(identity)
+		ch, err := cli.Watch(context.Background(), tblOwnerId, tbl, viper.GetString(ConfigKeyKeyPattern), interval)
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		for e := range ch {
+			switch {
+			case e.Err != nil:
+				Logf("Poll failed: %s", e.Err.Error())
+			case e.Type == zetabase.ChangeAdded:
+				fmt.Printf("+ %s: %s\n", e.Key, string(e.Value))
+			case e.Type == zetabase.ChangeModified:
+				fmt.Printf("~ %s: %s\n", e.Key, string(e.Value))
+			case e.Type == zetabase.ChangeDeleted:
+				fmt.Printf("- %s\n", e.Key)
+			}
+		}
+	},
+}
+
+var cmdSweep = &cobra.Command{
+	Use:   "sweep",
+	Short: "Delete expired keys",
+	Long:  `Delete the keys in a table whose TTL (set with PutWithTTL) has passed.`,
+	Args:  cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		if len(tbl) == 0 {
+			PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+		}
+		cli, tblOwnerId := connectForTable(identity)
+		n, err := cli.SweepExpired(tblOwnerId, tbl)
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		Logf("Deleted %d expired keys.", n)
+	},
+}
+
+var cmdReindex = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild a secondary index",
+	Long: `Rebuild a client-maintained secondary index of a table from its contents, adding missing
+index entries and removing stale ones. Keys are indexed under their whole value, or under each
+match of --regexp (its first subgroup, if any).`,
+	Args: cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		field := viper.GetString(ConfigKeyIndexField)
+		if len(tbl) == 0 || len(field) == 0 {
+			PrintErrorStringAndQuit("Please specify a table and index (e.g. with `-t tablename -f field`).")
+		}
+		idx := &zetabase.SecondaryIndex{Field: field, Extract: zetabase.IndexWholeValue()}
+		if pat := viper.GetString(ConfigKeyIndexRegexp); len(pat) > 0 {
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			idx.Extract = zetabase.IndexRegexp(re)
+		}
+		cli, tblOwnerId := connectForTable(identity)
+		if err := cli.AddSecondaryIndex(tblOwnerId, tbl, idx); err != nil {
+			PrintErrorAndQuit(err)
+		}
+		added, removed, err := cli.RebuildSecondaryIndex(tblOwnerId, tbl, field)
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		Logf("Rebuilt index %s: %d entries added, %d removed.", field, added, removed)
+	},
+}
+
+var cmdMigrate = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, roll back or list schema migrations",
+	Long: `Run the declarative migrations in a JSON file (-f) against your tables: migrate up applies pending
+migrations (up to --target, if given), migrate down rolls back the latest one (or all above --target),
+migrate status lists them, and migrate unlock removes the lock left by an interrupted run.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		var migrations []*migrate.Migration
+		if fn := viper.GetString(ConfigKeyMigrationsFile); len(fn) > 0 {
+			var err error
+			if migrations, err = migrate.LoadFile(fn); err != nil {
+				PrintErrorAndQuit(err)
+			}
+		} else if args[0] != "status" && args[0] != "unlock" {
+			PrintErrorStringAndQuit("Please specify a migrations file (e.g. with `-f migrations.json`).")
+		}
+		cli, _ := connectForTable(identity)
+		m, err := migrate.New(cli, migrations...)
+		if err != nil {
+			PrintErrorAndQuit(err)
+		}
+		m.Progress = func(mig *migrate.Migration, up bool) {
+			if up {
+				Logf("Applying %d (%s)...", mig.Version, mig.Name)
+			} else {
+				Logf("Rolling back %d (%s)...", mig.Version, mig.Name)
+			}
+		}
+		target := viper.GetInt64(ConfigKeyMigrateTarget)
+		switch strings.ToLower(args[0]) {
+		case "up":
+			if target < 0 {
+				target = 0
+			}
+			n, err := m.Up(target)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Applied %d migrations.", n)
+		case "down":
+			if target < 0 {
+				// Roll back only the latest applied migration
+				sts, err := m.Status()
+				if err != nil {
+					PrintErrorAndQuit(err)
+				}
+				target = 0
+				applied := 0
+				for i := len(sts) - 1; i >= 0; i-- {
+					if sts[i].Applied {
+						if applied++; applied == 2 {
+							target = sts[i].Migration.Version
+							break
+						}
+					}
+				}
+			}
+			n, err := m.Down(target)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Rolled back %d migrations.", n)
+		case "status":
+			sts, err := m.Status()
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			for _, st := range sts {
+				if st.Applied {
+					fmt.Printf("%d\t%s\tapplied %s\n", st.Migration.Version, st.Migration.Name, st.AppliedAt.Format(time.RFC3339))
+				} else {
+					fmt.Printf("%d\t%s\tpending\n", st.Migration.Version, st.Migration.Name)
+				}
+			}
+		case "unlock":
+			if err := m.Unlock(); err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Unlocked.")
+		default:
+			PrintErrorStringAndQuit("Unknown migrate command (use up, down, status or unlock).")
+		}
+	},
+}
+
+var cmdSchema = &cobra.Command{
+	Use:   "schema",
+	Short: "Set, show or clear the JSON Schema of a table",
+	Long: `Manage the JSON Schema that documents written to a JSON table (-t) must satisfy: schema set attaches
+the schema in a file (-f), schema get prints it, and schema clear removes it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity := loadIdentityFromConfigs()
+		tbl := viper.GetString(ConfigKeyTableId)
+		if len(tbl) == 0 {
+			PrintErrorStringAndQuit("Please specify a table name (e.g. with `-t tablename`).")
+		}
+		cli, tblOwnerId := connectForTable(identity)
+		switch strings.ToLower(args[0]) {
+		case "set":
+			fn := viper.GetString(ConfigKeySchemaFile)
+			if len(fn) == 0 {
+				PrintErrorStringAndQuit("Please specify a schema file (e.g. with `-f schema.json`).")
+			}
+			bs, err := ioutil.ReadFile(fn)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			}
+			if err := cli.SetTableSchema(tblOwnerId, tbl, bs); err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Schema set.")
+		case "get":
+			s, err := cli.GetTableSchema(tblOwnerId, tbl)
+			if err != nil {
+				PrintErrorAndQuit(err)
+			} else if s == nil {
+				Logf("Table has no schema.")
+				return
+			}
+			fmt.Println(string(s.Bytes()))
+		case "clear":
+			if err := cli.SetTableSchema(tblOwnerId, tbl, nil); err != nil {
+				PrintErrorAndQuit(err)
+			}
+			Logf("Schema removed.")
+		default:
+			PrintErrorStringAndQuit("Unknown schema command (use set, get or clear).")
+		}
+	},
+}
+
 var cmdShell = &cobra.Command{
 	Use:   "shell",
 	Short: "Start a shell session",
@@ -401,7 +1355,6 @@ func createTable(ctr *zbprotocol.TableCreate, client zbprotocol.ZetabaseProvider
 }
 
 func getTableCreate(permsRaw string, identity *UserIdentity, tblId string, typ string, idxArgs []string, nonce int64, poc *zbprotocol.ProofOfCredential) *zbprotocol.TableCreate {
-	var perms []*zbprotocol.PermissionsEntry
 	var idxFields []string
 	var idxTypes []string
 	var tdf zbprotocol.TableDataFormat
@@ -465,6 +1418,28 @@ func getTableCreate(permsRaw string, identity *UserIdentity, tblId string, typ s
 	tblIdxFieldsWrap := &zbprotocol.TableIndexFields{
 		Fields: tblIdxFields,
 	}
+	perms, ok := parsePermissionsString(permsRaw, identity, tblId, nonce, poc)
+	if !ok {
+		return nil
+	}
+	// Form TableCreate message
+	ctr := &zbprotocol.TableCreate{
+		Id:             identity.Id,
+		TableId:        tblId,
+		DataFormat:     tdf,
+		Indices:        tblIdxFieldsWrap,
+		AllowTokenAuth: true,
+		Nonce:          nonce,
+		Credential:     poc,
+		Permissions:    perms,
+	}
+	return ctr
+}
+
+// Parse a permissions specification string (as given to `create -p` or `perms add`) into
+// permission entries for table tblId. Returns false if the specification is malformed.
+func parsePermissionsString(permsRaw string, identity *UserIdentity, tblId string, nonce int64, poc *zbprotocol.ProofOfCredential) ([]*zbprotocol.PermissionsEntry, bool) {
+	var perms []*zbprotocol.PermissionsEntry
 	permsSep := ","
 	if isWindows() {
 		permsSep = ";"
@@ -478,7 +1453,7 @@ func getTableCreate(permsRaw string, identity *UserIdentity, tblId string, typ s
 		arr := strings.Split(strings.TrimSpace(permsRawArr[i]), " ")
 		if len(arr) < 2 {
 			Logf("Error: incorrect permissions specification, should be `perm1,perm2,perm3` where each permi is:\n\ttype level <audience> [constraint field] [constraint value (e.g. @uid)].\n\ttype is one of public, user, single\n\tlevel is one of read, append, delete, admin\n\taudience is user ID if type single, otherwise `_`.")
-			return nil
+			return nil, false
 		}
 		typ := strings.TrimSpace(arr[0])
 		lvl := strings.TrimSpace(arr[1])
@@ -498,7 +1473,7 @@ func getTableCreate(permsRaw string, identity *UserIdentity, tblId string, typ s
 			at = zbprotocol.PermissionAudienceType_USER
 		default:
 			Logf("Error: incorrect permissions specification, unknown qualifier: %s.", typ)
-			return nil
+			return nil, false
 		}
 
 		switch lvl {
@@ -512,7 +1487,7 @@ func getTableCreate(permsRaw string, identity *UserIdentity, tblId string, typ s
 			lv = zbprotocol.PermissionLevel_ADMINISTER
 		default:
 			Logf("Error: incorrect permissions specification, unknown qualifier: %s.", lvl)
-			return nil
+			return nil, false
 		}
 
 		var permConstraints []*zbprotocol.PermissionConstraint
@@ -631,18 +1606,7 @@ func getTableCreate(permsRaw string, identity *UserIdentity, tblId string, typ s
 		}
 		perms = append(perms, p)
 	}
-	// Form TableCreate message
-	ctr := &zbprotocol.TableCreate{
-		Id:             identity.Id,
-		TableId:        tblId,
-		DataFormat:     tdf,
-		Indices:        tblIdxFieldsWrap,
-		AllowTokenAuth: true,
-		Nonce:          nonce,
-		Credential:     poc,
-		Permissions:    perms,
-	}
-	return ctr
+	return perms, true
 }
 
 func getUserCredential(identity *UserIdentity, nonce int64, extraSigningBytes []byte, client zbprotocol.ZetabaseProviderClient) (string, *zbprotocol.ProofOfCredential, error) {
@@ -699,6 +1663,22 @@ func chooseDefaultTableOwnerId(identity *UserIdentity) string {
 	return tblOwnerId
 }
 
+// Files too large for a single value are stored in chunks (see PutBlob)
+func putFileAsBlob(identity *UserIdentity, tbl, key, fn string) {
+	f, err := os.Open(fn)
+	if err != nil {
+		PrintErrorAndQuit(err)
+	}
+	defer f.Close()
+	cli, tblOwnerId := connectForTable(identity)
+	Logf("File is larger than %d bytes; storing it in chunks.", zetabase.MaxPlainValueSize)
+	n, err := cli.PutBlob(tblOwnerId, tbl, key, f, viper.GetBool(ConfigKeyPutOverwrite), nil)
+	if err != nil {
+		PrintErrorAndQuit(err)
+	}
+	Logf("Success (%d bytes).", n)
+}
+
 var cmdPut = &cobra.Command{
 	Use:   "put",
 	Short: "Put a new piece of data",
@@ -712,6 +1692,10 @@ var cmdPut = &cobra.Command{
 		dValu := []byte(dValuS)
 		dValueFn := viper.GetString(ConfigKeyPutBinInFile)
 		if len(dValueFn) > 0 {
+			if st, err := os.Stat(dValueFn); err == nil && st.Size() > zetabase.MaxPlainValueSize {
+				putFileAsBlob(identity, tbl, dKey, dValueFn)
+				return
+			}
 			bs, err := ioutil.ReadFile(dValueFn)
 			if err != nil {
 				PrintErrorAndQuit(err)
@@ -721,6 +1705,11 @@ var cmdPut = &cobra.Command{
 
 		doOverwrite := viper.GetBool(ConfigKeyPutOverwrite)
 		tblOwnerId := chooseDefaultTableOwnerId(identity)
+		// Reject documents the table's schema does not allow before sending them
+		vcli, vOwnerId := connectForTable(identity)
+		if err := vcli.ValidateDocuments(vOwnerId, tbl, []string{dKey}, [][]byte{dValu}); err != nil {
+			PrintErrorAndQuit(err)
+		}
 		//tblOwnerId := viper.GetString(ConfigKeyTableOwnerId)
 		//if len(tblOwnerId) == 0 {
 		//	if identity.ParentId != nil {
@@ -871,7 +1860,8 @@ var cmdView = &cobra.Command{
 				}
 			case "jsonwithkey":
 				for k, v := range datAll {
-					fmt.Printf("{\"%s\": %s}\n", k, string(v))
+					kEnc, _ := json.Marshal(k)
+					fmt.Printf("{%s: %s}\n", string(kEnc), string(v))
 				}
 			case "base64":
 				for _, v := range datAll {
@@ -1,6 +1,15 @@
 package main
 
-import "testing"
+import (
+	"encoding/json"
+	"github.com/zetabase/zetabase-client"
+	"github.com/zetabase/zetabase-client/internal/zbfake"
+	"github.com/zetabase/zetabase-client/zbprotocol"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func Test_ValidatePhoneNumber(t *testing.T) {
 	num := "+12035613094"
@@ -16,3 +25,70 @@ func Test_ValidatePhoneNumber(t *testing.T) {
 		t.Fatalf("Should not have validated %s\n", num)
 	}
 }
+
+func Test_ArchiveIdentityKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbident")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	identFn := filepath.Join(dir, "zetabase.test.identity")
+	for _, k := range []string{"key1", "key2"} {
+		err := archiveIdentityKey(identFn, &IdentityDefinition{Id: "abc", PubKeyEnc: k, PrivKeyEnc: k})
+		if err != nil {
+			t.Fatalf("Failed to archive key: %s", err.Error())
+		}
+	}
+	bs, _ := ioutil.ReadFile(identFn + ".archive")
+	var entries []ArchivedIdentityKey
+	json.Unmarshal(bs, &entries)
+	if len(entries) != 2 || entries[0].PrivKeyEnc != "key1" || entries[1].PrivKeyEnc != "key2" {
+		t.Fatalf("Unexpected archive contents: %s", string(bs))
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Temporary files left behind: %d files", len(files))
+	}
+
+	// A rotation that is rolled back leaves earlier entries alone
+	unarchiveIdentityKey(identFn, &IdentityDefinition{Id: "abc", PubKeyEnc: "key1"})
+	unarchiveIdentityKey(identFn, &IdentityDefinition{Id: "abc", PubKeyEnc: "key2"})
+	bs, _ = ioutil.ReadFile(identFn + ".archive")
+	entries = nil
+	json.Unmarshal(bs, &entries)
+	if len(entries) != 1 || entries[0].PrivKeyEnc != "key1" {
+		t.Fatalf("Unexpected archive contents after rollback: %s", string(bs))
+	}
+}
+
+func Test_ParseTableIdList(t *testing.T) {
+	tbls, err := parseTableIdList("abc/t1, def/t/2,")
+	if err != nil || len(tbls) != 2 || tbls[0] != [2]string{"abc", "t1"} || tbls[1] != [2]string{"def", "t/2"} {
+		t.Fatalf("Unexpected tables: %v (%v)", tbls, err)
+	}
+	if _, err := parseTableIdList("abc"); err == nil {
+		t.Fatalf("Table without owner accepted")
+	}
+}
+
+func Test_RevokeNumberedPermission(t *testing.T) {
+	owner := "11111111-2222-3333-4444-555555555555"
+	cli := zetabase.NewZetabaseClient(owner)
+	priv, pub := zetabase.GenerateKeyPair()
+	cli.SetIdKey(priv, pub)
+	cli.ConnectProvider(zbfake.NewServer())
+	perms := []*zetabase.PermEntry{zetabase.Perm.Public().Read().MustBuild(), zetabase.Perm.Users().Append().MustBuild()}
+	if err := cli.CreateTable("tbl", zbprotocol.TableDataFormat_JSON, nil, perms, false); err != nil {
+		t.Fatalf("Failed to create table: %s", err.Error())
+	}
+	if err := revokeNumberedPermission(cli, owner, "tbl", 3); err == nil {
+		t.Fatalf("Revoked an entry that does not exist")
+	}
+	if err := revokeNumberedPermission(cli, owner, "tbl", 1); err != nil {
+		t.Fatalf("Revoke failed: %s", err.Error())
+	}
+	left, err := cli.ListPermissions(owner, "tbl")
+	if err != nil || len(left) != 1 || left[0].Level != zbprotocol.PermissionLevel_APPEND {
+		t.Fatalf("Unexpected permissions after revoking: %v (%v)", left, err)
+	}
+}
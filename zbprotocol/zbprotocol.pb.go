@@ -894,6 +894,203 @@ func (m *PermissionsEntry) GetConstraints() []*PermissionConstraint {
 	return nil
 }
 
+type ListPermissionsRequest struct {
+	Id                   string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TableOwnerId         string             `protobuf:"bytes,2,opt,name=tableOwnerId,proto3" json:"tableOwnerId,omitempty"`
+	TableId              string             `protobuf:"bytes,3,opt,name=tableId,proto3" json:"tableId,omitempty"`
+	Nonce                int64              `protobuf:"varint,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Credential           *ProofOfCredential `protobuf:"bytes,5,opt,name=credential,proto3" json:"credential,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ListPermissionsRequest) Reset()         { *m = ListPermissionsRequest{} }
+func (m *ListPermissionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPermissionsRequest) ProtoMessage()    {}
+func (*ListPermissionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f3574f20b61059ff, []int{9}
+}
+
+func (m *ListPermissionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPermissionsRequest.Unmarshal(m, b)
+}
+func (m *ListPermissionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPermissionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListPermissionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPermissionsRequest.Merge(m, src)
+}
+func (m *ListPermissionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListPermissionsRequest.Size(m)
+}
+func (m *ListPermissionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPermissionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPermissionsRequest proto.InternalMessageInfo
+
+func (m *ListPermissionsRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ListPermissionsRequest) GetTableOwnerId() string {
+	if m != nil {
+		return m.TableOwnerId
+	}
+	return ""
+}
+
+func (m *ListPermissionsRequest) GetTableId() string {
+	if m != nil {
+		return m.TableId
+	}
+	return ""
+}
+
+func (m *ListPermissionsRequest) GetNonce() int64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *ListPermissionsRequest) GetCredential() *ProofOfCredential {
+	if m != nil {
+		return m.Credential
+	}
+	return nil
+}
+
+type ListPermissionsResponse struct {
+	Error                *ZbError            `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	Entries              []*PermissionsEntry `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *ListPermissionsResponse) Reset()         { *m = ListPermissionsResponse{} }
+func (m *ListPermissionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPermissionsResponse) ProtoMessage()    {}
+func (*ListPermissionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f3574f20b61059ff, []int{10}
+}
+
+func (m *ListPermissionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPermissionsResponse.Unmarshal(m, b)
+}
+func (m *ListPermissionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPermissionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListPermissionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPermissionsResponse.Merge(m, src)
+}
+func (m *ListPermissionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListPermissionsResponse.Size(m)
+}
+func (m *ListPermissionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPermissionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPermissionsResponse proto.InternalMessageInfo
+
+func (m *ListPermissionsResponse) GetError() *ZbError {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *ListPermissionsResponse) GetEntries() []*PermissionsEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type PermissionsReplace struct {
+	Id                   string              `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TableOwnerId         string              `protobuf:"bytes,2,opt,name=tableOwnerId,proto3" json:"tableOwnerId,omitempty"`
+	TableId              string              `protobuf:"bytes,3,opt,name=tableId,proto3" json:"tableId,omitempty"`
+	Nonce                int64               `protobuf:"varint,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Credential           *ProofOfCredential  `protobuf:"bytes,5,opt,name=credential,proto3" json:"credential,omitempty"`
+	Permissions          []*PermissionsEntry `protobuf:"bytes,6,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *PermissionsReplace) Reset()         { *m = PermissionsReplace{} }
+func (m *PermissionsReplace) String() string { return proto.CompactTextString(m) }
+func (*PermissionsReplace) ProtoMessage()    {}
+func (*PermissionsReplace) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f3574f20b61059ff, []int{11}
+}
+
+func (m *PermissionsReplace) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PermissionsReplace.Unmarshal(m, b)
+}
+func (m *PermissionsReplace) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PermissionsReplace.Marshal(b, m, deterministic)
+}
+func (m *PermissionsReplace) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PermissionsReplace.Merge(m, src)
+}
+func (m *PermissionsReplace) XXX_Size() int {
+	return xxx_messageInfo_PermissionsReplace.Size(m)
+}
+func (m *PermissionsReplace) XXX_DiscardUnknown() {
+	xxx_messageInfo_PermissionsReplace.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PermissionsReplace proto.InternalMessageInfo
+
+func (m *PermissionsReplace) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *PermissionsReplace) GetTableOwnerId() string {
+	if m != nil {
+		return m.TableOwnerId
+	}
+	return ""
+}
+
+func (m *PermissionsReplace) GetTableId() string {
+	if m != nil {
+		return m.TableId
+	}
+	return ""
+}
+
+func (m *PermissionsReplace) GetNonce() int64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *PermissionsReplace) GetCredential() *ProofOfCredential {
+	if m != nil {
+		return m.Credential
+	}
+	return nil
+}
+
+func (m *PermissionsReplace) GetPermissions() []*PermissionsEntry {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
 type AuthenticateUser struct {
 	ParentId             string             `protobuf:"bytes,1,opt,name=parentId,proto3" json:"parentId,omitempty"`
 	Handle               string             `protobuf:"bytes,2,opt,name=handle,proto3" json:"handle,omitempty"`
@@ -913,7 +1110,7 @@ func (m *AuthenticateUser) Reset()         { *m = AuthenticateUser{} }
 func (m *AuthenticateUser) String() string { return proto.CompactTextString(m) }
 func (*AuthenticateUser) ProtoMessage()    {}
 func (*AuthenticateUser) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{9}
+	return fileDescriptor_f3574f20b61059ff, []int{12}
 }
 
 func (m *AuthenticateUser) XXX_Unmarshal(b []byte) error {
@@ -1010,7 +1207,7 @@ func (m *AuthenticateUserResponse) Reset()         { *m = AuthenticateUserRespon
 func (m *AuthenticateUserResponse) String() string { return proto.CompactTextString(m) }
 func (*AuthenticateUserResponse) ProtoMessage()    {}
 func (*AuthenticateUserResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{10}
+	return fileDescriptor_f3574f20b61059ff, []int{13}
 }
 
 func (m *AuthenticateUserResponse) XXX_Unmarshal(b []byte) error {
@@ -1070,7 +1267,7 @@ func (m *NewSubIdentityRequest) Reset()         { *m = NewSubIdentityRequest{} }
 func (m *NewSubIdentityRequest) String() string { return proto.CompactTextString(m) }
 func (*NewSubIdentityRequest) ProtoMessage()    {}
 func (*NewSubIdentityRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{11}
+	return fileDescriptor_f3574f20b61059ff, []int{14}
 }
 
 func (m *NewSubIdentityRequest) XXX_Unmarshal(b []byte) error {
@@ -1162,7 +1359,7 @@ func (m *NewIdentityRequest) Reset()         { *m = NewIdentityRequest{} }
 func (m *NewIdentityRequest) String() string { return proto.CompactTextString(m) }
 func (*NewIdentityRequest) ProtoMessage()    {}
 func (*NewIdentityRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{12}
+	return fileDescriptor_f3574f20b61059ff, []int{15}
 }
 
 func (m *NewIdentityRequest) XXX_Unmarshal(b []byte) error {
@@ -1237,7 +1434,7 @@ func (m *SubIdentityModify) Reset()         { *m = SubIdentityModify{} }
 func (m *SubIdentityModify) String() string { return proto.CompactTextString(m) }
 func (*SubIdentityModify) ProtoMessage()    {}
 func (*SubIdentityModify) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{13}
+	return fileDescriptor_f3574f20b61059ff, []int{16}
 }
 
 func (m *SubIdentityModify) XXX_Unmarshal(b []byte) error {
@@ -1332,7 +1529,7 @@ func (m *SubIdentitiesList) Reset()         { *m = SubIdentitiesList{} }
 func (m *SubIdentitiesList) String() string { return proto.CompactTextString(m) }
 func (*SubIdentitiesList) ProtoMessage()    {}
 func (*SubIdentitiesList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{14}
+	return fileDescriptor_f3574f20b61059ff, []int{17}
 }
 
 func (m *SubIdentitiesList) XXX_Unmarshal(b []byte) error {
@@ -1373,7 +1570,7 @@ func (m *NewIdentityConfirm) Reset()         { *m = NewIdentityConfirm{} }
 func (m *NewIdentityConfirm) String() string { return proto.CompactTextString(m) }
 func (*NewIdentityConfirm) ProtoMessage()    {}
 func (*NewIdentityConfirm) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{15}
+	return fileDescriptor_f3574f20b61059ff, []int{18}
 }
 
 func (m *NewIdentityConfirm) XXX_Unmarshal(b []byte) error {
@@ -1427,7 +1624,7 @@ func (m *NewIdentityResponse) Reset()         { *m = NewIdentityResponse{} }
 func (m *NewIdentityResponse) String() string { return proto.CompactTextString(m) }
 func (*NewIdentityResponse) ProtoMessage()    {}
 func (*NewIdentityResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{16}
+	return fileDescriptor_f3574f20b61059ff, []int{19}
 }
 
 func (m *NewIdentityResponse) XXX_Unmarshal(b []byte) error {
@@ -1475,7 +1672,7 @@ func (m *TableIndexField) Reset()         { *m = TableIndexField{} }
 func (m *TableIndexField) String() string { return proto.CompactTextString(m) }
 func (*TableIndexField) ProtoMessage()    {}
 func (*TableIndexField) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{17}
+	return fileDescriptor_f3574f20b61059ff, []int{20}
 }
 
 func (m *TableIndexField) XXX_Unmarshal(b []byte) error {
@@ -1528,7 +1725,7 @@ func (m *TableIndexFields) Reset()         { *m = TableIndexFields{} }
 func (m *TableIndexFields) String() string { return proto.CompactTextString(m) }
 func (*TableIndexFields) ProtoMessage()    {}
 func (*TableIndexFields) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{18}
+	return fileDescriptor_f3574f20b61059ff, []int{21}
 }
 
 func (m *TableIndexFields) XXX_Unmarshal(b []byte) error {
@@ -1569,7 +1766,7 @@ func (m *SimpleRequest) Reset()         { *m = SimpleRequest{} }
 func (m *SimpleRequest) String() string { return proto.CompactTextString(m) }
 func (*SimpleRequest) ProtoMessage()    {}
 func (*SimpleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{19}
+	return fileDescriptor_f3574f20b61059ff, []int{22}
 }
 
 func (m *SimpleRequest) XXX_Unmarshal(b []byte) error {
@@ -1625,7 +1822,7 @@ func (m *ListTablesRequest) Reset()         { *m = ListTablesRequest{} }
 func (m *ListTablesRequest) String() string { return proto.CompactTextString(m) }
 func (*ListTablesRequest) ProtoMessage()    {}
 func (*ListTablesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{20}
+	return fileDescriptor_f3574f20b61059ff, []int{23}
 }
 
 func (m *ListTablesRequest) XXX_Unmarshal(b []byte) error {
@@ -1686,7 +1883,7 @@ func (m *ListTablesResponse) Reset()         { *m = ListTablesResponse{} }
 func (m *ListTablesResponse) String() string { return proto.CompactTextString(m) }
 func (*ListTablesResponse) ProtoMessage()    {}
 func (*ListTablesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{21}
+	return fileDescriptor_f3574f20b61059ff, []int{24}
 }
 
 func (m *ListTablesResponse) XXX_Unmarshal(b []byte) error {
@@ -1738,7 +1935,7 @@ func (m *ListKeysRequest) Reset()         { *m = ListKeysRequest{} }
 func (m *ListKeysRequest) String() string { return proto.CompactTextString(m) }
 func (*ListKeysRequest) ProtoMessage()    {}
 func (*ListKeysRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{22}
+	return fileDescriptor_f3574f20b61059ff, []int{25}
 }
 
 func (m *ListKeysRequest) XXX_Unmarshal(b []byte) error {
@@ -1821,7 +2018,7 @@ func (m *ListKeysResponse) Reset()         { *m = ListKeysResponse{} }
 func (m *ListKeysResponse) String() string { return proto.CompactTextString(m) }
 func (*ListKeysResponse) ProtoMessage()    {}
 func (*ListKeysResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{23}
+	return fileDescriptor_f3574f20b61059ff, []int{26}
 }
 
 func (m *ListKeysResponse) XXX_Unmarshal(b []byte) error {
@@ -1881,7 +2078,7 @@ func (m *TableCreate) Reset()         { *m = TableCreate{} }
 func (m *TableCreate) String() string { return proto.CompactTextString(m) }
 func (*TableCreate) ProtoMessage()    {}
 func (*TableCreate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{24}
+	return fileDescriptor_f3574f20b61059ff, []int{27}
 }
 
 func (m *TableCreate) XXX_Unmarshal(b []byte) error {
@@ -1975,7 +2172,7 @@ func (m *TablePutMulti) Reset()         { *m = TablePutMulti{} }
 func (m *TablePutMulti) String() string { return proto.CompactTextString(m) }
 func (*TablePutMulti) ProtoMessage()    {}
 func (*TablePutMulti) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{25}
+	return fileDescriptor_f3574f20b61059ff, []int{28}
 }
 
 func (m *TablePutMulti) XXX_Unmarshal(b []byte) error {
@@ -2045,6 +2242,94 @@ func (m *TablePutMulti) GetPairs() []*DataPair {
 	return nil
 }
 
+// Puts and deletes in one table that are applied together or not at all
+type TableBatch struct {
+	Id                   string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TableOwnerId         string             `protobuf:"bytes,2,opt,name=tableOwnerId,proto3" json:"tableOwnerId,omitempty"`
+	TableId              string             `protobuf:"bytes,3,opt,name=tableId,proto3" json:"tableId,omitempty"`
+	Nonce                int64              `protobuf:"varint,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Credential           *ProofOfCredential `protobuf:"bytes,5,opt,name=credential,proto3" json:"credential,omitempty"`
+	Puts                 []*DataPair        `protobuf:"bytes,6,rep,name=puts,proto3" json:"puts,omitempty"`
+	Deletes              []string           `protobuf:"bytes,7,rep,name=deletes,proto3" json:"deletes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *TableBatch) Reset()         { *m = TableBatch{} }
+func (m *TableBatch) String() string { return proto.CompactTextString(m) }
+func (*TableBatch) ProtoMessage()    {}
+func (*TableBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f3574f20b61059ff, []int{29}
+}
+
+func (m *TableBatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TableBatch.Unmarshal(m, b)
+}
+func (m *TableBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TableBatch.Marshal(b, m, deterministic)
+}
+func (m *TableBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TableBatch.Merge(m, src)
+}
+func (m *TableBatch) XXX_Size() int {
+	return xxx_messageInfo_TableBatch.Size(m)
+}
+func (m *TableBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_TableBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TableBatch proto.InternalMessageInfo
+
+func (m *TableBatch) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *TableBatch) GetTableOwnerId() string {
+	if m != nil {
+		return m.TableOwnerId
+	}
+	return ""
+}
+
+func (m *TableBatch) GetTableId() string {
+	if m != nil {
+		return m.TableId
+	}
+	return ""
+}
+
+func (m *TableBatch) GetNonce() int64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *TableBatch) GetCredential() *ProofOfCredential {
+	if m != nil {
+		return m.Credential
+	}
+	return nil
+}
+
+func (m *TableBatch) GetPuts() []*DataPair {
+	if m != nil {
+		return m.Puts
+	}
+	return nil
+}
+
+func (m *TableBatch) GetDeletes() []string {
+	if m != nil {
+		return m.Deletes
+	}
+	return nil
+}
+
 type TablePut struct {
 	Id                   string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	TableOwnerId         string             `protobuf:"bytes,2,opt,name=tableOwnerId,proto3" json:"tableOwnerId,omitempty"`
@@ -2054,6 +2339,7 @@ type TablePut struct {
 	Overwrite            bool               `protobuf:"varint,6,opt,name=overwrite,proto3" json:"overwrite,omitempty"`
 	Nonce                int64              `protobuf:"varint,7,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	Credential           *ProofOfCredential `protobuf:"bytes,8,opt,name=credential,proto3" json:"credential,omitempty"`
+	ExpectedValueHash    []byte             `protobuf:"bytes,9,opt,name=expectedValueHash,proto3" json:"expectedValueHash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
 	XXX_unrecognized     []byte             `json:"-"`
 	XXX_sizecache        int32              `json:"-"`
@@ -2063,7 +2349,7 @@ func (m *TablePut) Reset()         { *m = TablePut{} }
 func (m *TablePut) String() string { return proto.CompactTextString(m) }
 func (*TablePut) ProtoMessage()    {}
 func (*TablePut) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{26}
+	return fileDescriptor_f3574f20b61059ff, []int{30}
 }
 
 func (m *TablePut) XXX_Unmarshal(b []byte) error {
@@ -2140,6 +2426,13 @@ func (m *TablePut) GetCredential() *ProofOfCredential {
 	return nil
 }
 
+func (m *TablePut) GetExpectedValueHash() []byte {
+	if m != nil {
+		return m.ExpectedValueHash
+	}
+	return nil
+}
+
 type TableGet struct {
 	Id                   string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	TableOwnerId         string             `protobuf:"bytes,2,opt,name=tableOwnerId,proto3" json:"tableOwnerId,omitempty"`
@@ -2157,7 +2450,7 @@ func (m *TableGet) Reset()         { *m = TableGet{} }
 func (m *TableGet) String() string { return proto.CompactTextString(m) }
 func (*TableGet) ProtoMessage()    {}
 func (*TableGet) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{27}
+	return fileDescriptor_f3574f20b61059ff, []int{31}
 }
 
 func (m *TableGet) XXX_Unmarshal(b []byte) error {
@@ -2240,7 +2533,7 @@ func (m *PaginationInfo) Reset()         { *m = PaginationInfo{} }
 func (m *PaginationInfo) String() string { return proto.CompactTextString(m) }
 func (*PaginationInfo) ProtoMessage()    {}
 func (*PaginationInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{28}
+	return fileDescriptor_f3574f20b61059ff, []int{32}
 }
 
 func (m *PaginationInfo) XXX_Unmarshal(b []byte) error {
@@ -2294,7 +2587,7 @@ func (m *DataPair) Reset()         { *m = DataPair{} }
 func (m *DataPair) String() string { return proto.CompactTextString(m) }
 func (*DataPair) ProtoMessage()    {}
 func (*DataPair) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{29}
+	return fileDescriptor_f3574f20b61059ff, []int{33}
 }
 
 func (m *DataPair) XXX_Unmarshal(b []byte) error {
@@ -2342,7 +2635,7 @@ func (m *TableGetResponse) Reset()         { *m = TableGetResponse{} }
 func (m *TableGetResponse) String() string { return proto.CompactTextString(m) }
 func (*TableGetResponse) ProtoMessage()    {}
 func (*TableGetResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{30}
+	return fileDescriptor_f3574f20b61059ff, []int{34}
 }
 
 func (m *TableGetResponse) XXX_Unmarshal(b []byte) error {
@@ -2398,7 +2691,7 @@ func (m *TableSubqueryComparison) Reset()         { *m = TableSubqueryComparison
 func (m *TableSubqueryComparison) String() string { return proto.CompactTextString(m) }
 func (*TableSubqueryComparison) ProtoMessage()    {}
 func (*TableSubqueryComparison) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{31}
+	return fileDescriptor_f3574f20b61059ff, []int{35}
 }
 
 func (m *TableSubqueryComparison) XXX_Unmarshal(b []byte) error {
@@ -2464,7 +2757,7 @@ func (m *TableQuery) Reset()         { *m = TableQuery{} }
 func (m *TableQuery) String() string { return proto.CompactTextString(m) }
 func (*TableQuery) ProtoMessage()    {}
 func (*TableQuery) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{32}
+	return fileDescriptor_f3574f20b61059ff, []int{36}
 }
 
 func (m *TableQuery) XXX_Unmarshal(b []byte) error {
@@ -2549,7 +2842,7 @@ func (m *TableSubQuery) Reset()         { *m = TableSubQuery{} }
 func (m *TableSubQuery) String() string { return proto.CompactTextString(m) }
 func (*TableSubQuery) ProtoMessage()    {}
 func (*TableSubQuery) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{33}
+	return fileDescriptor_f3574f20b61059ff, []int{37}
 }
 
 func (m *TableSubQuery) XXX_Unmarshal(b []byte) error {
@@ -2622,7 +2915,7 @@ func (m *DeleteSystemObjectRequest) Reset()         { *m = DeleteSystemObjectReq
 func (m *DeleteSystemObjectRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteSystemObjectRequest) ProtoMessage()    {}
 func (*DeleteSystemObjectRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f3574f20b61059ff, []int{34}
+	return fileDescriptor_f3574f20b61059ff, []int{38}
 }
 
 func (m *DeleteSystemObjectRequest) XXX_Unmarshal(b []byte) error {
@@ -2714,6 +3007,9 @@ func init() {
 	proto.RegisterType((*KeyPatternConstraint)(nil), "zbprotocol.KeyPatternConstraint")
 	proto.RegisterType((*PermissionConstraint)(nil), "zbprotocol.PermissionConstraint")
 	proto.RegisterType((*PermissionsEntry)(nil), "zbprotocol.PermissionsEntry")
+	proto.RegisterType((*ListPermissionsRequest)(nil), "zbprotocol.ListPermissionsRequest")
+	proto.RegisterType((*ListPermissionsResponse)(nil), "zbprotocol.ListPermissionsResponse")
+	proto.RegisterType((*PermissionsReplace)(nil), "zbprotocol.PermissionsReplace")
 	proto.RegisterType((*AuthenticateUser)(nil), "zbprotocol.AuthenticateUser")
 	proto.RegisterType((*AuthenticateUserResponse)(nil), "zbprotocol.AuthenticateUserResponse")
 	proto.RegisterType((*NewSubIdentityRequest)(nil), "zbprotocol.NewSubIdentityRequest")
@@ -2731,6 +3027,7 @@ func init() {
 	proto.RegisterType((*ListKeysResponse)(nil), "zbprotocol.ListKeysResponse")
 	proto.RegisterType((*TableCreate)(nil), "zbprotocol.TableCreate")
 	proto.RegisterType((*TablePutMulti)(nil), "zbprotocol.TablePutMulti")
+	proto.RegisterType((*TableBatch)(nil), "zbprotocol.TableBatch")
 	proto.RegisterType((*TablePut)(nil), "zbprotocol.TablePut")
 	proto.RegisterType((*TableGet)(nil), "zbprotocol.TableGet")
 	proto.RegisterType((*PaginationInfo)(nil), "zbprotocol.PaginationInfo")
@@ -2747,174 +3044,184 @@ func init() {
 }
 
 var fileDescriptor_f3574f20b61059ff = []byte{
-	// 2666 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x59, 0xdf, 0x6f, 0xe3, 0xc6,
-	0xf1, 0x37, 0x29, 0xd9, 0x96, 0xc6, 0xbf, 0x78, 0x7b, 0xfe, 0x26, 0x3a, 0xc7, 0x49, 0xee, 0xcb,
-	0x5e, 0x83, 0x44, 0x28, 0x52, 0xf4, 0x0e, 0xbd, 0x14, 0x49, 0xf3, 0x83, 0x96, 0x68, 0x1f, 0x63,
-	0x59, 0xd2, 0xad, 0xe4, 0x6b, 0x2e, 0x28, 0x20, 0x50, 0xd2, 0x4a, 0xc7, 0x9c, 0x44, 0x2a, 0x4b,
-	0xca, 0x8e, 0xf2, 0x58, 0xe4, 0xa1, 0x2f, 0x7d, 0x6f, 0x81, 0x3e, 0xa5, 0x40, 0xfb, 0x7f, 0xf4,
-	0x1f, 0x69, 0x5f, 0x0b, 0x14, 0x6d, 0x81, 0xbe, 0xb6, 0x45, 0xb1, 0xbb, 0xa4, 0xb8, 0xa4, 0x48,
-	0xdf, 0xb9, 0xb9, 0xb4, 0x6f, 0xdc, 0xe1, 0xcc, 0x70, 0xe6, 0x33, 0xb3, 0xb3, 0xb3, 0x43, 0xd0,
-	0xbe, 0xec, 0xcf, 0xa8, 0x17, 0x78, 0x03, 0x6f, 0xf2, 0x36, 0x7f, 0x40, 0x10, 0x53, 0xf4, 0xef,
-	0xc1, 0xae, 0x39, 0x18, 0xfa, 0x76, 0xc7, 0x19, 0xbb, 0x76, 0x30, 0xa7, 0x04, 0x6d, 0x83, 0x42,
-	0x2b, 0xca, 0x6d, 0xe5, 0xcd, 0x32, 0x56, 0x28, 0x5b, 0xf9, 0x15, 0x55, 0xac, 0x7c, 0xfd, 0xb7,
-	0x0a, 0xdc, 0x68, 0x53, 0xcf, 0x1b, 0xb5, 0x46, 0x35, 0x4a, 0x86, 0xc4, 0x0d, 0x1c, 0x7b, 0x82,
-	0xde, 0x83, 0xd2, 0x80, 0x92, 0x61, 0x77, 0x31, 0x23, 0x5c, 0x70, 0xf7, 0xee, 0xeb, 0x6f, 0x4b,
-	0x1f, 0x8d, 0x39, 0xb9, 0x28, 0x63, 0xc3, 0x4b, 0x01, 0xf4, 0x23, 0x28, 0xfb, 0xd1, 0xb7, 0xf9,
-	0x87, 0xb6, 0xee, 0x1e, 0xc8, 0xd2, 0x49, 0xeb, 0x70, 0xcc, 0x8c, 0x0e, 0xa0, 0xf4, 0xd9, 0x65,
-	0xd0, 0xf5, 0x9e, 0x12, 0xb7, 0x52, 0xe0, 0x16, 0x2e, 0xd7, 0x7a, 0x19, 0x36, 0x3f, 0xed, 0x9b,
-	0xd3, 0x59, 0xb0, 0xd0, 0xdf, 0xe1, 0x8f, 0x94, 0x7a, 0x14, 0x21, 0x28, 0x0e, 0xbc, 0xa1, 0x30,
-	0xb2, 0x80, 0xf9, 0x33, 0xaa, 0xc0, 0xe6, 0x94, 0xf8, 0xbe, 0x3d, 0x26, 0xa1, 0x9b, 0xd1, 0x52,
-	0xff, 0xb9, 0x02, 0xbb, 0x8f, 0x08, 0xf5, 0x1d, 0xcf, 0xad, 0x93, 0xc0, 0x76, 0x26, 0x3e, 0xba,
-	0x03, 0x3b, 0x3e, 0xa1, 0x17, 0x84, 0x86, 0xf4, 0x10, 0xa7, 0x24, 0x91, 0x71, 0x0d, 0x26, 0x0e,
-	0x71, 0x83, 0x88, 0x4b, 0x28, 0x4e, 0x12, 0x51, 0x15, 0xb4, 0xa9, 0xe3, 0xd6, 0x12, 0x8c, 0xc2,
-	0x8d, 0x15, 0xba, 0xfe, 0x47, 0x05, 0xf6, 0x8e, 0x1d, 0x32, 0x19, 0xd6, 0x3c, 0xd7, 0x0f, 0xa8,
-	0xed, 0xb8, 0x01, 0x3a, 0x81, 0xdd, 0xc1, 0x72, 0x95, 0x87, 0x7d, 0x4a, 0x88, 0x63, 0x9f, 0x12,
-	0x63, 0x38, 0x8e, 0x18, 0xdb, 0x29, 0x59, 0x84, 0x96, 0x2e, 0xd7, 0xe8, 0x08, 0xca, 0x17, 0xf6,
-	0x64, 0x4e, 0xb8, 0xfe, 0x02, 0xd7, 0x7f, 0xe7, 0x0a, 0xfd, 0x8f, 0x22, 0x5e, 0x1c, 0x8b, 0x31,
-	0x38, 0x28, 0xf9, 0x7c, 0xee, 0x50, 0x32, 0xe4, 0xef, 0x2b, 0x45, 0x01, 0x47, 0x82, 0xa8, 0xff,
-	0x4a, 0x85, 0xfd, 0x53, 0xb2, 0x68, 0xdb, 0x41, 0x40, 0xa8, 0xfb, 0x6d, 0xf8, 0xf9, 0x06, 0xec,
-	0x46, 0x9f, 0x6c, 0x53, 0x32, 0x72, 0xbe, 0x08, 0xbd, 0x4d, 0x51, 0x65, 0xbe, 0xce, 0x7c, 0xc4,
-	0xf8, 0x0a, 0x49, 0x3e, 0x41, 0x4d, 0x62, 0x53, 0x7c, 0x41, 0xd8, 0xac, 0x67, 0x61, 0xf3, 0x77,
-	0x05, 0xf6, 0xdb, 0x84, 0x4e, 0x1d, 0x9f, 0x65, 0x83, 0x84, 0x4d, 0x23, 0x07, 0x9b, 0x84, 0x1d,
-	0x59, 0x92, 0x99, 0x00, 0x99, 0xb0, 0x37, 0x4a, 0xda, 0x1c, 0x6e, 0xc8, 0x57, 0xae, 0x70, 0x0b,
-	0xa7, 0x65, 0xd0, 0x31, 0xec, 0x3c, 0x25, 0x0b, 0x49, 0x49, 0x81, 0x2b, 0xb9, 0x2d, 0x2b, 0xc9,
-	0x8a, 0x34, 0x4e, 0x8a, 0xe9, 0x7f, 0x53, 0x41, 0x8b, 0x6d, 0xf7, 0x4d, 0x37, 0xa0, 0x0b, 0xb4,
-	0x0b, 0xaa, 0x33, 0x0c, 0xb7, 0x9d, 0xea, 0x0c, 0xd9, 0xf6, 0x0d, 0xec, 0xfe, 0x84, 0x58, 0xc3,
-	0x68, 0xfb, 0x86, 0x4b, 0x74, 0x0c, 0xdb, 0xf6, 0x7c, 0xe8, 0x10, 0x77, 0x20, 0x67, 0xaf, 0x9e,
-	0x8d, 0x8c, 0x21, 0x71, 0xe2, 0x84, 0x1c, 0x7a, 0x0d, 0x20, 0x5a, 0x5b, 0xc3, 0x30, 0x77, 0x25,
-	0x0a, 0xfa, 0x01, 0xac, 0x4f, 0xc8, 0x05, 0x99, 0xf0, 0xd0, 0xed, 0x26, 0xb1, 0x8a, 0x3f, 0xd0,
-	0x60, 0x2c, 0x58, 0x70, 0xa2, 0x7d, 0x58, 0x77, 0x3d, 0x77, 0x40, 0x2a, 0x1b, 0xbc, 0x10, 0x89,
-	0x05, 0x7a, 0x1f, 0x60, 0xb0, 0x2c, 0x95, 0x95, 0x4d, 0x0e, 0xda, 0xab, 0x09, 0x6d, 0xe9, 0xca,
-	0x8b, 0x25, 0x01, 0x74, 0x04, 0x5b, 0x71, 0x3c, 0xfd, 0x4a, 0xe9, 0x76, 0x21, 0x0d, 0x7a, 0x56,
-	0x22, 0x60, 0x59, 0x48, 0xff, 0xa7, 0x0a, 0x9a, 0x31, 0x0f, 0x9e, 0x30, 0x95, 0x03, 0x3b, 0x20,
-	0xe7, 0x3e, 0xa1, 0xac, 0x3e, 0xcc, 0x6c, 0x4a, 0xdc, 0xc0, 0x8a, 0x80, 0x5f, 0xae, 0xd1, 0x4b,
-	0xb0, 0xf1, 0xc4, 0x76, 0x87, 0x93, 0xa8, 0x78, 0x86, 0x2b, 0x21, 0xe3, 0xfb, 0x97, 0x1e, 0x1d,
-	0x46, 0xb5, 0x39, 0x5a, 0xc7, 0xde, 0x17, 0xf3, 0xbd, 0x5f, 0xbf, 0xae, 0xf7, 0xef, 0x42, 0x79,
-	0xe2, 0x8d, 0x1d, 0x97, 0x87, 0x7a, 0x83, 0x47, 0xe2, 0x50, 0x96, 0xee, 0xcc, 0xfb, 0x73, 0x9f,
-	0xd0, 0x46, 0xc4, 0x83, 0x63, 0x76, 0x74, 0x17, 0xf6, 0x83, 0x27, 0x0e, 0x1d, 0xb6, 0x6d, 0x1a,
-	0x2c, 0x6a, 0xc9, 0x10, 0x94, 0x71, 0xe6, 0x3b, 0x56, 0xbd, 0x63, 0x7a, 0xc7, 0x9b, 0xd3, 0x01,
-	0xa9, 0x94, 0x44, 0xf5, 0x4e, 0xd3, 0xd1, 0x9b, 0xb0, 0x17, 0xd3, 0xcc, 0xa9, 0xed, 0x4c, 0x2a,
-	0x65, 0xce, 0x9a, 0x26, 0xeb, 0x9f, 0x41, 0x25, 0x0d, 0x3f, 0x26, 0xfe, 0xcc, 0x73, 0x7d, 0xb2,
-	0x92, 0xf9, 0xf2, 0xf1, 0xa7, 0x26, 0x8f, 0x3f, 0xa4, 0xc3, 0x36, 0x25, 0x23, 0x4a, 0xfc, 0x27,
-	0xf2, 0xf1, 0x98, 0xa0, 0xe9, 0x7f, 0x55, 0xe0, 0xff, 0x9a, 0xe4, 0xb2, 0x33, 0xef, 0x5b, 0xdc,
-	0xa9, 0x60, 0x81, 0xc9, 0xe7, 0x73, 0xe2, 0x07, 0x2b, 0x5f, 0x42, 0x50, 0x74, 0xed, 0x69, 0x14,
-	0x62, 0xfe, 0xcc, 0x82, 0x48, 0xb8, 0x27, 0x42, 0xb5, 0x58, 0xb0, 0x74, 0x98, 0x7a, 0x7d, 0x67,
-	0x12, 0xd5, 0xf8, 0x70, 0xc5, 0xca, 0x1c, 0x87, 0xbb, 0x1d, 0xe5, 0x44, 0x58, 0xe6, 0x12, 0x44,
-	0xc6, 0x35, 0x9b, 0xf7, 0x4f, 0xc9, 0xc2, 0x74, 0xd9, 0xd1, 0x3c, 0xe4, 0x71, 0x2c, 0xe3, 0x24,
-	0x91, 0xed, 0x47, 0xd6, 0x03, 0xcc, 0x67, 0x35, 0x76, 0x94, 0x8b, 0x18, 0x49, 0x14, 0x56, 0x11,
-	0xc6, 0xd4, 0x9b, 0xcf, 0xac, 0x61, 0x18, 0x90, 0x68, 0xa9, 0x7f, 0xad, 0x00, 0x6a, 0x92, 0xcb,
-	0xb4, 0xbb, 0x91, 0x7b, 0x4a, 0x96, 0x7b, 0x6a, 0xb6, 0x7b, 0x85, 0xb4, 0x7b, 0xf6, 0x70, 0x2a,
-	0xb9, 0x17, 0x9e, 0x70, 0x09, 0xe2, 0xaa, 0x7b, 0xeb, 0x19, 0xee, 0xe9, 0x5f, 0xab, 0x70, 0x43,
-	0x8a, 0xc9, 0x99, 0x37, 0x74, 0x46, 0xab, 0x65, 0x6f, 0x1f, 0xd6, 0x7d, 0xc6, 0x14, 0xd9, 0xc7,
-	0x17, 0xcc, 0x75, 0x97, 0x5c, 0x36, 0x99, 0x33, 0xc2, 0xc0, 0x68, 0xc9, 0x92, 0xc5, 0x25, 0x97,
-	0x22, 0xf7, 0x84, 0x71, 0xcb, 0x35, 0x3a, 0x84, 0xb2, 0x4b, 0x2e, 0xcf, 0x84, 0x63, 0xc2, 0xa6,
-	0x98, 0x80, 0x6e, 0xc3, 0x96, 0x4b, 0x2e, 0x97, 0x9e, 0x89, 0x90, 0xc8, 0xa4, 0x50, 0xbe, 0xcd,
-	0xbd, 0x08, 0xe3, 0x11, 0x13, 0xe2, 0xdd, 0x5e, 0xca, 0xdf, 0xed, 0xe5, 0x6b, 0xee, 0x76, 0xfd,
-	0xa7, 0x32, 0x46, 0x0e, 0xf1, 0x1b, 0x8e, 0xcf, 0x1a, 0x85, 0x1d, 0x5f, 0x26, 0x56, 0x14, 0x5e,
-	0x02, 0xff, 0x5f, 0x56, 0x9b, 0x99, 0xf0, 0x38, 0x29, 0xa7, 0x4f, 0x12, 0x69, 0x52, 0xf3, 0xdc,
-	0x91, 0x43, 0xa7, 0x59, 0xfb, 0x6f, 0x59, 0x16, 0xd5, 0x54, 0x59, 0xac, 0x82, 0x76, 0x41, 0xa8,
-	0x33, 0x62, 0xbb, 0x98, 0x97, 0xdb, 0x61, 0x14, 0x91, 0x15, 0xba, 0xde, 0x86, 0x9b, 0x89, 0xa4,
-	0xcc, 0xd9, 0xee, 0x6f, 0xc1, 0x3a, 0x61, 0x4d, 0x6c, 0x78, 0x24, 0xdf, 0x94, 0xbd, 0x0a, 0xfb,
-	0x5b, 0x2c, 0x38, 0xf4, 0x9f, 0x29, 0xb0, 0xd7, 0xe5, 0xa7, 0xa0, 0x3b, 0x24, 0x5f, 0xf0, 0xf3,
-	0x9a, 0x85, 0x81, 0x9f, 0xd3, 0xa1, 0x46, 0xb1, 0x40, 0x3f, 0x84, 0x92, 0x47, 0x87, 0x84, 0x3a,
-	0xee, 0x98, 0xeb, 0xdd, 0xbd, 0x7b, 0x4b, 0xd6, 0xfb, 0x70, 0x4e, 0xe8, 0xa2, 0x15, 0x32, 0xe0,
-	0x25, 0x2b, 0x2b, 0x2f, 0x13, 0xdb, 0x1d, 0xcf, 0xed, 0x31, 0x91, 0x5c, 0x4b, 0xd0, 0xf4, 0x13,
-	0xd0, 0x52, 0x36, 0xf8, 0xe8, 0x1e, 0x6c, 0xf0, 0xef, 0x46, 0xa1, 0x49, 0x9c, 0x95, 0x29, 0x6e,
-	0x1c, 0xb2, 0xea, 0x01, 0xec, 0x74, 0x9c, 0xe9, 0x6c, 0x42, 0xf2, 0xca, 0xd3, 0x32, 0xc3, 0xd4,
-	0xfc, 0x0c, 0x2b, 0x5c, 0x37, 0xc3, 0x7e, 0xad, 0xc0, 0x0d, 0x96, 0x55, 0xdc, 0x2a, 0x3f, 0xef,
-	0xd3, 0x3a, 0x6c, 0xf3, 0x76, 0xa3, 0x75, 0xe9, 0x12, 0xba, 0xcc, 0x83, 0x04, 0x2d, 0x36, 0xaf,
-	0x90, 0x6f, 0x5e, 0xf1, 0xba, 0xe6, 0x7d, 0xa5, 0x00, 0x92, 0xcd, 0x0b, 0x93, 0x66, 0x99, 0x24,
-	0xca, 0xb3, 0x92, 0x04, 0xd5, 0x40, 0xe3, 0x66, 0xd6, 0xc9, 0xc8, 0x71, 0x1d, 0x96, 0x8d, 0xec,
-	0x9e, 0xc7, 0xa2, 0xf2, 0xf2, 0x4a, 0x54, 0x6a, 0x94, 0xd8, 0x01, 0xc1, 0x2b, 0x02, 0xfa, 0x9f,
-	0x15, 0xd8, 0x63, 0x66, 0x9c, 0x92, 0x45, 0x2e, 0x46, 0xf9, 0x1d, 0x5a, 0x1a, 0xbd, 0x42, 0x06,
-	0x7a, 0x15, 0xd8, 0x9c, 0x89, 0x46, 0x31, 0xac, 0x5b, 0xd1, 0x32, 0xc6, 0x75, 0x5d, 0xc6, 0xf5,
-	0x10, 0xca, 0x33, 0x7b, 0x2c, 0xf2, 0x28, 0x6c, 0xaf, 0x62, 0xc2, 0x37, 0x6c, 0xb1, 0xf4, 0x5f,
-	0x28, 0xa0, 0xc5, 0xee, 0x5e, 0x1f, 0xf3, 0x77, 0x01, 0x66, 0xf6, 0xd8, 0x71, 0xf9, 0xe6, 0xcf,
-	0xba, 0xec, 0xb6, 0x97, 0x6f, 0x2d, 0x77, 0xe4, 0x61, 0x89, 0x9b, 0x9d, 0x52, 0x4f, 0xc9, 0xc2,
-	0xaf, 0x14, 0x6e, 0x17, 0xd8, 0x29, 0xc5, 0x9e, 0xf5, 0x3f, 0xa9, 0xb0, 0x25, 0x05, 0xe8, 0x1a,
-	0xd0, 0xbf, 0x07, 0x30, 0xb4, 0x03, 0xfb, 0xd8, 0xa3, 0x53, 0x3b, 0x08, 0x5b, 0xe3, 0xd5, 0xdd,
-	0x58, 0x5f, 0xb2, 0x60, 0x89, 0x1d, 0xdd, 0x87, 0x4d, 0xc7, 0x1d, 0x3a, 0x03, 0xe2, 0x87, 0x89,
-	0x7b, 0x78, 0xc5, 0x3e, 0xf6, 0x71, 0xc4, 0x9c, 0x13, 0xb1, 0x37, 0x60, 0xd7, 0x9e, 0x4c, 0xbc,
-	0x4b, 0xde, 0x95, 0xb0, 0xee, 0x87, 0x87, 0xad, 0x84, 0x53, 0xd4, 0x6f, 0xda, 0x1e, 0x7f, 0x00,
-	0x5b, 0xb3, 0xf8, 0x32, 0x11, 0xb6, 0xc7, 0x87, 0xd9, 0xed, 0xb1, 0xb8, 0x6b, 0x60, 0x59, 0x40,
-	0xff, 0x87, 0x02, 0x3b, 0xdc, 0xb5, 0xf6, 0x3c, 0x38, 0x9b, 0x4f, 0x02, 0xe7, 0x3f, 0x2a, 0x06,
-	0x52, 0x44, 0x0a, 0xc9, 0x88, 0x1c, 0x42, 0xd9, 0xbb, 0x20, 0xf4, 0x92, 0x3a, 0x81, 0xe8, 0x9e,
-	0x4a, 0x38, 0x26, 0xe4, 0x40, 0x97, 0x84, 0x64, 0xe3, 0xba, 0x90, 0x54, 0x61, 0x7d, 0x66, 0x3b,
-	0xd4, 0xaf, 0x6c, 0x72, 0x30, 0xf6, 0x65, 0x49, 0x16, 0xfa, 0xb6, 0xed, 0x50, 0x2c, 0x58, 0xd8,
-	0x15, 0xb4, 0x14, 0xb9, 0xff, 0x82, 0x3d, 0xd7, 0xa0, 0xf0, 0x94, 0x2c, 0xc2, 0xed, 0xcd, 0x1e,
-	0x99, 0xb7, 0x17, 0xcb, 0xdb, 0xf0, 0x36, 0x16, 0x8b, 0x24, 0x42, 0x1b, 0xb9, 0x08, 0x6d, 0xe6,
-	0x23, 0x54, 0xba, 0xee, 0x86, 0xff, 0x43, 0xe4, 0xf5, 0x09, 0x79, 0xd1, 0x5e, 0x7f, 0x2b, 0xb7,
-	0xa0, 0xab, 0xab, 0x5f, 0x54, 0x42, 0x36, 0xa5, 0x12, 0x72, 0x01, 0xbb, 0xc9, 0xa2, 0x93, 0xd4,
-	0xa1, 0xa4, 0x75, 0xdc, 0x81, 0x1d, 0x97, 0x7c, 0x11, 0xb4, 0x97, 0x1c, 0xe2, 0xd0, 0x4d, 0x12,
-	0x59, 0xd3, 0xf8, 0xc4, 0xf6, 0x9b, 0x21, 0x8d, 0xbb, 0x5e, 0xc2, 0x32, 0x49, 0xbf, 0x0b, 0xa5,
-	0x28, 0xc5, 0xa2, 0x04, 0x50, 0x32, 0x12, 0x40, 0x95, 0x12, 0x40, 0xff, 0x8d, 0x12, 0xf6, 0x14,
-	0x27, 0x24, 0xf8, 0x6f, 0x97, 0xdf, 0x37, 0xa1, 0xc8, 0x2a, 0x20, 0x2f, 0xbf, 0x79, 0x5b, 0x85,
-	0x73, 0xe8, 0xbf, 0x53, 0xe0, 0x65, 0x6e, 0x65, 0x67, 0xde, 0xff, 0x9c, 0x35, 0x50, 0x35, 0x6f,
-	0x3a, 0xb3, 0xa9, 0xe3, 0x7b, 0x2e, 0x7a, 0x0b, 0x54, 0x6f, 0x16, 0xce, 0x68, 0x32, 0x3a, 0xad,
-	0x19, 0xa1, 0x76, 0xe0, 0x51, 0xac, 0x7a, 0xb3, 0xb8, 0x61, 0x53, 0xe5, 0x86, 0x6d, 0x09, 0x4c,
-	0x78, 0xed, 0x12, 0x3b, 0x43, 0x6e, 0xe3, 0x8a, 0xcf, 0xdd, 0xc6, 0xe9, 0xff, 0x52, 0x00, 0xb8,
-	0xa5, 0x9c, 0xe1, 0x05, 0xe7, 0xf7, 0xf7, 0x61, 0x9d, 0x7b, 0x1f, 0x1e, 0x11, 0xb7, 0x56, 0x8e,
-	0x88, 0xce, 0xbc, 0xcf, 0xbf, 0x8b, 0x05, 0xdf, 0xff, 0xe2, 0x3c, 0xff, 0xbd, 0x1a, 0xd6, 0xf4,
-	0xc8, 0x16, 0x76, 0xb9, 0x74, 0x7c, 0x16, 0x30, 0x6f, 0xee, 0x0a, 0x2c, 0x4a, 0x58, 0xa2, 0xa0,
-	0x06, 0x68, 0x83, 0xf0, 0x39, 0x8a, 0x56, 0xd8, 0x38, 0xdf, 0x5e, 0x41, 0xbc, 0xe1, 0x8d, 0x9d,
-	0x81, 0x3d, 0x59, 0x46, 0x75, 0x45, 0x12, 0xbd, 0x0f, 0xdb, 0x11, 0xad, 0x41, 0x46, 0xd1, 0xa0,
-	0xec, 0x0a, 0xa8, 0x12, 0xec, 0xe8, 0x43, 0xd8, 0x89, 0xd6, 0xd8, 0x19, 0x3f, 0x09, 0x9e, 0x0d,
-	0x75, 0x92, 0x1f, 0xd5, 0x00, 0x06, 0xcb, 0xe4, 0x0c, 0xab, 0xcd, 0x77, 0xb2, 0xa4, 0x53, 0x79,
-	0x8c, 0x25, 0x31, 0xfd, 0x97, 0x2a, 0xdc, 0xaa, 0x93, 0x09, 0x09, 0x48, 0x67, 0xe1, 0x07, 0x64,
-	0xda, 0xea, 0x7f, 0x46, 0x06, 0x41, 0x5e, 0x37, 0xf8, 0x63, 0x00, 0x8f, 0x33, 0xf0, 0x41, 0x8d,
-	0x9a, 0x31, 0xa8, 0x91, 0x94, 0xf0, 0x41, 0x8d, 0xc4, 0xff, 0xbc, 0x1d, 0x63, 0x94, 0x92, 0xc5,
-	0x64, 0x4a, 0x1e, 0x40, 0x49, 0xe8, 0xb2, 0xa2, 0xbb, 0xf7, 0x72, 0xfd, 0xad, 0x8c, 0xe4, 0xaa,
-	0xef, 0x84, 0xf7, 0xb0, 0xb8, 0x8f, 0x42, 0x00, 0x1b, 0x47, 0x56, 0xd3, 0xc0, 0x8f, 0xb5, 0x35,
-	0xb4, 0x0b, 0xd0, 0x6e, 0x18, 0x56, 0xb3, 0xd7, 0x35, 0x3f, 0xe9, 0x6a, 0x0a, 0x2a, 0x41, 0xf1,
-	0xe3, 0x4e, 0xab, 0xa9, 0xa9, 0xd5, 0xfb, 0x50, 0xc9, 0x9b, 0xda, 0xa2, 0x32, 0xac, 0x1f, 0x5b,
-	0x66, 0xa3, 0xae, 0xad, 0xa1, 0x3d, 0xd8, 0x3a, 0x35, 0x1f, 0xf7, 0xda, 0x46, 0xb7, 0x6b, 0xe2,
-	0xa6, 0xa6, 0x54, 0xef, 0xc3, 0xcd, 0x8c, 0x49, 0x38, 0xd2, 0x60, 0xdb, 0x7c, 0x78, 0x6e, 0x34,
-	0x3a, 0xbd, 0x47, 0x46, 0xe3, 0xdc, 0xd4, 0xd6, 0xd0, 0x0e, 0x94, 0x3b, 0x66, 0x37, 0x5c, 0x2a,
-	0xd5, 0x1e, 0x54, 0xf2, 0xa6, 0xd5, 0x68, 0x1b, 0x4a, 0xb5, 0x56, 0xb3, 0xd3, 0x35, 0x9a, 0x5d,
-	0x6d, 0x0d, 0x6d, 0x42, 0xe1, 0xdc, 0xaa, 0x6b, 0x0a, 0xd3, 0xd0, 0xb5, 0xce, 0xcc, 0x4e, 0xd7,
-	0x38, 0x6b, 0x6b, 0x2a, 0xba, 0x01, 0x3b, 0x4d, 0xa3, 0x7b, 0x8e, 0x8d, 0x46, 0xaf, 0x85, 0xeb,
-	0x26, 0xd6, 0x0a, 0xcc, 0x55, 0x6c, 0x34, 0xeb, 0xad, 0x33, 0xad, 0x58, 0xfd, 0x00, 0x5e, 0xca,
-	0x1e, 0xb6, 0x32, 0x10, 0xac, 0x66, 0xdd, 0x7a, 0x64, 0xd5, 0xcf, 0x8d, 0x86, 0xb6, 0xc6, 0x40,
-	0x38, 0xef, 0x98, 0x58, 0x53, 0x98, 0x7c, 0xfb, 0xfc, 0xa8, 0x61, 0xd5, 0x34, 0xb5, 0x7a, 0x06,
-	0x7b, 0xa9, 0x59, 0x2a, 0x63, 0x6c, 0xb6, 0x9a, 0xa6, 0x10, 0xc1, 0xa6, 0x51, 0x17, 0x22, 0x46,
-	0xbb, 0x6d, 0x36, 0xeb, 0x9a, 0xca, 0x9e, 0xeb, 0x66, 0xc3, 0xec, 0x9a, 0x5a, 0x81, 0x7d, 0xc4,
-	0xa8, 0x9f, 0x59, 0x4d, 0xab, 0xd3, 0x35, 0xb1, 0x56, 0xac, 0xde, 0x83, 0x9b, 0x19, 0x7f, 0xa5,
-	0x38, 0x2a, 0xd6, 0x09, 0xf7, 0x23, 0x04, 0xe9, 0xe3, 0x9f, 0x74, 0x7b, 0xdd, 0xd6, 0xa9, 0xc9,
-	0xc0, 0xfd, 0x4a, 0x81, 0x9d, 0x44, 0x9d, 0x66, 0x9f, 0x10, 0xb8, 0x6a, 0x6b, 0x0c, 0xe3, 0x13,
-	0x6c, 0x1a, 0x5d, 0x13, 0xf7, 0xba, 0x0f, 0x8c, 0xa6, 0x40, 0xa8, 0x61, 0x76, 0x3a, 0x62, 0xa9,
-	0x32, 0x1b, 0x9a, 0xad, 0x6e, 0x2f, 0x14, 0x28, 0xa0, 0x9b, 0xb0, 0x27, 0x0b, 0xf4, 0xcc, 0x87,
-	0x5a, 0x91, 0x69, 0x59, 0xca, 0x30, 0xca, 0x3a, 0x8b, 0x31, 0x4b, 0x8f, 0x5e, 0xc7, 0x34, 0x70,
-	0xed, 0x81, 0xb6, 0x51, 0x7d, 0x07, 0xf6, 0xb3, 0xca, 0x0b, 0xd3, 0xdf, 0x68, 0x9d, 0x58, 0x35,
-	0x1e, 0x01, 0x91, 0x1c, 0xd1, 0xda, 0x68, 0xd6, 0x35, 0xa5, 0xfa, 0x38, 0x32, 0x3f, 0xba, 0xc6,
-	0xdf, 0x80, 0x9d, 0x86, 0xf9, 0x89, 0x55, 0x6b, 0x9d, 0x60, 0xa3, 0xfd, 0xc0, 0xaa, 0x09, 0x2f,
-	0xb0, 0x69, 0x34, 0x7a, 0xcd, 0xf3, 0xb3, 0x23, 0x13, 0x77, 0x34, 0x05, 0xed, 0x83, 0x66, 0x35,
-	0xbb, 0xe6, 0x09, 0x96, 0xa8, 0x2a, 0xf3, 0xed, 0xf8, 0xbc, 0xd1, 0x10, 0x99, 0x5b, 0xa8, 0xde,
-	0x03, 0x2d, 0xbd, 0x6f, 0xd1, 0x16, 0x6c, 0x76, 0xce, 0x8f, 0x78, 0x2c, 0xd7, 0x58, 0xd2, 0x76,
-	0x8d, 0xa3, 0x86, 0xa9, 0x29, 0x2c, 0x83, 0x4e, 0xcd, 0xc7, 0x9a, 0x5a, 0x3d, 0x02, 0x2d, 0x3d,
-	0x95, 0x65, 0x88, 0x3e, 0x30, 0x9a, 0xf5, 0x86, 0x29, 0x1c, 0xe8, 0x3e, 0xb0, 0x70, 0xbd, 0xd7,
-	0x36, 0x70, 0xf7, 0xb1, 0xa6, 0x30, 0x7b, 0x79, 0x2c, 0x7a, 0xd8, 0x3c, 0xc6, 0x66, 0xe7, 0x81,
-	0xa6, 0xde, 0xfd, 0x4b, 0x19, 0xb4, 0x4f, 0x49, 0x60, 0xf7, 0x6d, 0x9f, 0xb4, 0xa9, 0x77, 0xe1,
-	0x0c, 0x09, 0x45, 0x1f, 0xc1, 0x56, 0xf8, 0xe3, 0x8c, 0x37, 0x34, 0xe9, 0x96, 0x60, 0x3a, 0x0b,
-	0x16, 0x07, 0x89, 0x73, 0x3f, 0xf9, 0x97, 0x4f, 0x5f, 0x43, 0x16, 0xdc, 0x10, 0x83, 0x37, 0x69,
-	0x58, 0x84, 0x5e, 0x4d, 0xcd, 0x93, 0x93, 0x23, 0xba, 0x83, 0xac, 0xce, 0x43, 0x5f, 0x43, 0x2d,
-	0x31, 0x47, 0x48, 0x8c, 0xab, 0x50, 0xa2, 0x44, 0x27, 0xa6, 0x1b, 0x07, 0x39, 0x5f, 0x09, 0x87,
-	0x5c, 0xfa, 0x1a, 0xfa, 0x04, 0x6e, 0x62, 0x32, 0x76, 0xfc, 0x80, 0x50, 0x69, 0x6e, 0x84, 0x5e,
-	0x4b, 0x8d, 0xb9, 0x52, 0x33, 0xae, 0x83, 0xd7, 0x73, 0xdf, 0x8b, 0x46, 0x4a, 0x5f, 0x43, 0xa7,
-	0x80, 0xc2, 0x61, 0xd7, 0xf3, 0x28, 0x0e, 0x59, 0xf3, 0xfc, 0xbe, 0x0f, 0x9b, 0xed, 0x79, 0xc0,
-	0x2a, 0x1f, 0xda, 0x5f, 0x39, 0x52, 0xda, 0xf3, 0x20, 0x4f, 0xee, 0x23, 0xd8, 0x0e, 0xe5, 0xc4,
-	0x2d, 0xeb, 0x56, 0x96, 0x30, 0x7f, 0x95, 0xa7, 0xe1, 0x7d, 0xd8, 0x12, 0xf7, 0x61, 0xce, 0x8d,
-	0xf2, 0xc6, 0x19, 0x79, 0xe2, 0x1f, 0xc2, 0xe6, 0x09, 0xc9, 0x33, 0xfc, 0x84, 0x04, 0x07, 0x87,
-	0x59, 0x54, 0x09, 0xc6, 0x1a, 0x94, 0xf9, 0x3e, 0xe3, 0x2a, 0x5e, 0x5a, 0x61, 0xe6, 0xef, 0x9e,
-	0x5b, 0xc9, 0x29, 0x59, 0xf8, 0xcf, 0xa7, 0x24, 0x3d, 0x98, 0xd0, 0xd7, 0x10, 0x06, 0x10, 0xbe,
-	0xf2, 0xff, 0x38, 0xcf, 0x1e, 0x84, 0x3e, 0x4f, 0x92, 0xd4, 0x61, 0xa7, 0x43, 0x82, 0xb8, 0x18,
-	0xa3, 0x2b, 0xef, 0xd0, 0x79, 0x20, 0x3f, 0x84, 0x32, 0xdf, 0xf4, 0xdc, 0xb0, 0x84, 0x86, 0xf4,
-	0xff, 0x8f, 0x83, 0x3b, 0x57, 0xbd, 0x95, 0x0c, 0x3b, 0x03, 0x88, 0x27, 0x62, 0xc9, 0xcd, 0xba,
-	0x32, 0xc8, 0x3b, 0x78, 0x2d, 0xef, 0xf5, 0x52, 0xdd, 0x09, 0x94, 0x22, 0x44, 0xd1, 0x2b, 0xd9,
-	0x38, 0x0b, 0x55, 0xcf, 0x0a, 0x42, 0x03, 0xb6, 0x45, 0x7b, 0x24, 0x6a, 0x23, 0xfa, 0x6e, 0xe2,
-	0xee, 0x90, 0xd7, 0x38, 0xe5, 0x00, 0x77, 0xa4, 0xc3, 0xb6, 0x47, 0xc7, 0x6f, 0x7f, 0x19, 0xd6,
-	0xbc, 0x23, 0xb9, 0xfa, 0x71, 0xde, 0xb6, 0xd2, 0xdf, 0xe0, 0x72, 0xf7, 0xfe, 0x1d, 0x00, 0x00,
-	0xff, 0xff, 0xe4, 0x33, 0xc1, 0x92, 0xfa, 0x21, 0x00, 0x00,
+	// 2831 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x59, 0x5f, 0x6f, 0xe3, 0xc6,
+	0xb5, 0x37, 0x29, 0xd9, 0x96, 0x8e, 0xff, 0xd1, 0xb3, 0xbe, 0x1b, 0xad, 0xb3, 0xd9, 0xec, 0x65,
+	0xf6, 0x06, 0x1b, 0x21, 0xc8, 0xc5, 0xf5, 0xe2, 0x6e, 0x8a, 0xa4, 0xf9, 0x43, 0x4b, 0xb4, 0xac,
+	0x58, 0x96, 0xb4, 0x23, 0x79, 0x9b, 0x0d, 0x02, 0x18, 0xb4, 0x34, 0xb2, 0x99, 0x95, 0x48, 0x65,
+	0x48, 0xd9, 0xab, 0xa0, 0x4f, 0x45, 0x1e, 0xfa, 0xd2, 0xf7, 0x16, 0x28, 0x50, 0x34, 0x05, 0xda,
+	0x0f, 0xd1, 0xb7, 0x7e, 0x91, 0xf6, 0xb5, 0x40, 0x81, 0x16, 0xe8, 0x43, 0x5f, 0xda, 0xa2, 0x98,
+	0x19, 0x92, 0x1a, 0x52, 0xa4, 0xd7, 0xc6, 0x6e, 0x5a, 0xe4, 0x8d, 0x73, 0x78, 0xce, 0xe1, 0x39,
+	0xbf, 0x73, 0x66, 0xce, 0xe1, 0x19, 0xd0, 0xbe, 0x3a, 0x19, 0x53, 0xd7, 0x77, 0x7b, 0xee, 0xf0,
+	0x1d, 0xfe, 0x80, 0x60, 0x46, 0xd1, 0xdf, 0x86, 0x75, 0xb3, 0xd7, 0xf7, 0xac, 0x8e, 0x7d, 0xea,
+	0x58, 0xfe, 0x84, 0x12, 0xb4, 0x0a, 0x0a, 0x2d, 0x29, 0x77, 0x95, 0xfb, 0x45, 0xac, 0x50, 0xb6,
+	0xf2, 0x4a, 0xaa, 0x58, 0x79, 0xfa, 0xaf, 0x15, 0xd8, 0x6c, 0x53, 0xd7, 0x1d, 0xb4, 0x06, 0x15,
+	0x4a, 0xfa, 0xc4, 0xf1, 0x6d, 0x6b, 0x88, 0xde, 0x87, 0x42, 0x8f, 0x92, 0x7e, 0x77, 0x3a, 0x26,
+	0x5c, 0x70, 0x7d, 0xe7, 0xf5, 0x77, 0xa4, 0x8f, 0xce, 0x38, 0xb9, 0x28, 0x63, 0xc3, 0x91, 0x00,
+	0xfa, 0x1e, 0x14, 0xbd, 0xf0, 0xdb, 0xfc, 0x43, 0x2b, 0x3b, 0xdb, 0xb2, 0x74, 0xdc, 0x3a, 0x3c,
+	0x63, 0x46, 0xdb, 0x50, 0xf8, 0xe2, 0xc2, 0xef, 0xba, 0x4f, 0x89, 0x53, 0xca, 0x71, 0x0b, 0xa3,
+	0xb5, 0x5e, 0x84, 0xe5, 0xcf, 0x4e, 0xcc, 0xd1, 0xd8, 0x9f, 0xea, 0xef, 0xf2, 0x47, 0x4a, 0x5d,
+	0x8a, 0x10, 0xe4, 0x7b, 0x6e, 0x5f, 0x18, 0x99, 0xc3, 0xfc, 0x19, 0x95, 0x60, 0x79, 0x44, 0x3c,
+	0xcf, 0x3a, 0x25, 0x81, 0x9b, 0xe1, 0x52, 0xff, 0xb1, 0x02, 0xeb, 0x8f, 0x09, 0xf5, 0x6c, 0xd7,
+	0xa9, 0x12, 0xdf, 0xb2, 0x87, 0x1e, 0xba, 0x07, 0x6b, 0x1e, 0xa1, 0xe7, 0x84, 0x06, 0xf4, 0x00,
+	0xa7, 0x38, 0x91, 0x71, 0xf5, 0x86, 0x36, 0x71, 0xfc, 0x90, 0x4b, 0x28, 0x8e, 0x13, 0x51, 0x19,
+	0xb4, 0x91, 0xed, 0x54, 0x62, 0x8c, 0xc2, 0x8d, 0x39, 0xba, 0xfe, 0x07, 0x05, 0x36, 0xf6, 0x6c,
+	0x32, 0xec, 0x57, 0x5c, 0xc7, 0xf3, 0xa9, 0x65, 0x3b, 0x3e, 0xaa, 0xc1, 0x7a, 0x2f, 0x5a, 0x65,
+	0x61, 0x9f, 0x10, 0xe2, 0xd8, 0x27, 0xc4, 0x18, 0x8e, 0x03, 0xc6, 0x76, 0x40, 0xa6, 0x81, 0xa5,
+	0xd1, 0x1a, 0xed, 0x42, 0xf1, 0xdc, 0x1a, 0x4e, 0x08, 0xd7, 0x9f, 0xe3, 0xfa, 0xef, 0x5d, 0xa2,
+	0xff, 0x71, 0xc8, 0x8b, 0x67, 0x62, 0x0c, 0x0e, 0x4a, 0xbe, 0x9c, 0xd8, 0x94, 0xf4, 0xf9, 0xfb,
+	0x52, 0x5e, 0xc0, 0x11, 0x23, 0xea, 0x3f, 0x53, 0x61, 0xeb, 0x80, 0x4c, 0xdb, 0x96, 0xef, 0x13,
+	0xea, 0x7c, 0x1b, 0x7e, 0xbe, 0x09, 0xeb, 0xe1, 0x27, 0xdb, 0x94, 0x0c, 0xec, 0x67, 0x81, 0xb7,
+	0x09, 0xaa, 0xcc, 0xd7, 0x99, 0x0c, 0x18, 0x5f, 0x2e, 0xce, 0x27, 0xa8, 0x71, 0x6c, 0xf2, 0x2f,
+	0x09, 0x9b, 0xc5, 0x34, 0x6c, 0xfe, 0xaa, 0xc0, 0x56, 0x9b, 0xd0, 0x91, 0xed, 0xb1, 0x6c, 0x90,
+	0xb0, 0x69, 0x64, 0x60, 0x13, 0xb3, 0x23, 0x4d, 0x32, 0x15, 0x20, 0x13, 0x36, 0x06, 0x71, 0x9b,
+	0x83, 0x0d, 0xf9, 0xea, 0x25, 0x6e, 0xe1, 0xa4, 0x0c, 0xda, 0x83, 0xb5, 0xa7, 0x64, 0x2a, 0x29,
+	0xc9, 0x71, 0x25, 0x77, 0x65, 0x25, 0x69, 0x91, 0xc6, 0x71, 0x31, 0xfd, 0x2f, 0x2a, 0x68, 0x33,
+	0xdb, 0x3d, 0xd3, 0xf1, 0xe9, 0x14, 0xad, 0x83, 0x6a, 0xf7, 0x83, 0x6d, 0xa7, 0xda, 0x7d, 0xb6,
+	0x7d, 0x7d, 0xeb, 0x64, 0x48, 0xea, 0xfd, 0x70, 0xfb, 0x06, 0x4b, 0xb4, 0x07, 0xab, 0xd6, 0xa4,
+	0x6f, 0x13, 0xa7, 0x27, 0x67, 0xaf, 0x9e, 0x8e, 0x8c, 0x21, 0x71, 0xe2, 0x98, 0x1c, 0xba, 0x03,
+	0x10, 0xae, 0xeb, 0xfd, 0x20, 0x77, 0x25, 0x0a, 0xfa, 0x3f, 0x58, 0x1c, 0x92, 0x73, 0x32, 0xe4,
+	0xa1, 0x5b, 0xdf, 0x79, 0x35, 0xfd, 0x03, 0x0d, 0xc6, 0x82, 0x05, 0x27, 0xda, 0x82, 0x45, 0xc7,
+	0x75, 0x7a, 0xa4, 0xb4, 0xc4, 0x0f, 0x22, 0xb1, 0x40, 0x1f, 0x00, 0xf4, 0xa2, 0xa3, 0xb2, 0xb4,
+	0xcc, 0x41, 0x7b, 0x2d, 0xa6, 0x2d, 0x79, 0xf2, 0x62, 0x49, 0x00, 0xed, 0xc2, 0xca, 0x2c, 0x9e,
+	0x5e, 0xa9, 0x70, 0x37, 0x97, 0x04, 0x3d, 0x2d, 0x11, 0xb0, 0x2c, 0xa4, 0xff, 0x56, 0x81, 0x9b,
+	0x0d, 0xdb, 0xf3, 0x25, 0xd8, 0x31, 0xf9, 0x72, 0x42, 0x3c, 0x7f, 0x0e, 0x78, 0x1d, 0x56, 0x39,
+	0xd2, 0xad, 0x0b, 0x87, 0xd0, 0x08, 0xfd, 0x18, 0x4d, 0x0e, 0x4e, 0x2e, 0x1e, 0x9c, 0x08, 0x81,
+	0x7c, 0x36, 0x02, 0x8b, 0xd7, 0x44, 0x40, 0xff, 0x21, 0xbc, 0x32, 0x67, 0xbc, 0x37, 0x76, 0x1d,
+	0x8f, 0xa0, 0xb7, 0x60, 0x91, 0x50, 0xea, 0x8a, 0xc2, 0xb6, 0xb2, 0x73, 0x43, 0x56, 0x1a, 0x54,
+	0x07, 0x2c, 0x38, 0xd0, 0x43, 0x58, 0x26, 0x8e, 0x4f, 0x6d, 0xc2, 0xea, 0x1e, 0xc3, 0xf0, 0x76,
+	0x3a, 0x86, 0x22, 0x21, 0x71, 0xc8, 0xac, 0xff, 0x4d, 0x01, 0x14, 0xfb, 0xf4, 0x78, 0x68, 0xf5,
+	0xc8, 0x77, 0x00, 0x37, 0xf4, 0x21, 0xac, 0x8c, 0x67, 0x86, 0x97, 0x96, 0xae, 0xe0, 0xb5, 0x2c,
+	0xa0, 0xff, 0x43, 0x05, 0xcd, 0x98, 0xf8, 0x67, 0x4c, 0x5d, 0xcf, 0xf2, 0xc9, 0x91, 0x47, 0x28,
+	0xab, 0x2a, 0x63, 0x8b, 0x12, 0xc7, 0xaf, 0x87, 0xde, 0x47, 0x6b, 0x74, 0x13, 0x96, 0xce, 0x2c,
+	0xa7, 0x3f, 0x0c, 0x4b, 0x6e, 0xb0, 0x12, 0x32, 0x9e, 0x77, 0xe1, 0xd2, 0xd0, 0xf1, 0x68, 0xfd,
+	0xed, 0x78, 0xfe, 0x1e, 0x14, 0x87, 0xee, 0xa9, 0xed, 0xf0, 0x03, 0x62, 0x89, 0xef, 0xdf, 0x98,
+	0xdf, 0x9d, 0xc9, 0xc9, 0xc4, 0x23, 0xb4, 0x11, 0xf2, 0xe0, 0x19, 0x3b, 0xda, 0x81, 0x2d, 0xff,
+	0xcc, 0xa6, 0xfd, 0xb6, 0x45, 0xfd, 0x69, 0x25, 0xbe, 0x71, 0x8b, 0x38, 0xf5, 0x1d, 0xab, 0xf9,
+	0x33, 0x7a, 0xc7, 0x9d, 0xd0, 0x1e, 0x29, 0x15, 0x44, 0xcd, 0x4f, 0xd2, 0xd1, 0x7d, 0xd8, 0x98,
+	0xd1, 0xcc, 0x91, 0x65, 0x0f, 0x4b, 0x45, 0xce, 0x9a, 0x24, 0xeb, 0x5f, 0x40, 0x29, 0x09, 0x7f,
+	0x94, 0xf8, 0xc9, 0xf4, 0x93, 0x9b, 0x26, 0x35, 0xde, 0x34, 0xb1, 0xd4, 0xa4, 0x64, 0x40, 0x89,
+	0x77, 0x26, 0x37, 0x55, 0x31, 0x9a, 0xfe, 0x67, 0x05, 0xfe, 0xab, 0x49, 0x2e, 0x3a, 0x93, 0x93,
+	0x3a, 0x77, 0xca, 0x9f, 0x66, 0x1d, 0x10, 0x08, 0xf2, 0x8e, 0x35, 0x0a, 0x43, 0xcc, 0x9f, 0x59,
+	0x10, 0x09, 0xf7, 0x44, 0xa8, 0x16, 0x0b, 0x96, 0x0e, 0x23, 0xf7, 0xc4, 0x1e, 0x86, 0x9d, 0x41,
+	0xb0, 0x62, 0xc5, 0x91, 0xc3, 0xdd, 0x0e, 0x73, 0x22, 0x28, 0x8e, 0x31, 0x22, 0xe3, 0x1a, 0x4f,
+	0x4e, 0x0e, 0xc8, 0xd4, 0x74, 0x58, 0x43, 0xd7, 0xe7, 0x71, 0x2c, 0xe2, 0x38, 0x91, 0x9d, 0xe2,
+	0xac, 0x73, 0x9c, 0x8c, 0x2b, 0xac, 0x01, 0x14, 0x31, 0x92, 0x28, 0x6c, 0xcb, 0x9d, 0x52, 0x77,
+	0x32, 0xae, 0xf7, 0x83, 0x80, 0x84, 0x4b, 0xfd, 0x1b, 0x05, 0x50, 0x93, 0x5c, 0x24, 0xdd, 0x0d,
+	0xdd, 0x53, 0xd2, 0xdc, 0x53, 0xd3, 0xdd, 0xcb, 0x25, 0xdd, 0xb3, 0xfa, 0x23, 0xc9, 0xbd, 0xa0,
+	0x2f, 0x8a, 0x11, 0xe7, 0xdd, 0x5b, 0x4c, 0x71, 0x4f, 0xff, 0x46, 0x85, 0x4d, 0x29, 0x26, 0x87,
+	0x6e, 0xdf, 0x1e, 0xcc, 0x17, 0xcb, 0x2d, 0x58, 0xf4, 0x18, 0x53, 0x68, 0x1f, 0x5f, 0x30, 0xd7,
+	0x1d, 0x72, 0xd1, 0xb4, 0x46, 0xa1, 0x81, 0xe1, 0x92, 0x25, 0x8b, 0x43, 0x2e, 0x44, 0xee, 0x09,
+	0xe3, 0xa2, 0x35, 0xba, 0x0d, 0x45, 0x87, 0x5c, 0x1c, 0x0a, 0xc7, 0x84, 0x4d, 0x33, 0x02, 0xba,
+	0x0b, 0x2b, 0x0e, 0xb9, 0x88, 0x3c, 0x13, 0x21, 0x91, 0x49, 0x81, 0x7c, 0x9b, 0x7b, 0x11, 0xc4,
+	0x63, 0x46, 0x98, 0xed, 0xf6, 0x42, 0xf6, 0x6e, 0x2f, 0x5e, 0xb7, 0x3e, 0x7c, 0x2e, 0x63, 0x64,
+	0x13, 0x8f, 0x15, 0x0b, 0x54, 0x83, 0x35, 0x4f, 0x26, 0x96, 0x14, 0x7e, 0xfc, 0xfd, 0xb7, 0xac,
+	0x36, 0x35, 0xe1, 0x71, 0x5c, 0x4e, 0x1f, 0xc6, 0xd2, 0xa4, 0xe2, 0x3a, 0x03, 0x9b, 0x8e, 0xd2,
+	0xf6, 0x5f, 0x74, 0x2c, 0xaa, 0x89, 0x63, 0xb1, 0x0c, 0xda, 0x39, 0xa1, 0xf6, 0x80, 0xed, 0x62,
+	0x5e, 0xa4, 0xfb, 0x61, 0x44, 0xe6, 0xe8, 0x7a, 0x1b, 0x6e, 0xc4, 0x92, 0x32, 0x63, 0xbb, 0x47,
+	0x75, 0x4f, 0x7d, 0x5e, 0xdd, 0xd3, 0x7f, 0xa4, 0xc0, 0x46, 0x97, 0x97, 0x19, 0xa7, 0x4f, 0x9e,
+	0xf1, 0x2e, 0x8f, 0x85, 0x81, 0x77, 0x77, 0x81, 0x46, 0xb1, 0x40, 0xff, 0x0f, 0x05, 0x97, 0xf6,
+	0x09, 0xb5, 0x9d, 0x53, 0xae, 0x77, 0x7d, 0xe7, 0x96, 0xac, 0xf7, 0xd1, 0x84, 0xd0, 0x69, 0x2b,
+	0x60, 0xc0, 0x11, 0x2b, 0x3b, 0x5e, 0x86, 0x96, 0x73, 0x3a, 0xb1, 0x4e, 0x89, 0xe4, 0x5a, 0x8c,
+	0xa6, 0xd7, 0x40, 0x4b, 0xd8, 0xe0, 0xa1, 0x07, 0xb0, 0xc4, 0xbf, 0x1b, 0x86, 0x26, 0xd6, 0x61,
+	0x25, 0xb8, 0x71, 0xc0, 0xaa, 0xfb, 0xb0, 0xd6, 0xb1, 0x47, 0xe3, 0x21, 0xc9, 0x3a, 0x9e, 0xa2,
+	0x0c, 0x53, 0xb3, 0x33, 0x2c, 0x77, 0xdd, 0x0c, 0xfb, 0xb9, 0x02, 0x9b, 0x2c, 0xab, 0xb8, 0x55,
+	0x2f, 0xd4, 0x3a, 0x45, 0xe6, 0xe5, 0xb2, 0xcd, 0xcb, 0x5f, 0xd7, 0xbc, 0xaf, 0x15, 0x40, 0xb2,
+	0x79, 0xd7, 0x6f, 0x8e, 0x2a, 0xa0, 0x71, 0x33, 0xab, 0x64, 0x60, 0x3b, 0xb6, 0xcf, 0xfb, 0x05,
+	0xd1, 0x25, 0xbd, 0x32, 0x17, 0x95, 0x0a, 0x25, 0x96, 0x4f, 0xf0, 0x9c, 0x80, 0xfe, 0x27, 0x05,
+	0x36, 0x98, 0x19, 0x07, 0x64, 0x9a, 0x89, 0x51, 0x76, 0x5f, 0x9f, 0x44, 0x2f, 0x97, 0xde, 0x40,
+	0x8d, 0xc5, 0xef, 0x45, 0x70, 0x6e, 0x85, 0xcb, 0x19, 0xae, 0x8b, 0x32, 0xae, 0xb7, 0xa1, 0x38,
+	0xb6, 0x4e, 0x45, 0x1e, 0x05, 0x4d, 0xf9, 0x8c, 0xf0, 0x82, 0x8d, 0xb9, 0xfe, 0x13, 0x05, 0xb4,
+	0x99, 0xbb, 0xd7, 0xc7, 0xfc, 0x3d, 0x80, 0xb1, 0x75, 0x6a, 0x3b, 0x7c, 0xf3, 0xa7, 0x8d, 0x48,
+	0xda, 0xd1, 0xdb, 0xba, 0x33, 0x70, 0xb1, 0xc4, 0xcd, 0xaa, 0xd4, 0x53, 0x32, 0xf5, 0x4a, 0xb9,
+	0xbb, 0x39, 0x56, 0xa5, 0xd8, 0xb3, 0xfe, 0x47, 0x15, 0x56, 0xa4, 0x00, 0x5d, 0x03, 0xfa, 0xf7,
+	0x01, 0xfa, 0x96, 0x6f, 0xed, 0xb9, 0x74, 0x64, 0xf9, 0xc1, 0x0f, 0xd5, 0xfc, 0x6e, 0xac, 0x46,
+	0x2c, 0x58, 0x62, 0x67, 0x7d, 0xb5, 0xed, 0xf4, 0xed, 0x1e, 0xf1, 0x82, 0xc4, 0xbd, 0x7d, 0xc9,
+	0x3e, 0xf6, 0x70, 0xc8, 0x9c, 0x11, 0xb1, 0x37, 0x61, 0xdd, 0x1a, 0x0e, 0xdd, 0x0b, 0xde, 0x95,
+	0xb0, 0xee, 0x87, 0x87, 0xad, 0x80, 0x13, 0xd4, 0x17, 0xfd, 0xa9, 0x4a, 0xb4, 0xc6, 0x85, 0xeb,
+	0xb6, 0xc6, 0x7f, 0x57, 0x60, 0x8d, 0xbb, 0xd6, 0x9e, 0xf8, 0x87, 0x93, 0xa1, 0x6f, 0xbf, 0xe4,
+	0xff, 0x81, 0xdb, 0x50, 0x74, 0xcf, 0x09, 0xbd, 0xa0, 0xb6, 0x2f, 0xba, 0xa7, 0x02, 0x9e, 0x11,
+	0x32, 0xa0, 0x8b, 0x43, 0xb2, 0x74, 0x5d, 0x48, 0xca, 0xb0, 0x38, 0xb6, 0x6c, 0xea, 0x95, 0x96,
+	0x39, 0x18, 0x5b, 0xb2, 0x24, 0x0b, 0x7d, 0xdb, 0xb2, 0x29, 0x16, 0x2c, 0x6c, 0x70, 0x01, 0xdc,
+	0xfd, 0x5d, 0xcb, 0xef, 0x9d, 0x7d, 0x17, 0xfe, 0x85, 0xee, 0x43, 0x7e, 0x3c, 0xf1, 0xc3, 0x9f,
+	0xa0, 0x74, 0xe7, 0x38, 0x07, 0x33, 0xac, 0x4f, 0x86, 0xc4, 0x27, 0x02, 0x89, 0x22, 0x0e, 0x97,
+	0xfa, 0x2f, 0x55, 0x28, 0x84, 0x41, 0x7f, 0xc9, 0x3e, 0x6b, 0x90, 0x7b, 0x4a, 0xa6, 0xc1, 0xa1,
+	0xc6, 0x1e, 0x19, 0x0a, 0xe7, 0xd1, 0xe4, 0x68, 0x15, 0x8b, 0x45, 0x3c, 0x2f, 0x96, 0x32, 0xf3,
+	0x62, 0x39, 0x1b, 0xb9, 0xc2, 0x75, 0x91, 0x7b, 0x1b, 0x36, 0xc9, 0xb3, 0x31, 0xe9, 0xf9, 0xc1,
+	0xd4, 0x6a, 0xdf, 0xf2, 0xce, 0x78, 0x8f, 0xb6, 0x8a, 0xe7, 0x5f, 0xe8, 0xbf, 0x57, 0x02, 0x8c,
+	0x6a, 0xe4, 0xbb, 0x30, 0x5b, 0x78, 0x4e, 0x85, 0x08, 0x8f, 0xd9, 0x65, 0xe9, 0x98, 0x3d, 0x87,
+	0xf5, 0xf8, 0xc1, 0x1c, 0xd7, 0xa1, 0x24, 0x75, 0xdc, 0x83, 0x35, 0x87, 0x3c, 0xf3, 0xdb, 0x11,
+	0x87, 0x68, 0x4c, 0xe2, 0x44, 0xd6, 0x58, 0x9f, 0x59, 0x5e, 0x33, 0xa0, 0x71, 0xd7, 0x0b, 0x58,
+	0x26, 0xe9, 0x3b, 0x50, 0x08, 0x33, 0x35, 0x4c, 0x17, 0x25, 0x25, 0x5d, 0x54, 0x29, 0x5d, 0xf4,
+	0x5f, 0x29, 0x41, 0xdf, 0x55, 0x23, 0xfe, 0xbf, 0xbb, 0x44, 0xdd, 0x87, 0x3c, 0xab, 0x12, 0xa5,
+	0xdc, 0x65, 0x3b, 0x8e, 0x71, 0xe8, 0xbf, 0x51, 0xe0, 0x15, 0x6e, 0x65, 0x67, 0x72, 0xf2, 0x25,
+	0x6b, 0x32, 0x2b, 0xee, 0x68, 0x6c, 0x51, 0xdb, 0x73, 0x1d, 0xf4, 0x16, 0xa8, 0xee, 0xb8, 0xa4,
+	0x64, 0x75, 0xa3, 0x63, 0x42, 0x2d, 0xdf, 0xa5, 0x58, 0x75, 0xc7, 0xb3, 0xa6, 0x56, 0x95, 0x9b,
+	0xda, 0x08, 0x98, 0xe0, 0xd7, 0x94, 0x2f, 0x62, 0xad, 0x6e, 0xfe, 0xca, 0xad, 0xae, 0xfe, 0xcf,
+	0xf0, 0xdc, 0xe3, 0x0c, 0x2f, 0x39, 0xbf, 0xff, 0x17, 0x16, 0xb9, 0xf7, 0x41, 0x19, 0xbd, 0x35,
+	0x57, 0x46, 0x3b, 0x93, 0x13, 0xfe, 0x5d, 0x2c, 0xf8, 0xfe, 0x13, 0x3d, 0xcf, 0xef, 0x54, 0x58,
+	0x8b, 0xd9, 0xc2, 0x7e, 0xc0, 0x6d, 0x8f, 0x05, 0xcc, 0x9d, 0x38, 0x02, 0x8b, 0x02, 0x96, 0x28,
+	0xa8, 0x01, 0x5a, 0x2f, 0x78, 0x0e, 0xa3, 0x15, 0xfc, 0x5c, 0xdc, 0x9d, 0x43, 0x9c, 0xcd, 0x63,
+	0x7a, 0xd6, 0x30, 0x8a, 0xea, 0x9c, 0x24, 0xfa, 0x00, 0x56, 0x43, 0x5a, 0x83, 0x0c, 0xc2, 0x11,
+	0xf4, 0x25, 0x50, 0xc5, 0xd8, 0xd1, 0x47, 0xb0, 0x16, 0xae, 0xb1, 0x7d, 0x7a, 0xe6, 0x3f, 0x1f,
+	0xea, 0x38, 0x3f, 0xaa, 0x00, 0xf4, 0xa2, 0xe4, 0x0c, 0x4e, 0x9b, 0x37, 0xd2, 0xa4, 0x13, 0x79,
+	0x8c, 0x25, 0x31, 0xfd, 0xa7, 0x2a, 0xdc, 0xaa, 0xf2, 0x9a, 0xd2, 0x99, 0x7a, 0x3e, 0x19, 0xb5,
+	0x4e, 0xbe, 0x20, 0x3d, 0x3f, 0xab, 0x63, 0xfe, 0x3e, 0x80, 0xcb, 0x19, 0xf8, 0x30, 0x4b, 0x4d,
+	0x19, 0x66, 0x49, 0x4a, 0x18, 0x0f, 0x96, 0xf8, 0xaf, 0xda, 0x55, 0x87, 0x29, 0x99, 0x8f, 0xa7,
+	0xe4, 0x36, 0x14, 0x84, 0xae, 0x7a, 0x38, 0x9f, 0x88, 0xd6, 0xdf, 0xca, 0xb0, 0xbb, 0xfc, 0x6e,
+	0xf0, 0xaf, 0x3a, 0xeb, 0x35, 0x11, 0xc0, 0xd2, 0x6e, 0xbd, 0x69, 0xe0, 0x27, 0xda, 0x02, 0x5a,
+	0x07, 0x68, 0x37, 0x8c, 0x7a, 0xf3, 0xb8, 0x6b, 0x7e, 0xda, 0xd5, 0x14, 0x54, 0x80, 0xfc, 0x27,
+	0x9d, 0x56, 0x53, 0x53, 0xcb, 0x0f, 0xa1, 0x94, 0x75, 0x1f, 0x82, 0x8a, 0xb0, 0xb8, 0x57, 0x37,
+	0x1b, 0x55, 0x6d, 0x01, 0x6d, 0xc0, 0xca, 0x81, 0xf9, 0xe4, 0xb8, 0x6d, 0x74, 0xbb, 0x26, 0x6e,
+	0x6a, 0x4a, 0xf9, 0x21, 0xdc, 0x48, 0xb9, 0x63, 0x42, 0x1a, 0xac, 0x9a, 0x8f, 0x8e, 0x8c, 0x46,
+	0xe7, 0xf8, 0xb1, 0xd1, 0x38, 0x32, 0xb5, 0x05, 0xb4, 0x06, 0xc5, 0x8e, 0xd9, 0x0d, 0x96, 0x4a,
+	0xf9, 0x18, 0x4a, 0x59, 0xf7, 0x40, 0x68, 0x15, 0x0a, 0x95, 0x56, 0xb3, 0xd3, 0x35, 0x9a, 0x5d,
+	0x6d, 0x01, 0x2d, 0x43, 0xee, 0xa8, 0x5e, 0xd5, 0x14, 0xa6, 0xa1, 0x5b, 0x3f, 0x34, 0x3b, 0x5d,
+	0xe3, 0xb0, 0xad, 0xa9, 0x68, 0x13, 0xd6, 0x9a, 0x46, 0xf7, 0x08, 0x1b, 0x8d, 0xe3, 0x16, 0xae,
+	0x9a, 0x58, 0xcb, 0x31, 0x57, 0xb1, 0xd1, 0xac, 0xb6, 0x0e, 0xb5, 0x7c, 0xf9, 0x43, 0xb8, 0x99,
+	0x7e, 0x8d, 0xc1, 0x40, 0xa8, 0x37, 0xab, 0xf5, 0xc7, 0xf5, 0xea, 0x91, 0xd1, 0xd0, 0x16, 0x18,
+	0x08, 0x47, 0x1d, 0x13, 0x6b, 0x0a, 0x93, 0x6f, 0x1f, 0xed, 0x36, 0xea, 0x15, 0x4d, 0x2d, 0x1f,
+	0xc2, 0x46, 0xe2, 0x96, 0x82, 0x31, 0x36, 0x5b, 0x4d, 0x53, 0x88, 0x60, 0xd3, 0xa8, 0x0a, 0x11,
+	0xa3, 0xdd, 0x36, 0x9b, 0x55, 0x4d, 0x65, 0xcf, 0x55, 0xb3, 0x61, 0x76, 0x4d, 0x2d, 0xc7, 0x3e,
+	0x62, 0x54, 0x0f, 0xeb, 0xcd, 0x7a, 0xa7, 0x6b, 0x62, 0x2d, 0x5f, 0x7e, 0x00, 0x37, 0x52, 0xee,
+	0x7b, 0x39, 0x2a, 0xf5, 0x1a, 0xf7, 0x23, 0x00, 0xe9, 0x93, 0x1f, 0x74, 0x8f, 0xbb, 0xad, 0x03,
+	0x93, 0x81, 0xfb, 0xb5, 0x02, 0x6b, 0xb1, 0x73, 0x9a, 0x7d, 0x42, 0xe0, 0xaa, 0x2d, 0x30, 0x8c,
+	0x6b, 0xd8, 0x34, 0xba, 0x26, 0x3e, 0xee, 0xee, 0x1b, 0x4d, 0x81, 0x50, 0xc3, 0xec, 0x74, 0xc4,
+	0x52, 0x65, 0x36, 0x34, 0x5b, 0xdd, 0xe3, 0x40, 0x20, 0x87, 0x6e, 0xc0, 0x86, 0x2c, 0x70, 0x6c,
+	0x3e, 0xd2, 0xf2, 0x4c, 0x4b, 0x24, 0xc3, 0x28, 0x8b, 0x2c, 0xc6, 0x2c, 0x3d, 0x8e, 0x3b, 0xa6,
+	0x81, 0x2b, 0xfb, 0xda, 0x52, 0xf9, 0x5d, 0xd8, 0x4a, 0x3b, 0x5e, 0x98, 0xfe, 0x46, 0xab, 0x56,
+	0xaf, 0xf0, 0x08, 0x88, 0xe4, 0x08, 0xd7, 0x46, 0xb3, 0xaa, 0x29, 0xe5, 0x27, 0xa1, 0xf9, 0xe1,
+	0xa8, 0x63, 0x13, 0xd6, 0x1a, 0xe6, 0xa7, 0xf5, 0x4a, 0xab, 0x86, 0x8d, 0xf6, 0x7e, 0xbd, 0x22,
+	0xbc, 0xc0, 0xa6, 0xd1, 0x38, 0x6e, 0x1e, 0x1d, 0xee, 0x9a, 0xb8, 0xa3, 0x29, 0x68, 0x0b, 0xb4,
+	0x7a, 0xb3, 0x6b, 0xd6, 0xb0, 0x44, 0x55, 0x99, 0x6f, 0x7b, 0x47, 0x8d, 0x86, 0xc8, 0xdc, 0x5c,
+	0xf9, 0x01, 0x68, 0xc9, 0x7d, 0x8b, 0x56, 0x60, 0xb9, 0x73, 0xb4, 0xcb, 0x63, 0xb9, 0xc0, 0x92,
+	0xb6, 0x6b, 0xec, 0x36, 0x4c, 0x4d, 0x61, 0x19, 0x74, 0x60, 0x3e, 0xd1, 0xd4, 0xf2, 0x2e, 0x68,
+	0xc9, 0xc9, 0x35, 0x43, 0x74, 0xdf, 0x68, 0x56, 0x1b, 0xa6, 0x70, 0xa0, 0xbb, 0x5f, 0xc7, 0xd5,
+	0xe3, 0xb6, 0x81, 0xbb, 0x4f, 0x34, 0x85, 0xd9, 0xcb, 0x63, 0x71, 0x8c, 0xcd, 0x3d, 0x6c, 0x76,
+	0xf6, 0x35, 0x75, 0xe7, 0x17, 0xab, 0xa0, 0x7d, 0x46, 0x7c, 0xeb, 0xc4, 0xf2, 0x48, 0x9b, 0xba,
+	0xe7, 0x76, 0x9f, 0x50, 0xf4, 0x31, 0xac, 0x04, 0x57, 0xd2, 0xbc, 0xa1, 0x49, 0xb6, 0x04, 0xec,
+	0xbe, 0x7d, 0x3b, 0x56, 0xf7, 0xe3, 0xf7, 0xe7, 0xfa, 0x02, 0xaa, 0xc3, 0xa6, 0x18, 0x4e, 0x4a,
+	0x03, 0x35, 0xf4, 0x5a, 0x62, 0xe6, 0x1e, 0x1f, 0x63, 0x6e, 0xa7, 0x75, 0x1e, 0xfa, 0x02, 0x6a,
+	0x89, 0x59, 0x4b, 0x6c, 0xa4, 0x87, 0x62, 0x47, 0x74, 0x6c, 0x02, 0xb4, 0x9d, 0xf1, 0x95, 0x60,
+	0x10, 0xa8, 0x2f, 0xa0, 0x4f, 0xe1, 0x06, 0x26, 0xa7, 0xb6, 0xe7, 0x13, 0x2a, 0xcd, 0xd6, 0xd0,
+	0x9d, 0xc4, 0x28, 0x30, 0x31, 0x07, 0xdc, 0x7e, 0x3d, 0xf3, 0xbd, 0x68, 0xa4, 0xf4, 0x05, 0x74,
+	0x00, 0x28, 0x18, 0x08, 0x5e, 0x45, 0x71, 0xc0, 0x9a, 0xe5, 0xf7, 0x43, 0x58, 0x6e, 0x4f, 0x7c,
+	0x76, 0xf2, 0xa1, 0xad, 0xb9, 0x92, 0xd2, 0x9e, 0xf8, 0x59, 0x72, 0x1f, 0xc3, 0x6a, 0x20, 0x27,
+	0xfe, 0x44, 0x6f, 0xa5, 0x09, 0xf3, 0x57, 0x59, 0x1a, 0xde, 0x07, 0x30, 0xc6, 0xe3, 0xe1, 0x54,
+	0xfc, 0xcd, 0xdd, 0x9c, 0x93, 0xe7, 0xf4, 0x2c, 0xe1, 0x0f, 0x60, 0x45, 0x0c, 0x1c, 0x38, 0x2b,
+	0xca, 0x9a, 0x17, 0x65, 0x89, 0x7f, 0x04, 0xcb, 0x35, 0x92, 0xe5, 0x75, 0x8d, 0xf8, 0xdb, 0xb7,
+	0xd3, 0xa8, 0x52, 0x0c, 0x2a, 0x50, 0xe4, 0x9b, 0x94, 0xab, 0x98, 0xb7, 0x9d, 0xbf, 0xbb, 0xb2,
+	0x12, 0x36, 0xcb, 0xb9, 0x9a, 0x92, 0xe4, 0xe4, 0x47, 0x5f, 0x40, 0x18, 0x40, 0xf8, 0xca, 0x2f,
+	0xca, 0x9e, 0x3f, 0x69, 0xbe, 0x4a, 0x86, 0x55, 0x61, 0xad, 0x43, 0xa4, 0xab, 0x4f, 0x74, 0xe9,
+	0x90, 0x22, 0x0b, 0xe4, 0x1a, 0x68, 0x98, 0x9c, 0xbb, 0x4f, 0xc9, 0x8b, 0x2a, 0xfa, 0x5c, 0x4c,
+	0xf8, 0x24, 0x76, 0xa4, 0x27, 0x51, 0x99, 0xbf, 0x64, 0xde, 0x7e, 0xe3, 0x52, 0x1e, 0x79, 0x3b,
+	0x05, 0xd7, 0xab, 0xf2, 0x07, 0xee, 0x64, 0x18, 0x1a, 0xb0, 0x66, 0x99, 0xfa, 0x08, 0x8a, 0xfc,
+	0x94, 0xe4, 0xc1, 0x88, 0x39, 0x9b, 0xbc, 0x54, 0xdb, 0xbe, 0x77, 0xd9, 0x5b, 0xc9, 0xbe, 0x43,
+	0x80, 0xd9, 0x98, 0x35, 0x7e, 0xba, 0xcd, 0x4d, 0x87, 0xb7, 0xef, 0x64, 0xbd, 0x8e, 0xd4, 0xd5,
+	0xa0, 0x10, 0x66, 0x11, 0x7a, 0x35, 0x3d, 0xb7, 0x84, 0xaa, 0xe7, 0x25, 0x5e, 0x03, 0x56, 0x45,
+	0x3f, 0x29, 0x8a, 0x09, 0xfa, 0x9f, 0xd8, 0xcf, 0x56, 0x56, 0xa7, 0x99, 0x01, 0xdc, 0xae, 0x0e,
+	0xab, 0x2e, 0x3d, 0x7d, 0xe7, 0xab, 0xa0, 0x48, 0xec, 0xca, 0xe5, 0x82, 0xf3, 0xb6, 0x95, 0x93,
+	0x25, 0x2e, 0xf7, 0xe0, 0x5f, 0x03, 0x00, 0xf9, 0x41, 0x9a, 0xd3, 0x85, 0x26, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -2936,12 +3243,16 @@ type ZetabaseProviderClient interface {
 	ConfirmNewIdentity(ctx context.Context, in *NewIdentityConfirm, opts ...grpc.CallOption) (*ZbError, error)
 	PutData(ctx context.Context, in *TablePut, opts ...grpc.CallOption) (*ZbError, error)
 	PutDataMulti(ctx context.Context, in *TablePutMulti, opts ...grpc.CallOption) (*ZbError, error)
+	ApplyBatch(ctx context.Context, in *TableBatch, opts ...grpc.CallOption) (*ZbError, error)
 	CreateTable(ctx context.Context, in *TableCreate, opts ...grpc.CallOption) (*ZbError, error)
 	GetData(ctx context.Context, in *TableGet, opts ...grpc.CallOption) (*TableGetResponse, error)
 	QueryData(ctx context.Context, in *TableQuery, opts ...grpc.CallOption) (*TableGetResponse, error)
 	QueryKeys(ctx context.Context, in *TableQuery, opts ...grpc.CallOption) (*ListKeysResponse, error)
 	CreateUser(ctx context.Context, in *NewSubIdentityRequest, opts ...grpc.CallOption) (*NewIdentityResponse, error)
 	SetPermission(ctx context.Context, in *PermissionsEntry, opts ...grpc.CallOption) (*ZbError, error)
+	RevokePermission(ctx context.Context, in *PermissionsEntry, opts ...grpc.CallOption) (*ZbError, error)
+	ListPermissions(ctx context.Context, in *ListPermissionsRequest, opts ...grpc.CallOption) (*ListPermissionsResponse, error)
+	ReplacePermissions(ctx context.Context, in *PermissionsReplace, opts ...grpc.CallOption) (*ZbError, error)
 	LoginUser(ctx context.Context, in *AuthenticateUser, opts ...grpc.CallOption) (*AuthenticateUserResponse, error)
 	ListTables(ctx context.Context, in *ListTablesRequest, opts ...grpc.CallOption) (*ListTablesResponse, error)
 	ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error)
@@ -3019,6 +3330,15 @@ func (c *zetabaseProviderClient) PutDataMulti(ctx context.Context, in *TablePutM
 	return out, nil
 }
 
+func (c *zetabaseProviderClient) ApplyBatch(ctx context.Context, in *TableBatch, opts ...grpc.CallOption) (*ZbError, error) {
+	out := new(ZbError)
+	err := c.cc.Invoke(ctx, "/zbprotocol.ZetabaseProvider/ApplyBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *zetabaseProviderClient) CreateTable(ctx context.Context, in *TableCreate, opts ...grpc.CallOption) (*ZbError, error) {
 	out := new(ZbError)
 	err := c.cc.Invoke(ctx, "/zbprotocol.ZetabaseProvider/CreateTable", in, out, opts...)
@@ -3073,6 +3393,33 @@ func (c *zetabaseProviderClient) SetPermission(ctx context.Context, in *Permissi
 	return out, nil
 }
 
+func (c *zetabaseProviderClient) RevokePermission(ctx context.Context, in *PermissionsEntry, opts ...grpc.CallOption) (*ZbError, error) {
+	out := new(ZbError)
+	err := c.cc.Invoke(ctx, "/zbprotocol.ZetabaseProvider/RevokePermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *zetabaseProviderClient) ListPermissions(ctx context.Context, in *ListPermissionsRequest, opts ...grpc.CallOption) (*ListPermissionsResponse, error) {
+	out := new(ListPermissionsResponse)
+	err := c.cc.Invoke(ctx, "/zbprotocol.ZetabaseProvider/ListPermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *zetabaseProviderClient) ReplacePermissions(ctx context.Context, in *PermissionsReplace, opts ...grpc.CallOption) (*ZbError, error) {
+	out := new(ZbError)
+	err := c.cc.Invoke(ctx, "/zbprotocol.ZetabaseProvider/ReplacePermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *zetabaseProviderClient) LoginUser(ctx context.Context, in *AuthenticateUser, opts ...grpc.CallOption) (*AuthenticateUserResponse, error) {
 	out := new(AuthenticateUserResponse)
 	err := c.cc.Invoke(ctx, "/zbprotocol.ZetabaseProvider/LoginUser", in, out, opts...)
@@ -3118,12 +3465,16 @@ type ZetabaseProviderServer interface {
 	ConfirmNewIdentity(context.Context, *NewIdentityConfirm) (*ZbError, error)
 	PutData(context.Context, *TablePut) (*ZbError, error)
 	PutDataMulti(context.Context, *TablePutMulti) (*ZbError, error)
+	ApplyBatch(context.Context, *TableBatch) (*ZbError, error)
 	CreateTable(context.Context, *TableCreate) (*ZbError, error)
 	GetData(context.Context, *TableGet) (*TableGetResponse, error)
 	QueryData(context.Context, *TableQuery) (*TableGetResponse, error)
 	QueryKeys(context.Context, *TableQuery) (*ListKeysResponse, error)
 	CreateUser(context.Context, *NewSubIdentityRequest) (*NewIdentityResponse, error)
 	SetPermission(context.Context, *PermissionsEntry) (*ZbError, error)
+	RevokePermission(context.Context, *PermissionsEntry) (*ZbError, error)
+	ListPermissions(context.Context, *ListPermissionsRequest) (*ListPermissionsResponse, error)
+	ReplacePermissions(context.Context, *PermissionsReplace) (*ZbError, error)
 	LoginUser(context.Context, *AuthenticateUser) (*AuthenticateUserResponse, error)
 	ListTables(context.Context, *ListTablesRequest) (*ListTablesResponse, error)
 	ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error)
@@ -3155,6 +3506,9 @@ func (*UnimplementedZetabaseProviderServer) PutData(ctx context.Context, req *Ta
 func (*UnimplementedZetabaseProviderServer) PutDataMulti(ctx context.Context, req *TablePutMulti) (*ZbError, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutDataMulti not implemented")
 }
+func (*UnimplementedZetabaseProviderServer) ApplyBatch(ctx context.Context, req *TableBatch) (*ZbError, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyBatch not implemented")
+}
 func (*UnimplementedZetabaseProviderServer) CreateTable(ctx context.Context, req *TableCreate) (*ZbError, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateTable not implemented")
 }
@@ -3173,6 +3527,15 @@ func (*UnimplementedZetabaseProviderServer) CreateUser(ctx context.Context, req
 func (*UnimplementedZetabaseProviderServer) SetPermission(ctx context.Context, req *PermissionsEntry) (*ZbError, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetPermission not implemented")
 }
+func (*UnimplementedZetabaseProviderServer) RevokePermission(ctx context.Context, req *PermissionsEntry) (*ZbError, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokePermission not implemented")
+}
+func (*UnimplementedZetabaseProviderServer) ListPermissions(ctx context.Context, req *ListPermissionsRequest) (*ListPermissionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPermissions not implemented")
+}
+func (*UnimplementedZetabaseProviderServer) ReplacePermissions(ctx context.Context, req *PermissionsReplace) (*ZbError, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplacePermissions not implemented")
+}
 func (*UnimplementedZetabaseProviderServer) LoginUser(ctx context.Context, req *AuthenticateUser) (*AuthenticateUserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LoginUser not implemented")
 }
@@ -3316,6 +3679,24 @@ func _ZetabaseProvider_PutDataMulti_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ZetabaseProvider_ApplyBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TableBatch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZetabaseProviderServer).ApplyBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/zbprotocol.ZetabaseProvider/ApplyBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZetabaseProviderServer).ApplyBatch(ctx, req.(*TableBatch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ZetabaseProvider_CreateTable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(TableCreate)
 	if err := dec(in); err != nil {
@@ -3424,6 +3805,60 @@ func _ZetabaseProvider_SetPermission_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ZetabaseProvider_RevokePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PermissionsEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZetabaseProviderServer).RevokePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/zbprotocol.ZetabaseProvider/RevokePermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZetabaseProviderServer).RevokePermission(ctx, req.(*PermissionsEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ZetabaseProvider_ListPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPermissionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZetabaseProviderServer).ListPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/zbprotocol.ZetabaseProvider/ListPermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZetabaseProviderServer).ListPermissions(ctx, req.(*ListPermissionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ZetabaseProvider_ReplacePermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PermissionsReplace)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZetabaseProviderServer).ReplacePermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/zbprotocol.ZetabaseProvider/ReplacePermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZetabaseProviderServer).ReplacePermissions(ctx, req.(*PermissionsReplace))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ZetabaseProvider_LoginUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AuthenticateUser)
 	if err := dec(in); err != nil {
@@ -3528,6 +3963,10 @@ var _ZetabaseProvider_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PutDataMulti",
 			Handler:    _ZetabaseProvider_PutDataMulti_Handler,
 		},
+		{
+			MethodName: "ApplyBatch",
+			Handler:    _ZetabaseProvider_ApplyBatch_Handler,
+		},
 		{
 			MethodName: "CreateTable",
 			Handler:    _ZetabaseProvider_CreateTable_Handler,
@@ -3552,6 +3991,18 @@ var _ZetabaseProvider_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetPermission",
 			Handler:    _ZetabaseProvider_SetPermission_Handler,
 		},
+		{
+			MethodName: "RevokePermission",
+			Handler:    _ZetabaseProvider_RevokePermission_Handler,
+		},
+		{
+			MethodName: "ListPermissions",
+			Handler:    _ZetabaseProvider_ListPermissions_Handler,
+		},
+		{
+			MethodName: "ReplacePermissions",
+			Handler:    _ZetabaseProvider_ReplacePermissions_Handler,
+		},
 		{
 			MethodName: "LoginUser",
 			Handler:    _ZetabaseProvider_LoginUser_Handler,